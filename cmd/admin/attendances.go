@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+)
+
+// attendances list
+func attendancesListCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	userID := fs.String("user", "", "only attendances of this user ID")
+	month := fs.String("month", "", "only attendances of this month (YYYY-MM, JST)")
+	return func(ctx context.Context, c *cli, args []string) error {
+		var from, to time.Time
+		if *month != "" {
+			m, err := parseMonth(*month)
+			if err != nil {
+				return err
+			}
+			from, to = m, m.AddDate(0, 1, 0)
+		}
+		var list []entity.Attendance
+		var err error
+		if *userID != "" {
+			list, err = c.app.Attendance.FindByUserID(ctx, *userID)
+		} else {
+			list, err = c.app.Attendance.FindAll(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		filtered := make([]entity.Attendance, 0, len(list))
+		for _, a := range list {
+			if !from.IsZero() && (a.Date.Before(from) || !a.Date.Before(to)) {
+				continue
+			}
+			filtered = append(filtered, a)
+		}
+		sort.SliceStable(filtered, func(i, j int) bool {
+			if !filtered[i].Date.Equal(filtered[j].Date) {
+				return filtered[i].Date.Before(filtered[j].Date)
+			}
+			return filtered[i].UserID < filtered[j].UserID
+		})
+		return c.printAttendances(filtered)
+	}
+}
+
+// attendances correct
+// PATCH /attendances/{id} と同じく訂正・取消イベントを追記して勤怠を組み立て直す
+func attendancesCorrectCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	checkIn := fs.String("check-in", "", "new check-in time (HH:MM on the work date, or RFC 3339)")
+	checkOut := fs.String("check-out", "", "new check-out time (HH:MM on the work date, or RFC 3339)")
+	clearCheckOut := fs.Bool("clear-check-out", false, "void the check-out")
+	version := fs.Int64("version", 0, "expected version of the attendance (default: current version)")
+	return func(ctx context.Context, c *cli, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("%w: attendance ID is required", errUsage)
+		}
+		if *checkIn == "" && *checkOut == "" && !*clearCheckOut {
+			return fmt.Errorf("%w: -check-in, -check-out or -clear-check-out is required", errUsage)
+		}
+		if *checkOut != "" && *clearCheckOut {
+			return fmt.Errorf("%w: -check-out and -clear-check-out cannot be used together", errUsage)
+		}
+		var corrected *entity.Attendance
+		err := c.mutate(ctx, func(ctx context.Context) error {
+			current, err := c.app.Attendance.FindByID(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			a := *current
+			if *version != 0 {
+				a.Version = *version
+			}
+			if *checkIn != "" {
+				if a.CheckIn, err = parsePunchTime(*checkIn, current.Date); err != nil {
+					return err
+				}
+			}
+			if *checkOut != "" {
+				if a.CheckOut, err = parsePunchTime(*checkOut, current.Date); err != nil {
+					return err
+				}
+			}
+			if *clearCheckOut {
+				a.CheckOut = time.Time{}
+			}
+			if err := c.app.Attendance.Update(ctx, a); err != nil {
+				return err
+			}
+			corrected, err = c.app.Attendance.FindByID(ctx, a.ID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return c.printAttendances([]entity.Attendance{*corrected})
+	}
+}
+
+// monthSummary はユーザーの1か月の勤怠の集計
+type monthSummary struct {
+	UserID      string `json:"user_id"`
+	Month       string `json:"month"`
+	Days        int    `json:"days"`
+	WorkMinutes int    `json:"work_minutes"`
+	Late        int    `json:"late"`
+	Incomplete  int    `json:"incomplete"`
+	NeedsReview int    `json:"needs_review"`
+}
+
+// attendances recompute
+// 打刻イベントから月の勤怠を組み立て直し、ユーザーごとに集計する（締めた月は組み立て直せない）
+func attendancesRecomputeCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	month := fs.String("month", "", "month to rebuild (YYYY-MM, JST, required)")
+	userID := fs.String("user", "", "only this user ID (default: all active users)")
+	return func(ctx context.Context, c *cli, args []string) error {
+		if *month == "" {
+			return fmt.Errorf("%w: -month is required", errUsage)
+		}
+		m, err := parseMonth(*month)
+		if err != nil {
+			return err
+		}
+		userIDs := []string{*userID}
+		if *userID == "" {
+			users, err := c.app.Users.FindAllUser(ctx)
+			if err != nil {
+				return err
+			}
+			userIDs = userIDs[:0]
+			for _, u := range users {
+				userIDs = append(userIDs, u.ID)
+			}
+		}
+		summaries := make([]monthSummary, 0, len(userIDs))
+		err = c.mutate(ctx, func(ctx context.Context) error {
+			for _, id := range userIDs {
+				list, err := c.app.Attendance.Rebuild(ctx, id, m)
+				if err != nil {
+					return fmt.Errorf("user %s: %w", id, err)
+				}
+				summaries = append(summaries, summarize(id, *month, list))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(summaries))
+		for _, s := range summaries {
+			rows = append(rows, []string{s.UserID, s.Month, strconv.Itoa(s.Days), formatMinutes(s.WorkMinutes), strconv.Itoa(s.Late), strconv.Itoa(s.Incomplete), strconv.Itoa(s.NeedsReview)})
+		}
+		return c.out.print(summaries, []string{"USER_ID", "MONTH", "DAYS", "WORKED", "LATE", "INCOMPLETE", "NEEDS_REVIEW"}, rows)
+	}
+}
+
+// attendances backfill
+// 打刻ログ導入前に作られた勤怠に出勤・退勤のイベントを補う（補った勤怠は組み立て直しの対象になる）
+func attendancesBackfillCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		var n int
+		err := c.mutate(ctx, func(ctx context.Context) error {
+			var err error
+			n, err = c.app.Attendance.BackfillPunchLog(ctx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return c.out.print(map[string]int{"backfilled": n}, []string{"BACKFILLED"}, [][]string{{strconv.Itoa(n)}})
+	}
+}
+
+// summarize は勤怠を集計する（退勤していない日は勤務時間に含めない）
+func summarize(userID, month string, list []entity.Attendance) monthSummary {
+	s := monthSummary{UserID: userID, Month: month}
+	for _, a := range list {
+		if a.CheckIn.IsZero() {
+			continue
+		}
+		s.Days++
+		if !a.CheckOut.IsZero() {
+			if d := int(a.CheckOut.Sub(a.CheckIn).Minutes()) - a.BreakMinutes; d > 0 {
+				s.WorkMinutes += d
+			}
+		}
+		if a.IsLate {
+			s.Late++
+		}
+		if a.Incomplete {
+			s.Incomplete++
+		}
+		if a.NeedsReview {
+			s.NeedsReview++
+		}
+	}
+	return s
+}
+
+func (c *cli) printAttendances(list []entity.Attendance) error {
+	rows := make([][]string, 0, len(list))
+	for _, a := range list {
+		rows = append(rows, []string{
+			a.ID, a.UserID, formatDate(a.Date), formatTime(a.CheckIn), formatTime(a.CheckOut),
+			strconv.Itoa(a.BreakMinutes), formatBool(a.IsLate), formatBool(a.Incomplete), formatBool(a.NeedsReview),
+			strconv.FormatInt(a.Version, 10),
+		})
+	}
+	return c.out.print(list, []string{"ID", "USER_ID", "DATE", "CHECK_IN", "CHECK_OUT", "BREAK_MIN", "LATE", "INCOMPLETE", "NEEDS_REVIEW", "VERSION"}, rows)
+}
+
+// parseMonth は YYYY-MM を日本時間の月初に変換する
+func parseMonth(s string) (time.Time, error) {
+	m, err := time.ParseInLocation("2006-01", s, domain.JST)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: month must be YYYY-MM", errUsage)
+	}
+	return m, nil
+}
+
+// parsePunchTime は打刻時刻を解釈する
+// HH:MM は勤務日 date の日本時間とし、それ以外は RFC 3339 とする
+func parsePunchTime(s string, date time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("15:04", s, domain.JST); err == nil {
+		y, m, d := date.In(domain.JST).Date()
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, domain.JST), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: time %q must be HH:MM or RFC 3339", errUsage, s)
+	}
+	return t, nil
+}
+
+// formatMinutes は分を H:MM で表示する
+func formatMinutes(m int) string {
+	return fmt.Sprintf("%d:%02d", m/60, m%60)
+}
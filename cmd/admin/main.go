@@ -0,0 +1,231 @@
+// admin は運用のための管理用CLI
+// サーバーと同じユースケース（Wireで組み立てる）を使うため、監査ログ・打刻ログ・ドメインイベントも記録される
+//
+// 使い方:
+//
+//	admin [-db app.db] [-o table|json] [-dry-run] [-actor USER_ID] [-reason TEXT] <command> [args]
+//
+// -dry-run は変更を1つのトランザクションで実行してからロールバックし、結果だけを表示する
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/audit"
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/logging"
+	"github.com/enkazu1116/go_home/internal/wire"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// globals はどのコマンドでも使えるフラグ
+type globals struct {
+	db     string
+	output string
+	dryRun bool
+	actor  string
+	reason string
+}
+
+// register はフラグを fs に登録する（コマンド名の前後どちらにも書けるようにする）
+// 既定値は g の現在の値なので、コマンド名の前で指定した値はコマンド名の後で上書きしない限り残る
+func (g *globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.db, "db", g.db, "SQLite database file")
+	fs.StringVar(&g.output, "o", g.output, "output format: table or json")
+	fs.BoolVar(&g.dryRun, "dry-run", g.dryRun, "run changes in a transaction and roll them back")
+	fs.StringVar(&g.actor, "actor", g.actor, "user ID recorded as the actor in audit logs")
+	fs.StringVar(&g.reason, "reason", g.reason, "reason recorded in audit logs")
+}
+
+// command はサブコマンド
+type command struct {
+	name    string
+	args    string
+	summary string
+	// setup はコマンドのフラグを登録し、実行する関数を返す
+	setup func(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error
+}
+
+// commands はサブコマンドの一覧
+var commands = []command{
+	{"migrate", "", "apply pending migrations (with -dry-run, only list them)", migrateCommand},
+	{"users list", "", "list users", usersListCommand},
+	{"users create", "", "create a user", usersCreateCommand},
+	{"users import", "FILE", "create users from a JSON array (- for stdin) in one transaction", usersImportCommand},
+	{"users import-csv", "FILE", "create or update users from a CSV file (- for stdin)", usersImportCSVCommand},
+	{"users deactivate", "ID...", "soft-delete users", usersDeactivateCommand},
+	{"attendances list", "", "list attendances", attendancesListCommand},
+	{"attendances correct", "ID", "correct check-in/check-out of an attendance", attendancesCorrectCommand},
+	{"attendances recompute", "", "rebuild a month of attendances from punch events and summarize them", attendancesRecomputeCommand},
+	{"attendances backfill", "", "add punch events for attendances created before the punch log (run once after upgrading)", attendancesBackfillCommand},
+	{"periods list", "", "list closed periods", periodsListCommand},
+	{"periods close", "YYYY-MM", "close a month", periodsCloseCommand},
+	{"periods reopen", "YYYY-MM", "reopen a closed month", periodsReopenCommand},
+}
+
+// errUsage は使い方の誤り（終了コード 2）
+var errUsage = errors.New("usage error")
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run はCLIを実行して終了コードを返す
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	// ログは標準エラー出力に出し、結果の出力と混ぜない
+	logger := logging.NewWithWriter(logging.NewConfigFromEnv(), stderr)
+	slog.SetDefault(logger)
+
+	g := globals{db: "app.db", output: "table"}
+	root := flag.NewFlagSet("admin", flag.ContinueOnError)
+	root.SetOutput(stderr)
+	g.register(root)
+	root.Usage = func() { printUsage(stderr, root) }
+	if err := root.Parse(args); err != nil {
+		return 2
+	}
+
+	cmd, rest := findCommand(root.Args())
+	if cmd == nil {
+		printUsage(stderr, root)
+		return 2
+	}
+	fs := flag.NewFlagSet("admin "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	g.register(fs)
+	exec := cmd.setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: admin %s [flags] %s\n\n%s\n\nflags:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(rest); err != nil {
+		return 2
+	}
+	if g.output != "table" && g.output != "json" {
+		fmt.Fprintf(stderr, "unknown output format %q (table or json)\n", g.output)
+		return 2
+	}
+
+	db, err := dbinfra.OpenSQLite(g.db, logger)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	c, err := newCLI(db, g, stdout, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	ctx, err = c.context(ctx)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	if err := exec(ctx, c, fs.Args()); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(stderr, "error:", err)
+			fs.Usage()
+			return 2
+		}
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// findCommand は引数の先頭からサブコマンドを探す（"users create" のような2語のコマンドを先に探す）
+func findCommand(args []string) (*command, []string) {
+	for n := 2; n >= 1; n-- {
+		if len(args) < n {
+			continue
+		}
+		name := strings.Join(args[:n], " ")
+		for i := range commands {
+			if commands[i].name == name {
+				return &commands[i], args[n:]
+			}
+		}
+	}
+	return nil, nil
+}
+
+func printUsage(w io.Writer, root *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: admin [flags] <command> [command flags] [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	byName := map[string]command{}
+	for _, c := range commands {
+		names = append(names, c.name)
+		byName[c.name] = c
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		c := byName[n]
+		fmt.Fprintf(w, "  %-24s %s\n", strings.TrimSpace(c.name+" "+c.args), c.summary)
+	}
+	fmt.Fprintln(w, "\nflags:")
+	root.PrintDefaults()
+}
+
+// cli はコマンドの実行に使う依存関係と設定
+type cli struct {
+	db     *gorm.DB
+	app    *wire.Admin
+	g      globals
+	out    printer
+	stderr io.Writer
+}
+
+func newCLI(db *gorm.DB, g globals, stdout, stderr io.Writer) (*cli, error) {
+	app, err := wire.InitializeAdmin(db)
+	if err != nil {
+		return nil, err
+	}
+	return &cli{db: db, app: app, g: g, out: printer{format: g.output, w: stdout}, stderr: stderr}, nil
+}
+
+// context は監査ログに記録する経路・理由・操作者をコンテキストに入れる
+func (c *cli) context(ctx context.Context) (context.Context, error) {
+	ctx = audit.WithMeta(ctx, audit.Meta{RequestID: uuid.NewString(), Source: audit.SourceCLI, Reason: c.g.reason})
+	if c.g.actor == "" {
+		return ctx, nil
+	}
+	user, err := c.app.Users.FindFirst(ctx, c.g.actor)
+	if err != nil {
+		return nil, fmt.Errorf("actor %s: %w", c.g.actor, err)
+	}
+	return auth.WithUser(ctx, user), nil
+}
+
+// errDryRun はドライランでトランザクションをロールバックするためのエラー
+var errDryRun = errors.New("dry run")
+
+// mutate は変更を1つのトランザクションで実行する
+// ドライランの場合は fn の実行後にロールバックする
+func (c *cli) mutate(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := c.app.Tx.Do(ctx, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		if c.g.dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		fmt.Fprintln(c.stderr, "dry run: changes were rolled back")
+		return nil
+	}
+	return err
+}
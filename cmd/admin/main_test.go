@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+)
+
+// runCLI はCLIを実行して終了コードと出力を返す
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// listUsers は -o json の users list の結果を返す
+func listUsers(t *testing.T, db string) []entity.User {
+	t.Helper()
+	code, out, errOut := runCLI(t, "-db", db, "-o", "json", "users", "list")
+	if code != 0 {
+		t.Fatalf("users list = %d: %s", code, errOut)
+	}
+	var users []entity.User
+	if err := json.Unmarshal([]byte(out), &users); err != nil {
+		t.Fatalf("users list output %q: %v", out, err)
+	}
+	return users
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "app.db")
+
+	code, out, errOut := runCLI(t, "-db", db, "-dry-run", "migrate")
+	if code != 0 || !strings.HasPrefix(out, "PENDING\n") || !strings.Contains(out, "\nusers\n") {
+		t.Fatalf("migrate -dry-run = %d %q %s, want the pending tables", code, out, errOut)
+	}
+	// ドライランでは適用しないため、もう一度実行しても同じものが残っている
+	if _, again, _ := runCLI(t, "-db", db, "migrate", "-dry-run"); again != out {
+		t.Errorf("second dry run = %q, want %q", again, out)
+	}
+
+	if code, out, errOut := runCLI(t, "-db", db, "migrate"); code != 0 || !strings.HasPrefix(out, "APPLIED\n") {
+		t.Fatalf("migrate = %d %q %s", code, out, errOut)
+	}
+	if code, out, _ := runCLI(t, "-db", db, "-dry-run", "migrate"); code != 0 || out != "PENDING\n" {
+		t.Errorf("migrate -dry-run after migrate = %d %q, want nothing pending", code, out)
+	}
+}
+
+// ドライランは結果を表示し、変更・監査ログをロールバックする
+func TestMutationDryRun(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "app.db")
+	if code, _, errOut := runCLI(t, "-db", db, "migrate"); code != 0 {
+		t.Fatalf("migrate: %s", errOut)
+	}
+	create := []string{"users", "create", "-id", "alice", "-auth-id", "a-alice", "-name", "Alice", "-email", "alice@example.com"}
+
+	code, out, errOut := runCLI(t, append([]string{"-db", db, "-dry-run"}, create...)...)
+	if code != 0 || !strings.Contains(out, "alice@example.com") || !strings.Contains(errOut, "dry run: changes were rolled back") {
+		t.Fatalf("users create -dry-run = %d %q %q", code, out, errOut)
+	}
+	if users := listUsers(t, db); len(users) != 0 {
+		t.Fatalf("users after dry run = %+v, want none", users)
+	}
+
+	// コマンド名の後ろに書いた -dry-run も効く
+	if code, _, errOut := runCLI(t, append(append([]string{"-db", db}, create...), "-dry-run")...); code != 0 || !strings.Contains(errOut, "rolled back") {
+		t.Fatalf("users create ... -dry-run = %d %q", code, errOut)
+	}
+	if users := listUsers(t, db); len(users) != 0 {
+		t.Fatalf("users after trailing dry run = %+v, want none", users)
+	}
+
+	if code, _, errOut := runCLI(t, append([]string{"-db", db}, create...)...); code != 0 || strings.Contains(errOut, "rolled back") {
+		t.Fatalf("users create = %d %q", code, errOut)
+	}
+	code, out, errOut = runCLI(t, "-db", db, "-dry-run", "-o", "json", "users", "deactivate", "alice")
+	if code != 0 || !strings.Contains(errOut, "rolled back") {
+		t.Fatalf("users deactivate -dry-run = %d %q", code, errOut)
+	}
+	var shown []entity.User
+	if err := json.Unmarshal([]byte(out), &shown); err != nil || len(shown) != 1 || !shown[0].DeletedAt.Valid {
+		t.Errorf("dry run output = %q, want alice deactivated", out)
+	}
+	if users := listUsers(t, db); len(users) != 1 || users[0].DeletedAt.Valid {
+		t.Errorf("users after dry run = %+v, want alice still active", users)
+	}
+
+	// 失敗した変更はドライランでもエラーになる
+	if code, _, errOut := runCLI(t, "-db", db, "-dry-run", "users", "deactivate", "nobody"); code != 1 || strings.Contains(errOut, "rolled back") {
+		t.Errorf("users deactivate nobody = %d %q, want an error", code, errOut)
+	}
+}
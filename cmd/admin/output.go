@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/domain"
+)
+
+// printer は結果を表か JSON で出力する
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+// print は v を出力する。表の場合は header と rows を出力する
+func (p printer) print(v any, header []string, rows [][]string) error {
+	if p.format == "json" {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// formatTime は時刻を日本時間で表示する（ゼロなら "-"）
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(domain.JST).Format("2006-01-02 15:04")
+}
+
+// formatDate は勤務日を表示する
+func formatDate(t time.Time) string {
+	return t.In(domain.JST).Format("2006-01-02")
+}
+
+// formatBool は真偽値を表で目立つように表示する
+func formatBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "-"
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/health"
+)
+
+// periods list
+func periodsListCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		list, err := c.app.Attendance.ListClosedPeriods(ctx)
+		if err != nil {
+			return err
+		}
+		return c.printPeriods(list)
+	}
+}
+
+// periods close
+func periodsCloseCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("%w: month (YYYY-MM) is required", errUsage)
+		}
+		m, err := parseMonth(args[0])
+		if err != nil {
+			return err
+		}
+		var closed *entity.ClosedPeriod
+		err = c.mutate(ctx, func(ctx context.Context) error {
+			var err error
+			closed, err = c.app.Attendance.ClosePeriod(ctx, m)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return c.printPeriods([]entity.ClosedPeriod{*closed})
+	}
+}
+
+// periods reopen
+func periodsReopenCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("%w: month (YYYY-MM) is required", errUsage)
+		}
+		m, err := parseMonth(args[0])
+		if err != nil {
+			return err
+		}
+		err = c.mutate(ctx, func(ctx context.Context) error {
+			return c.app.Attendance.ReopenPeriod(ctx, m)
+		})
+		if err != nil {
+			return err
+		}
+		return c.out.print(map[string]string{"reopened": args[0]}, []string{"REOPENED"}, [][]string{{args[0]}})
+	}
+}
+
+func (c *cli) printPeriods(list []entity.ClosedPeriod) error {
+	rows := make([][]string, 0, len(list))
+	for _, p := range list {
+		closedBy := p.ClosedBy
+		if closedBy == "" {
+			closedBy = "-"
+		}
+		rows = append(rows, []string{p.Month, closedBy, formatTime(p.CreatedAt)})
+	}
+	return c.out.print(list, []string{"MONTH", "CLOSED_BY", "CLOSED_AT"}, rows)
+}
+
+// migrate
+// 未適用のテーブル・カラム・古い一意制約の削除を表示してから適用する（ドライランでは表示だけ）
+func migrateCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		pending, err := health.PendingMigrations(c.db.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		if !c.g.dryRun {
+			if err := dbinfra.Migrate(c.db.WithContext(ctx)); err != nil {
+				return err
+			}
+		}
+		rows := make([][]string, 0, len(pending))
+		for _, p := range pending {
+			rows = append(rows, []string{p})
+		}
+		if c.g.dryRun {
+			return c.out.print(map[string][]string{"pending": pending}, []string{"PENDING"}, rows)
+		}
+		return c.out.print(map[string][]string{"applied": pending}, []string{"APPLIED"}, rows)
+	}
+}
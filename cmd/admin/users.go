@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+// users list
+func usersListCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	all := fs.Bool("all", false, "include deactivated users")
+	return func(ctx context.Context, c *cli, args []string) error {
+		var users []entity.User
+		var err error
+		if *all {
+			users, err = c.app.Users.FindAllUserIncludeDeleted(ctx)
+		} else {
+			users, err = c.app.Users.FindAllUser(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		return c.printUsers(users)
+	}
+}
+
+// users create
+func usersCreateCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	var u entity.User
+	fs.StringVar(&u.ID, "id", "", "user ID (default: generated)")
+	fs.StringVar(&u.AuthID, "auth-id", "", "ID of the user in the auth provider (required)")
+	fs.StringVar(&u.Name, "name", "", "name (required)")
+	fs.StringVar(&u.Email, "email", "", "email address (required)")
+	fs.StringVar(&u.Role, "role", entity.RoleEmployee, "role: admin, manager or employee")
+	fs.StringVar(&u.Department, "department", "", "department")
+	return func(ctx context.Context, c *cli, args []string) error {
+		if len(args) > 0 {
+			return fmt.Errorf("%w: unexpected arguments %v", errUsage, args)
+		}
+		if u.ID == "" {
+			u.ID = uuid.NewString()
+		}
+		var created *entity.User
+		err := c.mutate(ctx, func(ctx context.Context) error {
+			if err := c.app.Users.CreateUser(ctx, u); err != nil {
+				return err
+			}
+			var err error
+			created, err = c.app.Users.FindFirst(ctx, u.ID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return c.printUsers([]entity.User{*created})
+	}
+}
+
+// users import
+// ファイルは POST /users と同じ形のユーザーの JSON 配列
+// 1件でも登録できなければすべて取り消す
+func usersImportCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("%w: FILE is required", errUsage)
+		}
+		users, err := readUsersJSON(args[0])
+		if err != nil {
+			return err
+		}
+		for i := range users {
+			if users[i].ID == "" {
+				users[i].ID = uuid.NewString()
+			}
+			if users[i].Role == "" {
+				users[i].Role = entity.RoleEmployee
+			}
+		}
+		created := make([]entity.User, 0, len(users))
+		err = c.mutate(ctx, func(ctx context.Context) error {
+			for i, u := range users {
+				if err := c.app.Users.CreateUser(ctx, u); err != nil {
+					return fmt.Errorf("user #%d (%s): %w", i+1, u.Email, err)
+				}
+				after, err := c.app.Users.FindFirst(ctx, u.ID)
+				if err != nil {
+					return err
+				}
+				created = append(created, *after)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return c.printUsers(created)
+	}
+}
+
+// users deactivate
+// 論理削除する（users list -all で確認でき、API の POST /users/{id}/restore で戻せる）
+func usersDeactivateCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("%w: at least one user ID is required", errUsage)
+		}
+		deactivated := make([]entity.User, 0, len(args))
+		err := c.mutate(ctx, func(ctx context.Context) error {
+			for _, id := range args {
+				u, err := c.app.Users.FindFirst(ctx, id)
+				if err != nil {
+					return fmt.Errorf("user %s: %w", id, err)
+				}
+				if err := c.app.Users.DeleteUser(ctx, *u); err != nil {
+					return fmt.Errorf("user %s: %w", id, err)
+				}
+				after, err := c.app.Users.FindFirstIncludeDeleted(ctx, id)
+				if err != nil {
+					return err
+				}
+				deactivated = append(deactivated, *after)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return c.printUsers(deactivated)
+	}
+}
+
+// readUsersJSON はファイル（- なら標準入力）からユーザーの JSON 配列を読み込む
+func readUsersJSON(path string) ([]entity.User, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var users []entity.User
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&users); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return users, nil
+}
+
+func (c *cli) printUsers(users []entity.User) error {
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		deleted := "-"
+		if u.DeletedAt.Valid {
+			deleted = formatTime(u.DeletedAt.Time)
+		}
+		rows = append(rows, []string{u.ID, u.AuthID, u.Name, u.Email, u.Role, u.Department, deleted})
+	}
+	return c.out.print(users, []string{"ID", "AUTH_ID", "NAME", "EMAIL", "ROLE", "DEPARTMENT", "DEACTIVATED"}, rows)
+}
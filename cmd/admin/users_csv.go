@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/enkazu1116/go_home/internal/domain"
+)
+
+// users import-csv
+// POST /users/import と同じく、行ごとに登録・更新して結果を表示する
+// -dry-run では結果だけを表示し、-errors で失敗した行のCSVを書き出す
+func usersImportCSVCommand(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	matchBy := fs.String("match-by", domain.UserImportMatchEmail, "match existing users by email or auth_id")
+	mapping := fs.String("map", "", "column mapping, e.g. Email=mail,Name=氏名 (default: guessed from the header)")
+	allOrNothing := fs.Bool("all-or-nothing", false, "roll back every row if any row fails")
+	errorsFile := fs.String("errors", "", "write the failed rows with their errors to this CSV file")
+	return func(ctx context.Context, c *cli, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("%w: FILE is required", errUsage)
+		}
+		m, err := domain.ParseUserImportMapping(*mapping)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		report, err := c.app.UserImports.Import(ctx, r, domain.UserImportOptions{
+			MatchBy: *matchBy, Mapping: m, DryRun: c.g.dryRun, AllOrNothing: *allOrNothing,
+		})
+		if report == nil {
+			return err
+		}
+		if werr := c.printUserImport(report); werr != nil {
+			return werr
+		}
+		if *errorsFile != "" && report.Failed > 0 {
+			if werr := writeImportErrors(*errorsFile, report); werr != nil {
+				return werr
+			}
+		}
+		switch {
+		case errors.Is(err, domain.ErrUserImportRejected):
+			return fmt.Errorf("%w (%d of %d rows failed, nothing was saved)", err, report.Failed, report.Total)
+		case report.DryRun:
+			fmt.Fprintln(c.stderr, "dry run: changes were rolled back")
+		}
+		if report.Failed > 0 {
+			return fmt.Errorf("%d of %d rows failed", report.Failed, report.Total)
+		}
+		return nil
+	}
+}
+
+func (c *cli) printUserImport(report *domain.UserImportReport) error {
+	rows := make([][]string, 0, len(report.Rows))
+	for _, r := range report.Rows {
+		userID, msg := r.UserID, r.Error
+		if userID == "" {
+			userID = "-"
+		}
+		if msg == "" {
+			msg = "-"
+		}
+		rows = append(rows, []string{strconv.Itoa(r.Line), r.Status, userID, r.Email, msg})
+	}
+	if err := c.out.print(report, []string{"LINE", "STATUS", "USER_ID", "EMAIL", "ERROR"}, rows); err != nil {
+		return err
+	}
+	if c.g.output == "table" {
+		fmt.Fprintf(c.stderr, "%d rows: %d created, %d updated, %d unchanged, %d failed\n",
+			report.Total, report.Created, report.Updated, report.Unchanged, report.Failed)
+	}
+	return nil
+}
+
+// writeImportErrors は失敗した行のCSVをファイルに書き出す
+func writeImportErrors(path string, report *domain.UserImportReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteErrorCSV(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
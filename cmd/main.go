@@ -2,67 +2,275 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/enkazu1116/go_home/internal/entity"
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/audit"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/logging"
+	"github.com/enkazu1116/go_home/internal/pb"
 	"github.com/enkazu1116/go_home/internal/wire"
 
 	"github.com/go-chi/chi/v5"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func main() {
+	// 構造化ログ（標準の log パッケージの出力もこのロガーに流れる）
+	logger := logging.New(logging.NewConfigFromEnv())
+	slog.SetDefault(logger)
+
 	// DB初期化（SQLiteを使用）
-	db, err := gorm.Open(sqlite.Open("app.db"), &gorm.Config{})
+	db, err := dbinfra.OpenSQLite("app.db", logger)
 	if err != nil {
-		log.Fatalf("failed to open db: %v", err)
+		fatal("failed to open db", err)
 	}
 
 	// マイグレーション
-	if err := db.AutoMigrate(&entity.User{}); err != nil {
-		log.Fatalf("auto migrate failed: %v", err)
+	// AUTO_MIGRATE=false の場合は管理用CLI（admin migrate）で適用する（未適用の間は /readyz が失敗する）
+	if os.Getenv("AUTO_MIGRATE") != "false" {
+		if err := dbinfra.Migrate(db); err != nil {
+			fatal("auto migrate failed", err)
+		}
 	}
 
 	// Wireを使用した依存性注入でアプリケーションを初期化
-	app, err := wire.InitializeApp(db)
+	app, err := wire.InitializeApp(db, logger)
 	if err != nil {
-		log.Fatalf("failed to initialize app: %v", err)
+		fatal("failed to initialize app", err)
 	}
 
 	// HTTPサーバ設定
 	r := chi.NewRouter()
+	r.Use(audit.Middleware)
+	r.Use(app.Tracing.Middleware)
+	r.Use(app.AccessLog.Middleware)
+	r.Use(app.Metrics.Middleware)
+	r.Use(app.Authenticator.Middleware)
+	r.Use(app.Idempotency.Middleware)
 	app.UserHandler.RegisterRoutes(r)
+	app.AttendanceHandler.RegisterRoutes(r)
+	app.AuditHandler.RegisterRoutes(r)
+	app.PunchLogHandler.RegisterRoutes(r)
+	app.WebhookHandler.RegisterRoutes(r)
+	app.ChatHandler.RegisterRoutes(r)
+	app.NotificationHandler.RegisterRoutes(r)
+	app.KioskHandler.RegisterRoutes(r)
+	app.CardHandler.RegisterRoutes(r)
+	app.PunchPolicyHandler.RegisterRoutes(r)
+	app.DashboardHandler.RegisterRoutes(r)
+	app.PresenceHandler.RegisterRoutes(r)
+	app.CalendarHandler.RegisterRoutes(r)
+	app.MetricsHandler.RegisterRoutes(r)
+	app.HealthHandler.RegisterRoutes(r)
 
 	srv := &http.Server{
 		Addr:    ":8080",
 		Handler: r,
 	}
+	// 停止時はダッシュボードのストリームを終わらせる（Shutdown は実行中のリクエストを待つため）
+	srv.RegisterOnShutdown(app.LiveHub.Close)
+	srv.TLSConfig, err = serverTLSConfigFromEnv()
+	if err != nil {
+		fatal("failed to load tls config", err)
+	}
+
+	// gRPCサーバ設定
+	grpcSrv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			audit.UnaryServerInterceptor(),
+			app.Tracing.UnaryServerInterceptor(),
+			app.AccessLog.UnaryServerInterceptor(),
+			app.Metrics.UnaryServerInterceptor(),
+			app.Authenticator.UnaryServerInterceptor(),
+			app.Idempotency.UnaryServerInterceptor(),
+		),
+	)
+	pb.RegisterUserServiceServer(grpcSrv, app.UserGRPCServer)
+	pb.RegisterAttendanceServiceServer(grpcSrv, app.AttendanceGRPCServer)
+	healthpb.RegisterHealthServer(grpcSrv, app.HealthGRPCServer)
+
+	lis, err := net.Listen("tcp", ":9090")
+	if err != nil {
+		fatal("failed to listen for grpc", err)
+	}
+	go func() {
+		slog.Info("starting grpc server", "addr", lis.Addr().String())
+		if err := grpcSrv.Serve(lis); err != nil {
+			slog.Error("grpc serve failed", "err", err)
+		}
+	}()
+
+	// バックグラウンドのワーカーは停止時に workerCtx をキャンセルし、戻るのを待ってから DB を閉じる
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+
+	// 期限切れの冪等キーを1時間ごとに削除する
+	app.Health.Go(workerCtx, "idempotency_purge", func(ctx context.Context) {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if n, err := app.Idempotency.PurgeExpired(ctx); err != nil {
+				slog.ErrorContext(ctx, "purge idempotency keys failed", "err", err)
+			} else if n > 0 {
+				slog.InfoContext(ctx, "purged expired idempotency keys", "count", n)
+			}
+		}
+	})
+
+	// 打刻ログのチェックポイントを定期的に作成する
+	app.Health.Go(workerCtx, "punch_checkpoint", func(ctx context.Context) {
+		ticker := time.NewTicker(app.PunchLogConfig.CheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := app.PunchLog.CreateCheckpoint(ctx); err != nil {
+				slog.ErrorContext(ctx, "create punch checkpoint failed", "err", err)
+			}
+		}
+	})
+
+	// 定期実行のジョブ（複数のプロセスで動かしても、予定の時刻ごとに1つのプロセスだけが実行する）
+	// 退勤の打刻漏れを検出して通知する
+	err = app.Scheduler.Add("missing-check-out", app.MissingCheckOutConfig.Schedule, domain.JST, func(ctx context.Context, at time.Time) error {
+		n, err := app.MissingCheckOut.Detect(ctx, at)
+		if n > 0 {
+			slog.InfoContext(ctx, "flagged missing check-outs", "count", n)
+		}
+		return err
+	})
+	if err != nil {
+		fatal("failed to schedule job", err)
+	}
+	// 期限切れの在席ステータスを消して、同じ部署の人に知らせる
+	err = app.Scheduler.Add("presence-expire", "* * * * *", domain.JST, func(ctx context.Context, at time.Time) error {
+		_, err := app.Presence.Expire(ctx, at)
+		return err
+	})
+	if err != nil {
+		fatal("failed to schedule job", err)
+	}
+	// 平日の決まった時刻に、まだ出勤していないユーザーへリマインドを送る
+	if spec := app.ChatConfig.ReminderSchedule(); spec != "" {
+		err = app.Scheduler.Add("chat-reminder", spec, domain.JST, func(ctx context.Context, at time.Time) error {
+			n, err := app.Chat.SendReminders(ctx, at)
+			if n > 0 {
+				slog.InfoContext(ctx, "sent chat reminders", "count", n)
+			}
+			return err
+		})
+		if err != nil {
+			fatal("failed to schedule job", err)
+		}
+	}
+
+	// アウトボックスのイベントを配信し、Webhook・メールを送る
+	// 在席ステータスは他のサーバーからのイベントも受け取る
+	// ワーカーが止まったらヘルスチェックを失敗にする
+	app.Health.Go(workerCtx, "outbox_relay", app.Relay.Run)
+	app.Health.Go(workerCtx, "webhook_dispatcher", app.WebhookDispatcher.Run)
+	app.Health.Go(workerCtx, "mailer", app.Mailer.Run)
+	app.Health.Go(workerCtx, "scheduler", app.Scheduler.Run)
+	app.Health.Go(workerCtx, "live_broker", app.LiveBroker.Run)
 
 	// graceful shutdown 準備
 	idleConnsClosed := make(chan struct{})
 	go func() {
 		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt)
-		<-c
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		sig := <-c
+		// readiness を失敗にして、振り分け先から外れるまで待つ
+		slog.Info("shutting down", "signal", sig.String(), "drain_delay", app.Health.Config().DrainDelay)
+		app.Health.SetShuttingDown()
+		time.Sleep(app.Health.Config().DrainDelay)
 		// 5秒以内にクリーンにシャットダウン
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := srv.Shutdown(ctx); err != nil {
-			log.Printf("HTTP server Shutdown: %v", err)
+			slog.Error("http server shutdown failed", "err", err)
+		}
+		grpcSrv.GracefulStop()
+		stopWorkers()
+		if err := app.Health.Wait(ctx); err != nil {
+			slog.Error("background workers did not stop", "err", err)
+		}
+		// 送っていないスパンを送り切る
+		if err := app.TracerProvider.Shutdown(ctx); err != nil {
+			slog.Error("tracer provider shutdown failed", "err", err)
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				slog.Error("close db failed", "err", err)
+			}
 		}
 		close(idleConnsClosed)
 	}()
 
-	log.Printf("starting server on %s", srv.Addr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("ListenAndServe: %v", err)
+	slog.Info("starting server", "addr", srv.Addr)
+	if srv.TLSConfig != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		fatal("listen and serve failed", err)
 	}
 
 	<-idleConnsClosed
-	log.Println("server stopped")
+	slog.Info("server stopped")
+}
+
+// fatal はエラーを出力して終了する
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
+
+// serverTLSConfigFromEnv は TLS_CERT_FILE・TLS_KEY_FILE を設定した場合にHTTPSの設定を返す（未設定の場合は nil）
+// TLS_CLIENT_CA_FILE も設定すると、その認証局が発行したクライアント証明書を検証する
+// 証明書の提示は任意で、ICカードリーダーが APIキーの代わりに使う（CN を端末IDにする）
+func serverTLSConfigFromEnv() (*tls.Config, error) {
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both TLS_CERT_FILE and TLS_KEY_FILE are required")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if caFile := os.Getenv("TLS_CLIENT_CA_FILE"); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in TLS_CLIENT_CA_FILE")
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
 }
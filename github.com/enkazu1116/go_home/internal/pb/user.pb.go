@@ -10,6 +10,7 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -24,15 +25,17 @@ const (
 )
 
 type User struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	AuthId        string                 `protobuf:"bytes,2,opt,name=authId,proto3" json:"authId,omitempty"`
-	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
-	Email         string                 `protobuf:"bytes,4,opt,name=email,proto3" json:"email,omitempty"`
-	Role          string                 `protobuf:"bytes,5,opt,name=role,proto3" json:"role,omitempty"`
-	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
-	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
-	DeletedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=deletedAt,proto3" json:"deletedAt,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	AuthId    string                 `protobuf:"bytes,2,opt,name=authId,proto3" json:"authId,omitempty"`
+	Name      string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Email     string                 `protobuf:"bytes,4,opt,name=email,proto3" json:"email,omitempty"`
+	Role      string                 `protobuf:"bytes,5,opt,name=role,proto3" json:"role,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	UpdatedAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
+	DeletedAt *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=deletedAt,proto3" json:"deletedAt,omitempty"`
+	// 楽観的排他制御用のバージョン。UpdateUser では取得時の値をそのまま送る
+	Version       int64 `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -123,6 +126,13 @@ func (x *User) GetDeletedAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *User) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
 type CreateUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	AuthId        string                 `protobuf:"bytes,1,opt,name=authId,proto3" json:"authId,omitempty"`
@@ -455,6 +465,104 @@ func (x *UpdateUserResponse) GetUser() *User {
 	return nil
 }
 
+// updateMask で指定したフィールドだけを更新する（name, email, role のみ）
+// user.id と user.version は必須
+type PatchUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *User                  `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,2,opt,name=updateMask,proto3" json:"updateMask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchUserRequest) Reset() {
+	*x = PatchUserRequest{}
+	mi := &file_api_user_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchUserRequest) ProtoMessage() {}
+
+func (x *PatchUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_user_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchUserRequest.ProtoReflect.Descriptor instead.
+func (*PatchUserRequest) Descriptor() ([]byte, []int) {
+	return file_api_user_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PatchUserRequest) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *PatchUserRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+type PatchUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *User                  `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchUserResponse) Reset() {
+	*x = PatchUserResponse{}
+	mi := &file_api_user_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchUserResponse) ProtoMessage() {}
+
+func (x *PatchUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_user_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchUserResponse.ProtoReflect.Descriptor instead.
+func (*PatchUserResponse) Descriptor() ([]byte, []int) {
+	return file_api_user_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PatchUserResponse) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
 type DeleteUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -464,7 +572,7 @@ type DeleteUserRequest struct {
 
 func (x *DeleteUserRequest) Reset() {
 	*x = DeleteUserRequest{}
-	mi := &file_api_user_proto_msgTypes[8]
+	mi := &file_api_user_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -476,7 +584,7 @@ func (x *DeleteUserRequest) String() string {
 func (*DeleteUserRequest) ProtoMessage() {}
 
 func (x *DeleteUserRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_user_proto_msgTypes[8]
+	mi := &file_api_user_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -489,7 +597,7 @@ func (x *DeleteUserRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteUserRequest.ProtoReflect.Descriptor instead.
 func (*DeleteUserRequest) Descriptor() ([]byte, []int) {
-	return file_api_user_proto_rawDescGZIP(), []int{8}
+	return file_api_user_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *DeleteUserRequest) GetId() string {
@@ -503,7 +611,7 @@ var File_api_user_proto protoreflect.FileDescriptor
 
 const file_api_user_proto_rawDesc = "" +
 	"\n" +
-	"\x0eapi/user.proto\x12\x04user\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\"\x9a\x02\n" +
+	"\x0eapi/user.proto\x12\x04user\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\x1a google/protobuf/field_mask.proto\"\xb4\x02\n" +
 	"\x04User\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
 	"\x06authId\x18\x02 \x01(\tR\x06authId\x12\x12\n" +
@@ -512,7 +620,8 @@ const file_api_user_proto_rawDesc = "" +
 	"\x04role\x18\x05 \x01(\tR\x04role\x128\n" +
 	"\tcreatedAt\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x128\n" +
 	"\tupdatedAt\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x128\n" +
-	"\tdeletedAt\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\"i\n" +
+	"\tdeletedAt\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\x12\x18\n" +
+	"\aversion\x18\t \x01(\x03R\aversion\"i\n" +
 	"\x11CreateUserRequest\x12\x16\n" +
 	"\x06authId\x18\x01 \x01(\tR\x06authId\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
@@ -534,16 +643,26 @@ const file_api_user_proto_rawDesc = "" +
 	".user.UserR\x04user\"4\n" +
 	"\x12UpdateUserResponse\x12\x1e\n" +
 	"\x04user\x18\x01 \x01(\v2\n" +
+	".user.UserR\x04user\"n\n" +
+	"\x10PatchUserRequest\x12\x1e\n" +
+	"\x04user\x18\x01 \x01(\v2\n" +
+	".user.UserR\x04user\x12:\n" +
+	"\n" +
+	"updateMask\x18\x02 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\"3\n" +
+	"\x11PatchUserResponse\x12\x1e\n" +
+	"\x04user\x18\x01 \x01(\v2\n" +
 	".user.UserR\x04user\"#\n" +
 	"\x11DeleteUserRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id2\xc4\x02\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id2\x82\x03\n" +
 	"\vUserService\x12?\n" +
 	"\n" +
 	"CreateUser\x12\x17.user.CreateUserRequest\x1a\x18.user.CreateUserResponse\x12<\n" +
 	"\tListUsers\x12\x16.google.protobuf.Empty\x1a\x17.user.ListUsersResponse\x126\n" +
 	"\aGetUser\x12\x14.user.GetUserRequest\x1a\x15.user.GetUserResponse\x12?\n" +
 	"\n" +
-	"UpdateUser\x12\x17.user.UpdateUserRequest\x1a\x18.user.UpdateUserResponse\x12=\n" +
+	"UpdateUser\x12\x17.user.UpdateUserRequest\x1a\x18.user.UpdateUserResponse\x12<\n" +
+	"\tPatchUser\x12\x16.user.PatchUserRequest\x1a\x17.user.PatchUserResponse\x12=\n" +
 	"\n" +
 	"DeleteUser\x12\x17.user.DeleteUserRequest\x1a\x16.google.protobuf.EmptyB.Z,github.com/enkazu1116/go_home/internal/pb;pbb\x06proto3"
 
@@ -559,7 +678,7 @@ func file_api_user_proto_rawDescGZIP() []byte {
 	return file_api_user_proto_rawDescData
 }
 
-var file_api_user_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_api_user_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_api_user_proto_goTypes = []any{
 	(*User)(nil),                  // 0: user.User
 	(*CreateUserRequest)(nil),     // 1: user.CreateUserRequest
@@ -569,34 +688,42 @@ var file_api_user_proto_goTypes = []any{
 	(*GetUserResponse)(nil),       // 5: user.GetUserResponse
 	(*UpdateUserRequest)(nil),     // 6: user.UpdateUserRequest
 	(*UpdateUserResponse)(nil),    // 7: user.UpdateUserResponse
-	(*DeleteUserRequest)(nil),     // 8: user.DeleteUserRequest
-	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
-	(*emptypb.Empty)(nil),         // 10: google.protobuf.Empty
+	(*PatchUserRequest)(nil),      // 8: user.PatchUserRequest
+	(*PatchUserResponse)(nil),     // 9: user.PatchUserResponse
+	(*DeleteUserRequest)(nil),     // 10: user.DeleteUserRequest
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+	(*fieldmaskpb.FieldMask)(nil), // 12: google.protobuf.FieldMask
+	(*emptypb.Empty)(nil),         // 13: google.protobuf.Empty
 }
 var file_api_user_proto_depIdxs = []int32{
-	9,  // 0: user.User.createdAt:type_name -> google.protobuf.Timestamp
-	9,  // 1: user.User.updatedAt:type_name -> google.protobuf.Timestamp
-	9,  // 2: user.User.deletedAt:type_name -> google.protobuf.Timestamp
+	11, // 0: user.User.createdAt:type_name -> google.protobuf.Timestamp
+	11, // 1: user.User.updatedAt:type_name -> google.protobuf.Timestamp
+	11, // 2: user.User.deletedAt:type_name -> google.protobuf.Timestamp
 	0,  // 3: user.CreateUserResponse.user:type_name -> user.User
 	0,  // 4: user.ListUsersResponse.users:type_name -> user.User
 	0,  // 5: user.GetUserResponse.user:type_name -> user.User
 	0,  // 6: user.UpdateUserRequest.user:type_name -> user.User
 	0,  // 7: user.UpdateUserResponse.user:type_name -> user.User
-	1,  // 8: user.UserService.CreateUser:input_type -> user.CreateUserRequest
-	10, // 9: user.UserService.ListUsers:input_type -> google.protobuf.Empty
-	4,  // 10: user.UserService.GetUser:input_type -> user.GetUserRequest
-	6,  // 11: user.UserService.UpdateUser:input_type -> user.UpdateUserRequest
-	8,  // 12: user.UserService.DeleteUser:input_type -> user.DeleteUserRequest
-	2,  // 13: user.UserService.CreateUser:output_type -> user.CreateUserResponse
-	3,  // 14: user.UserService.ListUsers:output_type -> user.ListUsersResponse
-	5,  // 15: user.UserService.GetUser:output_type -> user.GetUserResponse
-	7,  // 16: user.UserService.UpdateUser:output_type -> user.UpdateUserResponse
-	10, // 17: user.UserService.DeleteUser:output_type -> google.protobuf.Empty
-	13, // [13:18] is the sub-list for method output_type
-	8,  // [8:13] is the sub-list for method input_type
-	8,  // [8:8] is the sub-list for extension type_name
-	8,  // [8:8] is the sub-list for extension extendee
-	0,  // [0:8] is the sub-list for field type_name
+	0,  // 8: user.PatchUserRequest.user:type_name -> user.User
+	12, // 9: user.PatchUserRequest.updateMask:type_name -> google.protobuf.FieldMask
+	0,  // 10: user.PatchUserResponse.user:type_name -> user.User
+	1,  // 11: user.UserService.CreateUser:input_type -> user.CreateUserRequest
+	13, // 12: user.UserService.ListUsers:input_type -> google.protobuf.Empty
+	4,  // 13: user.UserService.GetUser:input_type -> user.GetUserRequest
+	6,  // 14: user.UserService.UpdateUser:input_type -> user.UpdateUserRequest
+	8,  // 15: user.UserService.PatchUser:input_type -> user.PatchUserRequest
+	10, // 16: user.UserService.DeleteUser:input_type -> user.DeleteUserRequest
+	2,  // 17: user.UserService.CreateUser:output_type -> user.CreateUserResponse
+	3,  // 18: user.UserService.ListUsers:output_type -> user.ListUsersResponse
+	5,  // 19: user.UserService.GetUser:output_type -> user.GetUserResponse
+	7,  // 20: user.UserService.UpdateUser:output_type -> user.UpdateUserResponse
+	9,  // 21: user.UserService.PatchUser:output_type -> user.PatchUserResponse
+	13, // 22: user.UserService.DeleteUser:output_type -> google.protobuf.Empty
+	17, // [17:23] is the sub-list for method output_type
+	11, // [11:17] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_api_user_proto_init() }
@@ -610,7 +737,7 @@ func file_api_user_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_user_proto_rawDesc), len(file_api_user_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
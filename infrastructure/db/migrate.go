@@ -0,0 +1,221 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+// Migrate はテーブルを AutoMigrate し、AutoMigrate では直せない変更も適用する
+// 起動時・管理用CLIの migrate・OpenPostgres で使う
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(entity.Models()...); err != nil {
+		return fmt.Errorf("auto migrate: %w", err)
+	}
+	if err := dropLegacyUserUniques(db); err != nil {
+		return fmt.Errorf("drop legacy unique constraints on users: %w", err)
+	}
+	if err := hashLegacyKioskSecrets(db); err != nil {
+		return fmt.Errorf("hash kiosk secrets: %w", err)
+	}
+	return nil
+}
+
+// HasLegacyKioskSecrets は kiosks に平文の鍵の列（secret）が残っているかを返す
+func HasLegacyKioskSecrets(db *gorm.DB) bool {
+	return db.Migrator().HasColumn(&entity.Kiosk{}, "secret")
+}
+
+// kiosks は以前は端末の鍵を平文で secret 列に保存していた
+// 鍵の SHA-256（domain の hashDeviceKey と同じ）を key_hash に入れてから secret 列を消す
+func hashLegacyKioskSecrets(db *gorm.DB) error {
+	if !HasLegacyKioskSecrets(db) {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID     string
+			Secret string
+		}
+		if err := tx.Table("kiosks").Select("id, secret").Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, r := range rows {
+			sum := sha256.Sum256([]byte(r.Secret))
+			if err := tx.Table("kiosks").Where("id = ?", r.ID).Update("key_hash", hex.EncodeToString(sum[:])).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropColumn(&entity.Kiosk{}, "secret")
+	})
+}
+
+// users の AuthID・Email は、以前は論理削除済みの行も含めて一意（UNIQUE 制約）だった
+// 今は論理削除されていない行だけの部分ユニークインデックスにしているが、
+// AutoMigrate は自分が付けた名前（uni_users_email など）の制約しか消さないため、
+// 列に直接付いた UNIQUE（SQLite の sqlite_autoindex_users_N、Postgres の users_email_key）が残り、
+// 退職したユーザーと同じメールアドレス・認証IDで再入社したユーザーを登録できない
+var legacyUniqueColumns = []string{"auth_id", "email"}
+
+// LegacyUserUniques は users に残っている古い一意制約の名前を返す（"users.email (users_email_key)" の形）
+func LegacyUserUniques(db *gorm.DB) ([]string, error) {
+	var names []string
+	switch db.Dialector.Name() {
+	case "sqlite":
+		indexes, err := sqliteLegacyUniques(db)
+		if err != nil {
+			return nil, err
+		}
+		for column, index := range indexes {
+			names = append(names, fmt.Sprintf("users.%s (%s)", column, index))
+		}
+	case "postgres":
+		constraints, err := postgresLegacyUniques(db)
+		if err != nil {
+			return nil, err
+		}
+		for column, constraint := range constraints {
+			names = append(names, fmt.Sprintf("users.%s (%s)", column, constraint))
+		}
+	}
+	return names, nil
+}
+
+// dropLegacyUserUniques は古い一意制約を消す
+func dropLegacyUserUniques(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "sqlite":
+		indexes, err := sqliteLegacyUniques(db)
+		if err != nil || len(indexes) == 0 {
+			return err
+		}
+		return rebuildSQLiteUsers(db)
+	case "postgres":
+		constraints, err := postgresLegacyUniques(db)
+		if err != nil {
+			return err
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, name := range constraints {
+				if err := tx.Exec(fmt.Sprintf("ALTER TABLE users DROP CONSTRAINT %q", name)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// sqliteLegacyUniques は UNIQUE 制約から作られた1列だけのインデックスを列名ごとに返す
+// PRAGMA index_list の origin が "u" のものが UNIQUE 制約（CREATE UNIQUE INDEX は "c"）
+func sqliteLegacyUniques(db *gorm.DB) (map[string]string, error) {
+	var list []struct {
+		Name   string
+		Unique bool
+		Origin string
+	}
+	if err := db.Raw("PRAGMA index_list('users')").Scan(&list).Error; err != nil {
+		return nil, err
+	}
+	found := map[string]string{}
+	for _, idx := range list {
+		if !idx.Unique || idx.Origin != "u" {
+			continue
+		}
+		var cols []struct{ Name string }
+		if err := db.Raw(fmt.Sprintf("PRAGMA index_info(%q)", idx.Name)).Scan(&cols).Error; err != nil {
+			return nil, err
+		}
+		if len(cols) == 1 && isLegacyUniqueColumn(cols[0].Name) {
+			found[cols[0].Name] = idx.Name
+		}
+	}
+	return found, nil
+}
+
+// postgresLegacyUniques は users の1列だけの UNIQUE 制約を列名ごとに返す
+func postgresLegacyUniques(db *gorm.DB) (map[string]string, error) {
+	var rows []struct {
+		Constraint string
+		Column     string
+	}
+	err := db.Raw(`SELECT con.conname AS "constraint", att.attname AS "column"
+		FROM pg_constraint con
+		JOIN pg_class rel ON rel.oid = con.conrelid
+		JOIN pg_attribute att ON att.attrelid = rel.oid AND att.attnum = con.conkey[1]
+		WHERE rel.relname = 'users' AND pg_table_is_visible(rel.oid)
+			AND con.contype = 'u' AND array_length(con.conkey, 1) = 1`).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]string{}
+	for _, r := range rows {
+		if isLegacyUniqueColumn(r.Column) {
+			found[r.Column] = r.Constraint
+		}
+	}
+	return found, nil
+}
+
+func isLegacyUniqueColumn(column string) bool {
+	for _, c := range legacyUniqueColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildSQLiteUsers は users を今の定義で作り直して行を移す
+// SQLite は UNIQUE 制約を消せないため、テーブルを作り直すしかない（users を参照する外部キーは無い）
+func rebuildSQLiteUsers(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 作り直したテーブルに同じ名前のインデックスを作るため、古いテーブルのインデックスは先に消す
+		var indexes []string
+		if err := tx.Raw("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'users' AND sql IS NOT NULL").Scan(&indexes).Error; err != nil {
+			return err
+		}
+		for _, name := range indexes {
+			if err := tx.Exec(fmt.Sprintf("DROP INDEX %q", name)).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("ALTER TABLE users RENAME TO users_legacy").Error; err != nil {
+			return err
+		}
+		if err := tx.Migrator().CreateTable(&entity.User{}); err != nil {
+			return err
+		}
+
+		// 両方のテーブルにある列だけを移す
+		legacy, err := tx.Migrator().ColumnTypes("users_legacy")
+		if err != nil {
+			return err
+		}
+		current, err := tx.Migrator().ColumnTypes(&entity.User{})
+		if err != nil {
+			return err
+		}
+		has := map[string]bool{}
+		for _, c := range legacy {
+			has[c.Name()] = true
+		}
+		var columns []string
+		for _, c := range current {
+			if has[c.Name()] {
+				columns = append(columns, fmt.Sprintf("%q", c.Name()))
+			}
+		}
+		list := strings.Join(columns, ", ")
+		if err := tx.Exec(fmt.Sprintf("INSERT INTO users (%s) SELECT %s FROM users_legacy", list, list)).Error; err != nil {
+			return err
+		}
+		return tx.Exec("DROP TABLE users_legacy").Error
+	})
+}
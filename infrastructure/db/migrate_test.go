@@ -0,0 +1,112 @@
+package db
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+func TestMigrateDropsLegacyUserUniques(t *testing.T) {
+	tests := []struct {
+		name   string
+		legacy string
+	}{
+		{
+			// 以前の Gorm が列に直接付けた UNIQUE
+			name:   "column unique",
+			legacy: "CREATE TABLE `users` (`id` text,`auth_id` text NOT NULL UNIQUE,`name` text NOT NULL,`email` text NOT NULL UNIQUE,`role` text NOT NULL,`deleted_at` datetime,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`))",
+		},
+		{
+			// 名前付きの UNIQUE 制約
+			name:   "named constraint",
+			legacy: "CREATE TABLE `users` (`id` text,`auth_id` text NOT NULL,`name` text NOT NULL,`email` text NOT NULL,`role` text NOT NULL,`deleted_at` datetime,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`),CONSTRAINT `uni_users_email` UNIQUE (`email`),CONSTRAINT `uni_users_auth_id` UNIQUE (`auth_id`))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Exec(tt.legacy).Error; err != nil {
+				t.Fatal(err)
+			}
+			now := time.Now()
+			if err := db.Exec("INSERT INTO users (id, auth_id, name, email, role, deleted_at, created_at, updated_at) VALUES ('u1', 'a1', 'Alice', 'alice@example.com', 'employee', ?, ?, ?)", now, now, now).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			legacy, err := LegacyUserUniques(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(legacy) != 2 {
+				t.Fatalf("LegacyUserUniques() = %v, want 2 constraints", legacy)
+			}
+			if err := Migrate(db); err != nil {
+				t.Fatal(err)
+			}
+			if legacy, err := LegacyUserUniques(db); err != nil || len(legacy) != 0 {
+				t.Fatalf("LegacyUserUniques() after Migrate = %v, %v, want none", legacy, err)
+			}
+
+			// 既存の行は残る
+			var old entity.User
+			if err := db.Unscoped().First(&old, "id = ?", "u1").Error; err != nil {
+				t.Fatal(err)
+			}
+			if old.Email != "alice@example.com" || !old.DeletedAt.Valid || old.Version != 1 {
+				t.Fatalf("migrated user = %+v", old)
+			}
+
+			// 論理削除済みのユーザーと同じメールアドレス・認証IDで再入社できる
+			rehired := entity.User{ID: "u2", AuthID: "a1", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee}
+			if err := db.Create(&rehired).Error; err != nil {
+				t.Fatalf("create rehired user: %v", err)
+			}
+			// 有効なユーザーどうしは引き続き一意
+			dup := entity.User{ID: "u3", AuthID: "a3", Name: "Other", Email: "alice@example.com", Role: entity.RoleEmployee}
+			if err := db.Create(&dup).Error; !errors.Is(err, gorm.ErrDuplicatedKey) {
+				t.Fatalf("create duplicate active user: err = %v, want ErrDuplicatedKey", err)
+			}
+		})
+	}
+}
+
+// 平文で保存していた打刻端末の鍵は、ハッシュにしてから列を消す
+func TestMigrateHashesLegacyKioskSecrets(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE `kiosks` (`id` text,`name` text NOT NULL,`location` text,`secret` text NOT NULL,`active` numeric NOT NULL DEFAULT true,`last_seen_at` datetime,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`))").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO kiosks (id, name, secret, active) VALUES ('k1', 'Entrance', 'kiosk_abc', true)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if !HasLegacyKioskSecrets(db) {
+		t.Fatal("HasLegacyKioskSecrets() = false before Migrate")
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if HasLegacyKioskSecrets(db) {
+		t.Fatal("HasLegacyKioskSecrets() = true after Migrate")
+	}
+	var k entity.Kiosk
+	if err := db.First(&k, "id = ?", "k1").Error; err != nil {
+		t.Fatal(err)
+	}
+	// sha256("kiosk_abc")
+	if k.KeyHash != "f78534db5678b61a4a4d68c72ca36284347c9925bde397de69caa2a49095dfb4" || k.Name != "Entrance" {
+		t.Errorf("kiosk = %+v, want the hashed key", k)
+	}
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/enkazu1116/go_home/internal/logging"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// OpenSQLite はファイル path の SQLite を Gorm で開く（マイグレーションはしない）
+// TranslateErrorを有効にして、一意制約違反を gorm.ErrDuplicatedKey として扱えるようにする
+// トランザクションは書き込みロックを先に取り（_txlock=immediate）、ロック中の書き込みは待たせる（_busy_timeout）
+// Gorm のログはクエリの値を出さずに slog に流す
+func OpenSQLite(path string, logger *slog.Logger) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path+"?_txlock=immediate&_busy_timeout=5000"), &gorm.Config{TranslateError: true, Logger: logging.NewGormLogger(logger)})
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	return db, nil
+}
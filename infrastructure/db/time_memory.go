@@ -2,9 +2,9 @@ package db
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
-	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/logging"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -13,16 +13,16 @@ import (
 // OpenPostgres は DSN で Gorm(Postgres) を開いてマイグレーションまで行う簡易ヘルパー
 // DSN 例: "host=localhost user=postgres password=secret dbname=mydb port=5432 sslmode=disable TimeZone=Asia/Tokyo"
 func OpenPostgres(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true, Logger: logging.NewGormLogger(slog.Default())})
 	if err != nil {
 		return nil, fmt.Errorf("open postgres: %w", err)
 	}
 
 	// マイグレーション（必要に応じて実行）
-	if err := db.AutoMigrate(&entity.User{}); err != nil {
-		return nil, fmt.Errorf("auto migrate: %w", err)
+	if err := Migrate(db); err != nil {
+		return nil, err
 	}
 
-	log.Println("connected to postgres and migrated")
+	slog.Info("connected to postgres and migrated")
 	return db, nil
 }
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"context"
+)
+
+// 操作の経路
+const (
+	SourceHTTP      = "http"
+	SourceGRPC      = "grpc"
+	SourceCLI       = "cli"
+	SourceChat      = "chat"      // チャットのスラッシュコマンド
+	SourceScheduler = "scheduler" // 定期実行のジョブ
+	SourceKiosk     = "kiosk"     // 打刻端末（キオスク）
+	SourceCard      = "ic_card"   // ICカードリーダー
+)
+
+// Meta は監査ログに記録するリクエスト単位の情報
+// 操作者はauthパッケージのコンテキストから取得する
+type Meta struct {
+	RequestID string
+	Source    string
+	Reason    string
+	// 打刻に使った端末のID（打刻イベントに記録する）
+	DeviceID string
+	// 送信元のIPアドレス（打刻ポリシーの判定に使う）
+	ClientIP string
+}
+
+type contextKey struct{}
+
+// WithMeta は監査情報をコンテキストに格納する
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, contextKey{}, meta)
+}
+
+// MetaFrom はコンテキストから監査情報を取り出す
+func MetaFrom(ctx context.Context) Meta {
+	meta, _ := ctx.Value(contextKey{}).(Meta)
+	return meta
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ヘッダー名（gRPCではメタデータのキーとして小文字で使う）
+const (
+	RequestIDHeader = "X-Request-ID"
+	ReasonHeader    = "X-Audit-Reason"
+)
+
+// trustProxyHeaders が true のときは X-Forwarded-For の先頭を送信元とする
+// リバースプロキシの内側で動かす場合だけ TRUST_PROXY_HEADERS=true にすること
+var trustProxyHeaders = os.Getenv("TRUST_PROXY_HEADERS") == "true"
+
+// 受け付けるリクエストIDの最大長
+const maxRequestIDLen = 128
+
+// Middleware はHTTPリクエストの監査情報をコンテキストに格納する
+// X-Request-ID が無ければ（または使えない文字を含めば）生成し、レスポンスにも返す
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := requestIDOrNew(r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, requestID)
+		ctx := WithMeta(r.Context(), Meta{
+			RequestID: requestID,
+			Source:    SourceHTTP,
+			Reason:    r.Header.Get(ReasonHeader),
+			ClientIP:  clientIP(r),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// UnaryServerInterceptor はgRPCリクエストの監査情報をコンテキストに格納する
+// メタデータ x-request-id が無ければ生成し、レスポンスのヘッダーにも返す
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		meta := Meta{Source: SourceGRPC}
+		var requestID string
+		if v := md.Get("x-request-id"); len(v) > 0 {
+			requestID = v[0]
+		}
+		meta.RequestID = requestIDOrNew(requestID)
+		grpc.SetHeader(ctx, metadata.Pairs("x-request-id", meta.RequestID))
+		if v := md.Get("x-audit-reason"); len(v) > 0 {
+			meta.Reason = v[0]
+		}
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			meta.ClientIP = hostOf(p.Addr.String())
+		}
+		return handler(WithMeta(ctx, meta), req)
+	}
+}
+
+// requestIDOrNew は受け取ったリクエストIDを返す
+// 空・長すぎる・英数字と - _ . : 以外を含む場合は、ログを汚さないよう新しく生成する
+func requestIDOrNew(id string) string {
+	if id == "" || len(id) > maxRequestIDLen {
+		return uuid.NewString()
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return uuid.NewString()
+		}
+	}
+	return id
+}
+
+// clientIP はHTTPリクエストの送信元IPアドレスを返す
+func clientIP(r *http.Request) string {
+	if trustProxyHeaders {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	return hostOf(r.RemoteAddr)
+}
+
+// hostOf は "host:port" からホスト部分を取り出す
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
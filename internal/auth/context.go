@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+)
+
+// コンテキストのキー（他パッケージのキーと衝突しないよう非公開の型にする）
+type contextKey struct{}
+
+// WithUser は認証済みユーザーをコンテキストに格納する
+func WithUser(ctx context.Context, user *entity.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFrom はコンテキストから認証済みユーザーを取り出す
+// 未認証の場合は nil, false を返す
+func UserFrom(ctx context.Context) (*entity.User, bool) {
+	user, ok := ctx.Value(contextKey{}).(*entity.User)
+	return user, ok && user != nil
+}
+
+// HasRole は認証済みユーザーが指定したロールのいずれかを持つかを判定する
+func HasRole(ctx context.Context, roles ...string) bool {
+	user, ok := UserFrom(ctx)
+	if !ok {
+		return false
+	}
+	for _, role := range roles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Config は認証の設定
+type Config struct {
+	// Supabase AuthのJWTシークレット
+	JWTSecret []byte
+}
+
+// NewConfigFromEnv は環境変数から認証の設定を読み込む
+func NewConfigFromEnv() Config {
+	return Config{JWTSecret: []byte(os.Getenv("SUPABASE_JWT_SECRET"))}
+}
+
+// UserFinder は認証IDからユーザーを引くためのインターフェース
+// repository.UserRepository が満たす
+type UserFinder interface {
+	FindByAuthID(ctx context.Context, authID string) (*entity.User, error)
+}
+
+// Authenticator はリクエストのBearerトークンを検証し、ユーザーをコンテキストに格納する
+type Authenticator struct {
+	cfg   Config
+	users UserFinder
+}
+
+// NewAuthenticator はAuthenticatorを生成する
+func NewAuthenticator(cfg Config, users UserFinder) *Authenticator {
+	return &Authenticator{cfg: cfg, users: users}
+}
+
+// Middleware はAuthorizationヘッダーがBearerトークンであれば検証するミドルウェア
+// ヘッダーが無いリクエストはそのまま通し、権限が必要なルートで RequireRole によって弾く
+// Bearer 以外の方式（Mattermost の "Token xxx" など）は各ハンドラーで検証する
+// ブラウザの WebSocket はヘッダーを付けられないため、WebSocket へのアップグレードに限りクエリの access_token も受け付ける
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			token = r.URL.Query().Get("access_token")
+			ok = token != ""
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, err := a.authenticate(r.Context(), token)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// UnaryServerInterceptor はメタデータ authorization が Bearer トークンであれば検証するgRPCのインターセプター
+// メタデータが無いリクエストはそのまま通し、ユーザーが必要なメソッドで弾く
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		v := md.Get("authorization")
+		if len(v) == 0 {
+			return handler(ctx, req)
+		}
+		token, ok := strings.CutPrefix(v[0], "Bearer ")
+		if !ok {
+			return handler(ctx, req)
+		}
+		user, err := a.authenticate(ctx, token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(WithUser(ctx, user), req)
+	}
+}
+
+// authenticate はJWTを検証し、トークンのユーザーを返す
+func (a *Authenticator) authenticate(ctx context.Context, token string) (*entity.User, error) {
+	if len(a.cfg.JWTSecret) == 0 {
+		return nil, ErrInvalidToken
+	}
+	claims, err := VerifyHS256(token, a.cfg.JWTSecret, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.users.FindByAuthID(ctx, claims.Subject)
+	if err != nil {
+		return nil, ErrUnknownUser
+	}
+	return user, nil
+}
+
+// RequireUser は認証済みのユーザーだけを通すミドルウェア
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserFrom(r.Context()); !ok {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole は指定したロールのいずれかを持つユーザーだけを通すミドルウェア
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := UserFrom(r.Context()); !ok {
+				http.Error(w, "authentication required", http.StatusUnauthorized)
+				return
+			}
+			if !HasRole(r.Context(), roles...) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
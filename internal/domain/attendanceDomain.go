@@ -0,0 +1,715 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/audit"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+	"github.com/enkazu1116/go_home/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 勤務日は日本時間で区切る
+// 日本には夏時間が無いため、tzdataに依存しない固定オフセットで扱う
+var JST = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+// 始業時刻（これより後の出勤は遅刻とする）
+const WorkStart = 9 * time.Hour
+
+// 終業時刻（退勤の打刻漏れを自動退勤にする場合の退勤時刻）
+const WorkEnd = 18 * time.Hour
+
+var (
+	ErrAlreadyCheckedIn = errors.New("already checked in today")
+	ErrNotCheckedIn     = errors.New("not checked in")
+	ErrAlreadyOnBreak   = errors.New("already on break")
+	ErrNotOnBreak       = errors.New("not on break")
+	ErrInvalidPunchTime = errors.New("check-out must be after check-in")
+	ErrPeriodClosed     = errors.New("attendance period is closed")
+)
+
+// 打刻ログ導入前の勤怠から作ったイベントの Source
+const punchSourceBackfill = "backfill"
+
+// 退勤の打刻漏れを自動退勤にしたイベントの Source
+const punchSourceAutoClose = "auto_close"
+
+// WorkDate は打刻時刻から勤務日（日本時間の0時）を求める
+func WorkDate(t time.Time) time.Time {
+	y, m, d := t.In(JST).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, JST)
+}
+
+// 勤怠ユースケースのインターフェースを定義
+// 勤怠は打刻イベントからの投影のため、打刻・修正はすべてイベントを追記してから勤怠を組み立て直す
+type AttendanceUsecase interface {
+
+	// 1件取得
+	FindByID(ctx context.Context, id string) (*entity.Attendance, error)
+
+	// ユーザーごとの一覧取得
+	FindByUserID(ctx context.Context, userID string) ([]entity.Attendance, error)
+
+	// ユーザーの勤務日の勤怠を取得
+	FindByUserAndDate(ctx context.Context, userID string, date time.Time) (*entity.Attendance, error)
+
+	// 全件取得
+	FindAll(ctx context.Context) ([]entity.Attendance, error)
+
+	// 更新（打刻の修正）
+	// 出勤・退勤の時刻の変更は訂正イベント、退勤の取り消しは取消イベントとして記録する
+	Update(ctx context.Context, a entity.Attendance) error
+
+	// 出勤打刻
+	CheckIn(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error)
+
+	// 退勤打刻
+	CheckOut(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error)
+
+	// 休憩開始
+	BreakStart(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error)
+
+	// 休憩終了
+	BreakEnd(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error)
+
+	// 指定した月（日本時間）の勤怠を打刻イベントから組み立て直す
+	Rebuild(ctx context.Context, userID string, month time.Time) ([]entity.Attendance, error)
+
+	// 退勤していない勤怠を退勤の打刻漏れ（要修正）にする
+	// closeAt がゼロでなければその時刻で自動的に退勤し、ゼロなら打刻漏れの印だけを付ける
+	// 勤怠が既に退勤済み・打刻漏れの場合は ErrNotCheckedIn を返す
+	FlagMissingCheckOut(ctx context.Context, id string, closeAt time.Time) (*entity.Attendance, error)
+
+	// 月（日本時間）を締める。締めた月の勤怠は修正・再構築・オフライン打刻の取り込みができなくなる
+	ClosePeriod(ctx context.Context, month time.Time) (*entity.ClosedPeriod, error)
+
+	// 月の締めを解除する
+	ReopenPeriod(ctx context.Context, month time.Time) error
+
+	// 締め済みの月の一覧取得
+	ListClosedPeriods(ctx context.Context) ([]entity.ClosedPeriod, error)
+
+	// オフラインで記録した打刻をまとめて取り込み、打刻ごとの結果を送られた順に返す
+	Sync(ctx context.Context, userID string, punches []OfflinePunch, now time.Time) ([]SyncResult, error)
+
+	// 打刻ポリシーに違反した勤怠をマネージャーが確認済みにする
+	Review(ctx context.Context, id string) (*entity.Attendance, error)
+
+	// 打刻ログ導入前に作られた勤怠の出勤・退勤のイベントを補い、補った勤怠の件数を返す
+	// 打刻ログを導入した後に一度だけ実行する（既にイベントのある勤怠は補わないため、何度実行してもよい）
+	BackfillPunchLog(ctx context.Context) (int, error)
+}
+
+// 勤怠ユースケースの構造体を定義
+// 打刻・修正・再構築は、打刻イベント・勤怠・監査ログ・ドメインイベントを1つのトランザクションで保存する
+type attendanceUsecase struct {
+	repo      repository.AttendanceRepository
+	audit     repository.AuditRepository
+	outbox    repository.OutboxRepository
+	tx        repository.UnitOfWork
+	punchLog  PunchLogUsecase
+	periods   repository.ClosedPeriodRepository
+	policy    PunchPolicyUsecase
+	dashboard DashboardUsecase
+}
+
+// 1件取得呼び出し
+func (u *attendanceUsecase) FindByID(ctx context.Context, id string) (*entity.Attendance, error) {
+	return u.repo.FindByID(ctx, id)
+}
+
+// ユーザーごとの一覧取得呼び出し
+func (u *attendanceUsecase) FindByUserID(ctx context.Context, userID string) ([]entity.Attendance, error) {
+	return u.repo.FindByUserID(ctx, userID)
+}
+
+// 勤務日の勤怠取得呼び出し
+func (u *attendanceUsecase) FindByUserAndDate(ctx context.Context, userID string, date time.Time) (*entity.Attendance, error) {
+	return u.repo.FindByUserAndDate(ctx, userID, WorkDate(date))
+}
+
+// 全件取得呼び出し
+func (u *attendanceUsecase) FindAll(ctx context.Context) ([]entity.Attendance, error) {
+	return u.repo.FindAll(ctx)
+}
+
+// 更新処理呼び出し
+func (u *attendanceUsecase) Update(ctx context.Context, a entity.Attendance) (err error) {
+	ctx, span := tracing.Start(ctx, "AttendanceUsecase.Update", attribute.String("attendance.id", a.ID))
+	defer tracing.End(span, &err)
+	return u.tx.Do(ctx, func(ctx context.Context) error {
+		if err := u.correct(ctx, a); err != nil {
+			return err
+		}
+		after, err := u.repo.FindByID(ctx, a.ID)
+		if err != nil {
+			return err
+		}
+		return publishEvent(ctx, u.outbox, entity.EventAttendanceCorrected, entity.AuditEntityAttendance, a.ID, after)
+	})
+}
+
+// 打刻の修正を訂正・取消イベントとして追記し、勤怠を組み立て直す
+func (u *attendanceUsecase) correct(ctx context.Context, a entity.Attendance) error {
+	current, err := u.repo.FindByID(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	if current.Version != a.Version {
+		return repository.ErrVersionConflict
+	}
+	if !a.CheckOut.IsZero() && !a.CheckOut.After(a.CheckIn) {
+		return ErrInvalidPunchTime
+	}
+	// 締めた月の勤怠は修正できず、締めた月に勤務日を移すこともできない
+	if err := u.ensureOpen(ctx, current.Date); err != nil {
+		return err
+	}
+	if !a.CheckIn.IsZero() {
+		if err := u.ensureOpen(ctx, a.CheckIn); err != nil {
+			return err
+		}
+	}
+
+	from, to := current.Date, current.Date
+	if !a.CheckIn.IsZero() && !a.CheckIn.Equal(current.CheckIn) {
+		if date := WorkDate(a.CheckIn); date.Before(from) {
+			from = date
+		} else if date.After(to) {
+			to = date
+		}
+	}
+	to = to.AddDate(0, 0, 1)
+
+	events, err := u.events(ctx, current.UserID, from, to)
+	if err != nil {
+		return err
+	}
+	src, ok := projectAttendances(current.UserID, events).sources[current.ID]
+	if !ok {
+		// イベントから組み立てられない勤怠は、組み立て直すと消えるため修正できない
+		return repository.ErrNotFound
+	}
+
+	if !a.CheckIn.IsZero() && !a.CheckIn.Equal(current.CheckIn) {
+		e, err := u.appendPunch(ctx, entity.PunchEvent{
+			UserID:       current.UserID,
+			Type:         entity.PunchCorrection,
+			OccurredAt:   a.CheckIn,
+			AttendanceID: current.ID,
+			TargetID:     src.CheckIn,
+		})
+		if err != nil {
+			return err
+		}
+		events = append(events, *e)
+	}
+	if !a.CheckOut.Equal(current.CheckOut) {
+		e := entity.PunchEvent{UserID: current.UserID, OccurredAt: a.CheckOut, AttendanceID: current.ID, TargetID: src.CheckOut}
+		switch {
+		case a.CheckOut.IsZero():
+			// 退勤の取り消し（時刻は取り消した時点）
+			e.Type = entity.PunchVoid
+			e.OccurredAt = time.Now()
+		case src.CheckOut != "":
+			e.Type = entity.PunchCorrection
+		default:
+			// 退勤の打刻漏れを補う
+			e.Type = entity.PunchCheckOut
+		}
+		if e.TargetID != "" || e.Type == entity.PunchCheckOut {
+			appended, err := u.appendPunch(ctx, e)
+			if err != nil {
+				return err
+			}
+			events = append(events, *appended)
+		}
+	}
+
+	_, err = u.reproject(ctx, current.UserID, events, from, to)
+	return err
+}
+
+// 出勤打刻
+// 同じ勤務日に2回出勤することはできない
+// コミット後に在席状況をダッシュボードに配る（退勤・休憩も同じ）
+func (u *attendanceUsecase) CheckIn(ctx context.Context, userID string, at time.Time) (a *entity.Attendance, err error) {
+	ctx, span := tracing.Start(ctx, "AttendanceUsecase.Punch", attribute.String("user.id", userID), attribute.String("punch.type", entity.PunchCheckIn))
+	defer tracing.End(span, &err)
+	a, err = u.inTx(ctx, func(ctx context.Context) (*entity.Attendance, error) {
+		date := WorkDate(at)
+		events, err := u.events(ctx, userID, date, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		p := projectAttendances(userID, events)
+		if p.find(date) != nil {
+			return nil, ErrAlreadyCheckedIn
+		}
+		e := entity.PunchEvent{
+			UserID:       userID,
+			Type:         entity.PunchCheckIn,
+			OccurredAt:   at,
+			AttendanceID: uuid.NewString(),
+		}
+		if err := u.applyPolicy(ctx, &e); err != nil {
+			return nil, err
+		}
+		return u.punch(ctx, events, e, date)
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.dashboard.Publish(ctx, entity.EventCheckedIn, *a)
+	return a, nil
+}
+
+// 退勤打刻
+// まだ退勤していない最新の勤怠に退勤時刻を記録する
+func (u *attendanceUsecase) CheckOut(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error) {
+	return u.punchOpen(ctx, userID, entity.PunchCheckOut, at)
+}
+
+// 休憩開始
+func (u *attendanceUsecase) BreakStart(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error) {
+	return u.punchOpen(ctx, userID, entity.PunchBreakStart, at)
+}
+
+// 休憩終了
+func (u *attendanceUsecase) BreakEnd(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error) {
+	return u.punchOpen(ctx, userID, entity.PunchBreakEnd, at)
+}
+
+// 勤怠の再構築呼び出し
+func (u *attendanceUsecase) Rebuild(ctx context.Context, userID string, month time.Time) (list []entity.Attendance, err error) {
+	ctx, span := tracing.Start(ctx, "AttendanceUsecase.Rebuild", attribute.String("user.id", userID), attribute.String("period.month", periodKey(month)))
+	defer tracing.End(span, &err)
+	err = u.tx.Do(ctx, func(ctx context.Context) error {
+		y, m, _ := month.In(JST).Date()
+		from := time.Date(y, m, 1, 0, 0, 0, 0, JST)
+		to := from.AddDate(0, 1, 0)
+		if err := u.ensureOpen(ctx, from); err != nil {
+			return err
+		}
+		events, err := u.events(ctx, userID, from, to)
+		if err != nil {
+			return err
+		}
+		list, err = u.reproject(ctx, userID, events, from, to)
+		return err
+	})
+	return list, err
+}
+
+// 退勤の打刻漏れ呼び出し
+func (u *attendanceUsecase) FlagMissingCheckOut(ctx context.Context, id string, closeAt time.Time) (a *entity.Attendance, err error) {
+	ctx, span := tracing.Start(ctx, "AttendanceUsecase.FlagMissingCheckOut", attribute.String("attendance.id", id))
+	defer tracing.End(span, &err)
+	return u.inTx(ctx, func(ctx context.Context) (*entity.Attendance, error) {
+		current, err := u.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		// 後の勤務日に出勤していれば退勤していない勤怠ではなくなるため、上限を設けずに読み込む
+		events, err := u.events(ctx, current.UserID, current.Date, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		p := projectAttendances(current.UserID, events)
+		if p.open == nil || p.open.ID != id || p.open.Incomplete {
+			return nil, ErrNotCheckedIn
+		}
+
+		var a *entity.Attendance
+		if !closeAt.IsZero() {
+			a, err = u.punch(ctx, events, entity.PunchEvent{
+				UserID:       current.UserID,
+				Type:         entity.PunchCheckOut,
+				OccurredAt:   closeAt,
+				Source:       punchSourceAutoClose,
+				AttendanceID: id,
+			}, current.Date)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			e, err := u.appendPunch(ctx, entity.PunchEvent{
+				UserID:       current.UserID,
+				Type:         entity.PunchMissingCheckOut,
+				OccurredAt:   time.Now(),
+				AttendanceID: id,
+				TargetID:     p.sources[id].CheckIn,
+			})
+			if err != nil {
+				return nil, err
+			}
+			list, err := u.reproject(ctx, current.UserID, append(events, *e), current.Date, current.Date.AddDate(0, 0, 1))
+			if err != nil {
+				return nil, err
+			}
+			if len(list) == 0 {
+				return nil, repository.ErrNotFound
+			}
+			a = &list[0]
+		}
+		if err := publishEvent(ctx, u.outbox, entity.EventAttendanceIncomplete, entity.AuditEntityAttendance, a.ID, a); err != nil {
+			return nil, err
+		}
+		return a, nil
+	})
+}
+
+// 勤怠の確認呼び出し
+// 確認済みのイベントを追記し、違反の印を外す（確認待ちでなければ何もしない）
+func (u *attendanceUsecase) Review(ctx context.Context, id string) (a *entity.Attendance, err error) {
+	ctx, span := tracing.Start(ctx, "AttendanceUsecase.Review", attribute.String("attendance.id", id))
+	defer tracing.End(span, &err)
+	return u.inTx(ctx, func(ctx context.Context) (*entity.Attendance, error) {
+		current, err := u.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !current.NeedsReview {
+			return current, nil
+		}
+		if err := u.ensureOpen(ctx, current.Date); err != nil {
+			return nil, err
+		}
+		events, err := u.events(ctx, current.UserID, current.Date, current.Date.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+		e, err := u.appendPunch(ctx, entity.PunchEvent{
+			UserID:       current.UserID,
+			Type:         entity.PunchReview,
+			OccurredAt:   time.Now(),
+			AttendanceID: id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		list, err := u.reproject(ctx, current.UserID, append(events, *e), current.Date, current.Date.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return nil, repository.ErrNotFound
+		}
+		if err := publishEvent(ctx, u.outbox, entity.EventAttendanceReviewed, entity.AuditEntityAttendance, id, list[0]); err != nil {
+			return nil, err
+		}
+		return &list[0], nil
+	})
+}
+
+// 打刻ポリシーで勤務場所を判定し、違反した打刻は拒否するか確認待ちの印を付ける
+// 打刻端末（キオスク・ICカードリーダー）からの打刻は事務所での打刻とする
+func (u *attendanceUsecase) applyPolicy(ctx context.Context, e *entity.PunchEvent) error {
+	meta := audit.MetaFrom(ctx)
+	switch meta.Source {
+	case audit.SourceKiosk, audit.SourceCard:
+		e.WorkLocation = entity.WorkLocationOffice
+		return nil
+	}
+	d, err := u.policy.Evaluate(ctx, e.UserID, meta.ClientIP, punchLocationFrom(ctx))
+	if err != nil || d == nil {
+		return err
+	}
+	if d.Violation != "" && d.Reject {
+		return fmt.Errorf("%w: %s", ErrPunchPolicyViolation, d.Violation)
+	}
+	e.WorkLocation, e.Violation = d.WorkLocation, d.Violation
+	return nil
+}
+
+// 退勤していない勤怠に対する打刻（退勤・休憩開始・休憩終了）
+func (u *attendanceUsecase) punchOpen(ctx context.Context, userID, punchType string, at time.Time) (a *entity.Attendance, err error) {
+	ctx, span := tracing.Start(ctx, "AttendanceUsecase.Punch", attribute.String("user.id", userID), attribute.String("punch.type", punchType))
+	defer tracing.End(span, &err)
+	a, err = u.inTx(ctx, func(ctx context.Context) (*entity.Attendance, error) {
+		from, err := u.openFrom(ctx, userID, at)
+		if err != nil {
+			return nil, err
+		}
+		events, err := u.events(ctx, userID, from, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		p := projectAttendances(userID, events)
+		switch {
+		case p.open == nil:
+			return nil, ErrNotCheckedIn
+		case punchType == entity.PunchBreakStart && p.onBreak:
+			return nil, ErrAlreadyOnBreak
+		case punchType == entity.PunchBreakEnd && !p.onBreak:
+			return nil, ErrNotOnBreak
+		}
+		e := entity.PunchEvent{
+			UserID:       userID,
+			Type:         punchType,
+			OccurredAt:   at,
+			AttendanceID: p.open.ID,
+		}
+		if err := u.applyPolicy(ctx, &e); err != nil {
+			return nil, err
+		}
+		return u.punch(ctx, events, e, p.open.Date)
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.dashboard.Publish(ctx, punchEventTypes[punchType], *a)
+	return a, nil
+}
+
+// 打刻の種類ごとのドメインイベント
+var punchEventTypes = map[string]string{
+	entity.PunchCheckIn:    entity.EventCheckedIn,
+	entity.PunchCheckOut:   entity.EventCheckedOut,
+	entity.PunchBreakStart: entity.EventBreakStarted,
+	entity.PunchBreakEnd:   entity.EventBreakEnded,
+}
+
+// fn を1つのトランザクションで実行する
+func (u *attendanceUsecase) inTx(ctx context.Context, fn func(ctx context.Context) (*entity.Attendance, error)) (*entity.Attendance, error) {
+	var a *entity.Attendance
+	err := u.tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		a, err = fn(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// 打刻イベントを追記し、勤務日 date の勤怠を組み立て直して返す
+// 打刻に対応するドメインイベントもアウトボックスに追加する
+func (u *attendanceUsecase) punch(ctx context.Context, events []entity.PunchEvent, e entity.PunchEvent, date time.Time) (*entity.Attendance, error) {
+	recorded, err := u.appendPunch(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	list, err := u.reproject(ctx, e.UserID, append(events, *recorded), date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	if err := publishEvent(ctx, u.outbox, punchEventTypes[e.Type], entity.AuditEntityAttendance, list[0].ID, list[0]); err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// 打刻の生データをハッシュチェーンに記録する
+func (u *attendanceUsecase) appendPunch(ctx context.Context, e entity.PunchEvent) (*entity.PunchEvent, error) {
+	meta := audit.MetaFrom(ctx)
+	if e.Source == "" {
+		e.Source = meta.Source
+	}
+	e.Reason = meta.Reason
+	e.DeviceID = meta.DeviceID
+	return u.punchLog.Record(ctx, e)
+}
+
+// 勤務日が from 以上 to 未満の勤怠を組み立てるのに要る打刻イベントを取得する（to がゼロなら上限なし）
+// 日付をまたいだ勤務の退勤・休憩や前の勤務日から続く勤怠も組み立てられるよう、前後1日の打刻と、
+// それらと同じ勤怠の訂正・取消なども含めて読み込む
+func (u *attendanceUsecase) events(ctx context.Context, userID string, from, to time.Time) ([]entity.PunchEvent, error) {
+	var upper time.Time
+	if !to.IsZero() {
+		upper = to.AddDate(0, 0, 1)
+	}
+	return u.punchLog.EventsInRange(ctx, userID, from.AddDate(0, 0, -1), upper)
+}
+
+// openFrom は時刻 at の退勤・休憩の打刻を付ける勤怠を組み立てるのに要る期間の始まり（勤務日）を返す
+// 退勤していない勤怠は at 以前の最後の出勤の勤怠なので、その勤務日から読み込めば足りる
+func (u *attendanceUsecase) openFrom(ctx context.Context, userID string, at time.Time) (time.Time, error) {
+	date := WorkDate(at)
+	last, err := u.punchLog.LastCheckIn(ctx, userID, at)
+	if errors.Is(err, repository.ErrNotFound) {
+		return date, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if d := WorkDate(last.OccurredAt); d.Before(date) {
+		return d, nil
+	}
+	return date, nil
+}
+
+// 打刻ログの補完呼び出し
+// 打刻ログ導入前に作られた勤怠はイベントが無いため、組み立て直しで消えないよう出勤・退勤のイベントを補う
+func (u *attendanceUsecase) BackfillPunchLog(ctx context.Context) (n int, err error) {
+	ctx, span := tracing.Start(ctx, "AttendanceUsecase.BackfillPunchLog")
+	defer tracing.End(span, &err)
+	err = u.tx.Do(ctx, func(ctx context.Context) error {
+		all, err := u.repo.FindAll(ctx)
+		if err != nil {
+			return err
+		}
+		byUser := map[string][]entity.Attendance{}
+		var userIDs []string
+		for _, a := range all {
+			if _, ok := byUser[a.UserID]; !ok {
+				userIDs = append(userIDs, a.UserID)
+			}
+			byUser[a.UserID] = append(byUser[a.UserID], a)
+		}
+		for _, userID := range userIDs {
+			filled, err := u.backfill(ctx, userID, byUser[userID])
+			if err != nil {
+				return fmt.Errorf("user %s: %w", userID, err)
+			}
+			n += filled
+		}
+		return nil
+	})
+	return n, err
+}
+
+// ユーザーの勤怠のうち、打刻イベントから組み立てられないものに出勤・退勤のイベントを補う
+func (u *attendanceUsecase) backfill(ctx context.Context, userID string, list []entity.Attendance) (int, error) {
+	events, err := u.punchLog.Events(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	known := map[string]bool{}
+	for _, e := range events {
+		known[e.AttendanceID] = true
+	}
+	for _, a := range projectAttendances(userID, events).attendances {
+		known[a.ID] = true
+	}
+
+	n := 0
+	for _, a := range list {
+		if known[a.ID] || a.CheckIn.IsZero() {
+			continue
+		}
+		_, err := u.punchLog.Record(ctx, entity.PunchEvent{
+			UserID:       userID,
+			Type:         entity.PunchCheckIn,
+			OccurredAt:   a.CheckIn,
+			Source:       punchSourceBackfill,
+			AttendanceID: a.ID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		n++
+		if a.CheckOut.IsZero() {
+			continue
+		}
+		_, err = u.punchLog.Record(ctx, entity.PunchEvent{
+			UserID:       userID,
+			Type:         entity.PunchCheckOut,
+			OccurredAt:   a.CheckOut,
+			Source:       punchSourceBackfill,
+			AttendanceID: a.ID,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// 勤務日が from 以上 to 未満の勤怠を打刻イベントから組み立て直し、保存済みの勤怠との差分だけを反映する
+// 反映した内容は監査ログに記録し、組み立て直した後の勤怠を勤務日順に返す
+func (u *attendanceUsecase) reproject(ctx context.Context, userID string, events []entity.PunchEvent, from, to time.Time) ([]entity.Attendance, error) {
+	p := projectAttendances(userID, events)
+	existing, err := u.repo.FindByUserAndRange(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// 勤務日の変わった勤怠を作り直す前に、今の勤務日と合わない勤怠を消す
+	// 打刻イベントの1件も無い勤怠（打刻ログ導入前の勤怠で、まだ補っていないもの）は組み立て直さずに残す
+	kept := map[string]entity.Attendance{}
+	removed := map[string]entity.Attendance{}
+	var untracked []entity.Attendance
+	for _, old := range existing {
+		if a := p.find(old.Date); a != nil && a.ID == old.ID {
+			kept[old.ID] = old
+			continue
+		}
+		if _, ok := p.sources[old.ID]; !ok {
+			untracked = append(untracked, old)
+			continue
+		}
+		if err := u.repo.Delete(ctx, old); err != nil {
+			return nil, err
+		}
+		removed[old.ID] = old
+	}
+
+	result := []entity.Attendance{}
+	for _, a := range p.attendances {
+		if a.Date.Before(from) || !a.Date.Before(to) {
+			continue
+		}
+		before, ok := kept[a.ID]
+		if ok && !projectionChanged(before, a) {
+			result = append(result, before)
+			continue
+		}
+		if ok {
+			a.Version = before.Version
+			err = u.repo.Update(ctx, a)
+		} else {
+			// 勤務日が変わっただけの勤怠は、作り直してもバージョンを引き継ぐ
+			if moved, found := removed[a.ID]; found {
+				before, ok = moved, true
+				a.Version = moved.Version + 1
+				delete(removed, a.ID)
+			}
+			err = u.repo.Create(ctx, a)
+		}
+		if err != nil {
+			return nil, err
+		}
+		saved, err := u.repo.FindByID(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			err = recordAudit(ctx, u.audit, entity.AuditEntityAttendance, a.ID, entity.AuditActionUpdate, &before, saved)
+		} else {
+			err = recordAudit(ctx, u.audit, entity.AuditEntityAttendance, a.ID, entity.AuditActionCreate, nil, saved)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *saved)
+	}
+	for _, old := range existing {
+		if _, ok := removed[old.ID]; !ok {
+			continue
+		}
+		if err := recordAudit(ctx, u.audit, entity.AuditEntityAttendance, old.ID, entity.AuditActionDelete, &old, nil); err != nil {
+			return nil, err
+		}
+	}
+	if len(untracked) > 0 {
+		result = append(result, untracked...)
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Date.Before(result[j].Date) })
+	}
+	return result, nil
+}
+
+func NewAttendanceUsecase(repo repository.AttendanceRepository, auditRepo repository.AuditRepository, outbox repository.OutboxRepository, tx repository.UnitOfWork, punchLog PunchLogUsecase, periods repository.ClosedPeriodRepository, policy PunchPolicyUsecase, dashboard DashboardUsecase) AttendanceUsecase {
+	return &attendanceUsecase{repo: repo, audit: auditRepo, outbox: outbox, tx: tx, punchLog: punchLog, periods: periods, policy: policy, dashboard: dashboard}
+}
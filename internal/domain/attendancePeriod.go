@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// periodKey は時刻の属する月（日本時間）を "2006-01" で返す
+func periodKey(t time.Time) string {
+	return t.In(JST).Format("2006-01")
+}
+
+// 締め呼び出し
+func (u *attendanceUsecase) ClosePeriod(ctx context.Context, month time.Time) (_ *entity.ClosedPeriod, err error) {
+	ctx, span := tracing.Start(ctx, "AttendanceUsecase.ClosePeriod", attribute.String("period.month", periodKey(month)))
+	defer tracing.End(span, &err)
+	c := entity.ClosedPeriod{Month: periodKey(month), CreatedAt: time.Now()}
+	if user, ok := auth.UserFrom(ctx); ok {
+		c.ClosedBy = user.ID
+	}
+	if err := u.periods.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// 締めの解除呼び出し
+func (u *attendanceUsecase) ReopenPeriod(ctx context.Context, month time.Time) error {
+	return u.periods.Delete(ctx, periodKey(month))
+}
+
+// 締め済みの月の一覧取得呼び出し
+func (u *attendanceUsecase) ListClosedPeriods(ctx context.Context) ([]entity.ClosedPeriod, error) {
+	return u.periods.FindAll(ctx)
+}
+
+// ensureOpen は時刻の勤務日の属する月が締め済みなら ErrPeriodClosed を返す
+func (u *attendanceUsecase) ensureOpen(ctx context.Context, t time.Time) error {
+	closed, err := u.periods.IsClosed(ctx, periodKey(WorkDate(t)))
+	if err != nil {
+		return err
+	}
+	if closed {
+		return ErrPeriodClosed
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+// 打刻イベントから勤怠を組み立てた結果
+type attendanceProjection struct {
+	// 勤務日順の勤怠
+	attendances []entity.Attendance
+	// 勤怠IDごとの元になった出勤・退勤イベントのID（打刻の修正で訂正・取消の対象にする）
+	sources map[string]attendanceSource
+	// まだ退勤していない勤怠（無ければ nil）
+	open *entity.Attendance
+	// 休憩中かどうか
+	onBreak bool
+}
+
+type attendanceSource struct {
+	CheckIn  string
+	CheckOut string
+}
+
+// 勤怠を引く
+func (p *attendanceProjection) find(date time.Time) *entity.Attendance {
+	for i := range p.attendances {
+		if p.attendances[i].Date.Equal(date) {
+			return &p.attendances[i]
+		}
+	}
+	return nil
+}
+
+// projectAttendances は1人分の打刻イベントから勤怠を組み立てる
+// 同じイベント列からは常に同じ結果になるため、始業時刻などのルールを変えた後に何度でも作り直せる
+//
+//  1. 取消（void）されたイベントを除き、訂正（correction）を対象イベントの時刻に反映する
+//  2. 残った打刻を時刻順（同時刻はSeq順）に並べて、出勤から退勤までを1件の勤怠にまとめる
+//
+// 同じ勤務日に再度出勤した場合は同じ勤怠を続きとして扱い、退勤を付けずに翌日以降に出勤した場合は前の勤怠を退勤なしのまま閉じる
+//
+// 次の勤怠は退勤の打刻漏れ（Incomplete）とする
+//   - 退勤なしのまま閉じた勤怠
+//   - 打刻漏れの印（missing_check_out）があり、まだ退勤していない勤怠
+//   - 自動退勤した勤怠のうち、退勤時刻がまだ訂正されていないもの
+//
+// 勤務場所は出勤の打刻で判定したものとし、打刻ポリシーの違反が確認されていない勤怠は確認待ち（NeedsReview）とする
+func projectAttendances(userID string, events []entity.PunchEvent) attendanceProjection {
+	voided := map[string]bool{}
+	for _, e := range events {
+		if e.Type == entity.PunchVoid {
+			voided[e.TargetID] = true
+		}
+	}
+	flagged := map[string]bool{}
+	for _, e := range events {
+		if e.Type == entity.PunchMissingCheckOut && !voided[e.ID] {
+			flagged[e.TargetID] = true
+		}
+	}
+
+	punches := map[string]*entity.PunchEvent{}
+	var order []*entity.PunchEvent
+	for _, e := range events {
+		switch e.Type {
+		case entity.PunchCheckIn, entity.PunchCheckOut, entity.PunchBreakStart, entity.PunchBreakEnd:
+			if voided[e.ID] {
+				continue
+			}
+			e := e
+			punches[e.ID] = &e
+			order = append(order, &e)
+		}
+	}
+	// 訂正はSeq順に適用するので、同じイベントを何度か訂正した場合は最後の訂正が残る
+	corrected := map[string]bool{}
+	for _, e := range events {
+		if e.Type != entity.PunchCorrection || voided[e.ID] {
+			continue
+		}
+		if target, ok := punches[e.TargetID]; ok {
+			target.OccurredAt = e.OccurredAt
+			corrected[target.ID] = true
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if !order[i].OccurredAt.Equal(order[j].OccurredAt) {
+			return order[i].OccurredAt.Before(order[j].OccurredAt)
+		}
+		return order[i].Seq < order[j].Seq
+	})
+
+	p := attendanceProjection{sources: map[string]attendanceSource{}}
+	breaks := map[string]time.Duration{}
+	var openID string
+	var breakStart time.Time
+	current := func() *entity.Attendance {
+		for i := range p.attendances {
+			if p.attendances[i].ID == openID {
+				return &p.attendances[i]
+			}
+		}
+		return nil
+	}
+
+	for _, e := range order {
+		at := e.OccurredAt
+		switch e.Type {
+		case entity.PunchCheckIn:
+			date := WorkDate(at)
+			if a := current(); a != nil && a.Date.Equal(date) {
+				continue
+			}
+			openID, breakStart = "", time.Time{}
+			if a := p.find(date); a != nil {
+				// 同じ勤務日の再出勤は、前回の退勤を取り消して続きとする
+				a.CheckOut = time.Time{}
+				src := p.sources[a.ID]
+				src.CheckOut = ""
+				p.sources[a.ID] = src
+				openID = a.ID
+				continue
+			}
+			id := e.AttendanceID
+			if id == "" {
+				id = uuid.NewSHA1(uuid.NameSpaceOID, []byte(userID+"/"+date.Format(time.DateOnly))).String()
+			}
+			p.attendances = append(p.attendances, entity.Attendance{ID: id, UserID: userID, Date: date, CheckIn: at})
+			p.sources[id] = attendanceSource{CheckIn: e.ID}
+			openID = id
+		case entity.PunchCheckOut:
+			a := current()
+			if a == nil {
+				continue
+			}
+			if !breakStart.IsZero() {
+				breaks[a.ID] += at.Sub(breakStart)
+				breakStart = time.Time{}
+			}
+			a.CheckOut = at
+			src := p.sources[a.ID]
+			src.CheckOut = e.ID
+			p.sources[a.ID] = src
+			openID = ""
+		case entity.PunchBreakStart:
+			if current() != nil && breakStart.IsZero() {
+				breakStart = at
+			}
+		case entity.PunchBreakEnd:
+			if a := current(); a != nil && !breakStart.IsZero() {
+				breaks[a.ID] += at.Sub(breakStart)
+				breakStart = time.Time{}
+			}
+		}
+	}
+
+	// 打刻ポリシーに違反した打刻のうち、マネージャーの確認（review）より後のものがあれば確認待ちにする
+	reviewed := map[string]int64{}
+	for _, e := range events {
+		if e.Type == entity.PunchReview && !voided[e.ID] && e.Seq > reviewed[e.AttendanceID] {
+			reviewed[e.AttendanceID] = e.Seq
+		}
+	}
+	needsReview := map[string]bool{}
+	for _, e := range order {
+		if e.Violation != "" && e.Seq > reviewed[e.AttendanceID] {
+			needsReview[e.AttendanceID] = true
+		}
+	}
+
+	p.open = current()
+	for i := range p.attendances {
+		a := &p.attendances[i]
+		a.IsLate = a.CheckIn.Sub(a.Date) > WorkStart
+		a.BreakMinutes = int(breaks[a.ID] / time.Minute)
+
+		src := p.sources[a.ID]
+		if in := punches[src.CheckIn]; in != nil {
+			a.WorkLocation = in.WorkLocation
+		}
+		a.NeedsReview = needsReview[a.ID]
+		if a.CheckOut.IsZero() {
+			a.Incomplete = a.ID != openID || flagged[src.CheckIn]
+		} else if out := punches[src.CheckOut]; out != nil {
+			a.Incomplete = out.Source == punchSourceAutoClose && !corrected[out.ID]
+		}
+	}
+	p.onBreak = p.open != nil && !breakStart.IsZero()
+	return p
+}
+
+// projectionChanged は再構築で勤怠の内容が変わったかどうかを返す
+func projectionChanged(before, after entity.Attendance) bool {
+	return before.ID != after.ID ||
+		!before.CheckIn.Equal(after.CheckIn) ||
+		!before.CheckOut.Equal(after.CheckOut) ||
+		before.IsLate != after.IsLate ||
+		before.BreakMinutes != after.BreakMinutes ||
+		before.Incomplete != after.Incomplete ||
+		before.WorkLocation != after.WorkLocation ||
+		before.NeedsReview != after.NeedsReview
+}
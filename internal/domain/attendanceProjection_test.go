@@ -0,0 +1,295 @@
+package domain
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+)
+
+// jst は "2006-01-02 15:04" の形の日本時間を返す
+func jst(t *testing.T, s string) time.Time {
+	t.Helper()
+	at, err := time.ParseInLocation("2006-01-02 15:04", s, JST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return at
+}
+
+func TestProjectAttendances(t *testing.T) {
+	// ev は Seq 順に並べた打刻イベントを作る（ID は "e<Seq>"）
+	type ev struct {
+		typ        string
+		at         string
+		attendance string
+		target     string // 訂正・取消・打刻漏れの対象イベントの Seq
+		source     string
+		violation  string
+	}
+	type want struct {
+		id          string
+		date        string
+		checkIn     string
+		checkOut    string // 空なら退勤なし
+		breakMin    int
+		late        bool
+		incomplete  bool
+		needsReview bool
+	}
+	tests := []struct {
+		name    string
+		events  []ev
+		want    []want
+		open    string // まだ退勤していない勤怠のID
+		onBreak bool
+	}{
+		{
+			name: "check in and out",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 08:55", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:05", attendance: "a1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 08:55", checkOut: "2026-10-01 18:05"}},
+		},
+		{
+			name: "late check in",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:01", attendance: "a1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:01", late: true}},
+			open: "a1",
+		},
+		{
+			name: "breaks are summed",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchBreakStart, at: "2026-10-01 12:00", attendance: "a1"},
+				{typ: entity.PunchBreakEnd, at: "2026-10-01 12:45", attendance: "a1"},
+				{typ: entity.PunchBreakStart, at: "2026-10-01 15:00", attendance: "a1"},
+				{typ: entity.PunchBreakEnd, at: "2026-10-01 15:15", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", checkOut: "2026-10-01 18:00", breakMin: 60}},
+		},
+		{
+			name: "check out ends an open break",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchBreakStart, at: "2026-10-01 17:30", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", checkOut: "2026-10-01 18:00", breakMin: 30}},
+		},
+		{
+			name: "on break",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchBreakStart, at: "2026-10-01 12:00", attendance: "a1"},
+			},
+			want:    []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00"}},
+			open:    "a1",
+			onBreak: true,
+		},
+		{
+			name: "overnight shift belongs to the check-in work date",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 22:00", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-02 06:00", attendance: "a1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 22:00", checkOut: "2026-10-02 06:00", late: true}},
+		},
+		{
+			name: "correction moves the check-in",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:30", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1"},
+				{typ: entity.PunchCorrection, at: "2026-10-01 08:50", attendance: "a1", target: "1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 08:50", checkOut: "2026-10-01 18:00"}},
+		},
+		{
+			name: "last correction wins",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1"},
+				{typ: entity.PunchCorrection, at: "2026-10-01 19:00", attendance: "a1", target: "2"},
+				{typ: entity.PunchCorrection, at: "2026-10-01 20:00", attendance: "a1", target: "2"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", checkOut: "2026-10-01 20:00"}},
+		},
+		{
+			name: "correction moves the work date",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-02 09:00", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-02 18:00", attendance: "a1"},
+				{typ: entity.PunchCorrection, at: "2026-10-01 09:00", attendance: "a1", target: "1"},
+				{typ: entity.PunchCorrection, at: "2026-10-01 18:00", attendance: "a1", target: "2"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", checkOut: "2026-10-01 18:00"}},
+		},
+		{
+			name: "voided check-out reopens the attendance",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 12:00", attendance: "a1"},
+				{typ: entity.PunchVoid, at: "2026-10-01 12:01", attendance: "a1", target: "2"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00"}},
+			open: "a1",
+		},
+		{
+			name: "check in again on the same work date continues the attendance",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 12:00", attendance: "a1"},
+				{typ: entity.PunchCheckIn, at: "2026-10-01 13:00", attendance: "a2"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", checkOut: "2026-10-01 18:00"}},
+		},
+		{
+			name: "check in on the next day without checking out",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchCheckIn, at: "2026-10-02 09:00", attendance: "a2"},
+			},
+			want: []want{
+				{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", incomplete: true},
+				{id: "a2", date: "2026-10-02", checkIn: "2026-10-02 09:00"},
+			},
+			open: "a2",
+		},
+		{
+			name: "check-out without check-in is ignored",
+			events: []ev{
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1"},
+			},
+		},
+		{
+			name: "missing check-out flag",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchMissingCheckOut, at: "2026-10-02 00:00", attendance: "a1", target: "1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", incomplete: true}},
+			open: "a1",
+		},
+		{
+			name: "checking out clears the missing check-out flag",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchMissingCheckOut, at: "2026-10-02 00:00", attendance: "a1", target: "1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", checkOut: "2026-10-01 18:00"}},
+		},
+		{
+			name: "auto close is incomplete until corrected",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1", source: punchSourceAutoClose},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", checkOut: "2026-10-01 18:00", incomplete: true}},
+		},
+		{
+			name: "corrected auto close",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1", source: punchSourceAutoClose},
+				{typ: entity.PunchCorrection, at: "2026-10-01 19:30", attendance: "a1", target: "2"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", checkOut: "2026-10-01 19:30"}},
+		},
+		{
+			name: "policy violation needs review",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1", violation: "outside geofence"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", needsReview: true}},
+			open: "a1",
+		},
+		{
+			name: "reviewed violation",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1", violation: "outside geofence"},
+				{typ: entity.PunchReview, at: "2026-10-01 10:00", attendance: "a1"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00"}},
+			open: "a1",
+		},
+		{
+			name: "violation after review needs review again",
+			events: []ev{
+				{typ: entity.PunchCheckIn, at: "2026-10-01 09:00", attendance: "a1", violation: "outside geofence"},
+				{typ: entity.PunchReview, at: "2026-10-01 10:00", attendance: "a1"},
+				{typ: entity.PunchCheckOut, at: "2026-10-01 18:00", attendance: "a1", violation: "outside geofence"},
+			},
+			want: []want{{id: "a1", date: "2026-10-01", checkIn: "2026-10-01 09:00", checkOut: "2026-10-01 18:00", needsReview: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make([]entity.PunchEvent, len(tt.events))
+			for i, e := range tt.events {
+				events[i] = entity.PunchEvent{
+					ID:           fmt.Sprintf("e%d", i+1),
+					UserID:       "u1",
+					Seq:          int64(i + 1),
+					Type:         e.typ,
+					OccurredAt:   jst(t, e.at),
+					AttendanceID: e.attendance,
+					Source:       e.source,
+					Violation:    e.violation,
+				}
+				if e.target != "" {
+					events[i].TargetID = "e" + e.target
+				}
+			}
+
+			p := projectAttendances("u1", events)
+			if len(p.attendances) != len(tt.want) {
+				t.Fatalf("got %d attendances, want %d: %+v", len(p.attendances), len(tt.want), p.attendances)
+			}
+			for i, w := range tt.want {
+				a := p.attendances[i]
+				var checkOut time.Time
+				if w.checkOut != "" {
+					checkOut = jst(t, w.checkOut)
+				}
+				switch {
+				case a.ID != w.id, a.UserID != "u1", !a.Date.Equal(jst(t, w.date+" 00:00")):
+					t.Errorf("attendance %d: id/date = %s %s, want %s %s", i, a.ID, a.Date, w.id, w.date)
+				case !a.CheckIn.Equal(jst(t, w.checkIn)) || !a.CheckOut.Equal(checkOut):
+					t.Errorf("attendance %d: check-in/out = %s %s, want %s %s", i, a.CheckIn, a.CheckOut, w.checkIn, w.checkOut)
+				case a.BreakMinutes != w.breakMin, a.IsLate != w.late, a.Incomplete != w.incomplete, a.NeedsReview != w.needsReview:
+					t.Errorf("attendance %d: break=%d late=%v incomplete=%v needsReview=%v, want %+v", i, a.BreakMinutes, a.IsLate, a.Incomplete, a.NeedsReview, w)
+				}
+			}
+			open := ""
+			if p.open != nil {
+				open = p.open.ID
+			}
+			if open != tt.open || p.onBreak != tt.onBreak {
+				t.Errorf("open=%q onBreak=%v, want %q %v", open, p.onBreak, tt.open, tt.onBreak)
+			}
+		})
+	}
+}
+
+// 同じイベント列からは常に同じ勤怠になり、イベントの並び（Seq）が時刻順でなくても時刻順に組み立てる
+func TestProjectAttendancesOrdersByTime(t *testing.T) {
+	events := []entity.PunchEvent{
+		{ID: "e1", Seq: 1, Type: entity.PunchCheckOut, OccurredAt: jst(t, "2026-10-01 18:00"), AttendanceID: "a1"},
+		{ID: "e2", Seq: 2, Type: entity.PunchCheckIn, OccurredAt: jst(t, "2026-10-01 09:00"), AttendanceID: "a1"},
+	}
+	first := projectAttendances("u1", events)
+	second := projectAttendances("u1", events)
+	if len(first.attendances) != 1 || first.attendances[0].CheckOut.IsZero() {
+		t.Fatalf("got %+v, want one checked-out attendance", first.attendances)
+	}
+	if projectionChanged(first.attendances[0], second.attendances[0]) {
+		t.Fatalf("projection is not deterministic: %+v %+v", first.attendances[0], second.attendances[0])
+	}
+}
@@ -0,0 +1,235 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// オフラインで記録した打刻を取り込んだイベントの Source
+const punchSourceOfflineSync = "offline_sync"
+
+// 端末の時計のずれを見込んで、サーバーの時刻よりこれだけ先の打刻までは受け付ける
+const syncMaxClockSkew = 5 * time.Minute
+
+// クライアントが付けるIDの長さの上限
+const maxClientIDLength = 128
+
+// 取り込みの結果
+const (
+	SyncAccepted         = "accepted"          // 取り込んだ
+	SyncDuplicate        = "duplicate"         // 同じクライアントのIDの打刻を取り込み済み
+	SyncOverlapsExisting = "overlaps_existing" // サーバーの勤怠と重なる（同じ勤務日の出勤・退勤済みの勤怠への退勤など）
+	SyncClosedPeriod     = "closed_period"     // 勤務日が締め済みの月
+	SyncInvalid          = "invalid"           // 種類・時刻・IDが正しくない
+)
+
+// OfflinePunch はモバイルアプリがオフラインの間に記録した1件の打刻
+type OfflinePunch struct {
+	// クライアントが打刻ごとに付けるID（再送しても二重に取り込まないために使う）
+	ClientID string
+	// 打刻の種類（check_in・check_out・break_start・break_end）
+	Type string
+	// クライアントで打刻した時刻
+	OccurredAt time.Time
+}
+
+// SyncResult は1件の打刻の取り込みの結果
+type SyncResult struct {
+	ClientID     string
+	Status       string
+	EventID      string // 取り込んだ（重複の場合は取り込み済みの）打刻イベントのID
+	AttendanceID string
+	Message      string
+}
+
+// オフライン打刻の取り込み呼び出し
+// 打刻は時刻順に並べてから1件ずつ判定するため、送られた順番が前後していてもよい
+// 取り込めなかった打刻があっても、他の打刻の取り込みは続ける
+func (u *attendanceUsecase) Sync(ctx context.Context, userID string, punches []OfflinePunch, now time.Time) (_ []SyncResult, err error) {
+	ctx, span := tracing.Start(ctx, "AttendanceUsecase.Sync", attribute.String("user.id", userID), attribute.Int("punch.count", len(punches)))
+	defer tracing.End(span, &err)
+	results := make([]SyncResult, len(punches))
+	order := make([]int, len(punches))
+	for i := range order {
+		order[i] = i
+		results[i].ClientID = punches[i].ClientID
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return punches[order[i]].OccurredAt.Before(punches[order[j]].OccurredAt)
+	})
+
+	err = u.tx.Do(ctx, func(ctx context.Context) error {
+		clientIDs := make([]string, 0, len(punches))
+		for _, p := range punches {
+			clientIDs = append(clientIDs, p.ClientID)
+		}
+		done, err := u.punchLog.EventsByClientIDs(ctx, userID, clientIDs)
+		if err != nil {
+			return err
+		}
+		synced := map[string]entity.PunchEvent{}
+		for _, e := range done {
+			synced[e.ClientID] = e
+		}
+
+		// 最も早い打刻を付ける勤怠から後の打刻イベントだけを読み込む（時刻の無い打刻は取り込まない）
+		var earliest time.Time
+		for _, i := range order {
+			if at := punches[i].OccurredAt; !at.IsZero() {
+				earliest = at
+				break
+			}
+		}
+		var events []entity.PunchEvent
+		if !earliest.IsZero() {
+			from, err := u.openFrom(ctx, userID, earliest)
+			if err != nil {
+				return err
+			}
+			if events, err = u.events(ctx, userID, from, time.Time{}); err != nil {
+				return err
+			}
+		}
+
+		var accepted []entity.PunchEvent
+		var from, to time.Time
+		for _, i := range order {
+			p, res := punches[i], &results[i]
+			if e, ok := synced[p.ClientID]; ok {
+				res.Status, res.EventID, res.AttendanceID = SyncDuplicate, e.ID, e.AttendanceID
+				continue
+			}
+			if msg := validateOfflinePunch(p, now); msg != "" {
+				res.Status, res.Message = SyncInvalid, msg
+				continue
+			}
+			date := WorkDate(p.OccurredAt)
+			if err := u.ensureOpen(ctx, p.OccurredAt); errors.Is(err, ErrPeriodClosed) {
+				res.Status, res.Message = SyncClosedPeriod, err.Error()
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			e, status, msg := placeOfflinePunch(projectAttendances(userID, events), p)
+			if status != "" {
+				res.Status, res.Message = status, msg
+				continue
+			}
+			e.UserID = userID
+			e.Source = punchSourceOfflineSync
+			e.ClientID = p.ClientID
+			recorded, err := u.appendPunch(ctx, e)
+			if err != nil {
+				return err
+			}
+			events = append(events, *recorded)
+			accepted = append(accepted, *recorded)
+			synced[p.ClientID] = *recorded
+			res.Status, res.EventID, res.AttendanceID = SyncAccepted, recorded.ID, recorded.AttendanceID
+
+			// 退勤・休憩は前の勤務日の勤怠に付くことがあるため、勤怠の勤務日も範囲に含める
+			for _, d := range []time.Time{date, attendanceDate(projectAttendances(userID, events), recorded.AttendanceID)} {
+				if d.IsZero() {
+					continue
+				}
+				if from.IsZero() || d.Before(from) {
+					from = d
+				}
+				if d.After(to) {
+					to = d
+				}
+			}
+		}
+		if len(accepted) == 0 {
+			return nil
+		}
+
+		list, err := u.reproject(ctx, userID, events, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		byID := map[string]*entity.Attendance{}
+		for i := range list {
+			byID[list[i].ID] = &list[i]
+		}
+		for _, e := range accepted {
+			if a, ok := byID[e.AttendanceID]; ok {
+				if err := publishEvent(ctx, u.outbox, punchEventTypes[e.Type], entity.AuditEntityAttendance, a.ID, a); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// validateOfflinePunch は打刻の形式を確かめ、正しくなければ理由を返す
+func validateOfflinePunch(p OfflinePunch, now time.Time) string {
+	switch {
+	case p.ClientID == "" || len(p.ClientID) > maxClientIDLength:
+		return "client id is required (up to 128 characters)"
+	case punchEventTypes[p.Type] == "":
+		return "unknown punch type"
+	case p.OccurredAt.IsZero():
+		return "timestamp is required"
+	case p.OccurredAt.After(now.Add(syncMaxClockSkew)):
+		return "timestamp is in the future"
+	}
+	return ""
+}
+
+// placeOfflinePunch は打刻をどの勤怠に付けるかを決める
+// サーバーの勤怠と重なる場合は結果と理由を返す
+func placeOfflinePunch(p attendanceProjection, punch OfflinePunch) (entity.PunchEvent, string, string) {
+	at := punch.OccurredAt
+	e := entity.PunchEvent{Type: punch.Type, OccurredAt: at}
+	if punch.Type == entity.PunchCheckIn {
+		if a := p.find(WorkDate(at)); a != nil {
+			return e, SyncOverlapsExisting, "already checked in on this work date"
+		}
+		e.AttendanceID = uuid.NewString()
+		return e, "", ""
+	}
+
+	// 退勤・休憩は、打刻の時刻より前に出勤した最後の勤怠に付ける
+	var target *entity.Attendance
+	for i := range p.attendances {
+		a := &p.attendances[i]
+		if !a.CheckIn.After(at) && (target == nil || a.CheckIn.After(target.CheckIn)) {
+			target = a
+		}
+	}
+	switch {
+	case target == nil:
+		return e, SyncInvalid, "no check-in before this punch"
+	case punch.Type == entity.PunchCheckOut && !target.CheckOut.IsZero():
+		return e, SyncOverlapsExisting, "already checked out at " + target.CheckOut.In(JST).Format(time.RFC3339)
+	case !target.CheckOut.IsZero() && !at.Before(target.CheckOut):
+		return e, SyncOverlapsExisting, "punch is after check-out at " + target.CheckOut.In(JST).Format(time.RFC3339)
+	}
+	e.AttendanceID = target.ID
+	return e, "", ""
+}
+
+// attendanceDate は勤怠の勤務日を返す（見つからなければゼロ値）
+func attendanceDate(p attendanceProjection, id string) time.Time {
+	for _, a := range p.attendances {
+		if a.ID == id {
+			return a.Date
+		}
+	}
+	return time.Time{}
+}
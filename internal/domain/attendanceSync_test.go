@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+func TestAttendanceSync(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	attendances := repository.NewAttendanceRepository(db)
+	u := NewAttendanceUsecase(attendances, repository.NewAuditRepository(db), repository.NewOutboxRepository(db), repository.NewUnitOfWork(db),
+		NewPunchLogUsecase(PunchLogConfig{}, repository.NewPunchEventRepository(db)), repository.NewClosedPeriodRepository(db), nil, nil)
+	now := jst(t, "2026-10-03 12:00")
+	if _, err := u.ClosePeriod(ctx, jst(t, "2026-09-01 00:00")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 送られた順番が前後していても時刻順に取り込む
+	punches := []OfflinePunch{
+		{ClientID: "c-out", Type: entity.PunchCheckOut, OccurredAt: jst(t, "2026-10-01 18:00")},
+		{ClientID: "c-in", Type: entity.PunchCheckIn, OccurredAt: jst(t, "2026-10-01 09:00")},
+		{ClientID: "c-bs", Type: entity.PunchBreakStart, OccurredAt: jst(t, "2026-10-01 12:00")},
+		{ClientID: "c-be", Type: entity.PunchBreakEnd, OccurredAt: jst(t, "2026-10-01 13:00")},
+		{ClientID: "c-in2", Type: entity.PunchCheckIn, OccurredAt: jst(t, "2026-10-01 19:00")},   // 同じ勤務日の2回目の出勤
+		{ClientID: "c-out2", Type: entity.PunchCheckOut, OccurredAt: jst(t, "2026-10-01 20:00")}, // 退勤済みの勤怠への退勤
+		{ClientID: "c-night", Type: entity.PunchCheckIn, OccurredAt: jst(t, "2026-10-02 22:00")},
+		{ClientID: "c-dawn", Type: entity.PunchCheckOut, OccurredAt: jst(t, "2026-10-03 02:00")}, // 日付をまたいだ退勤
+		{ClientID: "c-sep", Type: entity.PunchCheckIn, OccurredAt: jst(t, "2026-09-30 09:00")},
+		{ClientID: "c-future", Type: entity.PunchCheckIn, OccurredAt: now.Add(syncMaxClockSkew + 1)},
+		{ClientID: "c-type", Type: "lunch", OccurredAt: jst(t, "2026-10-02 12:00")},
+		{ClientID: "", Type: entity.PunchCheckIn, OccurredAt: jst(t, "2026-10-02 09:00")},
+	}
+	results, err := u.Sync(ctx, "alice", punches, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		SyncAccepted, SyncAccepted, SyncAccepted, SyncAccepted, SyncOverlapsExisting, SyncOverlapsExisting,
+		SyncAccepted, SyncAccepted, SyncClosedPeriod, SyncInvalid, SyncInvalid, SyncInvalid,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v", results)
+	}
+	for i, w := range want {
+		if results[i].ClientID != punches[i].ClientID || results[i].Status != w {
+			t.Errorf("result %d = %s %s (%s), want %s", i, results[i].ClientID, results[i].Status, results[i].Message, w)
+		}
+	}
+	if results[0].AttendanceID != results[1].AttendanceID || results[3].AttendanceID != results[1].AttendanceID {
+		t.Errorf("punches on 10/01 attached to %s, %s, %s, want one attendance", results[1].AttendanceID, results[0].AttendanceID, results[3].AttendanceID)
+	}
+	if results[7].AttendanceID != results[6].AttendanceID {
+		t.Errorf("overnight check-out attached to %s, want %s", results[7].AttendanceID, results[6].AttendanceID)
+	}
+
+	list, err := attendances.FindByUserID(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byDate := map[string]entity.Attendance{}
+	for _, a := range list {
+		byDate[a.Date.In(JST).Format("2006-01-02")] = a
+	}
+	if len(byDate) != 2 {
+		t.Fatalf("attendances = %+v, want 10/01 and 10/02", list)
+	}
+	if a := byDate["2026-10-01"]; !a.CheckIn.Equal(jst(t, "2026-10-01 09:00")) || !a.CheckOut.Equal(jst(t, "2026-10-01 18:00")) || a.BreakMinutes != 60 {
+		t.Errorf("10/01 = %+v, want 09:00-18:00 with a 60 minute break", a)
+	}
+	if a := byDate["2026-10-02"]; !a.CheckOut.Equal(jst(t, "2026-10-03 02:00")) {
+		t.Errorf("10/02 check-out = %v, want 10/03 02:00", a.CheckOut)
+	}
+	// 取り込んだ打刻ごとにドメインイベントを発行する
+	if n := countRows(t, db, &entity.OutboxMessage{}); n != 6 {
+		t.Errorf("outbox events = %d, want 6", n)
+	}
+
+	// 再送しても二重に取り込まない
+	again, err := u.Sync(ctx, "alice", punches[:2], now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, res := range again {
+		if res.Status != SyncDuplicate || res.EventID != results[i].EventID || res.AttendanceID != results[i].AttendanceID {
+			t.Errorf("resent %s = %+v, want a duplicate of %+v", res.ClientID, res, results[i])
+		}
+	}
+	// クライアントのIDはユーザーごとに分かれる
+	other, err := u.Sync(ctx, "bob", punches[1:2], now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other[0].Status != SyncAccepted {
+		t.Errorf("other user's punch = %+v, want accepted", other[0])
+	}
+}
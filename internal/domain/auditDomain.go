@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/audit"
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// 監査ログの1回の検索で返す最大件数
+const maxAuditLogs = 1000
+
+// 監査ログユースケースのインターフェースを定義
+type AuditUsecase interface {
+
+	// 条件に一致する監査ログを新しい順に取得
+	FindAuditLogs(ctx context.Context, filter repository.AuditFilter) ([]entity.AuditLog, error)
+}
+
+// 監査ログユースケースの構造体を定義
+type auditUsecase struct {
+	repo repository.AuditRepository
+}
+
+// 監査ログ検索呼び出し
+func (u *auditUsecase) FindAuditLogs(ctx context.Context, filter repository.AuditFilter) ([]entity.AuditLog, error) {
+	if filter.Limit <= 0 || filter.Limit > maxAuditLogs {
+		filter.Limit = maxAuditLogs
+	}
+	return u.repo.Find(ctx, filter)
+}
+
+func NewAuditUsecase(repo repository.AuditRepository) AuditUsecase {
+	return &auditUsecase{repo: repo}
+}
+
+// recordAudit は変更前後のスナップショットを監査ログに記録する
+// 操作者はログインユーザー、リクエストIDなどはコンテキストの監査情報から取得する
+func recordAudit(ctx context.Context, repo repository.AuditRepository, entityType, entityID, action string, before, after any) error {
+	meta := audit.MetaFrom(ctx)
+	log := entity.AuditLog{
+		ID:         uuid.NewString(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Reason:     meta.Reason,
+		RequestID:  meta.RequestID,
+		Source:     meta.Source,
+		Before:     snapshot(before),
+		After:      snapshot(after),
+		CreatedAt:  time.Now(),
+	}
+	if user, ok := auth.UserFrom(ctx); ok {
+		log.ActorID = user.ID
+	}
+	return repo.Create(ctx, log)
+}
+
+// エンティティをJSONにする（nilの場合は空文字）
+func snapshot(v any) string {
+	if v == nil {
+		return ""
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
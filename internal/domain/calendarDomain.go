@@ -0,0 +1,430 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/ical"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidHoliday       = errors.New("invalid holiday")
+	ErrInvalidLeave         = errors.New("invalid leave")
+	ErrLeaveForbidden       = errors.New("leave of another department")
+	ErrTeamFeedForbidden    = errors.New("team calendar is for managers")
+	ErrInvalidCalendarToken = errors.New("invalid calendar token")
+)
+
+// カレンダーのフィードに載せる期間（過去・未来）
+const (
+	calendarPastDays   = 30
+	calendarFutureDays = 180
+)
+
+// 休暇の期間・休日の名前・メモの上限
+const (
+	maxLeaveDays    = 366
+	maxCalendarText = 200
+)
+
+// カレンダーのフィードのトークンの接頭辞
+const calendarTokenPrefix = "cal_"
+
+// 予定のUIDのドメイン
+const calendarUIDDomain = "go_home"
+
+// 休暇の種類とカレンダーに表示する名前
+var leaveKinds = map[string]string{
+	entity.LeavePaid:    "有給休暇",
+	entity.LeaveSick:    "病気休暇",
+	entity.LeaveSpecial: "特別休暇",
+	entity.LeaveUnpaid:  "無給休暇",
+}
+
+// CalendarConfig はカレンダーのフィードの設定
+type CalendarConfig struct {
+	// 本人のフィードに、平日の始業〜終業（WorkStart〜WorkEnd）を既定の勤務時間の予定として載せるか
+	// 実際のシフトではないため、すべての人が平日の同じ時間に働く場合だけ有効にする
+	DefaultShifts bool
+}
+
+// NewCalendarConfigFromEnv は環境変数 CALENDAR_DEFAULT_SHIFTS（既定 false）からカレンダーのフィードの設定を読み込む
+func NewCalendarConfigFromEnv() CalendarConfig {
+	var cfg CalendarConfig
+	if v := os.Getenv("CALENDAR_DEFAULT_SHIFTS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			slog.Warn("invalid CALENDAR_DEFAULT_SHIFTS, using default", "value", v, "default", cfg.DefaultShifts)
+		} else {
+			cfg.DefaultShifts = b
+		}
+	}
+	return cfg
+}
+
+// カレンダーユースケースのインターフェースを定義
+// 本人のフィードは休暇・会社の休日を載せ、設定で有効にした場合は既定の勤務時間の予定も載せる
+// フィードはトークンを知っていれば認証なしで読めるため、カレンダーアプリに URL を登録するだけで購読できる
+type CalendarUsecase interface {
+
+	// フィードのトークンの発行（前のトークンは使えなくなる）
+	IssueToken(ctx context.Context, userID string) (string, error)
+
+	// フィードのトークンの無効化
+	RevokeToken(ctx context.Context, userID string) error
+
+	// 本人の休暇・会社の休日（と既定の勤務時間の予定）のフィード
+	PersonalFeed(ctx context.Context, token string, now time.Time) (*ical.Calendar, error)
+
+	// チームの休暇のフィード（マネージャーは自分の部署、管理者は全員）
+	TeamFeed(ctx context.Context, token string, now time.Time) (*ical.Calendar, error)
+
+	// 会社の休日の登録
+	CreateHoliday(ctx context.Context, h entity.Holiday) (*entity.Holiday, error)
+
+	// 会社の休日の削除
+	DeleteHoliday(ctx context.Context, date string) error
+
+	// 期間内の会社の休日の一覧（"2006-01-02"、両端を含む）
+	ListHolidays(ctx context.Context, from, to string) ([]entity.Holiday, error)
+
+	// 承認済みの休暇の登録（マネージャーは自分の部署のユーザーだけ）
+	CreateLeave(ctx context.Context, l entity.Leave) (*entity.Leave, error)
+
+	// 休暇の削除（マネージャーは自分の部署のユーザーだけ）
+	DeleteLeave(ctx context.Context, id string) error
+
+	// 期間に重なる休暇の一覧（department が空なら全員）
+	ListLeaves(ctx context.Context, department, from, to string) ([]entity.Leave, error)
+}
+
+// カレンダーユースケースの構造体を定義
+type calendarUsecase struct {
+	cfg      CalendarConfig
+	tokens   repository.CalendarTokenRepository
+	holidays repository.HolidayRepository
+	leaves   repository.LeaveRepository
+	users    repository.UserRepository
+}
+
+// トークンの発行呼び出し
+func (u *calendarUsecase) IssueToken(ctx context.Context, userID string) (string, error) {
+	if _, err := u.users.FindFirst(ctx, userID); err != nil {
+		return "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := calendarTokenPrefix + hex.EncodeToString(b)
+	if err := u.tokens.Save(ctx, entity.CalendarToken{UserID: userID, TokenHash: hashCalendarToken(token)}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// トークンの無効化呼び出し
+func (u *calendarUsecase) RevokeToken(ctx context.Context, userID string) error {
+	return u.tokens.Delete(ctx, userID)
+}
+
+// 本人のフィード呼び出し
+func (u *calendarUsecase) PersonalFeed(ctx context.Context, token string, now time.Time) (*ical.Calendar, error) {
+	user, err := u.userFromToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	from, to := calendarWindow(now)
+	holidays, err := u.holidays.FindRange(ctx, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := u.leaves.FindRange(ctx, user.ID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	name := user.Name + " の休暇・休日"
+	if u.cfg.DefaultShifts {
+		name = user.Name + " の勤務予定"
+	}
+	cal := &ical.Calendar{Name: name}
+	off := map[string]bool{}
+	for _, h := range holidays {
+		off[h.Date] = true
+		cal.Events = append(cal.Events, holidayEvent(h))
+	}
+	for _, l := range leaves {
+		start, end := leaveDays(l)
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			off[d.Format(time.DateOnly)] = true
+		}
+		cal.Events = append(cal.Events, leaveEvent(l, leaveKinds[l.Kind]))
+	}
+	if !u.cfg.DefaultShifts {
+		return cal, nil
+	}
+	// 勤務の予定は実際のシフトではなく、平日の始業〜終業から作った既定の勤務時間であることを明記する
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		date := d.Format(time.DateOnly)
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday || off[date] {
+			continue
+		}
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         fmt.Sprintf("shift-%s-%s@%s", user.ID, date, calendarUIDDomain),
+			Summary:     "勤務（既定の勤務時間）",
+			Description: "平日の始業〜終業から作った予定です。実際のシフトではありません。",
+			Categories:  "勤務",
+			Start:       d.Add(WorkStart),
+			End:         d.Add(WorkEnd),
+			Stamp:       now,
+		})
+	}
+	return cal, nil
+}
+
+// チームのフィード呼び出し
+func (u *calendarUsecase) TeamFeed(ctx context.Context, token string, now time.Time) (*ical.Calendar, error) {
+	user, err := u.userFromToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	var department string
+	switch {
+	case user.Role == entity.RoleAdmin:
+	case user.Role == entity.RoleManager && user.Department != "":
+		department = user.Department
+	default:
+		return nil, ErrTeamFeedForbidden
+	}
+	from, to := calendarWindow(now)
+	leaves, err := u.ListLeaves(ctx, department, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	names, err := u.userNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := "全社の休暇"
+	if department != "" {
+		name = department + " の休暇"
+	}
+	cal := &ical.Calendar{Name: name}
+	for _, l := range leaves {
+		cal.Events = append(cal.Events, leaveEvent(l, names[l.UserID]+" "+leaveKinds[l.Kind]))
+	}
+	return cal, nil
+}
+
+// 休日の登録呼び出し
+func (u *calendarUsecase) CreateHoliday(ctx context.Context, h entity.Holiday) (*entity.Holiday, error) {
+	if _, err := time.Parse(time.DateOnly, h.Date); err != nil {
+		return nil, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidHoliday)
+	}
+	if h.Name == "" || utf8.RuneCountInString(h.Name) > maxCalendarText {
+		return nil, fmt.Errorf("%w: name is required and must be at most %d characters", ErrInvalidHoliday, maxCalendarText)
+	}
+	h.CreatedAt = time.Now()
+	if err := u.holidays.Create(ctx, h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// 休日の削除呼び出し
+func (u *calendarUsecase) DeleteHoliday(ctx context.Context, date string) error {
+	return u.holidays.Delete(ctx, date)
+}
+
+// 休日の一覧呼び出し
+func (u *calendarUsecase) ListHolidays(ctx context.Context, from, to string) ([]entity.Holiday, error) {
+	return u.holidays.FindRange(ctx, from, to)
+}
+
+// 休暇の登録呼び出し
+func (u *calendarUsecase) CreateLeave(ctx context.Context, l entity.Leave) (*entity.Leave, error) {
+	if _, ok := leaveKinds[l.Kind]; !ok {
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidLeave, l.Kind)
+	}
+	start, err := time.Parse(time.DateOnly, l.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: start date must be YYYY-MM-DD", ErrInvalidLeave)
+	}
+	if l.EndDate == "" {
+		l.EndDate = l.StartDate
+	}
+	end, err := time.Parse(time.DateOnly, l.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: end date must be YYYY-MM-DD", ErrInvalidLeave)
+	}
+	if end.Before(start) || end.Sub(start) >= maxLeaveDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: end date must be within %d days on or after start date", ErrInvalidLeave, maxLeaveDays)
+	}
+	if utf8.RuneCountInString(l.Note) > maxCalendarText {
+		return nil, fmt.Errorf("%w: note must be at most %d characters", ErrInvalidLeave, maxCalendarText)
+	}
+	if err := u.authorizeLeave(ctx, l.UserID); err != nil {
+		return nil, err
+	}
+
+	l.ID = uuid.NewString()
+	l.ApprovedBy = ""
+	if actor, ok := auth.UserFrom(ctx); ok {
+		l.ApprovedBy = actor.ID
+	}
+	l.CreatedAt = time.Now()
+	if err := u.leaves.Create(ctx, l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// 休暇の削除呼び出し
+func (u *calendarUsecase) DeleteLeave(ctx context.Context, id string) error {
+	l, err := u.leaves.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := u.authorizeLeave(ctx, l.UserID); err != nil {
+		return err
+	}
+	return u.leaves.Delete(ctx, id)
+}
+
+// 休暇の一覧呼び出し
+func (u *calendarUsecase) ListLeaves(ctx context.Context, department, from, to string) ([]entity.Leave, error) {
+	leaves, err := u.leaves.FindRange(ctx, "", from, to)
+	if err != nil || department == "" {
+		return leaves, err
+	}
+	users, err := u.users.FindAllUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members := map[string]bool{}
+	for _, user := range users {
+		if user.Department == department {
+			members[user.ID] = true
+		}
+	}
+	list := []entity.Leave{}
+	for _, l := range leaves {
+		if members[l.UserID] {
+			list = append(list, l)
+		}
+	}
+	return list, nil
+}
+
+// authorizeLeave は操作しているユーザーがそのユーザーの休暇を扱えるかを確かめる
+// 管理者は全員、マネージャーは自分の部署のユーザーだけ（ユーザーが無ければ ErrNotFound）
+func (u *calendarUsecase) authorizeLeave(ctx context.Context, userID string) error {
+	user, err := u.users.FindFirst(ctx, userID)
+	if err != nil {
+		return err
+	}
+	actor, ok := auth.UserFrom(ctx)
+	if !ok || actor.Role == entity.RoleAdmin {
+		return nil
+	}
+	if actor.Department == "" || actor.Department != user.Department {
+		return ErrLeaveForbidden
+	}
+	return nil
+}
+
+// userFromToken はフィードのトークンからユーザーを求める
+// トークンが無い・無効化された・ユーザーが削除された場合は ErrInvalidCalendarToken
+func (u *calendarUsecase) userFromToken(ctx context.Context, token string) (*entity.User, error) {
+	t, err := u.tokens.FindByHash(ctx, hashCalendarToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidCalendarToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.users.FindFirst(ctx, t.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidCalendarToken
+	}
+	return user, err
+}
+
+// userNames はユーザーIDから名前を引く表を作る（削除済みのユーザーの休暇も名前を出す）
+func (u *calendarUsecase) userNames(ctx context.Context) (map[string]string, error) {
+	users, err := u.users.FindAllUserIncludeDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Name
+	}
+	return names, nil
+}
+
+// calendarWindow はフィードに載せる期間の初日と最終日（日本時間の0時）を返す
+func calendarWindow(now time.Time) (time.Time, time.Time) {
+	today := WorkDate(now)
+	return today.AddDate(0, 0, -calendarPastDays), today.AddDate(0, 0, calendarFutureDays)
+}
+
+// leaveDays は休暇の初日と最終日（日本時間の0時）を返す
+func leaveDays(l entity.Leave) (time.Time, time.Time) {
+	start, _ := time.ParseInLocation(time.DateOnly, l.StartDate, JST)
+	end, _ := time.ParseInLocation(time.DateOnly, l.EndDate, JST)
+	return start, end
+}
+
+func holidayEvent(h entity.Holiday) ical.Event {
+	d, _ := time.ParseInLocation(time.DateOnly, h.Date, JST)
+	return ical.Event{
+		UID:        fmt.Sprintf("holiday-%s@%s", h.Date, calendarUIDDomain),
+		Summary:    h.Name,
+		Categories: "休日",
+		Start:      d,
+		End:        d,
+		AllDay:     true,
+		Stamp:      h.CreatedAt,
+	}
+}
+
+func leaveEvent(l entity.Leave, summary string) ical.Event {
+	start, end := leaveDays(l)
+	return ical.Event{
+		UID:         fmt.Sprintf("leave-%s@%s", l.ID, calendarUIDDomain),
+		Summary:     summary,
+		Description: l.Note,
+		Categories:  "休暇",
+		Start:       start,
+		End:         end,
+		AllDay:      true,
+		Stamp:       l.CreatedAt,
+	}
+}
+
+// フィードのトークンは SHA-256 で保存する
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewCalendarUsecase(cfg CalendarConfig, tokens repository.CalendarTokenRepository, holidays repository.HolidayRepository, leaves repository.LeaveRepository, users repository.UserRepository) CalendarUsecase {
+	return &calendarUsecase{cfg: cfg, tokens: tokens, holidays: holidays, leaves: leaves, users: users}
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/ical"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+func TestCalendarPersonalFeedShifts(t *testing.T) {
+	// 2026-10-19 は月曜日
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, JST)
+	tests := []struct {
+		name       string
+		cfg        CalendarConfig
+		wantName   string
+		wantShifts map[string]bool // 日付ごとに勤務の予定があるか
+	}{
+		{
+			name:     "default shifts off",
+			wantName: "Alice の休暇・休日",
+			wantShifts: map[string]bool{
+				"2026-10-19": false,
+				"2026-10-21": false,
+			},
+		},
+		{
+			name:     "default shifts on",
+			cfg:      CalendarConfig{DefaultShifts: true},
+			wantName: "Alice の勤務予定",
+			wantShifts: map[string]bool{
+				"2026-10-19": false, // 会社の休日
+				"2026-10-20": false, // 休暇
+				"2026-10-21": true,
+				"2026-10-24": false, // 土曜日
+				"2026-10-26": true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			users := repository.NewTimeIsMoneyRepository(db)
+			holidays := repository.NewHolidayRepository(db)
+			leaves := repository.NewLeaveRepository(db)
+			ctx := context.Background()
+			if err := users.CreateUser(ctx, entity.User{ID: "u1", AuthID: "a1", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee}); err != nil {
+				t.Fatal(err)
+			}
+			if err := holidays.Create(ctx, entity.Holiday{Date: "2026-10-19", Name: "創立記念日"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := leaves.Create(ctx, entity.Leave{ID: "l1", UserID: "u1", StartDate: "2026-10-20", EndDate: "2026-10-20", Kind: entity.LeavePaid}); err != nil {
+				t.Fatal(err)
+			}
+			u := NewCalendarUsecase(tt.cfg, repository.NewCalendarTokenRepository(db), holidays, leaves, users)
+			token, err := u.IssueToken(ctx, "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cal, err := u.PersonalFeed(ctx, token, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cal.Name != tt.wantName {
+				t.Errorf("name = %q, want %q", cal.Name, tt.wantName)
+			}
+			shifts := map[string]ical.Event{}
+			var categories []string
+			for _, e := range cal.Events {
+				categories = append(categories, e.Categories)
+				if e.Categories == "勤務" {
+					shifts[e.Start.In(JST).Format(time.DateOnly)] = e
+				}
+			}
+			if !strings.Contains(strings.Join(categories, ","), "休日") || !strings.Contains(strings.Join(categories, ","), "休暇") {
+				t.Errorf("categories = %v, want holiday and leave", categories)
+			}
+			if !tt.cfg.DefaultShifts && len(shifts) != 0 {
+				t.Errorf("shifts = %d, want none", len(shifts))
+			}
+			for date, want := range tt.wantShifts {
+				e, ok := shifts[date]
+				if ok != want {
+					t.Errorf("%s: shift = %v, want %v", date, ok, want)
+					continue
+				}
+				if !ok {
+					continue
+				}
+				d, _ := time.ParseInLocation(time.DateOnly, date, JST)
+				if e.Summary != "勤務（既定の勤務時間）" || e.Description == "" || !e.Start.Equal(d.Add(WorkStart)) || !e.End.Equal(d.Add(WorkEnd)) {
+					t.Errorf("%s: shift = %+v, want labelled default hours", date, e)
+				}
+			}
+		})
+	}
+}
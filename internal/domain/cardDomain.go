@@ -0,0 +1,351 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/audit"
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+	"github.com/enkazu1116/go_home/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	ErrInvalidDeviceKey = errors.New("invalid device credentials")
+	ErrInvalidCardID    = errors.New("invalid card id")
+	ErrUnknownCard      = errors.New("card is not registered")
+	ErrClockSkew        = errors.New("device clock is out of sync")
+	ErrUnknownPunchType = errors.New("unknown punch action")
+)
+
+// 打刻の結果（自動判定の場合に、どの打刻をしたかを端末に返す）
+const (
+	CardPunchIgnored = "ignored" // 続けて読み取られたため、打刻しなかった
+)
+
+// CardConfig はICカードリーダーの設定
+type CardConfig struct {
+	// 端末の時計とサーバーの時刻のずれの上限（超えた打刻は受け付けない）
+	MaxClockSkew time.Duration
+	// 同じカードが続けて読み取られた場合に、前の打刻から無視する時間
+	Debounce time.Duration
+}
+
+// NewCardConfigFromEnv は環境変数からICカードリーダーの設定を読み込む
+// CARD_MAX_CLOCK_SKEW（既定 2m）・CARD_DEBOUNCE（既定 1m）
+func NewCardConfigFromEnv() CardConfig {
+	cfg := CardConfig{MaxClockSkew: 2 * time.Minute, Debounce: time.Minute}
+	if v := os.Getenv("CARD_MAX_CLOCK_SKEW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slog.Warn("invalid CARD_MAX_CLOCK_SKEW, using default", "value", v, "default", cfg.MaxClockSkew)
+		} else {
+			cfg.MaxClockSkew = d
+		}
+	}
+	if v := os.Getenv("CARD_DEBOUNCE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			slog.Warn("invalid CARD_DEBOUNCE, using default", "value", v, "default", cfg.Debounce)
+		} else {
+			cfg.Debounce = d
+		}
+	}
+	return cfg
+}
+
+// CardPunch は端末から送られた1件の打刻
+type CardPunch struct {
+	CardID string
+	// 端末でカードを読み取った時刻（ゼロ値の場合はサーバーの受信時刻）
+	Timestamp time.Time
+	// 打刻の種類（check_in・check_out・break_start・break_end）
+	// 省略した場合は、出勤していなければ出勤、出勤中なら退勤にする
+	Action string
+}
+
+// CardPunchResult は打刻の結果
+type CardPunchResult struct {
+	Action     string
+	UserID     string
+	UserName   string
+	Attendance *entity.Attendance
+}
+
+// ICカードユースケースのインターフェースを定義
+type CardUsecase interface {
+
+	// 端末の登録（APIキーを生成して一度だけ返す）
+	RegisterReader(ctx context.Context, c entity.CardReader) (*entity.CardReader, string, error)
+
+	// 端末の更新（名前・設置場所・有効かどうか）
+	UpdateReader(ctx context.Context, c entity.CardReader) error
+
+	// 端末の削除
+	DeleteReader(ctx context.Context, id string) error
+
+	// 端末の取得
+	FindReader(ctx context.Context, id string) (*entity.CardReader, error)
+
+	// 端末の一覧取得
+	ListReaders(ctx context.Context) ([]entity.CardReader, error)
+
+	// APIキーで端末を認証する
+	Authenticate(ctx context.Context, id, key string) (*entity.CardReader, error)
+
+	// クライアント証明書で認証済みの端末を取得する（無効な端末はエラー）
+	AuthenticateCert(ctx context.Context, id string) (*entity.CardReader, error)
+
+	// カードとユーザーの紐づけ
+	RegisterCard(ctx context.Context, c entity.Card) (*entity.Card, error)
+
+	// カードの紐づけの解除
+	UnregisterCard(ctx context.Context, cardID string) error
+
+	// カードの一覧取得（userID を指定した場合はそのユーザーのカードのみ）
+	ListCards(ctx context.Context, userID string) ([]entity.Card, error)
+
+	// 認証済みの端末から送られた打刻を記録する
+	Punch(ctx context.Context, reader *entity.CardReader, p CardPunch, now time.Time) (*CardPunchResult, error)
+}
+
+// ICカードユースケースの構造体を定義
+type cardUsecase struct {
+	cfg        CardConfig
+	readers    repository.CardReaderRepository
+	cards      repository.CardRepository
+	users      repository.UserRepository
+	attendance AttendanceUsecase
+}
+
+// 端末の登録呼び出し
+func (u *cardUsecase) RegisterReader(ctx context.Context, c entity.CardReader) (*entity.CardReader, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	key := "dev_" + hex.EncodeToString(b)
+	c.ID = uuid.NewString()
+	c.KeyHash = hashDeviceKey(key)
+	c.Active = true
+	if err := u.readers.Create(ctx, c); err != nil {
+		return nil, "", err
+	}
+	created, err := u.readers.FindByID(ctx, c.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return created, key, nil
+}
+
+// 端末の更新呼び出し
+func (u *cardUsecase) UpdateReader(ctx context.Context, c entity.CardReader) error {
+	return u.readers.Update(ctx, c)
+}
+
+// 端末の削除呼び出し
+func (u *cardUsecase) DeleteReader(ctx context.Context, id string) error {
+	return u.readers.Delete(ctx, id)
+}
+
+// 端末の取得呼び出し
+func (u *cardUsecase) FindReader(ctx context.Context, id string) (*entity.CardReader, error) {
+	return u.readers.FindByID(ctx, id)
+}
+
+// 端末の一覧取得呼び出し
+func (u *cardUsecase) ListReaders(ctx context.Context) ([]entity.CardReader, error) {
+	return u.readers.FindAll(ctx)
+}
+
+// APIキーによる認証呼び出し
+func (u *cardUsecase) Authenticate(ctx context.Context, id, key string) (*entity.CardReader, error) {
+	c, err := u.AuthenticateCert(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashDeviceKey(key)), []byte(c.KeyHash)) != 1 {
+		return nil, ErrInvalidDeviceKey
+	}
+	return c, nil
+}
+
+// クライアント証明書による認証呼び出し
+func (u *cardUsecase) AuthenticateCert(ctx context.Context, id string) (*entity.CardReader, error) {
+	c, err := u.readers.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidDeviceKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !c.Active {
+		return nil, ErrInvalidDeviceKey
+	}
+	return c, nil
+}
+
+// カードの紐づけ呼び出し
+func (u *cardUsecase) RegisterCard(ctx context.Context, c entity.Card) (*entity.Card, error) {
+	id, ok := NormalizeCardID(c.CardID)
+	if !ok {
+		return nil, ErrInvalidCardID
+	}
+	c.CardID = id
+	if _, err := u.users.FindFirst(ctx, c.UserID); err != nil {
+		return nil, err
+	}
+	if err := u.cards.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return u.cards.Find(ctx, c.CardID)
+}
+
+// カードの紐づけの解除呼び出し
+func (u *cardUsecase) UnregisterCard(ctx context.Context, cardID string) error {
+	id, ok := NormalizeCardID(cardID)
+	if !ok {
+		return ErrInvalidCardID
+	}
+	return u.cards.Delete(ctx, id)
+}
+
+// カードの一覧取得呼び出し
+func (u *cardUsecase) ListCards(ctx context.Context, userID string) ([]entity.Card, error) {
+	return u.cards.FindAll(ctx, userID)
+}
+
+// 打刻呼び出し
+func (u *cardUsecase) Punch(ctx context.Context, reader *entity.CardReader, p CardPunch, now time.Time) (_ *CardPunchResult, err error) {
+	ctx, span := tracing.Start(ctx, "CardUsecase.Punch", attribute.String("card_reader.id", reader.ID))
+	defer tracing.End(span, &err)
+	at := p.Timestamp
+	if at.IsZero() {
+		at = now
+	}
+	if d := at.Sub(now); d > u.cfg.MaxClockSkew || d < -u.cfg.MaxClockSkew {
+		return nil, ErrClockSkew
+	}
+	if err := u.readers.Touch(ctx, reader.ID, now); err != nil {
+		return nil, err
+	}
+
+	cardID, ok := NormalizeCardID(p.CardID)
+	if !ok {
+		return nil, ErrInvalidCardID
+	}
+	card, err := u.cards.Find(ctx, cardID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnknownCard
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.users.FindFirst(ctx, card.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnknownCard
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 監査ログの操作者をカードの持ち主にし、打刻ログに端末を記録する
+	ctx = auth.WithUser(ctx, user)
+	meta := audit.MetaFrom(ctx)
+	meta.Source = audit.SourceCard
+	meta.DeviceID = reader.ID
+	ctx = audit.WithMeta(ctx, meta)
+
+	result := &CardPunchResult{Action: p.Action, UserID: user.ID, UserName: user.Name}
+	if result.Action == "" {
+		action, current, err := u.autoAction(ctx, user.ID, at)
+		if err != nil {
+			return nil, err
+		}
+		if action == CardPunchIgnored {
+			result.Action, result.Attendance = action, current
+			return result, nil
+		}
+		result.Action = action
+	}
+
+	var punch func(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error)
+	switch result.Action {
+	case entity.PunchCheckIn:
+		punch = u.attendance.CheckIn
+	case entity.PunchCheckOut:
+		punch = u.attendance.CheckOut
+	case entity.PunchBreakStart:
+		punch = u.attendance.BreakStart
+	case entity.PunchBreakEnd:
+		punch = u.attendance.BreakEnd
+	default:
+		return nil, ErrUnknownPunchType
+	}
+	result.Attendance, err = punch(ctx, user.ID, at)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// autoAction はカードをかざしただけの打刻の種類を決める
+// 出勤・退勤の直後に続けて読み取られた場合は、読み取りの重複として無視する
+func (u *cardUsecase) autoAction(ctx context.Context, userID string, at time.Time) (string, *entity.Attendance, error) {
+	a, err := u.attendance.FindByUserAndDate(ctx, userID, WorkDate(at))
+	if errors.Is(err, repository.ErrNotFound) {
+		return entity.PunchCheckIn, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	last := a.CheckIn
+	if !a.CheckOut.IsZero() {
+		last = a.CheckOut
+	}
+	if d := at.Sub(last); d >= 0 && d < u.cfg.Debounce {
+		return CardPunchIgnored, a, nil
+	}
+	return entity.PunchCheckOut, a, nil
+}
+
+// NormalizeCardID はカードのIDから区切り（コロン・ハイフン・空白）を除き、英大文字にする
+// 英数字以外を含む場合や空の場合は false を返す
+func NormalizeCardID(id string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(id) {
+		switch {
+		case r == ':' || r == '-' || r == ' ':
+		case r >= '0' && r <= '9', r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		default:
+			return "", false
+		}
+	}
+	if b.Len() == 0 || b.Len() > 64 {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// APIキーは SHA-256 で保存する
+func hashDeviceKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewCardUsecase(cfg CardConfig, readers repository.CardReaderRepository, cards repository.CardRepository, users repository.UserRepository, attendance AttendanceUsecase) CardUsecase {
+	return &cardUsecase{cfg: cfg, readers: readers, cards: cards, users: users, attendance: attendance}
+}
@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/notify"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+var ErrUnknownChatProvider = errors.New("unknown chat provider")
+
+// ChatConfig はチャット連携の設定
+type ChatConfig struct {
+	// Slack の Signing Secret（未設定の場合は Slack のコマンドを受け付けない）
+	SlackSigningSecret string
+	// Mattermost のスラッシュコマンドのトークン（未設定の場合は Mattermost のコマンドを受け付けない）
+	MattermostToken string
+	// 出勤打刻のリマインドを送る時刻（日本時間の0時からの経過時間、0 の場合は送らない）
+	ReminderTime time.Duration
+}
+
+// NewChatConfigFromEnv は環境変数からチャット連携の設定を読み込む
+// SLACK_SIGNING_SECRET・MATTERMOST_COMMAND_TOKEN・CHAT_REMINDER_TIME（例: "09:30"）
+func NewChatConfigFromEnv() ChatConfig {
+	cfg := ChatConfig{
+		SlackSigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+		MattermostToken:    os.Getenv("MATTERMOST_COMMAND_TOKEN"),
+	}
+	if v := os.Getenv("CHAT_REMINDER_TIME"); v != "" {
+		t, err := time.Parse("15:04", v)
+		if err != nil {
+			slog.Warn("invalid CHAT_REMINDER_TIME, reminders are disabled", "value", v, "err", err)
+		} else {
+			cfg.ReminderTime = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		}
+	}
+	return cfg
+}
+
+// ReminderSchedule はリマインドを送る予定を cron 式（日本時間、平日のみ）で返す
+// リマインドを送らない場合は空文字列
+func (c ChatConfig) ReminderSchedule() string {
+	if c.ReminderTime <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d %d * * 1-5", int(c.ReminderTime/time.Minute)%60, int(c.ReminderTime/time.Hour))
+}
+
+// チャットユースケースのインターフェースを定義
+type ChatUsecase interface {
+
+	// チャットアカウントとユーザーの紐づけ
+	LinkIdentity(ctx context.Context, c entity.ChatIdentity) (*entity.ChatIdentity, error)
+
+	// 紐づけの解除
+	UnlinkIdentity(ctx context.Context, provider, chatUserID string) error
+
+	// 紐づけの一覧取得
+	ListIdentities(ctx context.Context) ([]entity.ChatIdentity, error)
+
+	// スラッシュコマンドを実行し、返信の本文を返す
+	// 打刻できなかった理由などは返信の本文で伝え、エラーは内部の障害の場合だけ返す
+	HandleCommand(ctx context.Context, provider, chatUserID, text string, now time.Time) (string, error)
+
+	// まだ出勤していないユーザーに出勤打刻のリマインドを送り、送った件数を返す
+	SendReminders(ctx context.Context, now time.Time) (int, error)
+}
+
+// チャットユースケースの構造体を定義
+type chatUsecase struct {
+	identities repository.ChatIdentityRepository
+	users      repository.UserRepository
+	attendance AttendanceUsecase
+	notifier   notify.Notifier
+}
+
+// 紐づけ呼び出し
+func (u *chatUsecase) LinkIdentity(ctx context.Context, c entity.ChatIdentity) (*entity.ChatIdentity, error) {
+	if c.Provider != entity.ChatSlack && c.Provider != entity.ChatMattermost {
+		return nil, ErrUnknownChatProvider
+	}
+	if _, err := u.users.FindFirst(ctx, c.UserID); err != nil {
+		return nil, err
+	}
+	if err := u.identities.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return u.identities.Find(ctx, c.Provider, c.ChatUserID)
+}
+
+// 紐づけの解除呼び出し
+func (u *chatUsecase) UnlinkIdentity(ctx context.Context, provider, chatUserID string) error {
+	return u.identities.Delete(ctx, provider, chatUserID)
+}
+
+// 紐づけの一覧取得呼び出し
+func (u *chatUsecase) ListIdentities(ctx context.Context) ([]entity.ChatIdentity, error) {
+	return u.identities.FindAll(ctx)
+}
+
+// コマンドの使い方
+const chatUsage = "使い方: `/punch in`（出勤）・`/punch out`（退勤）・`/punch break`（休憩開始）・`/punch back`（休憩終了）・`/punch status`（今日の勤怠）"
+
+// スラッシュコマンド呼び出し
+func (u *chatUsecase) HandleCommand(ctx context.Context, provider, chatUserID, text string, now time.Time) (string, error) {
+	identity, err := u.identities.Find(ctx, provider, chatUserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "このチャットアカウントは勤怠システムのユーザーに紐づいていません。管理者に連絡してください。", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	user, err := u.users.FindFirst(ctx, identity.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "紐づいているユーザーが見つかりません。管理者に連絡してください。", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	// 監査ログの操作者をチャットのユーザーにする
+	ctx = auth.WithUser(ctx, user)
+
+	var punch func(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error)
+	var done string
+	switch cmd := strings.ToLower(strings.TrimSpace(text)); cmd {
+	case "", "status", "今日":
+		return u.status(ctx, user.ID, now)
+	case "in", "出勤":
+		punch, done = u.attendance.CheckIn, "出勤しました。"
+	case "out", "退勤":
+		punch, done = u.attendance.CheckOut, "退勤しました。お疲れさまでした。"
+	case "break", "休憩":
+		punch, done = u.attendance.BreakStart, "休憩を開始しました。"
+	case "back", "戻り":
+		punch, done = u.attendance.BreakEnd, "休憩を終了しました。"
+	default:
+		return chatUsage, nil
+	}
+
+	if _, err := punch(ctx, user.ID, now); err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyCheckedIn):
+			return "今日はすでに出勤しています。", nil
+		case errors.Is(err, ErrNotCheckedIn):
+			return "まだ出勤していません。`/punch in` で出勤してください。", nil
+		case errors.Is(err, ErrAlreadyOnBreak):
+			return "すでに休憩中です。", nil
+		case errors.Is(err, ErrNotOnBreak):
+			return "休憩中ではありません。", nil
+		}
+		return "", err
+	}
+	status, err := u.status(ctx, user.ID, now)
+	if err != nil {
+		return "", err
+	}
+	return done + "\n" + status, nil
+}
+
+// 今日の勤怠を返信の本文にする
+func (u *chatUsecase) status(ctx context.Context, userID string, now time.Time) (string, error) {
+	date := WorkDate(now)
+	header := date.Format("01/02") + " の勤怠"
+	a, err := u.attendance.FindByUserAndDate(ctx, userID, date)
+	if errors.Is(err, repository.ErrNotFound) {
+		return header + "\nまだ出勤していません。", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{header}
+	checkIn := "出勤 " + a.CheckIn.In(JST).Format("15:04")
+	if a.IsLate {
+		checkIn += "（遅刻）"
+	}
+	lines = append(lines, checkIn)
+	if a.BreakMinutes > 0 {
+		lines = append(lines, fmt.Sprintf("休憩 %d分", a.BreakMinutes))
+	}
+	if a.CheckOut.IsZero() {
+		lines = append(lines, "退勤 未打刻")
+	} else if a.Incomplete {
+		lines = append(lines, "退勤 "+a.CheckOut.In(JST).Format("15:04")+"（自動退勤・要修正）")
+	} else {
+		lines = append(lines, "退勤 "+a.CheckOut.In(JST).Format("15:04"))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// リマインド呼び出し
+func (u *chatUsecase) SendReminders(ctx context.Context, now time.Time) (int, error) {
+	identities, err := u.identities.FindAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, c := range identities {
+		if _, err := u.attendance.FindByUserAndDate(ctx, c.UserID, now); err == nil {
+			continue
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return sent, err
+		}
+		user, err := u.users.FindFirst(ctx, c.UserID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return sent, err
+		}
+
+		// Slack はユーザーIDでメンションできるが、Mattermost はユーザー名が必要なため名前で呼ぶ
+		mention := user.Name + " さん"
+		if c.Provider == entity.ChatSlack {
+			mention = "<@" + c.ChatUserID + ">"
+		}
+		err = u.notifier.Notify(ctx, notify.Message{
+			UserID:  user.ID,
+			Subject: "出勤打刻のリマインド",
+			Text:    mention + " まだ出勤打刻がありません。`/punch in` で打刻できます。",
+			Kind:    notify.KindCheckInReminder,
+			Data:    map[string]any{"Date": WorkDate(now).Format("2006/01/02")},
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "send reminder failed", "target_user_id", user.ID, "err", err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func NewChatUsecase(identities repository.ChatIdentityRepository, users repository.UserRepository, attendance AttendanceUsecase, notifier notify.Notifier) ChatUsecase {
+	return &chatUsecase{identities: identities, users: users, attendance: attendance, notifier: notifier}
+}
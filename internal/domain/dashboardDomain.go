@@ -0,0 +1,164 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/live"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// 在席状況
+const (
+	PresenceOut     = "out"      // まだ出勤していない
+	PresenceWorking = "working"  // 勤務中
+	PresenceOnBreak = "on_break" // 休憩中
+	PresenceDone    = "done"     // 退勤済み
+)
+
+// ダッシュボードの最初に送る、今日の在席状況の一覧のイベント
+const DashboardSnapshotEvent = "snapshot"
+
+// 打刻の種類ごとの打刻後の在席状況
+var punchPresences = map[string]string{
+	entity.EventCheckedIn:    PresenceWorking,
+	entity.EventCheckedOut:   PresenceDone,
+	entity.EventBreakStarted: PresenceOnBreak,
+	entity.EventBreakEnded:   PresenceWorking,
+}
+
+// DashboardConfig はダッシュボードの設定
+type DashboardConfig struct {
+	// 接続を保つために送るコメントの間隔（プロキシのアイドルタイムアウトより短くする）
+	Heartbeat time.Duration
+}
+
+// NewDashboardConfigFromEnv は環境変数 DASHBOARD_HEARTBEAT（既定 15s）からダッシュボードの設定を読み込む
+func NewDashboardConfigFromEnv() DashboardConfig {
+	cfg := DashboardConfig{Heartbeat: 15 * time.Second}
+	if v := os.Getenv("DASHBOARD_HEARTBEAT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Second {
+			slog.Warn("invalid DASHBOARD_HEARTBEAT, using default", "value", v, "default", cfg.Heartbeat)
+		} else {
+			cfg.Heartbeat = d
+		}
+	}
+	return cfg
+}
+
+// Presence はダッシュボードに表示するユーザーの今日の状況
+type Presence struct {
+	UserID       string
+	Name         string
+	Department   string
+	Status       string
+	AttendanceID string
+	CheckIn      time.Time
+	CheckOut     time.Time
+	WorkLocation string
+}
+
+// ダッシュボードユースケースのインターフェースを定義
+type DashboardUsecase interface {
+
+	// 今日の在席状況の一覧（department が空なら全員）
+	Snapshot(ctx context.Context, department string, now time.Time) ([]Presence, error)
+
+	// 打刻後の在席状況を購読者に配る（打刻のコミット後に呼ぶ）
+	Publish(ctx context.Context, eventType string, a entity.Attendance)
+
+	// 在席状況の変化の購読を始める（live.Hub.Subscribe と同じ）
+	Subscribe(lastEventID string) (*live.Subscription, []live.Event, bool)
+}
+
+// ダッシュボードユースケースの構造体を定義
+type dashboardUsecase struct {
+	hub        *live.Hub
+	users      repository.UserRepository
+	attendance repository.AttendanceRepository
+	punchLog   PunchLogUsecase
+}
+
+// 在席状況の一覧呼び出し
+func (u *dashboardUsecase) Snapshot(ctx context.Context, department string, now time.Time) ([]Presence, error) {
+	users, err := u.users.FindAllUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	date := WorkDate(now)
+	list := []Presence{}
+	for _, user := range users {
+		if department != "" && user.Department != department {
+			continue
+		}
+		p := Presence{UserID: user.ID, Name: user.Name, Department: user.Department, Status: PresenceOut}
+		a, err := u.attendance.FindByUserAndDate(ctx, user.ID, date)
+		if errors.Is(err, repository.ErrNotFound) {
+			list = append(list, p)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.fill(*a)
+		if p.Status == PresenceWorking {
+			// 休憩中かどうかは勤怠に残らないため、勤務日からの打刻イベントで判定する
+			events, err := u.punchLog.EventsInRange(ctx, user.ID, a.Date, time.Time{})
+			if err != nil {
+				return nil, err
+			}
+			if projectAttendances(user.ID, events).onBreak {
+				p.Status = PresenceOnBreak
+			}
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// 在席状況の配信呼び出し
+// 打刻は済んでいるため、配信に失敗してもログに残すだけにする
+func (u *dashboardUsecase) Publish(ctx context.Context, eventType string, a entity.Attendance) {
+	status, ok := punchPresences[eventType]
+	if !ok {
+		return
+	}
+	user, err := u.users.FindFirst(ctx, a.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, "dashboard find user failed", "target_user_id", a.UserID, "err", err)
+		return
+	}
+	p := Presence{UserID: user.ID, Name: user.Name, Department: user.Department}
+	p.fill(a)
+	p.Status = status
+	if err := u.hub.Publish(eventType, user.Department, p); err != nil {
+		slog.ErrorContext(ctx, "dashboard publish failed", "event_type", eventType, "err", err)
+	}
+}
+
+// 購読呼び出し
+func (u *dashboardUsecase) Subscribe(lastEventID string) (*live.Subscription, []live.Event, bool) {
+	return u.hub.Subscribe(lastEventID)
+}
+
+// 勤怠から在席状況を埋める（休憩中かどうかは呼び出し側で判定する）
+func (p *Presence) fill(a entity.Attendance) {
+	p.AttendanceID = a.ID
+	p.CheckIn = a.CheckIn
+	p.CheckOut = a.CheckOut
+	p.WorkLocation = a.WorkLocation
+	if a.CheckOut.IsZero() && !a.Incomplete {
+		p.Status = PresenceWorking
+	} else {
+		p.Status = PresenceDone
+	}
+}
+
+func NewDashboardUsecase(hub *live.Hub, users repository.UserRepository, attendance repository.AttendanceRepository, punchLog PunchLogUsecase) DashboardUsecase {
+	return &dashboardUsecase{hub: hub, users: users, attendance: attendance, punchLog: punchLog}
+}
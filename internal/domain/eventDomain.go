@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// publishEvent はドメインイベントをアウトボックスに追加する
+// 変更と同じトランザクションの ctx で呼ぶことで、変更がコミットされた場合だけ配信される
+func publishEvent(ctx context.Context, repo repository.OutboxRepository, eventType, aggregateType, aggregateID string, payload any) error {
+	return repo.Add(ctx, entity.OutboxMessage{
+		ID:            uuid.NewString(),
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       snapshot(payload),
+		OccurredAt:    time.Now(),
+	})
+}
@@ -0,0 +1,194 @@
+package domain
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidKioskKey   = errors.New("invalid kiosk key")
+	ErrInvalidKioskToken = errors.New("invalid or expired kiosk token")
+)
+
+// KioskConfig は打刻端末の設定
+type KioskConfig struct {
+	// QRコードのトークンを切り替える間隔
+	// 読み取りにかかる時間を見込み、1つ前の間隔のトークンまで受け付ける
+	TokenPeriod time.Duration
+}
+
+// NewKioskConfigFromEnv は環境変数 KIOSK_TOKEN_PERIOD（既定 30s）から打刻端末の設定を読み込む
+func NewKioskConfigFromEnv() KioskConfig {
+	cfg := KioskConfig{TokenPeriod: 30 * time.Second}
+	if v := os.Getenv("KIOSK_TOKEN_PERIOD"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Second {
+			slog.Warn("invalid KIOSK_TOKEN_PERIOD, using default", "value", v, "default", cfg.TokenPeriod)
+		} else {
+			cfg.TokenPeriod = d
+		}
+	}
+	return cfg
+}
+
+// KioskToken は打刻端末に表示するトークン
+type KioskToken struct {
+	Token string
+	// 次のトークンに切り替わる時刻（端末はこの時刻に表示を更新する）
+	RefreshAt time.Time
+}
+
+// 打刻端末ユースケースのインターフェースを定義
+type KioskUsecase interface {
+
+	// 端末の登録（鍵を生成し、鍵そのものはこの戻り値でのみ返す）
+	Register(ctx context.Context, k entity.Kiosk) (*entity.Kiosk, string, error)
+
+	// 端末の更新（名前・設置場所・有効かどうか）
+	Update(ctx context.Context, k entity.Kiosk) error
+
+	// 端末の削除
+	Delete(ctx context.Context, id string) error
+
+	// 1件取得
+	FindByID(ctx context.Context, id string) (*entity.Kiosk, error)
+
+	// 一覧取得
+	FindAll(ctx context.Context) ([]entity.Kiosk, error)
+
+	// 端末の鍵を確認して、いま表示するトークンを発行する
+	IssueToken(ctx context.Context, id, key string, now time.Time) (*KioskToken, error)
+
+	// QRコードから読み取ったトークンを検証し、表示していた端末を返す
+	VerifyToken(ctx context.Context, token string, now time.Time) (*entity.Kiosk, error)
+}
+
+// 打刻端末ユースケースの構造体を定義
+type kioskUsecase struct {
+	cfg  KioskConfig
+	repo repository.KioskRepository
+}
+
+// 端末の登録呼び出し
+func (u *kioskUsecase) Register(ctx context.Context, k entity.Kiosk) (*entity.Kiosk, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	key := "kiosk_" + hex.EncodeToString(b)
+	k.ID = uuid.NewString()
+	k.KeyHash = hashDeviceKey(key)
+	k.Active = true
+	if err := u.repo.Create(ctx, k); err != nil {
+		return nil, "", err
+	}
+	created, err := u.repo.FindByID(ctx, k.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return created, key, nil
+}
+
+// 端末の更新呼び出し
+func (u *kioskUsecase) Update(ctx context.Context, k entity.Kiosk) error {
+	return u.repo.Update(ctx, k)
+}
+
+// 端末の削除呼び出し
+func (u *kioskUsecase) Delete(ctx context.Context, id string) error {
+	return u.repo.Delete(ctx, id)
+}
+
+// 1件取得呼び出し
+func (u *kioskUsecase) FindByID(ctx context.Context, id string) (*entity.Kiosk, error) {
+	return u.repo.FindByID(ctx, id)
+}
+
+// 一覧取得呼び出し
+func (u *kioskUsecase) FindAll(ctx context.Context) ([]entity.Kiosk, error) {
+	return u.repo.FindAll(ctx)
+}
+
+// トークンの発行呼び出し
+func (u *kioskUsecase) IssueToken(ctx context.Context, id, key string, now time.Time) (*KioskToken, error) {
+	k, err := u.repo.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidKioskKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !k.Active || subtle.ConstantTimeCompare([]byte(hashDeviceKey(key)), []byte(k.KeyHash)) != 1 {
+		return nil, ErrInvalidKioskKey
+	}
+	if err := u.repo.Touch(ctx, id, now); err != nil {
+		return nil, err
+	}
+	step := u.step(now)
+	return &KioskToken{
+		Token:     signKioskToken(k, step),
+		RefreshAt: time.Unix(0, (step+1)*int64(u.cfg.TokenPeriod)),
+	}, nil
+}
+
+// トークンの検証呼び出し
+func (u *kioskUsecase) VerifyToken(ctx context.Context, token string, now time.Time) (*entity.Kiosk, error) {
+	id, stepStr, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidKioskToken
+	}
+	stepStr, _, _ = strings.Cut(stepStr, ".")
+	step, err := strconv.ParseInt(stepStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidKioskToken
+	}
+	if current := u.step(now); step != current && step != current-1 {
+		return nil, ErrInvalidKioskToken
+	}
+	k, err := u.repo.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidKioskToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !k.Active || !hmac.Equal([]byte(token), []byte(signKioskToken(k, step))) {
+		return nil, ErrInvalidKioskToken
+	}
+	return k, nil
+}
+
+// 時刻を切り替えの間隔ごとの番号にする（TOTP と同じ考え方）
+func (u *kioskUsecase) step(now time.Time) int64 {
+	return now.UnixNano() / int64(u.cfg.TokenPeriod)
+}
+
+// トークンは "端末ID.番号.署名" の形式
+// 署名は端末の鍵のハッシュで "端末ID.番号" を HMAC-SHA256 したものの先頭16バイト（base64url）
+// 署名も検証もサーバーだけが行うため、鍵そのものを保存しなくてよい
+func signKioskToken(k *entity.Kiosk, step int64) string {
+	payload := k.ID + "." + strconv.FormatInt(step, 10)
+	mac := hmac.New(sha256.New, []byte(k.KeyHash))
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func NewKioskUsecase(cfg KioskConfig, repo repository.KioskRepository) KioskUsecase {
+	return &kioskUsecase{cfg: cfg, repo: repo}
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// KPI は今日の勤怠の集計
+type KPI struct {
+	// 出勤中（休憩中を含む）の人数
+	CheckedIn int
+	// 退勤済みの人数
+	CheckedOut int
+	// 遅刻した人数
+	Late int
+	// 打刻ポリシーの確認待ちの勤怠の件数
+	NeedsReview int
+}
+
+// 勤怠の集計ユースケースのインターフェースを定義
+// メトリクスとして公開するため、個人を特定できる値は含めない
+type KPIUsecase interface {
+
+	// 今日（日本時間）の勤怠の集計
+	Today(ctx context.Context, now time.Time) (*KPI, error)
+}
+
+// 勤怠の集計ユースケースの構造体を定義
+type kpiUsecase struct {
+	attendance repository.AttendanceRepository
+}
+
+// 今日の集計呼び出し
+func (u *kpiUsecase) Today(ctx context.Context, now time.Time) (*KPI, error) {
+	list, err := u.attendance.FindByDate(ctx, WorkDate(now))
+	if err != nil {
+		return nil, err
+	}
+	var k KPI
+	for _, a := range list {
+		switch {
+		case a.CheckIn.IsZero():
+			continue
+		case a.CheckOut.IsZero():
+			k.CheckedIn++
+		default:
+			k.CheckedOut++
+		}
+		if a.IsLate {
+			k.Late++
+		}
+		if a.NeedsReview {
+			k.NeedsReview++
+		}
+	}
+	return &k, nil
+}
+
+func NewKPIUsecase(attendance repository.AttendanceRepository) KPIUsecase {
+	return &kpiUsecase{attendance: attendance}
+}
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/audit"
+	"github.com/enkazu1116/go_home/internal/notify"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// MissingCheckOutConfig は退勤の打刻漏れの検出の設定
+type MissingCheckOutConfig struct {
+	// 検出する予定（cron 式、日本時間）
+	Schedule string
+	// 勤務日の0時からこの時間が過ぎても退勤していない勤怠を打刻漏れとする
+	Cutoff time.Duration
+	// 打刻漏れを終業時刻で自動退勤にするか（しない場合は印を付けるだけ）
+	AutoClose bool
+}
+
+// NewMissingCheckOutConfigFromEnv は環境変数から退勤の打刻漏れの検出の設定を読み込む
+// MISSING_CHECKOUT_SCHEDULE: 検出する予定（既定 "*/30 * * * *"）
+// MISSING_CHECKOUT_CUTOFF: 勤務日の0時からの締めの時間（既定 24h、翌日の0時）
+// MISSING_CHECKOUT_AUTO_CLOSE: true で終業時刻に自動退勤する（既定 false）
+func NewMissingCheckOutConfigFromEnv() MissingCheckOutConfig {
+	cfg := MissingCheckOutConfig{Schedule: "*/30 * * * *", Cutoff: 24 * time.Hour}
+	if v := os.Getenv("MISSING_CHECKOUT_SCHEDULE"); v != "" {
+		cfg.Schedule = v
+	}
+	if v := os.Getenv("MISSING_CHECKOUT_CUTOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slog.Warn("invalid MISSING_CHECKOUT_CUTOFF, using default", "value", v, "default", cfg.Cutoff)
+		} else {
+			cfg.Cutoff = d
+		}
+	}
+	if v := os.Getenv("MISSING_CHECKOUT_AUTO_CLOSE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			slog.Warn("invalid MISSING_CHECKOUT_AUTO_CLOSE, using default", "value", v, "default", cfg.AutoClose)
+		} else {
+			cfg.AutoClose = b
+		}
+	}
+	return cfg
+}
+
+// 退勤の打刻漏れユースケースのインターフェースを定義
+type MissingCheckOutUsecase interface {
+
+	// 締めの時間を過ぎても退勤していない勤怠を打刻漏れにしてユーザーに通知し、件数を返す
+	Detect(ctx context.Context, now time.Time) (int, error)
+}
+
+// 退勤の打刻漏れユースケースの構造体を定義
+type missingCheckOutUsecase struct {
+	cfg        MissingCheckOutConfig
+	repo       repository.AttendanceRepository
+	users      repository.UserRepository
+	attendance AttendanceUsecase
+	notifier   notify.Notifier
+}
+
+// 打刻漏れの検出呼び出し
+func (u *missingCheckOutUsecase) Detect(ctx context.Context, now time.Time) (int, error) {
+	meta := audit.MetaFrom(ctx)
+	meta.Source = audit.SourceScheduler
+	meta.Reason = "missing check-out"
+	ctx = audit.WithMeta(ctx, meta)
+
+	// 勤務日は0時なので、勤務日 + 締めの時間 <= now の勤怠が対象
+	list, err := u.repo.FindOpenBefore(ctx, now.Add(-u.cfg.Cutoff).In(JST))
+	if err != nil {
+		return 0, err
+	}
+	flagged := 0
+	for _, open := range list {
+		var closeAt time.Time
+		if u.cfg.AutoClose {
+			// 終業時刻より後に出勤していた場合や、終業時刻がまだ来ていない場合は、印だけを付ける
+			if end := open.Date.Add(WorkEnd); end.After(open.CheckIn) && !end.After(now) {
+				closeAt = end
+			}
+		}
+		a, err := u.attendance.FlagMissingCheckOut(ctx, open.ID, closeAt)
+		if errors.Is(err, ErrNotCheckedIn) || errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return flagged, err
+		}
+		flagged++
+		u.notify(ctx, a.UserID, a.Date, a.CheckIn, !closeAt.IsZero())
+	}
+	return flagged, nil
+}
+
+// 打刻漏れをユーザーに通知する
+// 通知の失敗で検出を止めないよう、エラーはログに出すだけにする
+func (u *missingCheckOutUsecase) notify(ctx context.Context, userID string, date, checkIn time.Time, closed bool) {
+	user, err := u.users.FindFirst(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "notify missing check-out failed", "target_user_id", userID, "err", err)
+		return
+	}
+	text := user.Name + " さん " + date.Format("01/02") + " の退勤打刻がありません。"
+	if closed {
+		text += "終業時刻で仮に退勤にしたので、実際の退勤時刻に修正してください。"
+	} else {
+		text += "管理者に修正を依頼してください。"
+	}
+	err = u.notifier.Notify(ctx, notify.Message{
+		UserID:  user.ID,
+		Subject: "退勤打刻のリマインド",
+		Text:    text,
+		Kind:    notify.KindCheckOutReminder,
+		Data: map[string]any{
+			"Date":       date.Format("2006/01/02"),
+			"CheckIn":    checkIn.In(JST).Format("15:04"),
+			"AutoClosed": closed,
+		},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "notify missing check-out failed", "target_user_id", userID, "err", err)
+	}
+}
+
+func NewMissingCheckOutUsecase(cfg MissingCheckOutConfig, repo repository.AttendanceRepository, users repository.UserRepository, attendance AttendanceUsecase, notifier notify.Notifier) MissingCheckOutUsecase {
+	return &missingCheckOutUsecase{cfg: cfg, repo: repo, users: users, attendance: attendance, notifier: notifier}
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"errors"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+var ErrUnsupportedLocale = errors.New("unsupported locale")
+
+// 通知設定ユースケースのインターフェースを定義
+type NotificationUsecase interface {
+
+	// 通知設定の取得（設定していないユーザーは既定値）
+	GetPreference(ctx context.Context, userID string) (*entity.NotificationPreference, error)
+
+	// 通知設定の更新
+	UpdatePreference(ctx context.Context, p entity.NotificationPreference) (*entity.NotificationPreference, error)
+}
+
+// 通知設定ユースケースの構造体を定義
+type notificationUsecase struct {
+	prefs repository.NotificationPreferenceRepository
+	users repository.UserRepository
+}
+
+// 通知設定の取得呼び出し
+func (u *notificationUsecase) GetPreference(ctx context.Context, userID string) (*entity.NotificationPreference, error) {
+	if _, err := u.users.FindFirst(ctx, userID); err != nil {
+		return nil, err
+	}
+	return u.prefs.Find(ctx, userID)
+}
+
+// 通知設定の更新呼び出し
+func (u *notificationUsecase) UpdatePreference(ctx context.Context, p entity.NotificationPreference) (*entity.NotificationPreference, error) {
+	if p.Locale != entity.LocaleJa && p.Locale != entity.LocaleEn {
+		return nil, ErrUnsupportedLocale
+	}
+	if _, err := u.users.FindFirst(ctx, p.UserID); err != nil {
+		return nil, err
+	}
+	if err := u.prefs.Save(ctx, p); err != nil {
+		return nil, err
+	}
+	return u.prefs.Find(ctx, p.UserID)
+}
+
+func NewNotificationUsecase(prefs repository.NotificationPreferenceRepository, users repository.UserRepository) NotificationUsecase {
+	return &notificationUsecase{prefs: prefs, users: users}
+}
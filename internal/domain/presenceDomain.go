@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/live"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+var ErrInvalidStatus = errors.New("invalid presence status")
+
+// 在席ステータスを設定できる最長の期間
+const maxPresenceTTL = 24 * time.Hour
+
+// 在席ステータスのひとことの最大文字数
+const maxPresenceMessage = 100
+
+// 在席ステータスの変化のイベント
+const (
+	EventPresenceUpdated = "presence.updated"
+	EventPresenceCleared = "presence.cleared"
+)
+
+// 設定できる在席ステータス
+var presenceStatuses = map[string]bool{
+	entity.StatusAvailable: true,
+	entity.StatusInMeeting: true,
+	entity.StatusLunch:     true,
+	entity.StatusRemote:    true,
+}
+
+// PresenceConfig は在席ステータスの設定
+type PresenceConfig struct {
+	// 期間を指定せずに設定した在席ステータスの有効期間
+	DefaultTTL time.Duration
+}
+
+// NewPresenceConfigFromEnv は環境変数 PRESENCE_TTL（既定 4h）から在席ステータスの設定を読み込む
+func NewPresenceConfigFromEnv() PresenceConfig {
+	cfg := PresenceConfig{DefaultTTL: 4 * time.Hour}
+	if v := os.Getenv("PRESENCE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxPresenceTTL {
+			slog.Warn("invalid PRESENCE_TTL, using default", "value", v, "default", cfg.DefaultTTL)
+		} else {
+			cfg.DefaultTTL = d
+		}
+	}
+	return cfg
+}
+
+// 在席ステータスユースケースのインターフェースを定義
+// 在席ステータスは部署ごとの部屋に配り、同じ部署の人がすぐに見られるようにする
+type PresenceUsecase interface {
+
+	// 在席ステータスの設定（ttl が0なら既定の期間）
+	Set(ctx context.Context, userID, status, message string, ttl time.Duration, now time.Time) (*entity.UserStatus, error)
+
+	// 在席ステータスの解除
+	Clear(ctx context.Context, userID string) error
+
+	// 部署の在席ステータスの一覧
+	List(ctx context.Context, department string, now time.Time) ([]entity.UserStatus, error)
+
+	// 期限切れの在席ステータスを消し、同じ部署の人に知らせる
+	Expire(ctx context.Context, now time.Time) (int, error)
+
+	// 部署の部屋の購読を始める
+	Subscribe(department string) *live.Subscription
+}
+
+// 在席ステータスユースケースの構造体を定義
+type presenceUsecase struct {
+	cfg    PresenceConfig
+	repo   repository.UserStatusRepository
+	users  repository.UserRepository
+	broker live.Broker
+}
+
+// 在席ステータスの設定呼び出し
+func (u *presenceUsecase) Set(ctx context.Context, userID, status, message string, ttl time.Duration, now time.Time) (*entity.UserStatus, error) {
+	if !presenceStatuses[status] {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+	}
+	if utf8.RuneCountInString(message) > maxPresenceMessage {
+		return nil, fmt.Errorf("%w: message must be at most %d characters", ErrInvalidStatus, maxPresenceMessage)
+	}
+	if ttl == 0 {
+		ttl = u.cfg.DefaultTTL
+	}
+	if ttl < 0 || ttl > maxPresenceTTL {
+		return nil, fmt.Errorf("%w: ttl must be at most %s", ErrInvalidStatus, maxPresenceTTL)
+	}
+	user, err := u.users.FindFirst(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s := entity.UserStatus{
+		UserID:     userID,
+		Department: user.Department,
+		Status:     status,
+		Message:    message,
+		ExpiresAt:  now.Add(ttl),
+		UpdatedAt:  now,
+	}
+	// 部署が変わっていれば、前の部署の部屋から消す
+	if old, err := u.repo.FindByUserID(ctx, userID); err == nil && old.Department != s.Department {
+		u.publish(ctx, EventPresenceCleared, *old)
+	}
+	if err := u.repo.Save(ctx, s); err != nil {
+		return nil, err
+	}
+	u.publish(ctx, EventPresenceUpdated, s)
+	return &s, nil
+}
+
+// 在席ステータスの解除呼び出し
+func (u *presenceUsecase) Clear(ctx context.Context, userID string) error {
+	s, err := u.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := u.repo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	u.publish(ctx, EventPresenceCleared, *s)
+	return nil
+}
+
+// 一覧取得呼び出し
+func (u *presenceUsecase) List(ctx context.Context, department string, now time.Time) ([]entity.UserStatus, error) {
+	return u.repo.FindByDepartment(ctx, department, now)
+}
+
+// 期限切れの在席ステータスの削除呼び出し
+func (u *presenceUsecase) Expire(ctx context.Context, now time.Time) (int, error) {
+	list, err := u.repo.DeleteExpired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range list {
+		u.publish(ctx, EventPresenceCleared, s)
+	}
+	return len(list), nil
+}
+
+// 購読呼び出し
+func (u *presenceUsecase) Subscribe(department string) *live.Subscription {
+	return u.broker.Subscribe(department)
+}
+
+// 在席ステータスの変化を部署の部屋に配る
+// 保存は済んでいるため、配信に失敗してもログに残すだけにする（部屋の一覧には次の接続で反映される）
+func (u *presenceUsecase) publish(ctx context.Context, eventType string, s entity.UserStatus) {
+	e, err := live.NewEvent(eventType, s.Department, s)
+	if err == nil {
+		err = u.broker.Publish(ctx, e)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "presence publish failed", "event_type", eventType, "err", err)
+	}
+}
+
+func NewPresenceUsecase(cfg PresenceConfig, repo repository.UserStatusRepository, users repository.UserRepository, broker live.Broker) PresenceUsecase {
+	return &presenceUsecase{cfg: cfg, repo: repo, users: users, broker: broker}
+}
@@ -0,0 +1,414 @@
+package domain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+	"github.com/enkazu1116/go_home/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 同じユーザーの打刻が同時に記録された場合に、Seqを取り直す回数
+const punchAppendRetries = 3
+
+// 検証で見つかった問題の種類
+const (
+	ProblemGap              = "gap"                // Seqが飛んでいる（イベントが削除された）
+	ProblemPrevHashMismatch = "prev_hash_mismatch" // 直前のイベントのハッシュと一致しない
+	ProblemHashMismatch     = "hash_mismatch"      // 内容からハッシュを計算し直すと一致しない（書き換えられた）
+	ProblemHeadMissing      = "head_missing"       // チェックポイント時点の先頭イベントが存在しない
+	ProblemHeadMismatch     = "head_mismatch"      // チェックポイント時点の先頭イベントのハッシュと一致しない
+)
+
+// PunchLogConfig は打刻ログの設定
+type PunchLogConfig struct {
+	// チェックポイントの署名鍵（未設定の場合は署名しない）
+	SigningKey ed25519.PrivateKey
+	// チェックポイントを作成する間隔
+	CheckpointInterval time.Duration
+}
+
+// NewPunchLogConfigFromEnv は環境変数から打刻ログの設定を読み込む
+// PUNCH_SIGNING_KEY: Ed25519 の seed（32バイト）を base64 でエンコードしたもの
+// PUNCH_CHECKPOINT_INTERVAL: チェックポイントの作成間隔（既定 24h）
+func NewPunchLogConfigFromEnv() PunchLogConfig {
+	cfg := PunchLogConfig{CheckpointInterval: 24 * time.Hour}
+	if v := os.Getenv("PUNCH_SIGNING_KEY"); v != "" {
+		seed, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			slog.Warn("invalid PUNCH_SIGNING_KEY, checkpoints will not be signed")
+		} else {
+			cfg.SigningKey = ed25519.NewKeyFromSeed(seed)
+		}
+	}
+	if v := os.Getenv("PUNCH_CHECKPOINT_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.CheckpointInterval = d
+		} else {
+			slog.Warn("invalid PUNCH_CHECKPOINT_INTERVAL, using default", "value", v, "default", cfg.CheckpointInterval)
+		}
+	}
+	return cfg
+}
+
+// ChainProblem は検証で見つかった問題
+type ChainProblem struct {
+	UserID string `json:"userId"`
+	Seq    int64  `json:"seq"`
+	Kind   string `json:"kind"`
+}
+
+// ChainReport はユーザー1人分のハッシュチェーンの検証結果
+type ChainReport struct {
+	UserID   string         `json:"userId"`
+	Events   int            `json:"events"`
+	Valid    bool           `json:"valid"`
+	Problems []ChainProblem `json:"problems"`
+}
+
+// CheckpointReport はチェックポイントの検証結果
+type CheckpointReport struct {
+	CheckpointID string         `json:"checkpointId"`
+	Signed       bool           `json:"signed"`
+	SignatureOK  bool           `json:"signatureOk"`
+	DigestOK     bool           `json:"digestOk"`
+	Valid        bool           `json:"valid"`
+	Problems     []ChainProblem `json:"problems"`
+}
+
+// チェックポイントに記録するユーザーごとの先頭
+type chainHead struct {
+	UserID string `json:"userId"`
+	Seq    int64  `json:"seq"`
+	Hash   string `json:"hash"`
+}
+
+// 打刻ログユースケースのインターフェースを定義
+type PunchLogUsecase interface {
+
+	// 打刻イベントをハッシュチェーンに追記する（ID・Seq・PrevHash・Hashはここで決める）
+	Record(ctx context.Context, e entity.PunchEvent) (*entity.PunchEvent, error)
+
+	// ユーザーの打刻イベントをSeq順に取得
+	Events(ctx context.Context, userID string) ([]entity.PunchEvent, error)
+
+	// ユーザーの打刻時刻が from 以上 to 未満のイベントと、それらと同じ勤怠のイベントをSeq順に取得（to がゼロなら上限なし）
+	EventsInRange(ctx context.Context, userID string, from, to time.Time) ([]entity.PunchEvent, error)
+
+	// ユーザーの打刻時刻が before 以前で最新の出勤イベントを取得
+	LastCheckIn(ctx context.Context, userID string, before time.Time) (*entity.PunchEvent, error)
+
+	// オフライン打刻のクライアントのIDで取り込み済みのイベントを取得
+	EventsByClientIDs(ctx context.Context, userID string, clientIDs []string) ([]entity.PunchEvent, error)
+
+	// ユーザーのハッシュチェーンを検証
+	Verify(ctx context.Context, userID string) (*ChainReport, error)
+
+	// 全ユーザーのハッシュチェーンを検証
+	VerifyAll(ctx context.Context) ([]ChainReport, error)
+
+	// 現在の全ユーザーの先頭からチェックポイントを作成
+	CreateCheckpoint(ctx context.Context) (*entity.PunchCheckpoint, error)
+
+	// チェックポイントの署名と、チェックポイント時点の先頭が今も残っているかを検証
+	VerifyCheckpoint(ctx context.Context, id string) (*CheckpointReport, error)
+
+	// チェックポイントを新しい順に取得
+	ListCheckpoints(ctx context.Context, limit int) ([]entity.PunchCheckpoint, error)
+}
+
+// 打刻ログユースケースの構造体を定義
+type punchLogUsecase struct {
+	cfg  PunchLogConfig
+	repo repository.PunchEventRepository
+}
+
+// 打刻イベントのハッシュの計算方法（entity.PunchEvent.HashVersion）
+// 記録済みのイベントは記録したときの方法で検証するため、方法を変えるときは番号を増やす
+const (
+	// 項目を改行でつなぐ
+	// 項目に改行を含めると、別の項目の組み合わせと同じハッシュになりうる（理由の末尾を端末のIDとして読めるなど）
+	PunchHashV1 = 1
+	// 計算方法の番号と各項目を、バイト長（8バイトのビッグエンディアン）を前に付けてつなぐ
+	PunchHashV2 = 2
+)
+
+// 新しく記録するイベントのハッシュの計算方法
+const currentPunchHashVersion = PunchHashV2
+
+// HashPunchEvent は打刻イベントのハッシュを計算する
+// 直前のイベントのハッシュ（PrevHash）を含めることでチェーンになる
+// 計算方法は HashVersion に従い、知らない番号の場合は空を返す（検証ではハッシュの不一致になる）
+func HashPunchEvent(e entity.PunchEvent) string {
+	switch e.HashVersion {
+	case 0, PunchHashV1:
+		return hashPunchEventV1(e)
+	case PunchHashV2:
+		return hashPunchEventV2(e)
+	default:
+		return ""
+	}
+}
+
+// hashPunchEventV1 は項目を改行でつないでハッシュを計算する（導入前に記録したイベントの検証用）
+func hashPunchEventV1(e entity.PunchEvent) string {
+	fields := []string{
+		e.PrevHash,
+		e.ID,
+		e.UserID,
+		strconv.FormatInt(e.Seq, 10),
+		e.Type,
+		e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		e.Source,
+		e.AttendanceID,
+		e.TargetID,
+		e.Reason,
+	}
+	// 端末のID・クライアントのIDは後から追加した項目のため、空の場合はハッシュに含めない（以前のイベントのハッシュを変えない）
+	if e.DeviceID != "" {
+		fields = append(fields, e.DeviceID)
+	}
+	// 端末のIDが空の場合と区別できるよう、以降の項目には接頭辞を付ける
+	if e.ClientID != "" {
+		fields = append(fields, "client:"+e.ClientID)
+	}
+	if e.WorkLocation != "" {
+		fields = append(fields, "location:"+e.WorkLocation)
+	}
+	if e.Violation != "" {
+		fields = append(fields, "violation:"+e.Violation)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashPunchEventV2 は各項目の前にバイト長を付けてハッシュを計算する
+// 項目の区切りが値に左右されないため、空の項目も含めてすべての項目を同じ順に入れる
+// 項目を増やすときは末尾に足し、計算方法の番号を増やす
+func hashPunchEventV2(e entity.PunchEvent) string {
+	fields := []string{
+		strconv.Itoa(PunchHashV2),
+		e.PrevHash,
+		e.ID,
+		e.UserID,
+		strconv.FormatInt(e.Seq, 10),
+		e.Type,
+		e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		e.Source,
+		e.AttendanceID,
+		e.TargetID,
+		e.Reason,
+		e.DeviceID,
+		e.ClientID,
+		e.WorkLocation,
+		e.Violation,
+	}
+	h := sha256.New()
+	var length [8]byte
+	for _, f := range fields {
+		binary.BigEndian.PutUint64(length[:], uint64(len(f)))
+		h.Write(length[:])
+		h.Write([]byte(f))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 打刻イベント追記呼び出し
+func (u *punchLogUsecase) Record(ctx context.Context, e entity.PunchEvent) (_ *entity.PunchEvent, err error) {
+	ctx, span := tracing.Start(ctx, "PunchLogUsecase.Record", attribute.String("user.id", e.UserID), attribute.String("punch.type", e.Type))
+	defer tracing.End(span, &err)
+
+	// Postgresはマイクロ秒までしか保持しないため、保存後もハッシュが一致するよう丸めておく
+	e.OccurredAt = e.OccurredAt.UTC().Truncate(time.Microsecond)
+
+	for i := 0; i < punchAppendRetries; i++ {
+		e.ID = uuid.NewString()
+		e.Seq = 1
+		e.PrevHash = ""
+		e.HashVersion = currentPunchHashVersion
+		last, err := u.repo.LastByUser(ctx, e.UserID)
+		if err == nil {
+			e.Seq = last.Seq + 1
+			e.PrevHash = last.Hash
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		e.Hash = HashPunchEvent(e)
+
+		err = u.repo.Append(ctx, e)
+		if err == nil {
+			return &e, nil
+		}
+		if !errors.Is(err, repository.ErrConflict) {
+			return nil, err
+		}
+		// 同じSeqを他の打刻が先に使ったので、最新を取り直す
+	}
+	return nil, fmt.Errorf("append punch event: %w", repository.ErrConflict)
+}
+
+// 打刻イベント一覧取得呼び出し
+func (u *punchLogUsecase) Events(ctx context.Context, userID string) ([]entity.PunchEvent, error) {
+	return u.repo.ListByUser(ctx, userID)
+}
+
+// 期間の打刻イベント取得呼び出し
+func (u *punchLogUsecase) EventsInRange(ctx context.Context, userID string, from, to time.Time) ([]entity.PunchEvent, error) {
+	return u.repo.ListByUserAndRange(ctx, userID, from, to)
+}
+
+// 最新の出勤イベント取得呼び出し
+func (u *punchLogUsecase) LastCheckIn(ctx context.Context, userID string, before time.Time) (*entity.PunchEvent, error) {
+	return u.repo.LastByUserAndType(ctx, userID, entity.PunchCheckIn, before)
+}
+
+// 取り込み済みのオフライン打刻取得呼び出し
+func (u *punchLogUsecase) EventsByClientIDs(ctx context.Context, userID string, clientIDs []string) ([]entity.PunchEvent, error) {
+	return u.repo.ListByClientIDs(ctx, userID, clientIDs)
+}
+
+// ハッシュチェーン検証呼び出し
+func (u *punchLogUsecase) Verify(ctx context.Context, userID string) (*ChainReport, error) {
+	events, err := u.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	report := &ChainReport{UserID: userID, Events: len(events), Problems: []ChainProblem{}}
+	var prev *entity.PunchEvent
+	for i := range events {
+		e := events[i]
+		expectedSeq := int64(1)
+		expectedPrev := ""
+		if prev != nil {
+			expectedSeq = prev.Seq + 1
+			expectedPrev = prev.Hash
+		}
+		if e.Seq != expectedSeq {
+			report.Problems = append(report.Problems, ChainProblem{UserID: userID, Seq: e.Seq, Kind: ProblemGap})
+		}
+		if e.PrevHash != expectedPrev {
+			report.Problems = append(report.Problems, ChainProblem{UserID: userID, Seq: e.Seq, Kind: ProblemPrevHashMismatch})
+		}
+		if HashPunchEvent(e) != e.Hash {
+			report.Problems = append(report.Problems, ChainProblem{UserID: userID, Seq: e.Seq, Kind: ProblemHashMismatch})
+		}
+		prev = &e
+	}
+	report.Valid = len(report.Problems) == 0
+	return report, nil
+}
+
+// 全ユーザーのハッシュチェーン検証呼び出し
+func (u *punchLogUsecase) VerifyAll(ctx context.Context) ([]ChainReport, error) {
+	ids, err := u.repo.UserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]ChainReport, 0, len(ids))
+	for _, id := range ids {
+		report, err := u.Verify(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// チェックポイント作成呼び出し
+func (u *punchLogUsecase) CreateCheckpoint(ctx context.Context) (*entity.PunchCheckpoint, error) {
+	ids, err := u.repo.UserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	heads := make([]chainHead, 0, len(ids))
+	for _, id := range ids {
+		last, err := u.repo.LastByUser(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		heads = append(heads, chainHead{UserID: id, Seq: last.Seq, Hash: last.Hash})
+	}
+	headsJSON, err := json.Marshal(heads)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(headsJSON)
+
+	c := entity.PunchCheckpoint{
+		ID:        uuid.NewString(),
+		Heads:     string(headsJSON),
+		Digest:    hex.EncodeToString(digest[:]),
+		CreatedAt: time.Now(),
+	}
+	if u.cfg.SigningKey != nil {
+		c.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(u.cfg.SigningKey, digest[:]))
+		c.PublicKey = base64.StdEncoding.EncodeToString(u.cfg.SigningKey.Public().(ed25519.PublicKey))
+	}
+	if err := u.repo.CreateCheckpoint(ctx, c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// チェックポイント検証呼び出し
+// 署名は保存されている公開鍵ではなく、設定された鍵で検証する（鍵ごと差し替えられた場合も検知するため）
+func (u *punchLogUsecase) VerifyCheckpoint(ctx context.Context, id string) (*CheckpointReport, error) {
+	c, err := u.repo.FindCheckpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	report := &CheckpointReport{CheckpointID: c.ID, Signed: c.Signature != "", Problems: []ChainProblem{}}
+
+	digest := sha256.Sum256([]byte(c.Heads))
+	report.DigestOK = hex.EncodeToString(digest[:]) == c.Digest
+	if report.Signed && u.cfg.SigningKey != nil {
+		sig, err := base64.StdEncoding.DecodeString(c.Signature)
+		report.SignatureOK = err == nil && ed25519.Verify(u.cfg.SigningKey.Public().(ed25519.PublicKey), digest[:], sig)
+	}
+
+	var heads []chainHead
+	if err := json.Unmarshal([]byte(c.Heads), &heads); err != nil {
+		return nil, err
+	}
+	for _, head := range heads {
+		e, err := u.repo.FindByUserAndSeq(ctx, head.UserID, head.Seq)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			report.Problems = append(report.Problems, ChainProblem{UserID: head.UserID, Seq: head.Seq, Kind: ProblemHeadMissing})
+		case err != nil:
+			return nil, err
+		case e.Hash != head.Hash:
+			report.Problems = append(report.Problems, ChainProblem{UserID: head.UserID, Seq: head.Seq, Kind: ProblemHeadMismatch})
+		case HashPunchEvent(*e) != e.Hash:
+			report.Problems = append(report.Problems, ChainProblem{UserID: head.UserID, Seq: head.Seq, Kind: ProblemHashMismatch})
+		}
+	}
+	report.Valid = report.DigestOK && (!report.Signed || report.SignatureOK) && len(report.Problems) == 0
+	return report, nil
+}
+
+// チェックポイント一覧取得呼び出し
+func (u *punchLogUsecase) ListCheckpoints(ctx context.Context, limit int) ([]entity.PunchCheckpoint, error) {
+	return u.repo.ListCheckpoints(ctx, limit)
+}
+
+func NewPunchLogUsecase(cfg PunchLogConfig, repo repository.PunchEventRepository) PunchLogUsecase {
+	return &punchLogUsecase{cfg: cfg, repo: repo}
+}
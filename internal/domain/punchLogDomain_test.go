@@ -0,0 +1,348 @@
+package domain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// openTestDB はマイグレーション済みの SQLite を開く
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := dbinfra.OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dbinfra.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// recordPunches は打刻イベントを n 件記録する
+func recordPunches(t *testing.T, u PunchLogUsecase, userID string, n int) []entity.PunchEvent {
+	t.Helper()
+	types := []string{entity.PunchCheckIn, entity.PunchBreakStart, entity.PunchBreakEnd, entity.PunchCheckOut}
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, JST)
+	var list []entity.PunchEvent
+	for i := 0; i < n; i++ {
+		e, err := u.Record(context.Background(), entity.PunchEvent{
+			UserID:       userID,
+			Type:         types[i%len(types)],
+			OccurredAt:   at.Add(time.Duration(i) * time.Hour),
+			AttendanceID: "a1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, *e)
+	}
+	return list
+}
+
+func TestPunchLogRecordChainsEvents(t *testing.T) {
+	u := NewPunchLogUsecase(PunchLogConfig{}, repository.NewPunchEventRepository(openTestDB(t)))
+	list := recordPunches(t, u, "u1", 3)
+	for i, e := range list {
+		if e.Seq != int64(i+1) {
+			t.Errorf("event %d: seq = %d", i, e.Seq)
+		}
+		prev := ""
+		if i > 0 {
+			prev = list[i-1].Hash
+		}
+		if e.PrevHash != prev || e.Hash != HashPunchEvent(e) {
+			t.Errorf("event %d: prevHash=%s hash=%s, not chained", i, e.PrevHash, e.Hash)
+		}
+	}
+	// ユーザーごとに別のチェーンになる
+	if other := recordPunches(t, u, "u2", 1); other[0].Seq != 1 || other[0].PrevHash != "" {
+		t.Errorf("other user's first event = %+v", other[0])
+	}
+}
+
+func TestHashPunchEvent(t *testing.T) {
+	base := entity.PunchEvent{ID: "e1", UserID: "u1", Seq: 1, Type: entity.PunchCheckIn, OccurredAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)}
+	with := func(version int, f func(e *entity.PunchEvent)) entity.PunchEvent {
+		e := base
+		e.HashVersion = version
+		f(&e)
+		return e
+	}
+	tests := []struct {
+		name     string
+		a, b     entity.PunchEvent
+		wantSame bool
+	}{
+		{
+			// 理由の改行の後ろが端末のIDとして読めてしまう
+			name:     "v1 reason spills into device id",
+			a:        with(PunchHashV1, func(e *entity.PunchEvent) { e.Reason = "late\nkiosk-1" }),
+			b:        with(PunchHashV1, func(e *entity.PunchEvent) { e.Reason = "late"; e.DeviceID = "kiosk-1" }),
+			wantSame: true,
+		},
+		{
+			name: "v2 reason does not spill into device id",
+			a:    with(PunchHashV2, func(e *entity.PunchEvent) { e.Reason = "late\nkiosk-1" }),
+			b:    with(PunchHashV2, func(e *entity.PunchEvent) { e.Reason = "late"; e.DeviceID = "kiosk-1" }),
+		},
+		{
+			name: "v2 empty field is not skipped",
+			a:    with(PunchHashV2, func(e *entity.PunchEvent) { e.DeviceID = "d1" }),
+			b:    with(PunchHashV2, func(e *entity.PunchEvent) { e.ClientID = "d1" }),
+		},
+		{
+			name:     "unset version is v1",
+			a:        with(0, func(e *entity.PunchEvent) {}),
+			b:        with(PunchHashV1, func(e *entity.PunchEvent) {}),
+			wantSame: true,
+		},
+		{
+			name: "version changes the hash",
+			a:    with(PunchHashV1, func(e *entity.PunchEvent) {}),
+			b:    with(PunchHashV2, func(e *entity.PunchEvent) {}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := HashPunchEvent(tt.a) == HashPunchEvent(tt.b); same != tt.wantSame {
+				t.Errorf("same hash = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+	if got := HashPunchEvent(with(99, func(e *entity.PunchEvent) {})); got != "" {
+		t.Errorf("unknown version hash = %q, want empty", got)
+	}
+}
+
+// 導入前の計算方法で記録したイベントに、新しい計算方法のイベントが続いても検証できる
+func TestPunchLogVerifyMixedHashVersions(t *testing.T) {
+	db := openTestDB(t)
+	u := NewPunchLogUsecase(PunchLogConfig{}, repository.NewPunchEventRepository(db))
+	legacy := recordPunches(t, u, "u1", 2)
+	prev := ""
+	for _, e := range legacy {
+		e.HashVersion = PunchHashV1
+		e.PrevHash = prev
+		e.Hash = HashPunchEvent(e)
+		exec(t, db, "UPDATE punch_events SET hash_version = ?, prev_hash = ?, hash = ? WHERE id = ?", e.HashVersion, e.PrevHash, e.Hash, e.ID)
+		prev = e.Hash
+	}
+	current := recordPunches(t, u, "u1", 2)
+	if current[0].HashVersion != PunchHashV2 || current[0].PrevHash != prev {
+		t.Fatalf("event after legacy = %+v", current[0])
+	}
+
+	report, err := u.Verify(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || len(report.Problems) != 0 {
+		t.Errorf("report = %+v, want valid", report)
+	}
+
+	// 計算方法の番号を書き換えると検知できる
+	exec(t, db, "UPDATE punch_events SET hash_version = ? WHERE id = ?", PunchHashV2, legacy[0].ID)
+	report, err = u.Verify(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ChainProblem{{UserID: "u1", Seq: 1, Kind: ProblemHashMismatch}}
+	if !reflect.DeepEqual(report.Problems, want) {
+		t.Errorf("problems = %+v, want %+v", report.Problems, want)
+	}
+}
+
+func TestPunchLogVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, db *gorm.DB, events []entity.PunchEvent)
+		want   []ChainProblem
+	}{
+		{
+			name:   "intact",
+			tamper: func(t *testing.T, db *gorm.DB, events []entity.PunchEvent) {},
+		},
+		{
+			name: "rewritten time",
+			tamper: func(t *testing.T, db *gorm.DB, events []entity.PunchEvent) {
+				exec(t, db, "UPDATE punch_events SET occurred_at = ? WHERE id = ?", events[1].OccurredAt.Add(-time.Hour), events[1].ID)
+			},
+			want: []ChainProblem{{UserID: "u1", Seq: 2, Kind: ProblemHashMismatch}},
+		},
+		{
+			name: "rewritten type",
+			tamper: func(t *testing.T, db *gorm.DB, events []entity.PunchEvent) {
+				exec(t, db, "UPDATE punch_events SET type = ? WHERE id = ?", entity.PunchCheckOut, events[0].ID)
+			},
+			want: []ChainProblem{{UserID: "u1", Seq: 1, Kind: ProblemHashMismatch}},
+		},
+		{
+			name: "rewritten and rehashed",
+			tamper: func(t *testing.T, db *gorm.DB, events []entity.PunchEvent) {
+				e := events[1]
+				e.Reason = "edited"
+				exec(t, db, "UPDATE punch_events SET reason = ?, hash = ? WHERE id = ?", e.Reason, HashPunchEvent(e), e.ID)
+			},
+			want: []ChainProblem{{UserID: "u1", Seq: 3, Kind: ProblemPrevHashMismatch}},
+		},
+		{
+			name: "deleted event",
+			tamper: func(t *testing.T, db *gorm.DB, events []entity.PunchEvent) {
+				exec(t, db, "DELETE FROM punch_events WHERE id = ?", events[1].ID)
+			},
+			want: []ChainProblem{
+				{UserID: "u1", Seq: 3, Kind: ProblemGap},
+				{UserID: "u1", Seq: 3, Kind: ProblemPrevHashMismatch},
+			},
+		},
+		{
+			name: "deleted head",
+			tamper: func(t *testing.T, db *gorm.DB, events []entity.PunchEvent) {
+				exec(t, db, "DELETE FROM punch_events WHERE id = ?", events[0].ID)
+			},
+			want: []ChainProblem{
+				{UserID: "u1", Seq: 2, Kind: ProblemGap},
+				{UserID: "u1", Seq: 2, Kind: ProblemPrevHashMismatch},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			u := NewPunchLogUsecase(PunchLogConfig{}, repository.NewPunchEventRepository(db))
+			events := recordPunches(t, u, "u1", 4)
+			tt.tamper(t, db, events)
+
+			report, err := u.Verify(context.Background(), "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if want == nil {
+				want = []ChainProblem{}
+			}
+			if !reflect.DeepEqual(report.Problems, want) {
+				t.Errorf("problems = %+v, want %+v", report.Problems, want)
+			}
+			if report.Valid != (len(want) == 0) {
+				t.Errorf("valid = %v", report.Valid)
+			}
+		})
+	}
+}
+
+func TestPunchLogVerifyCheckpoint(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	otherKey := ed25519.NewKeyFromSeed(append(make([]byte, ed25519.SeedSize-1), 1))
+	tests := []struct {
+		name          string
+		signingKey    ed25519.PrivateKey
+		verifyKey     ed25519.PrivateKey
+		tamper        func(t *testing.T, db *gorm.DB, u PunchLogUsecase, c *entity.PunchCheckpoint)
+		wantValid     bool
+		wantSignature bool
+		wantDigest    bool
+		wantProblems  []string
+	}{
+		{
+			name:          "signed and intact",
+			signingKey:    key,
+			verifyKey:     key,
+			tamper:        func(t *testing.T, db *gorm.DB, u PunchLogUsecase, c *entity.PunchCheckpoint) {},
+			wantValid:     true,
+			wantSignature: true,
+			wantDigest:    true,
+		},
+		{
+			name:       "unsigned and intact",
+			tamper:     func(t *testing.T, db *gorm.DB, u PunchLogUsecase, c *entity.PunchCheckpoint) {},
+			wantValid:  true,
+			wantDigest: true,
+		},
+		{
+			name:       "signed with another key",
+			signingKey: otherKey,
+			verifyKey:  key,
+			tamper:     func(t *testing.T, db *gorm.DB, u PunchLogUsecase, c *entity.PunchCheckpoint) {},
+			wantDigest: true,
+		},
+		{
+			name:       "heads rewritten",
+			signingKey: key,
+			verifyKey:  key,
+			tamper: func(t *testing.T, db *gorm.DB, u PunchLogUsecase, c *entity.PunchCheckpoint) {
+				exec(t, db, "UPDATE punch_checkpoints SET heads = REPLACE(heads, '\"seq\":2', '\"seq\":1') WHERE id = ?", c.ID)
+			},
+			// 署名は Heads から計算し直したダイジェストで検証するため、署名も合わなくなる
+			wantProblems: []string{ProblemHeadMismatch},
+		},
+		{
+			name:       "head deleted",
+			signingKey: key,
+			verifyKey:  key,
+			tamper: func(t *testing.T, db *gorm.DB, u PunchLogUsecase, c *entity.PunchCheckpoint) {
+				exec(t, db, "DELETE FROM punch_events WHERE user_id = 'u1' AND seq = 2")
+			},
+			wantSignature: true,
+			wantDigest:    true,
+			wantProblems:  []string{ProblemHeadMissing},
+		},
+		{
+			name:       "chain rebuilt after the checkpoint",
+			signingKey: key,
+			verifyKey:  key,
+			tamper: func(t *testing.T, db *gorm.DB, u PunchLogUsecase, c *entity.PunchCheckpoint) {
+				exec(t, db, "DELETE FROM punch_events WHERE user_id = 'u1'")
+				recordPunches(t, u, "u1", 2)
+			},
+			wantSignature: true,
+			wantDigest:    true,
+			wantProblems:  []string{ProblemHeadMismatch},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			repo := repository.NewPunchEventRepository(db)
+			u := NewPunchLogUsecase(PunchLogConfig{SigningKey: tt.signingKey}, repo)
+			recordPunches(t, u, "u1", 2)
+			c, err := u.CreateCheckpoint(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(t, db, u, c)
+
+			verifier := NewPunchLogUsecase(PunchLogConfig{SigningKey: tt.verifyKey}, repo)
+			report, err := verifier.VerifyCheckpoint(context.Background(), c.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kinds []string
+			for _, p := range report.Problems {
+				kinds = append(kinds, p.Kind)
+			}
+			if report.Valid != tt.wantValid || report.SignatureOK != tt.wantSignature || report.DigestOK != tt.wantDigest || !reflect.DeepEqual(kinds, tt.wantProblems) {
+				t.Errorf("report = %+v, want valid=%v signatureOk=%v digestOk=%v problems=%v", report, tt.wantValid, tt.wantSignature, tt.wantDigest, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func exec(t *testing.T, db *gorm.DB, sql string, values ...any) {
+	t.Helper()
+	if err := db.Exec(sql, values...).Error; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,229 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+	"github.com/enkazu1116/go_home/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	ErrPunchPolicyViolation = errors.New("punch violates the punch policy")
+	ErrInvalidPunchPolicy   = errors.New("invalid punch policy")
+)
+
+// 位置情報の誤差がこれより大きい場合はジオフェンスの判定に使わない
+const maxLocationAccuracyMeters = 1000
+
+// PunchLocation はモバイルアプリが打刻と一緒に送る位置情報
+type PunchLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// 誤差（メートル、0 は不明）
+	Accuracy float64 `json:"accuracy,omitempty"`
+}
+
+type punchLocationKey struct{}
+
+// WithPunchLocation は打刻の位置情報をコンテキストに格納する
+func WithPunchLocation(ctx context.Context, loc *PunchLocation) context.Context {
+	return context.WithValue(ctx, punchLocationKey{}, loc)
+}
+
+// punchLocationFrom はコンテキストから打刻の位置情報を取り出す（無ければ nil）
+func punchLocationFrom(ctx context.Context) *PunchLocation {
+	loc, _ := ctx.Value(punchLocationKey{}).(*PunchLocation)
+	return loc
+}
+
+// PunchDecision は打刻ポリシーによる判定結果
+type PunchDecision struct {
+	WorkLocation string
+	// 違反の内容（違反が無ければ空）
+	Violation string
+	// 違反した打刻を受け付けないかどうか
+	Reject bool
+}
+
+// 打刻ポリシーユースケースのインターフェースを定義
+type PunchPolicyUsecase interface {
+
+	// ポリシーの登録
+	Create(ctx context.Context, p entity.PunchPolicy) (*entity.PunchPolicy, error)
+
+	// ポリシーの更新
+	Update(ctx context.Context, p entity.PunchPolicy) error
+
+	// ポリシーの削除
+	Delete(ctx context.Context, id string) error
+
+	// 1件取得
+	FindByID(ctx context.Context, id string) (*entity.PunchPolicy, error)
+
+	// 一覧取得
+	FindAll(ctx context.Context) ([]entity.PunchPolicy, error)
+
+	// 打刻の送信元IPアドレスと位置情報から勤務場所を判定する
+	// ユーザーに適用するポリシーが無ければ nil を返す
+	Evaluate(ctx context.Context, userID, clientIP string, loc *PunchLocation) (*PunchDecision, error)
+}
+
+// 打刻ポリシーユースケースの構造体を定義
+type punchPolicyUsecase struct {
+	repo  repository.PunchPolicyRepository
+	users repository.UserRepository
+}
+
+// ポリシーの登録呼び出し
+func (u *punchPolicyUsecase) Create(ctx context.Context, p entity.PunchPolicy) (*entity.PunchPolicy, error) {
+	if err := validatePunchPolicy(&p); err != nil {
+		return nil, err
+	}
+	p.ID = uuid.NewString()
+	if err := u.repo.Create(ctx, p); err != nil {
+		return nil, err
+	}
+	return u.repo.FindByID(ctx, p.ID)
+}
+
+// ポリシーの更新呼び出し
+func (u *punchPolicyUsecase) Update(ctx context.Context, p entity.PunchPolicy) error {
+	if err := validatePunchPolicy(&p); err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Now()
+	return u.repo.Update(ctx, p)
+}
+
+// ポリシーの削除呼び出し
+func (u *punchPolicyUsecase) Delete(ctx context.Context, id string) error {
+	return u.repo.Delete(ctx, id)
+}
+
+// 1件取得呼び出し
+func (u *punchPolicyUsecase) FindByID(ctx context.Context, id string) (*entity.PunchPolicy, error) {
+	return u.repo.FindByID(ctx, id)
+}
+
+// 一覧取得呼び出し
+func (u *punchPolicyUsecase) FindAll(ctx context.Context) ([]entity.PunchPolicy, error) {
+	return u.repo.FindAll(ctx)
+}
+
+// 勤務場所の判定呼び出し
+// 事務所のネットワーク・ジオフェンスの順に当てはめ、どれにも当てはまらなければ在宅勤務とする
+func (u *punchPolicyUsecase) Evaluate(ctx context.Context, userID, clientIP string, loc *PunchLocation) (_ *PunchDecision, err error) {
+	ctx, span := tracing.Start(ctx, "PunchPolicyUsecase.Evaluate", attribute.String("user.id", userID))
+	defer tracing.End(span, &err)
+	user, err := u.users.FindFirst(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := u.repo.FindForUser(ctx, userID, user.Department)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &PunchDecision{WorkLocation: entity.WorkLocationRemote}
+	if inOfficeNetwork(policy.OfficeCIDRs, clientIP) {
+		decision.WorkLocation = entity.WorkLocationOffice
+	} else if g := matchGeofence(policy.Geofences, loc); g != nil {
+		decision.WorkLocation = g.Location
+	}
+	if decision.WorkLocation == entity.WorkLocationRemote && !policy.AllowRemote {
+		decision.Violation = fmt.Sprintf("punch from outside the office networks and geofences of policy %q", policy.Name)
+		decision.Reject = policy.OnViolation == entity.PolicyReject
+	}
+	return decision, nil
+}
+
+// ポリシーの内容を検証し、省略された値に既定値を入れる
+func validatePunchPolicy(p *entity.PunchPolicy) error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPunchPolicy)
+	}
+	if p.UserID != "" && p.Department != "" {
+		return fmt.Errorf("%w: set either user_id or department, not both", ErrInvalidPunchPolicy)
+	}
+	switch p.OnViolation {
+	case "":
+		p.OnViolation = entity.PolicyFlag
+	case entity.PolicyReject, entity.PolicyFlag:
+	default:
+		return fmt.Errorf("%w: on_violation must be %q or %q", ErrInvalidPunchPolicy, entity.PolicyReject, entity.PolicyFlag)
+	}
+	for _, cidr := range p.OfficeCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("%w: invalid CIDR %q", ErrInvalidPunchPolicy, cidr)
+		}
+	}
+	for i := range p.Geofences {
+		g := &p.Geofences[i]
+		if g.Latitude < -90 || g.Latitude > 90 || g.Longitude < -180 || g.Longitude > 180 || g.RadiusMeters <= 0 {
+			return fmt.Errorf("%w: invalid geofence %q", ErrInvalidPunchPolicy, g.Name)
+		}
+		switch g.Location {
+		case "":
+			g.Location = entity.WorkLocationOffice
+		case entity.WorkLocationOffice, entity.WorkLocationClientSite:
+		default:
+			return fmt.Errorf("%w: geofence location must be %q or %q", ErrInvalidPunchPolicy, entity.WorkLocationOffice, entity.WorkLocationClientSite)
+		}
+	}
+	return nil
+}
+
+// 送信元IPアドレスが事務所のネットワークに含まれるかどうか
+func inOfficeNetwork(cidrs []string, clientIP string) bool {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if _, n, err := net.ParseCIDR(cidr); err == nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 位置情報が含まれる最初のジオフェンスを返す
+// 誤差が大きすぎる位置情報は判定に使わない
+func matchGeofence(fences []entity.Geofence, loc *PunchLocation) *entity.Geofence {
+	if loc == nil || loc.Accuracy > maxLocationAccuracyMeters {
+		return nil
+	}
+	for i := range fences {
+		if distanceMeters(loc.Latitude, loc.Longitude, fences[i].Latitude, fences[i].Longitude) <= fences[i].RadiusMeters {
+			return &fences[i]
+		}
+	}
+	return nil
+}
+
+// 2点間の距離（メートル、haversine の公式）
+func distanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+func NewPunchPolicyUsecase(repo repository.PunchPolicyRepository, users repository.UserRepository) PunchPolicyUsecase {
+	return &punchPolicyUsecase{repo: repo, users: users}
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+func TestInOfficeNetwork(t *testing.T) {
+	cidrs := []string{"203.0.113.0/24", "198.51.100.7/32", "2001:db8:1::/48"}
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"203.0.113.0", true}, // ネットワークの先頭
+		{"203.0.113.255", true},
+		{"203.0.112.255", false}, // 範囲の直前
+		{"203.0.114.0", false},   // 範囲の直後
+		{"::ffff:203.0.113.10", true},
+		{"198.51.100.7", true},
+		{"198.51.100.8", false},
+		{"2001:db8:1:ffff::1", true},
+		{"2001:db8:2::1", false},
+		{"203.0.113.10:443", false}, // ポート付きは IP アドレスではない
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := inOfficeNetwork(cidrs, tt.ip); got != tt.want {
+			t.Errorf("inOfficeNetwork(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGeofence(t *testing.T) {
+	office := entity.Geofence{Name: "office", Latitude: 35.681236, Longitude: 139.767125, Location: entity.WorkLocationOffice}
+	edge := PunchLocation{Latitude: 35.682236, Longitude: 139.767125} // 北へ約111m
+	office.RadiusMeters = distanceMeters(edge.Latitude, edge.Longitude, office.Latitude, office.Longitude)
+	site := entity.Geofence{Name: "site", Latitude: 35.681236, Longitude: 139.767125, RadiusMeters: 500, Location: entity.WorkLocationClientSite}
+
+	tests := []struct {
+		name   string
+		fences []entity.Geofence
+		loc    *PunchLocation
+		want   string
+	}{
+		{"center", []entity.Geofence{office}, &PunchLocation{Latitude: office.Latitude, Longitude: office.Longitude}, "office"},
+		{"on the boundary", []entity.Geofence{office}, &edge, "office"},
+		{"just outside", []entity.Geofence{office}, &PunchLocation{Latitude: 35.682246, Longitude: 139.767125}, ""},
+		{"first match wins", []entity.Geofence{site, office}, &edge, "site"},
+		{"accuracy at the limit", []entity.Geofence{office}, &PunchLocation{Latitude: office.Latitude, Longitude: office.Longitude, Accuracy: maxLocationAccuracyMeters}, "office"},
+		{"accuracy too large", []entity.Geofence{office}, &PunchLocation{Latitude: office.Latitude, Longitude: office.Longitude, Accuracy: maxLocationAccuracyMeters + 1}, ""},
+		{"no location", []entity.Geofence{office}, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if g := matchGeofence(tt.fences, tt.loc); g != nil {
+				got = g.Name
+			}
+			if got != tt.want {
+				t.Errorf("matchGeofence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPunchPolicyEvaluate(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	userRepo := repository.NewTimeIsMoneyRepository(db)
+	u := NewPunchPolicyUsecase(repository.NewPunchPolicyRepository(db), userRepo)
+	for _, user := range []entity.User{
+		{ID: "alice", AuthID: "a-alice", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee, Department: "sales"},
+		{ID: "bob", AuthID: "a-bob", Name: "Bob", Email: "bob@example.com", Role: entity.RoleEmployee, Department: "dev"},
+		{ID: "carol", AuthID: "a-carol", Name: "Carol", Email: "carol@example.com", Role: entity.RoleEmployee, Department: "sales"},
+	} {
+		if err := userRepo.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []entity.PunchPolicy{
+		{Name: "sales", Department: "sales", OfficeCIDRs: []string{"203.0.113.0/24"},
+			Geofences:   []entity.Geofence{{Name: "client", Latitude: 35.0, Longitude: 135.0, RadiusMeters: 200, Location: entity.WorkLocationClientSite}},
+			OnViolation: entity.PolicyReject},
+		{Name: "carol", UserID: "carol", AllowRemote: true},
+	} {
+		if _, err := u.Create(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		userID    string
+		ip        string
+		loc       *PunchLocation
+		location  string
+		violation bool
+		reject    bool
+	}{
+		{"office network", "alice", "203.0.113.255", nil, entity.WorkLocationOffice, false, false},
+		{"client site", "alice", "192.0.2.1", &PunchLocation{Latitude: 35.0, Longitude: 135.0}, entity.WorkLocationClientSite, false, false},
+		{"outside", "alice", "203.0.114.0", &PunchLocation{Latitude: 35.01, Longitude: 135.0}, entity.WorkLocationRemote, true, true},
+		{"user policy overrides department", "carol", "192.0.2.1", nil, entity.WorkLocationRemote, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := u.Evaluate(ctx, tt.userID, tt.ip, tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d == nil || d.WorkLocation != tt.location || (d.Violation != "") != tt.violation || d.Reject != tt.reject {
+				t.Errorf("Evaluate() = %+v, want %s (violation %v, reject %v)", d, tt.location, tt.violation, tt.reject)
+			}
+		})
+	}
+	// 適用するポリシーが無ければ判定しない
+	if d, err := u.Evaluate(ctx, "bob", "192.0.2.1", nil); err != nil || d != nil {
+		t.Errorf("Evaluate(bob) = %+v, %v, want no decision", d, err)
+	}
+}
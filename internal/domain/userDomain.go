@@ -2,11 +2,48 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
 
 	"github.com/enkazu1116/go_home/internal/entity"
 	"github.com/enkazu1116/go_home/internal/repository"
+	"github.com/enkazu1116/go_home/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrInvalidUser はユーザーの項目が足りない・正しくない場合のエラー
+var ErrInvalidUser = errors.New("invalid user")
+
+// ValidateUser は登録・更新するユーザーの必須項目・メールアドレス・ロールを確認する
+// CreateUser・UpdateUser が保存の前に呼ぶため、HTTP・gRPC・管理用CLI・一括登録のどこから登録しても同じ確認になる
+func ValidateUser(u entity.User) error {
+	switch {
+	case strings.TrimSpace(u.ID) == "":
+		return fmt.Errorf("%w: id is required", ErrInvalidUser)
+	case strings.TrimSpace(u.AuthID) == "":
+		return fmt.Errorf("%w: auth id is required", ErrInvalidUser)
+	case strings.TrimSpace(u.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidUser)
+	case !validEmail(u.Email):
+		return fmt.Errorf("%w: email %q is not a valid address", ErrInvalidUser, u.Email)
+	}
+	switch u.Role {
+	case entity.RoleAdmin, entity.RoleManager, entity.RoleEmployee:
+	default:
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidUser, u.Role)
+	}
+	return nil
+}
+
+// validEmail はメールアドレスとして解釈できるか（表示名の無いアドレスだけを受け付ける）
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
 // Userを使用してお試し
 // ユーザーユースケースのインターフェースを定義
 type UserUsecase interface {
@@ -20,26 +57,60 @@ type UserUsecase interface {
 	// 最初の1件を取得
 	FindFirst(ctx context.Context, id string) (*entity.User, error)
 
+	// 最初の1件を取得（論理削除済みを含む）
+	FindFirstIncludeDeleted(ctx context.Context, id string) (*entity.User, error)
+
 	// 全件取得
 	FindAllUser(ctx context.Context) ([]entity.User, error)
 
+	// 全件取得（論理削除済みを含む）
+	FindAllUserIncludeDeleted(ctx context.Context) ([]entity.User, error)
+
 	// 削除
 	DeleteUser(ctx context.Context, user entity.User) error
+
+	// 論理削除の取り消し
+	RestoreUser(ctx context.Context, id string) error
+
+	// 物理削除
+	PurgeUser(ctx context.Context, id string) error
 }
 
 // ユーザーユースケースの構造体を定義
 type userUsecase struct {
-	repo repository.UserRepository
+	repo   repository.UserRepository
+	audit  repository.AuditRepository
+	outbox repository.OutboxRepository
+	tx     repository.UnitOfWork
 }
 
 // 新規登録呼び出し
-func (u *userUsecase) CreateUser(ctx context.Context, user entity.User) error {
-	return u.repo.CreateUser(ctx, user)
+func (u *userUsecase) CreateUser(ctx context.Context, user entity.User) (err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.CreateUser", attribute.String("user.id", user.ID))
+	defer tracing.End(span, &err)
+	if err := ValidateUser(user); err != nil {
+		return err
+	}
+	return u.tx.Do(ctx, func(ctx context.Context) error {
+		if err := u.repo.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		after, err := u.repo.FindFirst(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, u.audit, entity.AuditEntityUser, user.ID, entity.AuditActionCreate, nil, after); err != nil {
+			return err
+		}
+		return publishEvent(ctx, u.outbox, entity.EventUserCreated, entity.AuditEntityUser, user.ID, after)
+	})
 }
 
 // 削除処理呼び出し
 func (u *userUsecase) DeleteUser(ctx context.Context, user entity.User) error {
-	return u.repo.DeleteUser(ctx, user)
+	return u.remove(ctx, user.ID, entity.AuditActionDelete, entity.EventUserDeleted, func(ctx context.Context) error {
+		return u.repo.DeleteUser(ctx, user)
+	})
 }
 
 // 全件取得呼び出し
@@ -47,16 +118,89 @@ func (u *userUsecase) FindAllUser(ctx context.Context) ([]entity.User, error) {
 	return u.repo.FindAllUser(ctx)
 }
 
+// 全件取得呼び出し（論理削除済みを含む）
+func (u *userUsecase) FindAllUserIncludeDeleted(ctx context.Context) ([]entity.User, error) {
+	return u.repo.FindAllUserIncludeDeleted(ctx)
+}
+
 // 最初の1件取得呼び出し
 func (u *userUsecase) FindFirst(ctx context.Context, id string) (*entity.User, error) {
 	return u.repo.FindFirst(ctx, id)
 }
 
+// 最初の1件取得呼び出し（論理削除済みを含む）
+func (u *userUsecase) FindFirstIncludeDeleted(ctx context.Context, id string) (*entity.User, error) {
+	return u.repo.FindFirstIncludeDeleted(ctx, id)
+}
+
 // 更新処理呼び出し
 func (u *userUsecase) UpdateUser(ctx context.Context, user entity.User) error {
-	return u.repo.UpdateUser(ctx, user)
+	if err := ValidateUser(user); err != nil {
+		return err
+	}
+	return u.mutate(ctx, user.ID, entity.AuditActionUpdate, entity.EventUserUpdated, func(ctx context.Context) error {
+		return u.repo.UpdateUser(ctx, user)
+	})
+}
+
+// 論理削除の取り消し呼び出し
+func (u *userUsecase) RestoreUser(ctx context.Context, id string) error {
+	return u.mutate(ctx, id, entity.AuditActionRestore, entity.EventUserRestored, func(ctx context.Context) error {
+		return u.repo.RestoreUser(ctx, id)
+	})
+}
+
+// 物理削除呼び出し
+func (u *userUsecase) PurgeUser(ctx context.Context, id string) error {
+	return u.remove(ctx, id, entity.AuditActionPurge, entity.EventUserPurged, func(ctx context.Context) error {
+		return u.repo.PurgeUser(ctx, id)
+	})
+}
+
+// 変更前後のユーザーを取得し、変更内容を監査ログとドメインイベントに記録する
+// 変更・監査ログ・イベントは同じトランザクションで保存する
+// スパン名は監査ログの操作（UserUsecase.update など）にする
+func (u *userUsecase) mutate(ctx context.Context, id, action, eventType string, fn func(ctx context.Context) error) (err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase."+action, attribute.String("user.id", id))
+	defer tracing.End(span, &err)
+	return u.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := u.repo.FindFirstIncludeDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		after, err := u.repo.FindFirstIncludeDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, u.audit, entity.AuditEntityUser, id, action, before, after); err != nil {
+			return err
+		}
+		return publishEvent(ctx, u.outbox, eventType, entity.AuditEntityUser, id, after)
+	})
+}
+
+// 削除前のユーザーを取得し、削除を監査ログとドメインイベントに記録する
+func (u *userUsecase) remove(ctx context.Context, id, action, eventType string, fn func(ctx context.Context) error) (err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase."+action, attribute.String("user.id", id))
+	defer tracing.End(span, &err)
+	return u.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := u.repo.FindFirstIncludeDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, u.audit, entity.AuditEntityUser, id, action, before, nil); err != nil {
+			return err
+		}
+		return publishEvent(ctx, u.outbox, eventType, entity.AuditEntityUser, id, before)
+	})
 }
 
-func NewUserUsecase(repo repository.UserRepository) UserUsecase {
-	return &userUsecase{repo: repo}
+func NewUserUsecase(repo repository.UserRepository, audit repository.AuditRepository, outbox repository.OutboxRepository, tx repository.UnitOfWork) UserUsecase {
+	return &userUsecase{repo: repo, audit: audit, outbox: outbox, tx: tx}
 }
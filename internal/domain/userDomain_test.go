@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+)
+
+func TestValidateUser(t *testing.T) {
+	valid := entity.User{ID: "u1", AuthID: "a1", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee}
+	tests := []struct {
+		name   string
+		modify func(u *entity.User)
+		ok     bool
+	}{
+		{"valid", func(u *entity.User) {}, true},
+		{"manager", func(u *entity.User) { u.Role = entity.RoleManager }, true},
+		{"missing id", func(u *entity.User) { u.ID = "" }, false},
+		{"missing auth id", func(u *entity.User) { u.AuthID = " " }, false},
+		{"missing name", func(u *entity.User) { u.Name = "" }, false},
+		{"invalid email", func(u *entity.User) { u.Email = "alice" }, false},
+		{"email with display name", func(u *entity.User) { u.Email = "Alice <alice@example.com>" }, false},
+		{"empty role", func(u *entity.User) { u.Role = "" }, false},
+		{"unknown role", func(u *entity.User) { u.Role = "root" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := valid
+			tt.modify(&u)
+			err := ValidateUser(u)
+			if tt.ok && err != nil {
+				t.Fatalf("ValidateUser() = %v, want nil", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidUser) {
+				t.Fatalf("ValidateUser() = %v, want ErrInvalidUser", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,522 @@
+package domain
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+	"github.com/enkazu1116/go_home/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// CSV・列の対応付け・オプションが正しくない（どの行も取り込まない）
+	ErrInvalidUserImport = errors.New("invalid user import")
+
+	// すべての行を取り込む指定で、失敗した行があったためすべて取り消した
+	ErrUserImportRejected = errors.New("user import rejected: some rows failed")
+)
+
+// 一括登録で既存のユーザーと照合する項目
+const (
+	UserImportMatchEmail  = "email"
+	UserImportMatchAuthID = "auth_id"
+)
+
+// 一括登録の行ごとの結果
+const (
+	UserImportCreated   = "created"   // 新しいユーザーを登録した
+	UserImportUpdated   = "updated"   // 既存のユーザーを更新した
+	UserImportUnchanged = "unchanged" // 既存のユーザーと同じ内容だった
+	UserImportFailed    = "failed"    // 項目が正しくない・他のユーザーと重なるなどで取り込めなかった
+)
+
+// 1回に取り込める行数の上限
+const maxUserImportRows = 5000
+
+// CSVの列に対応付けるユーザーの項目
+var userImportFields = []string{"ID", "AuthID", "Name", "Email", "Role", "Department"}
+
+// 列名から項目を推測するための別名（小文字にして空白・_・- を除いたもの）
+var userImportAliases = map[string]string{
+	"id": "ID", "userid": "ID",
+	"authid": "AuthID", "authuserid": "AuthID",
+	"name": "Name", "fullname": "Name", "氏名": "Name", "名前": "Name",
+	"email": "Email", "mail": "Email", "emailaddress": "Email", "メールアドレス": "Email",
+	"role": "Role", "ロール": "Role", "権限": "Role",
+	"department": "Department", "dept": "Department", "部署": "Department",
+}
+
+// UserImportOptions は一括登録のオプション
+type UserImportOptions struct {
+	// 既存のユーザーと照合する項目（email（既定）・auth_id）。一致したユーザーは更新する
+	MatchBy string
+	// ユーザーの項目名（Email など）から CSV の列名への対応付け
+	// 指定しない項目は列名から推測する
+	Mapping map[string]string
+	// 結果だけを返して、登録・更新は取り消す
+	DryRun bool
+	// 失敗した行が1つでもあれば、すべての行を取り消す
+	AllOrNothing bool
+}
+
+// UserImportRow は1行の取り込みの結果
+type UserImportRow struct {
+	Line   int // CSVの行番号（ヘッダーが1行目）
+	Status string
+	UserID string
+	Email  string
+	Error  string
+	record []string
+}
+
+// UserImportReport は一括登録の結果
+type UserImportReport struct {
+	MatchBy      string
+	DryRun       bool
+	AllOrNothing bool
+	// 登録・更新を保存したか（ドライラン・すべて取り消した場合は false）
+	Committed bool
+	Total     int
+	Created   int
+	Updated   int
+	Unchanged int
+	Failed    int
+	Rows      []UserImportRow
+	header    []string
+}
+
+// WriteErrorCSV は失敗した行だけを元の列のまま書き出し、行番号（line）と理由（error）の列を加える
+// 直してそのまま取り込み直せるように、列の並びはアップロードされた CSV と同じにする
+func (r *UserImportReport) WriteErrorCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"line"}, append(append([]string(nil), r.header...), "error")...)); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		if row.Status != UserImportFailed {
+			continue
+		}
+		record := append([]string{strconv.Itoa(row.Line)}, row.record...)
+		if err := cw.Write(append(record, row.Error)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ParseUserImportMapping は Field=列名 をカンマで区切った列の対応付けを解釈する
+// HTTPの map パラメーター・管理用CLIの -map で使う
+func ParseUserImportMapping(values ...string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, v := range values {
+		for _, pair := range strings.Split(v, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			field, column, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(field) == "" || strings.TrimSpace(column) == "" {
+				return nil, fmt.Errorf("%w: map must be Field=column, got %q", ErrInvalidUserImport, pair)
+			}
+			mapping[strings.TrimSpace(field)] = strings.TrimSpace(column)
+		}
+	}
+	return mapping, nil
+}
+
+// ユーザーの一括登録のユースケース
+type UserImportUsecase interface {
+
+	// CSVからユーザーを登録・更新する
+	Import(ctx context.Context, r io.Reader, opts UserImportOptions) (*UserImportReport, error)
+}
+
+// ユーザーの一括登録のユースケースの構造体
+type userImportUsecase struct {
+	users UserUsecase
+	tx    repository.UnitOfWork
+}
+
+// ドライランでトランザクションを取り消すためのエラー
+var errUserImportDryRun = errors.New("user import dry run")
+
+// 一括登録呼び出し
+// 全体を1つのトランザクションで実行し、失敗した行だけを取り消して次の行に進む
+// 登録・更新は UserUsecase を通すため、1行ごとに監査ログとドメインイベントも記録される
+func (u *userImportUsecase) Import(ctx context.Context, r io.Reader, opts UserImportOptions) (_ *UserImportReport, err error) {
+	if opts.MatchBy == "" {
+		opts.MatchBy = UserImportMatchEmail
+	}
+	ctx, span := tracing.Start(ctx, "UserImportUsecase.Import",
+		attribute.String("import.match_by", opts.MatchBy), attribute.Bool("import.dry_run", opts.DryRun))
+	defer tracing.End(span, &err)
+	if opts.MatchBy != UserImportMatchEmail && opts.MatchBy != UserImportMatchAuthID {
+		return nil, fmt.Errorf("%w: match by must be %s or %s", ErrInvalidUserImport, UserImportMatchEmail, UserImportMatchAuthID)
+	}
+	parsed, err := parseUserCSV(r, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("import.rows", len(parsed.rows)))
+
+	report := &UserImportReport{
+		MatchBy: opts.MatchBy, DryRun: opts.DryRun, AllOrNothing: opts.AllOrNothing,
+		Total: len(parsed.rows), Rows: make([]UserImportRow, 0, len(parsed.rows)), header: parsed.header,
+	}
+	err = u.tx.Do(ctx, func(ctx context.Context) error {
+		active, err := u.users.FindAllUser(ctx)
+		if err != nil {
+			return err
+		}
+		idx := newUserImportIndex(active)
+		seen := map[string]int{}
+		for _, row := range parsed.rows {
+			res := UserImportRow{Line: row.line, record: row.record}
+			if err := u.importRow(ctx, parsed, row, opts.MatchBy, idx, seen, &res); err != nil {
+				if !isUserImportRowError(err) {
+					return err
+				}
+				res.Status, res.Error = UserImportFailed, err.Error()
+			}
+			report.add(res)
+		}
+		if opts.DryRun {
+			return errUserImportDryRun
+		}
+		if opts.AllOrNothing && report.Failed > 0 {
+			return ErrUserImportRejected
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errUserImportDryRun):
+		return report, nil
+	case errors.Is(err, ErrUserImportRejected):
+		return report, err
+	case err != nil:
+		return nil, err
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// importRow は1行を登録・更新する
+func (u *userImportUsecase) importRow(ctx context.Context, parsed *userCSV, row userCSVRow, matchBy string, idx *userImportIndex, seen map[string]int, res *UserImportRow) error {
+	in := parsed.user(row.record)
+	res.Email = in.Email
+	if row.err != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidUser, row.err)
+	}
+
+	key := in.Email
+	if matchBy == UserImportMatchAuthID {
+		key = in.AuthID
+	}
+	key = idx.key(matchBy, key)
+	if key != "" {
+		if line, ok := seen[key]; ok {
+			return fmt.Errorf("%w: same %s as line %d", ErrInvalidUser, matchBy, line)
+		}
+		seen[key] = row.line
+	}
+
+	existing := idx.find(matchBy, key)
+	user := in
+	if existing == nil {
+		if user.ID == "" {
+			user.ID = uuid.NewString()
+		}
+		if user.Role == "" {
+			user.Role = entity.RoleEmployee
+		}
+	} else {
+		res.UserID = existing.ID
+		var err error
+		if user, err = mergeImportedUser(*existing, in, matchBy); err != nil {
+			return err
+		}
+	}
+	// 項目の確認は CreateUser・UpdateUser が行う
+	// 照合に使わない方の項目が他のユーザーのものなら、一意制約の違反になる前に理由を返す
+	if other := idx.find(UserImportMatchEmail, idx.key(UserImportMatchEmail, user.Email)); other != nil && other.ID != user.ID {
+		return fmt.Errorf("%w: email is already used by user %s", repository.ErrConflict, other.ID)
+	}
+	if other := idx.find(UserImportMatchAuthID, user.AuthID); other != nil && other.ID != user.ID {
+		return fmt.Errorf("%w: auth id is already used by user %s", repository.ErrConflict, other.ID)
+	}
+
+	if existing != nil && sameImportedUser(*existing, user) {
+		res.Status = UserImportUnchanged
+		return nil
+	}
+	// UserUsecase は外側のトランザクションの中でセーブポイントを張るため、失敗してもこの行だけが取り消される
+	if existing == nil {
+		if err := u.users.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		res.Status, res.UserID = UserImportCreated, user.ID
+		user.Version = 1
+	} else {
+		if err := u.users.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		res.Status = UserImportUpdated
+		user.Version++
+		idx.remove(*existing)
+	}
+	idx.put(user)
+	return nil
+}
+
+// mergeImportedUser は既存のユーザーに CSV の値を重ねる
+// 空のセルは既存の値のままにする。AuthID は認証基盤と紐づくため変更させない
+func mergeImportedUser(existing, in entity.User, matchBy string) (entity.User, error) {
+	if in.ID != "" && in.ID != existing.ID {
+		return entity.User{}, fmt.Errorf("%w: id %q differs from the existing user %s", ErrInvalidUser, in.ID, existing.ID)
+	}
+	if matchBy == UserImportMatchEmail && in.AuthID != "" && in.AuthID != existing.AuthID {
+		return entity.User{}, fmt.Errorf("%w: auth id differs from the existing user %s", ErrInvalidUser, existing.ID)
+	}
+	merged := existing
+	for _, v := range []struct {
+		dst *string
+		src string
+	}{{&merged.Name, in.Name}, {&merged.Email, in.Email}, {&merged.Role, in.Role}, {&merged.Department, in.Department}} {
+		if v.src != "" {
+			*v.dst = v.src
+		}
+	}
+	return merged, nil
+}
+
+// sameImportedUser は一括登録で変更できる項目が同じか
+func sameImportedUser(a, b entity.User) bool {
+	return a.AuthID == b.AuthID && a.Name == b.Name && a.Email == b.Email && a.Role == b.Role && a.Department == b.Department
+}
+
+// isUserImportRowError はその行だけを失敗にするエラーか（それ以外は取り込み全体を止める）
+func isUserImportRowError(err error) bool {
+	return errors.Is(err, ErrInvalidUser) || errors.Is(err, repository.ErrConflict) ||
+		errors.Is(err, repository.ErrVersionConflict) || errors.Is(err, repository.ErrNotFound)
+}
+
+func (r *UserImportReport) add(row UserImportRow) {
+	switch row.Status {
+	case UserImportCreated:
+		r.Created++
+	case UserImportUpdated:
+		r.Updated++
+	case UserImportUnchanged:
+		r.Unchanged++
+	case UserImportFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// userImportIndex は有効なユーザーをメールアドレス・認証IDで引く索引
+// メールアドレスは大文字・小文字を区別せずに照合する
+type userImportIndex struct {
+	byEmail  map[string]entity.User
+	byAuthID map[string]entity.User
+}
+
+func newUserImportIndex(users []entity.User) *userImportIndex {
+	idx := &userImportIndex{byEmail: map[string]entity.User{}, byAuthID: map[string]entity.User{}}
+	for _, u := range users {
+		idx.put(u)
+	}
+	return idx
+}
+
+func (idx *userImportIndex) key(matchBy, v string) string {
+	if matchBy == UserImportMatchEmail {
+		return strings.ToLower(v)
+	}
+	return v
+}
+
+func (idx *userImportIndex) find(matchBy, key string) *entity.User {
+	if key == "" {
+		return nil
+	}
+	m := idx.byEmail
+	if matchBy == UserImportMatchAuthID {
+		m = idx.byAuthID
+	}
+	if u, ok := m[key]; ok {
+		return &u
+	}
+	return nil
+}
+
+func (idx *userImportIndex) put(u entity.User) {
+	idx.byEmail[strings.ToLower(u.Email)] = u
+	idx.byAuthID[u.AuthID] = u
+}
+
+func (idx *userImportIndex) remove(u entity.User) {
+	delete(idx.byEmail, strings.ToLower(u.Email))
+	delete(idx.byAuthID, u.AuthID)
+}
+
+// userCSV は読み込んだ CSV
+type userCSV struct {
+	header  []string
+	columns map[string]int // ユーザーの項目名から列の位置
+	rows    []userCSVRow
+}
+
+type userCSVRow struct {
+	line   int
+	record []string
+	err    string // 列の数が合わないなど、行そのものが正しくない場合の理由
+}
+
+// user は行の値からユーザーを組み立てる（前後の空白は除き、ロールは小文字にする）
+func (c *userCSV) user(record []string) entity.User {
+	get := func(field string) string {
+		if i, ok := c.columns[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	return entity.User{
+		ID:         get("ID"),
+		AuthID:     get("AuthID"),
+		Name:       get("Name"),
+		Email:      get("Email"),
+		Role:       strings.ToLower(get("Role")),
+		Department: get("Department"),
+	}
+}
+
+// parseUserCSV は1行目をヘッダーとして CSV を読み込み、列をユーザーの項目に対応付ける
+// Excel が付ける UTF-8 の BOM は読み飛ばす。すべて空の行は無視する
+func parseUserCSV(r io.Reader, mapping map[string]string) (*userCSV, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidUserImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUserImport, err)
+	}
+	columns, err := mapUserColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &userCSV{header: header, columns: columns}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUserImport, err)
+		}
+		if blankRecord(record) {
+			continue
+		}
+		if len(parsed.rows) == maxUserImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidUserImport, maxUserImportRows)
+		}
+		line, _ := cr.FieldPos(0)
+		row := userCSVRow{line: line, record: record}
+		if len(record) != len(header) {
+			row.err = fmt.Sprintf("expected %d fields, got %d", len(header), len(record))
+		}
+		parsed.rows = append(parsed.rows, row)
+	}
+	return parsed, nil
+}
+
+// mapUserColumns は列の位置をユーザーの項目に対応付ける
+// mapping で指定した項目はその列名（大文字・小文字は区別しない）、それ以外は別名から推測する
+func mapUserColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	columns := map[string]int{}
+	for field, name := range mapping {
+		if !knownUserImportField(field) {
+			return nil, fmt.Errorf("%w: unknown field %q in mapping (fields: %s)", ErrInvalidUserImport, field, strings.Join(userImportFields, ", "))
+		}
+		i := indexOfColumn(header, name)
+		if i < 0 {
+			return nil, fmt.Errorf("%w: column %q for %s not found", ErrInvalidUserImport, name, field)
+		}
+		columns[field] = i
+	}
+	for i, name := range header {
+		field, ok := userImportAliases[normalizeColumn(name)]
+		if !ok {
+			continue
+		}
+		if _, mapped := mapping[field]; mapped {
+			continue
+		}
+		if _, dup := columns[field]; dup {
+			return nil, fmt.Errorf("%w: more than one column for %s", ErrInvalidUserImport, field)
+		}
+		columns[field] = i
+	}
+	var missing []string
+	for _, field := range []string{"AuthID", "Name", "Email"} {
+		if _, ok := columns[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: missing columns for %s", ErrInvalidUserImport, strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+func knownUserImportField(field string) bool {
+	for _, f := range userImportFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOfColumn(header []string, name string) int {
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+func normalizeColumn(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+func blankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func NewUserImportUsecase(users UserUsecase, tx repository.UnitOfWork) UserImportUsecase {
+	return &userImportUsecase{users: users, tx: tx}
+}
@@ -0,0 +1,209 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+func TestMapUserColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		mapping map[string]string
+		want    map[string]int
+		ok      bool
+	}{
+		{"english aliases", []string{"auth_id", "Full Name", "E-Mail", "dept"}, nil,
+			map[string]int{"AuthID": 0, "Name": 1, "Email": 2, "Department": 3}, true},
+		{"japanese aliases", []string{"ID", "AuthID", "氏名", "メールアドレス", "部署", "ロール"}, nil,
+			map[string]int{"ID": 0, "AuthID": 1, "Name": 2, "Email": 3, "Department": 4, "Role": 5}, true},
+		{"unknown columns ignored", []string{"auth_id", "name", "email", "社員番号"}, nil,
+			map[string]int{"AuthID": 0, "Name": 1, "Email": 2}, true},
+		{"mapping", []string{"login", "name", "連絡先"}, map[string]string{"AuthID": "LOGIN", "Email": "連絡先"},
+			map[string]int{"AuthID": 0, "Name": 1, "Email": 2}, true},
+		{"mapping overrides alias", []string{"auth_id", "name", "email", "private email"}, map[string]string{"Email": "private email"},
+			map[string]int{"AuthID": 0, "Name": 1, "Email": 3}, true},
+		{"missing required column", []string{"auth_id", "name"}, nil, nil, false},
+		{"duplicate column", []string{"auth_id", "name", "email", "mail"}, nil, nil, false},
+		{"unknown field in mapping", []string{"auth_id", "name", "email"}, map[string]string{"Phone": "name"}, nil, false},
+		{"mapped column not found", []string{"auth_id", "name", "email"}, map[string]string{"Email": "mail"}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapUserColumns(tt.header, tt.mapping)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidUserImport) {
+					t.Fatalf("mapUserColumns() = %v, %v, want ErrInvalidUserImport", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mapUserColumns() = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("mapUserColumns() = %v, want %v", got, tt.want)
+			}
+			for field, i := range tt.want {
+				if got[field] != i {
+					t.Fatalf("mapUserColumns() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// newTestUserImport は SQLite の上にユーザーと一括登録のユースケースを作る
+func newTestUserImport(t *testing.T) (*gorm.DB, UserUsecase, UserImportUsecase) {
+	t.Helper()
+	db := openTestDB(t)
+	tx := repository.NewUnitOfWork(db)
+	users := NewUserUsecase(repository.NewTimeIsMoneyRepository(db), repository.NewAuditRepository(db), repository.NewOutboxRepository(db), tx)
+	return db, users, NewUserImportUsecase(users, tx)
+}
+
+// activeUsers は有効なユーザーの ID から名前を引く表を返す
+func activeUsers(t *testing.T, users UserUsecase) map[string]string {
+	t.Helper()
+	list, err := users.FindAllUser(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{}
+	for _, u := range list {
+		names[u.ID] = u.Name
+	}
+	return names
+}
+
+func countRows(t *testing.T, db *gorm.DB, model any) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(model).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestUserImportDryRun(t *testing.T) {
+	db, users, imports := newTestUserImport(t)
+	csv := "auth_id,name,email\na1,Alice,alice@example.com\na2,Bob,bob@example.com\n"
+
+	report, err := imports.Import(context.Background(), strings.NewReader(csv), UserImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Committed || report.Created != 2 || report.Failed != 0 {
+		t.Errorf("report = committed %v, created %d, failed %d, want a 2-row dry run", report.Committed, report.Created, report.Failed)
+	}
+	// ドライランはユーザーも監査ログ・ドメインイベントも残さない
+	if names := activeUsers(t, users); len(names) != 0 {
+		t.Errorf("users after dry run = %v, want none", names)
+	}
+	if n := countRows(t, db, &entity.AuditLog{}); n != 0 {
+		t.Errorf("audit logs after dry run = %d, want 0", n)
+	}
+	if n := countRows(t, db, &entity.OutboxMessage{}); n != 0 {
+		t.Errorf("outbox events after dry run = %d, want 0", n)
+	}
+}
+
+func TestUserImportAllOrNothing(t *testing.T) {
+	_, users, imports := newTestUserImport(t)
+	csv := "auth_id,name,email\na1,Alice,alice@example.com\na2,Bob,not-an-email\n"
+
+	report, err := imports.Import(context.Background(), strings.NewReader(csv), UserImportOptions{AllOrNothing: true})
+	if !errors.Is(err, ErrUserImportRejected) {
+		t.Fatalf("Import() = %v, want ErrUserImportRejected", err)
+	}
+	if report == nil || report.Committed || report.Created != 1 || report.Failed != 1 {
+		t.Fatalf("report = %+v, want 1 created and 1 failed, not committed", report)
+	}
+	if names := activeUsers(t, users); len(names) != 0 {
+		t.Errorf("users after rejected import = %v, want none", names)
+	}
+}
+
+func TestUserImportPartial(t *testing.T) {
+	ctx := context.Background()
+	_, users, imports := newTestUserImport(t)
+	for _, u := range []entity.User{
+		{ID: "bob", AuthID: "a-bob", Name: "Bob", Email: "bob@example.com", Role: entity.RoleEmployee},
+		{ID: "carol", AuthID: "a-carol", Name: "Carol", Email: "carol@example.com", Role: entity.RoleEmployee},
+	} {
+		if err := users.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 退職したユーザーの ID は索引に無いため、登録時に DB の主キーの重複で失敗する
+	if err := users.DeleteUser(ctx, entity.User{ID: "carol"}); err != nil {
+		t.Fatal(err)
+	}
+	csv := strings.Join([]string{
+		"id,auth_id,name,email",
+		",a-alice,Alice,alice@example.com",      // 2: 登録
+		",,Robert,BOB@example.com",              // 3: メールアドレスで照合して更新
+		",a-dave,Dave,dave",                     // 4: メールアドレスが正しくない
+		",a-alice2,Alice 2,alice@example.com",   // 5: 2行目と同じメールアドレス
+		"carol,a-carol2,Carol 2,c2@example.com", // 6: 主キーの重複（セーブポイントまで取り消す）
+		",a-erin,Erin,erin@example.com",         // 7: 失敗した行の後も取り込みを続ける
+		",a-bob,Bob,bob@example.com",            // 8: 3行目と同じメールアドレス
+	}, "\n")
+
+	report, err := imports.Import(ctx, strings.NewReader(csv), UserImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line   int
+		status string
+	}{
+		{2, UserImportCreated}, {3, UserImportUpdated}, {4, UserImportFailed}, {5, UserImportFailed},
+		{6, UserImportFailed}, {7, UserImportCreated}, {8, UserImportFailed},
+	}
+	if len(report.Rows) != len(want) {
+		t.Fatalf("rows = %+v", report.Rows)
+	}
+	for i, w := range want {
+		if got := report.Rows[i]; got.Line != w.line || got.Status != w.status {
+			t.Errorf("row %d = line %d %s (%s), want line %d %s", i, got.Line, got.Status, got.Error, w.line, w.status)
+		}
+	}
+	if !strings.Contains(report.Rows[4].Error, repository.ErrConflict.Error()) {
+		t.Errorf("line 6 error = %q, want a conflict", report.Rows[4].Error)
+	}
+	if !report.Committed || report.Created != 2 || report.Updated != 1 || report.Failed != 4 {
+		t.Errorf("report = committed %v, created %d, updated %d, failed %d", report.Committed, report.Created, report.Updated, report.Failed)
+	}
+
+	names := activeUsers(t, users)
+	if len(names) != 3 || names["bob"] != "Robert" {
+		t.Errorf("users = %v, want bob renamed to Robert plus alice and erin", names)
+	}
+	for _, row := range report.Rows {
+		if row.Status == UserImportCreated && names[row.UserID] == "" {
+			t.Errorf("line %d: created user %s not found", row.Line, row.UserID)
+		}
+	}
+
+	// 失敗した行だけを元の列のまま書き出す
+	var b strings.Builder
+	if err := report.WriteErrorCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 5 || lines[0] != "line,id,auth_id,name,email,error" {
+		t.Fatalf("error csv =\n%s", b.String())
+	}
+	for i, prefix := range []string{"4,,a-dave,Dave,dave,", "5,,a-alice2,Alice 2,alice@example.com,", "6,carol,a-carol2,Carol 2,c2@example.com,", "8,,a-bob,Bob,bob@example.com,"} {
+		if !strings.HasPrefix(lines[i+1], prefix) || strings.HasSuffix(lines[i+1], ",") {
+			t.Errorf("error csv line %d = %q, want %q followed by the reason", i+1, lines[i+1], prefix)
+		}
+	}
+}
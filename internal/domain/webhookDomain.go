@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/netguard"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// 配信の1回の検索で返す最大件数
+const maxWebhookDeliveries = 1000
+
+var ErrInvalidWebhookURL = errors.New("invalid webhook url")
+
+// 購読のURLの名前解決のタイムアウト
+const webhookResolveTimeout = 5 * time.Second
+
+// Webhookユースケースのインターフェースを定義
+type WebhookUsecase interface {
+
+	// 購読の登録（Secret を省略した場合は生成する）
+	CreateSubscription(ctx context.Context, s entity.WebhookSubscription) (*entity.WebhookSubscription, error)
+
+	// 購読の更新
+	UpdateSubscription(ctx context.Context, s entity.WebhookSubscription) error
+
+	// 購読の削除
+	DeleteSubscription(ctx context.Context, id string) error
+
+	// 購読の1件取得
+	FindSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error)
+
+	// 購読の一覧取得
+	ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error)
+
+	// 配信を新しい順に取得（Status に dead を指定するとデッドレター）
+	ListDeliveries(ctx context.Context, filter repository.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error)
+
+	// 配信をやり直す（配信済み・デッドレターのどちらも配信待ちに戻す）
+	Redeliver(ctx context.Context, id string) (*entity.WebhookDelivery, error)
+}
+
+// Webhookユースケースの構造体を定義
+type webhookUsecase struct {
+	repo  repository.WebhookRepository
+	guard *netguard.Guard
+}
+
+// 購読の登録呼び出し
+func (u *webhookUsecase) CreateSubscription(ctx context.Context, s entity.WebhookSubscription) (*entity.WebhookSubscription, error) {
+	if err := u.validateURL(ctx, s.URL); err != nil {
+		return nil, err
+	}
+	s.ID = uuid.NewString()
+	s.Active = true
+	if s.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		s.Secret = secret
+	}
+	if err := u.repo.CreateSubscription(ctx, s); err != nil {
+		return nil, err
+	}
+	return u.repo.FindSubscription(ctx, s.ID)
+}
+
+// 購読の更新呼び出し
+func (u *webhookUsecase) UpdateSubscription(ctx context.Context, s entity.WebhookSubscription) error {
+	if err := u.validateURL(ctx, s.URL); err != nil {
+		return err
+	}
+	return u.repo.UpdateSubscription(ctx, s)
+}
+
+// 購読の削除呼び出し
+func (u *webhookUsecase) DeleteSubscription(ctx context.Context, id string) error {
+	return u.repo.DeleteSubscription(ctx, id)
+}
+
+// 購読の1件取得呼び出し
+func (u *webhookUsecase) FindSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	return u.repo.FindSubscription(ctx, id)
+}
+
+// 購読の一覧取得呼び出し
+func (u *webhookUsecase) ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	return u.repo.ListSubscriptions(ctx)
+}
+
+// 配信の検索呼び出し
+func (u *webhookUsecase) ListDeliveries(ctx context.Context, filter repository.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error) {
+	if filter.Limit <= 0 || filter.Limit > maxWebhookDeliveries {
+		filter.Limit = maxWebhookDeliveries
+	}
+	return u.repo.ListDeliveries(ctx, filter)
+}
+
+// 再配信呼び出し
+func (u *webhookUsecase) Redeliver(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	if err := u.repo.ResetDelivery(ctx, id, time.Now()); err != nil {
+		return nil, err
+	}
+	return u.repo.FindDelivery(ctx, id)
+}
+
+// 送信先は http・https の絶対URLのみ
+// 内部のネットワーク（ループバック・リンクローカル・プライベートアドレス）に名前解決されるホストも拒否する
+// 登録後に名前解決の結果が変わる場合に備えて、配信時にも接続先のアドレスを確かめる
+func (u *webhookUsecase) validateURL(ctx context.Context, raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("%w: must be an absolute http or https url", ErrInvalidWebhookURL)
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookResolveTimeout)
+	defer cancel()
+	if err := u.guard.CheckHost(ctx, parsed.Hostname()); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhookURL, err)
+	}
+	return nil
+}
+
+// 署名の鍵を生成する
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func NewWebhookUsecase(repo repository.WebhookRepository, guard *netguard.Guard) WebhookUsecase {
+	return &webhookUsecase{repo: repo, guard: guard}
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/enkazu1116/go_home/internal/netguard"
+)
+
+func TestWebhookValidateURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		ok           bool
+		allowPrivate bool
+	}{
+		{url: "https://93.184.216.34/hooks", ok: true},
+		{url: "http://93.184.216.34:8080/hooks", ok: true},
+		{url: "ftp://93.184.216.34/hooks"},
+		{url: "/hooks"},
+		{url: "https://"},
+		{url: "http://169.254.169.254/latest/meta-data/"},
+		{url: "http://127.0.0.1:8080/hooks"},
+		{url: "http://[::1]/hooks"},
+		{url: "http://[::ffff:10.0.0.1]/hooks"},
+		{url: "http://10.0.0.1/hooks"},
+		{url: "http://192.168.0.10/hooks"},
+		{url: "http://0.0.0.0/hooks"},
+		// ローカルの検証用の受信先は設定で許せる
+		{url: "http://127.0.0.1:8080/hooks", ok: true, allowPrivate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u := &webhookUsecase{guard: netguard.NewGuard(tt.allowPrivate)}
+			err := u.validateURL(context.Background(), tt.url)
+			if tt.ok && err != nil {
+				t.Fatalf("validateURL() = %v, want nil", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidWebhookURL) {
+				t.Fatalf("validateURL() = %v, want ErrInvalidWebhookURL", err)
+			}
+		})
+	}
+}
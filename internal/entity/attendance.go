@@ -5,13 +5,19 @@ import (
 )
 
 // 勤怠エンティティ
+// 打刻イベント（PunchEvent）から組み立てる投影のため、直接更新せずイベントを追記して再構築する
 type Attendance struct {
-	ID        string    `gorm:"primaryKey"`
-	UserID    string    `gorm:"primaryKey"`
-	Date      time.Time `gorm:"primaryKey"`
-	CheckIn   time.Time
-	CheckOut  time.Time
-	IsLate    bool
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID           string    `gorm:"primaryKey"`
+	UserID       string    `gorm:"primaryKey;uniqueIndex:idx_attendances_user_date"`
+	Date         time.Time `gorm:"primaryKey;uniqueIndex:idx_attendances_user_date"` // 勤務日（日本時間の0時）
+	CheckIn      time.Time
+	CheckOut     time.Time
+	IsLate       bool
+	BreakMinutes int       // 休憩時間（分）
+	Incomplete   bool      `gorm:"not null;default:false"` // 退勤の打刻漏れ（修正が必要）
+	WorkLocation string    // 勤務場所（出勤の打刻で判定した office・remote・client_site）
+	NeedsReview  bool      `gorm:"not null;default:false"` // 打刻ポリシーに違反した打刻があり、マネージャーの確認待ち
+	Version      int64     `gorm:"not null;default:1"`     // 楽観的排他制御用のバージョン
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
 }
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+)
+
+// 監査ログの対象
+const (
+	AuditEntityUser       = "user"
+	AuditEntityAttendance = "attendance"
+)
+
+// 監査ログの操作
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+)
+
+// 監査ログエンティティ
+// 勤怠記録は法令上の保存期間（3〜5年）があるため、追記のみで更新・削除はしない
+// Before・After は変更前後のエンティティをJSONで保存する
+type AuditLog struct {
+	ID         string `gorm:"primaryKey"`
+	EntityType string `gorm:"not null;index:idx_audit_logs_entity"`
+	EntityID   string `gorm:"not null;index:idx_audit_logs_entity"`
+	Action     string `gorm:"not null"`
+	ActorID    string `gorm:"index"` // 操作したユーザー（未認証・システムの場合は空）
+	Reason     string
+	RequestID  string
+	Source     string
+	Before     string
+	After      string
+	CreatedAt  time.Time `gorm:"autoCreateTime;index"`
+}
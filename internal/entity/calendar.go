@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+)
+
+// 休暇の種類
+const (
+	LeavePaid    = "paid"    // 有給休暇
+	LeaveSick    = "sick"    // 病気休暇
+	LeaveSpecial = "special" // 特別休暇（慶弔など）
+	LeaveUnpaid  = "unpaid"  // 無給休暇
+)
+
+// 会社の休日エンティティ
+// 休日は勤務の予定を作らず、カレンダーには終日の予定として載せる
+type Holiday struct {
+	Date      string    `gorm:"primaryKey"` // "2006-01-02"
+	Name      string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// 休暇エンティティ
+// 承認済みの休暇を管理者・マネージャーが登録する（申請・承認の手続きはこのサービスの外で行う）
+type Leave struct {
+	ID         string `gorm:"primaryKey"`
+	UserID     string `gorm:"not null;index"`
+	StartDate  string `gorm:"not null;index"` // 初日（"2006-01-02"）
+	EndDate    string `gorm:"not null;index"` // 最終日（その日を含む）
+	Kind       string `gorm:"not null"`
+	Note       string
+	ApprovedBy string    // 登録した管理者・マネージャーのユーザーID
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// カレンダーのフィードのトークンエンティティ
+// トークンを知っていれば認証なしでフィードを読めるため、ユーザーごとに1つだけ持ち、発行し直すと前のトークンは使えなくなる
+type CalendarToken struct {
+	UserID string `gorm:"primaryKey"`
+	// トークンの SHA-256（16進）。トークンそのものは発行時に一度だけ返す
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
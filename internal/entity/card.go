@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"time"
+)
+
+// ICカードリーダーエンティティ
+// FeliCa などのICカード・NFCの社員証を読み取り、カードのIDをHTTPで送ってくる打刻端末
+type CardReader struct {
+	ID       string `gorm:"primaryKey"`
+	Name     string `gorm:"not null"`
+	Location string
+	// 端末のAPIキーの SHA-256（16進）。キーそのものは登録時に一度だけ返す
+	KeyHash    string     `gorm:"not null"`
+	Active     bool       `gorm:"not null;default:true"`
+	LastSeenAt *time.Time // 端末が最後に打刻を送った時刻
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime"`
+}
+
+// ICカードエンティティ
+// カードのID（FeliCa の IDm など）をユーザーに紐づける
+type Card struct {
+	CardID    string    `gorm:"primaryKey"` // 英大文字・数字に正規化したID
+	UserID    string    `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+)
+
+// チャットの種類
+const (
+	ChatSlack      = "slack"
+	ChatMattermost = "mattermost"
+)
+
+// チャットアカウントエンティティ
+// チャットのユーザーIDを勤怠システムのユーザーに紐づける
+type ChatIdentity struct {
+	Provider   string    `gorm:"primaryKey"`
+	ChatUserID string    `gorm:"primaryKey"`
+	UserID     string    `gorm:"not null;index"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
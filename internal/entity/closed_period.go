@@ -0,0 +1,13 @@
+package entity
+
+import (
+	"time"
+)
+
+// 締め済みの期間エンティティ
+// 給与計算などのために締めた月（日本時間）の勤怠は、打刻の修正・オフライン打刻の取り込み・再構築で変えない
+type ClosedPeriod struct {
+	Month     string    `gorm:"primaryKey"` // "2006-01"
+	ClosedBy  string    // 締めたユーザーのID
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"time"
+)
+
+// 冪等キーエンティティ
+// Idempotency-Key ヘッダーごとに、リクエストのハッシュと最初のレスポンスを保存する
+// StatusCode が 0 の行は処理中を表す
+type IdempotencyKey struct {
+	Key         string `gorm:"primaryKey"`
+	Scope       string `gorm:"primaryKey"` // 呼び出し元ユーザー・メソッド・パスの組み合わせ
+	RequestHash string `gorm:"not null"`
+	StatusCode  int    `gorm:"not null;default:0"`
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	ExpiresAt   time.Time `gorm:"index"`
+}
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+)
+
+// ジョブのロックエンティティ
+// 複数のプロセスで同じジョブを動かしても、予定の時刻ごとに1つのプロセスだけが実行するために使う
+type JobLock struct {
+	Name        string    `gorm:"primaryKey"`
+	Owner       string    // 実行中（または最後に実行した）プロセス
+	LastRunAt   time.Time // 最後に実行した予定の時刻
+	LockedUntil time.Time // 実行中のロックの期限（プロセスが落ちても期限を過ぎれば他のプロセスが取れる）
+	LastError   string
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
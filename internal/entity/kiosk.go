@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+)
+
+// 打刻端末（キオスク）エンティティ
+// 事業所の入口に置いた画面に、短時間で切り替わる署名付きのトークンをQRコードで表示する
+// 従業員はモバイルアプリでQRコードを読み取って打刻し、その場にいたことを示す
+type Kiosk struct {
+	ID         string `gorm:"primaryKey"`
+	Name       string `gorm:"not null"`
+	Location   string
+	// 端末の鍵の SHA-256（16進）。鍵そのものは登録時に一度だけ返す
+	// 端末からのトークン取得の認証と、トークンの署名に使う
+	KeyHash    string     `gorm:"not null;default:''"`
+	Active     bool       `gorm:"not null;default:true"`
+	LastSeenAt *time.Time // 端末が最後にトークンを取得した時刻
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime"`
+}
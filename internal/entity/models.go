@@ -0,0 +1,7 @@
+package entity
+
+// Models はマイグレーションするエンティティの一覧
+// 起動時の AutoMigrate と、ヘルスチェックの未適用のマイグレーションの確認で使う
+func Models() []any {
+	return []any{&User{}, &Attendance{}, &IdempotencyKey{}, &AuditLog{}, &PunchEvent{}, &PunchCheckpoint{}, &OutboxMessage{}, &WebhookSubscription{}, &WebhookDelivery{}, &ChatIdentity{}, &NotificationPreference{}, &EmailMessage{}, &JobLock{}, &Kiosk{}, &CardReader{}, &Card{}, &ClosedPeriod{}, &PunchPolicy{}, &UserStatus{}, &Holiday{}, &Leave{}, &CalendarToken{}}
+}
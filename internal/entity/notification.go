@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"time"
+)
+
+// 通知の言語
+const (
+	LocaleJa = "ja"
+	LocaleEn = "en"
+)
+
+// ユーザーごとの通知設定エンティティ
+// 設定していないユーザーは DefaultNotificationPreference の値（日本語・メール・チャットとも受け取る）を使う
+// bool の既定値を gorm の default タグにすると false を保存できないため、既定値はコードで持つ
+type NotificationPreference struct {
+	UserID    string    `gorm:"primaryKey"`
+	Locale    string    `gorm:"not null"`
+	Email     bool      `gorm:"not null"` // メールで受け取るか
+	Chat      bool      `gorm:"not null"` // チャットで受け取るか
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// DefaultNotificationPreference は通知設定の既定値を返す
+func DefaultNotificationPreference(userID string) NotificationPreference {
+	return NotificationPreference{UserID: userID, Locale: LocaleJa, Email: true, Chat: true}
+}
+
+// メールの送信状態（アウトボックスと同じ）
+const (
+	EmailPending = OutboxPending
+	EmailSent    = OutboxDelivered
+	EmailDead    = OutboxDead
+)
+
+// 送信待ちのメールエンティティ
+// 通知はテンプレートから組み立てた本文をこのテーブルに積み、送信は Mailer が再試行しながら行う
+type EmailMessage struct {
+	ID            string    `gorm:"primaryKey"`
+	UserID        string    `gorm:"index"`
+	To            string    `gorm:"not null"`
+	Subject       string    `gorm:"not null"`
+	Text          string    `gorm:"not null"` // text/plain の本文
+	HTML          string    // text/html の本文（空の場合は text/plain だけ送る）
+	Status        string    `gorm:"not null;default:pending;index:idx_email_messages_due"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index:idx_email_messages_due"`
+	LastError     string
+	SentAt        time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"time"
+)
+
+// ドメインイベントの種類
+const (
+	EventUserCreated          = "user.created"
+	EventUserUpdated          = "user.updated"
+	EventUserDeleted          = "user.deleted"
+	EventUserRestored         = "user.restored"
+	EventUserPurged           = "user.purged"
+	EventCheckedIn            = "attendance.checked_in"
+	EventCheckedOut           = "attendance.checked_out"
+	EventBreakStarted         = "attendance.break_started"
+	EventBreakEnded           = "attendance.break_ended"
+	EventAttendanceCorrected  = "attendance.corrected"
+	EventAttendanceIncomplete = "attendance.incomplete" // 退勤の打刻漏れ
+	EventAttendanceReviewed   = "attendance.reviewed"   // 打刻ポリシー違反の確認
+)
+
+// アウトボックスの配信状態
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead" // 再試行の上限に達した
+)
+
+// アウトボックスエンティティ
+// ドメインイベントを変更と同じトランザクションで保存しておき、コミット後にリレーが配信先へ送る
+// 配信は少なくとも1回（at-least-once）のため、配信先は ID で重複を除くこと
+type OutboxMessage struct {
+	ID            string    `gorm:"primaryKey"`
+	EventType     string    `gorm:"not null"`
+	AggregateType string    `gorm:"not null"` // 監査ログの対象と同じ（user・attendance）
+	AggregateID   string    `gorm:"not null"`
+	Payload       string    // イベント発生後のエンティティ（JSON）
+	OccurredAt    time.Time `gorm:"not null"`
+	Status        string    `gorm:"not null;default:pending;index:idx_outbox_messages_due"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index:idx_outbox_messages_due"`
+	LastError     string
+	DeliveredAt   time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"time"
+)
+
+// 打刻イベントの種類
+const (
+	PunchCheckIn    = "check_in"
+	PunchCheckOut   = "check_out"
+	PunchBreakStart = "break_start"
+	PunchBreakEnd   = "break_end"
+	PunchCorrection = "correction" // TargetID のイベントの時刻を OccurredAt に訂正する
+	PunchVoid       = "void"       // TargetID のイベントを取り消す
+	// TargetID の出勤に対する退勤が無いまま締めの時刻を過ぎたことを記録する（勤怠は要修正になる）
+	PunchMissingCheckOut = "missing_check_out"
+	// AttendanceID の勤怠の、これまでの打刻ポリシー違反をマネージャーが確認したことを記録する
+	PunchReview = "review"
+)
+
+// 打刻イベントエンティティ
+// 打刻の生データを追記のみで保存する（更新・削除はしない）
+// ユーザーごとに Seq を1から連番で振り、各イベントは直前のイベントのハッシュを含めてハッシュ化する（ハッシュチェーン）
+// 後から行を書き換えたり消したりすると、以降のハッシュが合わなくなるため改ざんを検知できる
+// 勤怠（Attendance）はこのイベントから組み立てる投影で、訂正・取消もイベントとして追記する
+type PunchEvent struct {
+	ID           string    `gorm:"primaryKey"`
+	UserID       string    `gorm:"not null;uniqueIndex:idx_punch_events_user_seq;index:idx_punch_events_user_occurred_at"`
+	Seq          int64     `gorm:"not null;uniqueIndex:idx_punch_events_user_seq"`
+	Type         string    `gorm:"not null"`
+	OccurredAt   time.Time `gorm:"not null;index:idx_punch_events_user_occurred_at"`
+	Source       string
+	AttendanceID string `gorm:"index"`
+	TargetID     string `gorm:"index"` // 訂正・取消の対象イベントのID
+	Reason       string
+	DeviceID     string // 打刻に使った端末（キオスクなど）のID
+	ClientID     string `gorm:"index"` // オフラインで打刻したクライアントが付けたID（取り込みの重複を除く）
+	WorkLocation string // 打刻ポリシーで判定した勤務場所（office・remote・client_site）
+	Violation    string // 打刻ポリシーに違反した理由（マネージャーの確認待ち）
+	PrevHash     string
+	Hash         string    `gorm:"not null"`
+	HashVersion  int       `gorm:"not null;default:1"` // Hash の計算方法（導入前に記録したイベントは 1）
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// 打刻チェックポイントエンティティ
+// 定期的に全ユーザーのチェーンの先頭（最新のSeqとHash）をまとめたダイジェストを作り、署名して保存する
+// チェーンを丸ごと作り直されても、過去のチェックポイントと突き合わせれば検知できる
+type PunchCheckpoint struct {
+	ID        string    `gorm:"primaryKey"`
+	Heads     string    `gorm:"not null"` // ユーザーごとの先頭（JSON）
+	Digest    string    `gorm:"not null"` // Heads の SHA-256
+	Signature string    // Digest の Ed25519 署名（base64）
+	PublicKey string    // 署名に使った公開鍵（base64）
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
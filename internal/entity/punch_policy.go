@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"time"
+)
+
+// 勤務場所
+const (
+	WorkLocationOffice     = "office"
+	WorkLocationRemote     = "remote"
+	WorkLocationClientSite = "client_site"
+)
+
+// ポリシー違反の打刻の扱い
+const (
+	PolicyReject = "reject" // 打刻を受け付けない
+	PolicyFlag   = "flag"   // 打刻は受け付け、勤怠をマネージャーの確認待ちにする
+)
+
+// 打刻ポリシーエンティティ
+// 打刻した場所を、事務所のネットワーク（CIDR）・モバイルアプリが送る位置情報（ジオフェンス）から判定する
+// 対象は UserID（そのユーザー）・Department（その部署）の順に探し、どちらも空のポリシーを全員の既定とする
+type PunchPolicy struct {
+	ID         string `gorm:"primaryKey"`
+	Name       string `gorm:"not null"`
+	UserID     string `gorm:"index"`
+	Department string `gorm:"index"`
+	// 事務所のネットワーク（例: "203.0.113.0/24"）。ここからの打刻は事務所での打刻とする
+	OfficeCIDRs []string `gorm:"serializer:json"`
+	// 事務所・客先の範囲。位置情報が範囲内の打刻はその場所での打刻とする
+	Geofences []Geofence `gorm:"serializer:json"`
+	// 在宅勤務（どの範囲にも当てはまらない打刻）を認めるかどうか
+	AllowRemote bool
+	// 違反した打刻の扱い（reject・flag）
+	OnViolation string    `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// ジオフェンス（中心の緯度経度と半径）
+type Geofence struct {
+	Name         string
+	Latitude     float64
+	Longitude    float64
+	RadiusMeters float64
+	// 範囲内の打刻の勤務場所（office・client_site）
+	Location string
+}
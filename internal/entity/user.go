@@ -1,19 +1,41 @@
 package entity
 
 import (
+	"log/slog"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// ロール
+const (
+	RoleAdmin    = "admin"
+	RoleManager  = "manager"
+	RoleEmployee = "employee"
+)
+
 // Userエンティティ
+// AuthID・Emailは論理削除されていない行の中でのみ一意とする（部分ユニークインデックス）
+// Postgres・SQLiteともに WHERE 句付きインデックスをサポートしているため、同じタグで両方に対応できる
+// これにより、論理削除済みユーザーと同じメールアドレスで再入社したユーザーを登録できる
 type User struct {
-	ID        string         `gorm:"primaryKey"`
-	AuthID    string         `gorm:"unique;not null"`
-	Name      string         `gorm:"not null"`
-	Email     string         `gorm:"unique;not null"`
-	Role      string         `gorm:"not null"`
-	DeletedAt gorm.DeletedAt `gorm:"index"`
-	CreatedAt time.Time      `gorm:"autoCreateTime"`
-	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+	ID         string         `gorm:"primaryKey"`
+	AuthID     string         `gorm:"not null;uniqueIndex:idx_users_auth_id,where:deleted_at IS NULL"`
+	Name       string         `gorm:"not null"`
+	Email      string         `gorm:"not null;uniqueIndex:idx_users_email,where:deleted_at IS NULL"`
+	Role       string         `gorm:"not null"`
+	Department string         `gorm:"index"`              // 所属部署（打刻ポリシーの対象の判定に使う）
+	Version    int64          `gorm:"not null;default:1"` // 楽観的排他制御用のバージョン
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime"`
+}
+
+// LogValue はログに出すユーザーの項目を ID・ロール・部署に絞る（名前・メールアドレス・認証IDは出さない）
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", u.ID),
+		slog.String("role", u.Role),
+		slog.String("department", u.Department),
+	)
 }
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+)
+
+// 在席ステータス
+const (
+	StatusAvailable = "available"  // 在席
+	StatusInMeeting = "in_meeting" // 会議中
+	StatusLunch     = "lunch"      // 昼休み
+	StatusRemote    = "remote"     // 在宅勤務
+)
+
+// 在席ステータスエンティティ
+// 打刻とは別に、ユーザーが自分で設定してチームに知らせる状態。ExpiresAt を過ぎたものは無いものとして扱う
+type UserStatus struct {
+	UserID string `gorm:"primaryKey"`
+	// 設定した時点のユーザーの部署（同じ部署の人に配る）
+	Department string    `gorm:"index"`
+	Status     string    `gorm:"not null"`
+	Message    string    // 任意のひとこと（例: "15時まで"）
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"time"
+)
+
+// Webhook の配信状態（アウトボックスと同じ）
+const (
+	WebhookPending   = OutboxPending
+	WebhookDelivered = OutboxDelivered
+	WebhookDead      = OutboxDead
+)
+
+// Webhook の購読エンティティ
+// Events はカンマ区切りのイベントの種類（"attendance.*" のような前方一致、空や "*" はすべて）
+type WebhookSubscription struct {
+	ID        string `gorm:"primaryKey"`
+	URL       string `gorm:"not null"`
+	Events    string
+	Secret    string    `gorm:"not null"` // 署名（HMAC-SHA256）の鍵
+	Active    bool      `gorm:"not null;default:true"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// Webhook の配信エンティティ
+// アウトボックスのイベント1件を購読1件に送るごとに1行作り、購読ごとに再試行する
+// 再試行の上限に達した行（Status が dead）はデッドレターとして残し、手動で再配信できる
+type WebhookDelivery struct {
+	ID             string    `gorm:"primaryKey"`
+	SubscriptionID string    `gorm:"not null;uniqueIndex:idx_webhook_deliveries_subscription_event"`
+	EventID        string    `gorm:"not null;uniqueIndex:idx_webhook_deliveries_subscription_event"` // アウトボックスのイベントID
+	EventType      string    `gorm:"not null"`
+	Payload        string    `gorm:"not null"` // 送信するボディ（JSON）
+	Status         string    `gorm:"not null;default:pending;index:idx_webhook_deliveries_due"`
+	Attempts       int       `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time `gorm:"index:idx_webhook_deliveries_due"`
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AttendanceGRPCServerは勤怠用のgRPCハンドラー
+type AttendanceGRPCServer struct {
+	pb.UnimplementedAttendanceServiceServer
+	Usecase domain.AttendanceUsecase
+}
+
+// NewAttendanceGRPCServerはAttendanceGRPCServerを生成
+func NewAttendanceGRPCServer(u domain.AttendanceUsecase) *AttendanceGRPCServer {
+	return &AttendanceGRPCServer{Usecase: u}
+}
+
+// SyncPunches: rpc SyncPunches
+// ログインユーザーがオフラインで記録した打刻を取り込む
+func (s *AttendanceGRPCServer) SyncPunches(ctx context.Context, req *pb.SyncPunchesRequest) (*pb.SyncPunchesResponse, error) {
+	user, ok := auth.UserFrom(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if len(req.GetPunches()) > maxSyncPunches {
+		return nil, status.Errorf(codes.InvalidArgument, "too many punches (max %d)", maxSyncPunches)
+	}
+	punches := make([]domain.OfflinePunch, 0, len(req.GetPunches()))
+	for _, p := range req.GetPunches() {
+		var at time.Time
+		if p.GetOccurredAt() != nil {
+			at = p.GetOccurredAt().AsTime()
+		}
+		punches = append(punches, domain.OfflinePunch{ClientID: p.GetClientId(), Type: p.GetType(), OccurredAt: at})
+	}
+	results, err := s.Usecase.Sync(ctx, user.ID, punches, time.Now())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	res := &pb.SyncPunchesResponse{Results: make([]*pb.SyncResult, 0, len(results))}
+	for _, r := range results {
+		res.Results = append(res.Results, &pb.SyncResult{
+			ClientId:     r.ClientID,
+			Status:       r.Status,
+			EventId:      r.EventID,
+			AttendanceId: r.AttendanceID,
+			Message:      r.Message,
+		})
+	}
+	return res, nil
+}
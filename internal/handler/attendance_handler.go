@@ -0,0 +1,436 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/audit"
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AttendanceHandlerは勤怠用のHTTPハンドラー
+type AttendanceHandler struct {
+	Usecase domain.AttendanceUsecase
+	Kiosks  domain.KioskUsecase
+	// マネージャーが見られる勤怠（自分の部署のユーザー）の判定に使う
+	Users domain.UserUsecase
+}
+
+// NewAttendanceHandlerはAttendanceHandlerを生成
+func NewAttendanceHandler(u domain.AttendanceUsecase, kiosks domain.KioskUsecase, users domain.UserUsecase) *AttendanceHandler {
+	return &AttendanceHandler{Usecase: u, Kiosks: kiosks, Users: users}
+}
+
+// ルーティング設定
+func (h *AttendanceHandler) RegisterRoutes(r chi.Router) {
+	// 勤怠の参照は、管理者は全員、マネージャーは自分の部署、それ以外は本人の分だけ
+	r.With(auth.RequireUser).Get("/attendances", h.ListAttendances)
+	r.With(auth.RequireUser).Get("/attendances/{id}", h.GetAttendance)
+
+	// 打刻はログインユーザー本人のみ
+	r.With(auth.RequireUser).Post("/attendances/check-in", h.CheckIn)
+	r.With(auth.RequireUser).Post("/attendances/check-out", h.CheckOut)
+	r.With(auth.RequireUser).Post("/attendances/break-start", h.BreakStart)
+	r.With(auth.RequireUser).Post("/attendances/break-end", h.BreakEnd)
+	r.With(auth.RequireUser).Post("/attendances/sync", h.Sync)
+
+	// 打刻の修正は管理者・マネージャーのみ
+	r.With(auth.RequireRole(entity.RoleAdmin, entity.RoleManager)).Patch("/attendances/{id}", h.PatchAttendance)
+
+	// 打刻ポリシー違反の確認は管理者・マネージャーのみ
+	r.With(auth.RequireRole(entity.RoleAdmin, entity.RoleManager)).Post("/attendances/{id}/review", h.Review)
+
+	// 勤怠の再構築は管理者のみ
+	r.With(auth.RequireRole(entity.RoleAdmin)).Post("/attendances/rebuild", h.Rebuild)
+
+	// 月の締めは管理者のみ（一覧は管理者・マネージャー）
+	r.With(auth.RequireRole(entity.RoleAdmin, entity.RoleManager)).Get("/attendances/closed-periods", h.ListClosedPeriods)
+	r.With(auth.RequireRole(entity.RoleAdmin)).Put("/attendances/closed-periods/{month}", h.ClosePeriod)
+	r.With(auth.RequireRole(entity.RoleAdmin)).Delete("/attendances/closed-periods/{month}", h.ReopenPeriod)
+}
+
+// canViewAttendance はログインユーザーがそのユーザーの勤怠を見られるかを返す
+// 管理者は全員、マネージャーは自分の部署のユーザー（退職者を含む）、それ以外は本人だけ
+func (h *AttendanceHandler) canViewAttendance(ctx context.Context, userID string) (bool, error) {
+	user, _ := auth.UserFrom(ctx)
+	if user.Role == entity.RoleAdmin || user.ID == userID {
+		return true, nil
+	}
+	if user.Role != entity.RoleManager || user.Department == "" {
+		return false, nil
+	}
+	owner, err := h.Users.FindFirstIncludeDeleted(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return owner.Department == user.Department, nil
+}
+
+// visibleAttendances はログインユーザーが見られる勤怠の一覧を返す
+func (h *AttendanceHandler) visibleAttendances(ctx context.Context) ([]entity.Attendance, error) {
+	user, _ := auth.UserFrom(ctx)
+	switch {
+	case user.Role == entity.RoleAdmin:
+		return h.Usecase.FindAll(ctx)
+	case user.Role != entity.RoleManager || user.Department == "":
+		return h.Usecase.FindByUserID(ctx, user.ID)
+	}
+	users, err := h.Users.FindAllUserIncludeDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members := map[string]bool{user.ID: true}
+	for _, u := range users {
+		if u.Department == user.Department {
+			members[u.ID] = true
+		}
+	}
+	all, err := h.Usecase.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := []entity.Attendance{}
+	for _, a := range all {
+		if members[a.UserID] {
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+
+// ListAttendances: GET /attendances?user_id=xxx&needs_review=true
+// needs_review=true で打刻ポリシー違反の確認待ちの勤怠だけに絞り込む
+// user_id を省略した場合は見られる勤怠をすべて返し、見られないユーザーを指定した場合は 403
+func (h *AttendanceHandler) ListAttendances(w http.ResponseWriter, r *http.Request) {
+	var list []entity.Attendance
+	var err error
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		var ok bool
+		ok, err = h.canViewAttendance(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		list, err = h.Usecase.FindByUserID(r.Context(), userID)
+	} else {
+		list, err = h.visibleAttendances(r.Context())
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("needs_review") == "true" {
+		filtered := []entity.Attendance{}
+		for _, a := range list {
+			if a.NeedsReview {
+				filtered = append(filtered, a)
+			}
+		}
+		list = filtered
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetAttendance: GET /attendances/{id}
+func (h *AttendanceHandler) GetAttendance(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	a, err := h.Usecase.FindByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	ok, err := h.canViewAttendance(r.Context(), a.UserID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	etag := formatETag(a.Version)
+	w.Header().Set("ETag", etag)
+	if matchesIfNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
+// PATCHで変更できる勤怠のフィールド
+// 退勤の打刻漏れを取り消せるよう、CheckOut は null（ゼロ値）を許可する
+// IsLate・BreakMinutes は打刻イベントから計算するため変更できない
+var attendancePatchAllowlist = patchAllowlist{
+	"CheckIn":  {},
+	"CheckOut": {nullable: true},
+}
+
+// PatchAttendance: PATCH /attendances/{id}
+// ボディは JSON Merge Patch (RFC 7396)。更新対象のバージョンは If-Match ヘッダーで必ず指定する
+func (h *AttendanceHandler) PatchAttendance(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !isMergePatch(r) {
+		http.Error(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+		return
+	}
+	version, ifMatch, err := versionFromIfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ifMatch {
+		http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.Usecase.FindByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	var patched entity.Attendance
+	if err := applyMergePatch(current, patch, attendancePatchAllowlist, &patched); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	patched.ID = id
+	patched.Version = version
+
+	if err := h.Usecase.Update(r.Context(), patched); err != nil {
+		status := statusFromError(err, http.StatusInternalServerError)
+		if errors.Is(err, repository.ErrVersionConflict) {
+			status = http.StatusPreconditionFailed
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	updated, err := h.Usecase.FindByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", formatETag(updated.Version))
+	json.NewEncoder(w).Encode(updated)
+}
+
+// Review: POST /attendances/{id}/review
+// 打刻ポリシー違反の勤怠を確認済みにする
+func (h *AttendanceHandler) Review(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Usecase.Review(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("ETag", formatETag(a.Version))
+	json.NewEncoder(w).Encode(a)
+}
+
+// 打刻のリクエストボディ（省略可）
+type punchRequest struct {
+	// 打刻端末のQRコードから読み取ったトークン
+	KioskToken string
+	// モバイルアプリが送る位置情報（打刻ポリシーのジオフェンスの判定に使う）
+	Location *domain.PunchLocation
+}
+
+// punchContext はボディの内容を打刻のコンテキストに入れる
+// 打刻端末のトークンがあれば検証し、打刻の経路と端末を監査情報に入れる
+func (h *AttendanceHandler) punchContext(r *http.Request, now time.Time) (context.Context, error) {
+	var req punchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	ctx := r.Context()
+	if req.Location != nil {
+		ctx = domain.WithPunchLocation(ctx, req.Location)
+	}
+	if req.KioskToken == "" {
+		return ctx, nil
+	}
+	k, err := h.Kiosks.VerifyToken(ctx, req.KioskToken, now)
+	if err != nil {
+		return nil, err
+	}
+	meta := audit.MetaFrom(ctx)
+	meta.Source = audit.SourceKiosk
+	meta.DeviceID = k.ID
+	return audit.WithMeta(ctx, meta), nil
+}
+
+// CheckIn: POST /attendances/check-in
+// ボディに KioskToken を指定すると、打刻端末のQRコードを読み取った打刻として記録する
+// モバイルアプリは Location に位置情報を指定する（打刻ポリシーで勤務場所を判定する）
+func (h *AttendanceHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFrom(r.Context())
+	now := time.Now()
+	ctx, err := h.punchContext(r, now)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusBadRequest))
+		return
+	}
+	a, err := h.Usecase.CheckIn(ctx, user.ID, now)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("ETag", formatETag(a.Version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(a)
+}
+
+// CheckOut: POST /attendances/check-out
+// 休憩を含め、出勤と同じく KioskToken・Location を指定できる
+func (h *AttendanceHandler) CheckOut(w http.ResponseWriter, r *http.Request) {
+	h.punch(w, r, h.Usecase.CheckOut)
+}
+
+// BreakStart: POST /attendances/break-start
+func (h *AttendanceHandler) BreakStart(w http.ResponseWriter, r *http.Request) {
+	h.punch(w, r, h.Usecase.BreakStart)
+}
+
+// BreakEnd: POST /attendances/break-end
+func (h *AttendanceHandler) BreakEnd(w http.ResponseWriter, r *http.Request) {
+	h.punch(w, r, h.Usecase.BreakEnd)
+}
+
+// 出勤中の勤怠に対する打刻の共通処理
+func (h *AttendanceHandler) punch(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, userID string, at time.Time) (*entity.Attendance, error)) {
+	user, _ := auth.UserFrom(r.Context())
+	now := time.Now()
+	ctx, err := h.punchContext(r, now)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusBadRequest))
+		return
+	}
+	a, err := fn(ctx, user.ID, now)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("ETag", formatETag(a.Version))
+	json.NewEncoder(w).Encode(a)
+}
+
+// Rebuild: POST /attendances/rebuild?user_id=xxx&month=2006-01
+// 打刻イベントから指定した月の勤怠を組み立て直す。month を省略した場合は今月
+func (h *AttendanceHandler) Rebuild(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	month := time.Now()
+	if v := r.URL.Query().Get("month"); v != "" {
+		m, err := time.ParseInLocation("2006-01", v, domain.JST)
+		if err != nil {
+			http.Error(w, "month must be YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = m
+	}
+	list, err := h.Usecase.Rebuild(r.Context(), userID, month)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// 1回の取り込みで受け付ける打刻の上限
+const maxSyncPunches = 500
+
+// オフライン打刻の取り込みのリクエストボディ
+type syncRequest struct {
+	Punches []domain.OfflinePunch
+}
+
+// Sync: POST /attendances/sync
+// ボディは Punches（ClientID・Type・OccurredAt）。結果は送られた順に Results で返す
+func (h *AttendanceHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFrom(r.Context())
+	var req syncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Punches) > maxSyncPunches {
+		http.Error(w, "too many punches", http.StatusRequestEntityTooLarge)
+		return
+	}
+	results, err := h.Usecase.Sync(r.Context(), user.ID, req.Punches, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"Results": results})
+}
+
+// 締めの対象の月をパスから読む
+func monthParam(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	m, err := time.ParseInLocation("2006-01", chi.URLParam(r, "month"), domain.JST)
+	if err != nil {
+		http.Error(w, "month must be YYYY-MM", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+	return m, true
+}
+
+// ListClosedPeriods: GET /attendances/closed-periods
+func (h *AttendanceHandler) ListClosedPeriods(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Usecase.ListClosedPeriods(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// ClosePeriod: PUT /attendances/closed-periods/{month}
+func (h *AttendanceHandler) ClosePeriod(w http.ResponseWriter, r *http.Request) {
+	month, ok := monthParam(w, r)
+	if !ok {
+		return
+	}
+	c, err := h.Usecase.ClosePeriod(r.Context(), month)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// ReopenPeriod: DELETE /attendances/closed-periods/{month}
+func (h *AttendanceHandler) ReopenPeriod(w http.ResponseWriter, r *http.Request) {
+	month, ok := monthParam(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Usecase.ReopenPeriod(r.Context(), month); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := dbinfra.OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dbinfra.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// asUser はユーザー（nil なら未認証）としてリクエストを処理するルーターを作る
+func asUser(routes func(chi.Router), user *entity.User) http.Handler {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if user != nil {
+				req = req.WithContext(auth.WithUser(req.Context(), user))
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	routes(r)
+	return r
+}
+
+// serveAs はユーザー（nil なら未認証）としてリクエストを処理する
+func serveAs(routes func(chi.Router), user *entity.User, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	asUser(routes, user).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAttendanceReadScope(t *testing.T) {
+	db := openTestDB(t)
+	userRepo := repository.NewTimeIsMoneyRepository(db)
+	users := domain.NewUserUsecase(userRepo, repository.NewAuditRepository(db), repository.NewOutboxRepository(db), repository.NewUnitOfWork(db))
+	attendance := domain.NewAttendanceUsecase(repository.NewAttendanceRepository(db), nil, nil, nil, nil, nil, nil, nil)
+	h := NewAttendanceHandler(attendance, nil, users)
+
+	admin := &entity.User{ID: "admin", Role: entity.RoleAdmin}
+	salesManager := &entity.User{ID: "m1", Role: entity.RoleManager, Department: "sales"}
+	noDeptManager := &entity.User{ID: "m2", Role: entity.RoleManager}
+	alice := &entity.User{ID: "alice", Role: entity.RoleEmployee, Department: "sales"}
+	for _, u := range []entity.User{
+		{ID: "alice", AuthID: "a-alice", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee, Department: "sales"},
+		{ID: "bob", AuthID: "a-bob", Name: "Bob", Email: "bob@example.com", Role: entity.RoleEmployee, Department: "dev"},
+		{ID: "carol", AuthID: "a-carol", Name: "Carol", Email: "carol@example.com", Role: entity.RoleEmployee, Department: "sales"},
+	} {
+		if err := userRepo.CreateUser(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 退職したユーザーの勤怠も部署のマネージャーは見られる
+	if err := userRepo.DeleteUser(context.Background(), entity.User{ID: "carol"}); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2026, 10, 1, 0, 0, 0, 0, domain.JST)
+	for _, userID := range []string{"alice", "bob", "carol"} {
+		a := entity.Attendance{ID: "att-" + userID, UserID: userID, Date: date, CheckIn: date.Add(9 * time.Hour), Version: 1}
+		if err := db.Create(&a).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		user   *entity.User
+		path   string
+		status int
+		ids    []string
+	}{
+		{"unauthenticated list", nil, "/attendances", http.StatusUnauthorized, nil},
+		{"unauthenticated get", nil, "/attendances/att-alice", http.StatusUnauthorized, nil},
+		{"admin lists everyone", admin, "/attendances", http.StatusOK, []string{"att-alice", "att-bob", "att-carol"}},
+		{"admin gets other department", admin, "/attendances/att-bob", http.StatusOK, []string{"att-bob"}},
+		{"manager lists own department", salesManager, "/attendances", http.StatusOK, []string{"att-alice", "att-carol"}},
+		{"manager filters own department", salesManager, "/attendances?user_id=alice", http.StatusOK, []string{"att-alice"}},
+		{"manager gets own department", salesManager, "/attendances/att-carol", http.StatusOK, []string{"att-carol"}},
+		{"manager filters other department", salesManager, "/attendances?user_id=bob", http.StatusForbidden, nil},
+		{"manager gets other department", salesManager, "/attendances/att-bob", http.StatusForbidden, nil},
+		{"manager without department", noDeptManager, "/attendances/att-alice", http.StatusForbidden, nil},
+		{"employee lists own", alice, "/attendances", http.StatusOK, []string{"att-alice"}},
+		{"employee gets own", alice, "/attendances/att-alice", http.StatusOK, []string{"att-alice"}},
+		{"employee filters colleague", alice, "/attendances?user_id=carol", http.StatusForbidden, nil},
+		{"employee gets colleague", alice, "/attendances/att-carol", http.StatusForbidden, nil},
+		{"missing attendance", alice, "/attendances/none", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveAs(h.RegisterRoutes, tt.user, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (body = %s)", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			// 一覧は配列、1件取得はオブジェクトで返る
+			var list []entity.Attendance
+			body := rec.Body.Bytes()
+			if len(body) > 0 && body[0] == '{' {
+				body = append(append([]byte{'['}, body...), ']')
+			}
+			if err := json.Unmarshal(body, &list); err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, a := range list {
+				ids = append(ids, a.ID)
+			}
+			sort.Strings(ids)
+			if len(ids) != len(tt.ids) {
+				t.Fatalf("ids = %v, want %v", ids, tt.ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Fatalf("ids = %v, want %v", ids, tt.ids)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AuditHandlerは監査ログ用のHTTPハンドラー
+type AuditHandler struct {
+	Usecase domain.AuditUsecase
+}
+
+// NewAuditHandlerはAuditHandlerを生成
+func NewAuditHandler(u domain.AuditUsecase) *AuditHandler {
+	return &AuditHandler{Usecase: u}
+}
+
+// ルーティング設定
+func (h *AuditHandler) RegisterRoutes(r chi.Router) {
+	// 監査ログの参照は管理者のみ
+	r.With(auth.RequireRole(entity.RoleAdmin)).Get("/audit-logs", h.ListAuditLogs)
+}
+
+// ListAuditLogs: GET /audit-logs?entity_type=&entity_id=&actor_id=&from=&to=&limit=
+// from・to は RFC 3339 形式（例: 2025-04-01T00:00:00+09:00）
+func (h *AuditHandler) ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.AuditFilter{
+		EntityType: q.Get("entity_type"),
+		EntityID:   q.Get("entity_id"),
+		ActorID:    q.Get("actor_id"),
+	}
+	var err error
+	if v := q.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "from must be RFC 3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "to must be RFC 3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "limit must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	logs, err := h.Usecase.FindAuditLogs(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(logs)
+}
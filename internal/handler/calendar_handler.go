@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/ical"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CalendarHandlerはカレンダーのフィード・会社の休日・休暇用のHTTPハンドラー
+type CalendarHandler struct {
+	Usecase domain.CalendarUsecase
+}
+
+// NewCalendarHandlerはCalendarHandlerを生成
+func NewCalendarHandler(u domain.CalendarUsecase) *CalendarHandler {
+	return &CalendarHandler{Usecase: u}
+}
+
+// ルーティング設定
+// フィードは URL のトークンで本人を確かめるため認証なし（カレンダーアプリはJWTを付けられない）
+// 休日の登録は管理者のみ、休暇の登録は管理者・マネージャーのみ
+func (h *CalendarHandler) RegisterRoutes(r chi.Router) {
+	r.With(auth.RequireUser).Post("/users/{id}/calendar-token", h.IssueToken)
+	r.With(auth.RequireUser).Delete("/users/{id}/calendar-token", h.RevokeToken)
+	r.Get("/calendar/{token}/personal.ics", h.PersonalFeed)
+	r.Get("/calendar/{token}/team.ics", h.TeamFeed)
+
+	r.With(auth.RequireUser).Get("/holidays", h.ListHolidays)
+	r.With(auth.RequireRole(entity.RoleAdmin)).Post("/holidays", h.CreateHoliday)
+	r.With(auth.RequireRole(entity.RoleAdmin)).Delete("/holidays/{date}", h.DeleteHoliday)
+
+	r.Route("/leaves", func(r chi.Router) {
+		r.Use(auth.RequireRole(entity.RoleAdmin, entity.RoleManager))
+		r.Get("/", h.ListLeaves)
+		r.Post("/", h.CreateLeave)
+		r.Delete("/{id}", h.DeleteLeave)
+	})
+}
+
+// トークンの発行のレスポンス
+// トークンはこのときだけ返すため、カレンダーアプリに URL を登録してもらう
+type calendarTokenResponse struct {
+	Token       string
+	PersonalURL string
+	TeamURL     string
+}
+
+// IssueToken: POST /users/{id}/calendar-token
+// 発行し直すと前の URL は使えなくなる
+func (h *CalendarHandler) IssueToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !selfOrAdmin(w, r, id) {
+		return
+	}
+	token, err := h.Usecase.IssueToken(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	base := requestOrigin(r) + "/calendar/" + token
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(calendarTokenResponse{
+		Token:       token,
+		PersonalURL: base + "/personal.ics",
+		TeamURL:     base + "/team.ics",
+	})
+}
+
+// RevokeToken: DELETE /users/{id}/calendar-token
+func (h *CalendarHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !selfOrAdmin(w, r, id) {
+		return
+	}
+	if err := h.Usecase.RevokeToken(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PersonalFeed: GET /calendar/{token}/personal.ics
+// 本人の休暇・会社の休日（CALENDAR_DEFAULT_SHIFTS を有効にした場合は既定の勤務時間の予定も）
+func (h *CalendarHandler) PersonalFeed(w http.ResponseWriter, r *http.Request) {
+	cal, err := h.Usecase.PersonalFeed(r.Context(), chi.URLParam(r, "token"), time.Now())
+	writeCalendar(w, cal, err)
+}
+
+// TeamFeed: GET /calendar/{token}/team.ics
+// チームの休暇（マネージャー・管理者のトークンのみ）
+func (h *CalendarHandler) TeamFeed(w http.ResponseWriter, r *http.Request) {
+	cal, err := h.Usecase.TeamFeed(r.Context(), chi.URLParam(r, "token"), time.Now())
+	writeCalendar(w, cal, err)
+}
+
+// writeCalendar は iCalendar のフィードを書き込む
+func writeCalendar(w http.ResponseWriter, cal *ical.Calendar, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	// URL にトークンを含むため、共有のキャッシュには残させない
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(cal.Encode())
+}
+
+// requestOrigin はリクエストを受けた URL のスキームとホストを返す
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// calendarRange は from・to クエリ（"2006-01-02"）を返す。省略時は今年の1月1日から12月31日まで
+func calendarRange(r *http.Request) (string, string) {
+	year := time.Now().In(domain.JST).Format("2006")
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" {
+		from = year + "-01-01"
+	}
+	if to == "" {
+		to = year + "-12-31"
+	}
+	return from, to
+}
+
+// ListHolidays: GET /holidays?from=2006-01-01&to=2006-12-31
+func (h *CalendarHandler) ListHolidays(w http.ResponseWriter, r *http.Request) {
+	from, to := calendarRange(r)
+	list, err := h.Usecase.ListHolidays(r.Context(), from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateHoliday: POST /holidays
+// ボディは Date（"2006-01-02"）・Name
+func (h *CalendarHandler) CreateHoliday(w http.ResponseWriter, r *http.Request) {
+	var req entity.Holiday
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	holiday, err := h.Usecase.CreateHoliday(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(holiday)
+}
+
+// DeleteHoliday: DELETE /holidays/{date}
+func (h *CalendarHandler) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	if err := h.Usecase.DeleteHoliday(r.Context(), chi.URLParam(r, "date")); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListLeaves: GET /leaves?from=2006-01-01&to=2006-12-31&department=xxx
+func (h *CalendarHandler) ListLeaves(w http.ResponseWriter, r *http.Request) {
+	department, ok := departmentScope(w, r)
+	if !ok {
+		return
+	}
+	from, to := calendarRange(r)
+	list, err := h.Usecase.ListLeaves(r.Context(), department, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateLeave: POST /leaves
+// 承認済みの休暇を登録する。ボディは UserID・StartDate・EndDate（省略時は StartDate の1日）・Kind・Note
+func (h *CalendarHandler) CreateLeave(w http.ResponseWriter, r *http.Request) {
+	var req entity.Leave
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	leave, err := h.Usecase.CreateLeave(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(leave)
+}
+
+// DeleteLeave: DELETE /leaves/{id}
+func (h *CalendarHandler) DeleteLeave(w http.ResponseWriter, r *http.Request) {
+	if err := h.Usecase.DeleteLeave(r.Context(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CardHandlerはICカードリーダー用のHTTPハンドラー
+type CardHandler struct {
+	Usecase domain.CardUsecase
+}
+
+// NewCardHandlerはCardHandlerを生成
+func NewCardHandler(u domain.CardUsecase) *CardHandler {
+	return &CardHandler{Usecase: u}
+}
+
+// ルーティング設定
+// 端末・カードの管理は管理者のみ。打刻はJWTではなく端末のAPIキーかクライアント証明書で認証する
+func (h *CardHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/card-readers/punches", h.Punch)
+
+	r.Route("/card-readers", func(r chi.Router) {
+		r.Use(auth.RequireRole(entity.RoleAdmin))
+		r.Post("/", h.CreateReader)
+		r.Get("/", h.ListReaders)
+		r.Get("/{id}", h.GetReader)
+		r.Patch("/{id}", h.PatchReader)
+		r.Delete("/{id}", h.DeleteReader)
+	})
+	r.Route("/cards", func(r chi.Router) {
+		r.Use(auth.RequireRole(entity.RoleAdmin))
+		r.Post("/", h.RegisterCard)
+		r.Get("/", h.ListCards)
+		r.Delete("/{cardID}", h.UnregisterCard)
+	})
+}
+
+// 端末から送られる打刻
+// 既存の端末の形式に合わせて、JSONのキーはスネークケース
+type cardPunchRequest struct {
+	CardID    string    `json:"card_id"`
+	Timestamp time.Time `json:"timestamp"` // RFC 3339
+	DeviceID  string    `json:"device_id"`
+	Action    string    `json:"action"`
+}
+
+// 端末に返す打刻の結果
+type cardPunchResponse struct {
+	Action     string             `json:"action"`
+	UserID     string             `json:"user_id"`
+	UserName   string             `json:"user_name"`
+	Attendance *entity.Attendance `json:"attendance"`
+}
+
+// 端末を認証する
+// TLSのクライアント証明書が検証済みの場合は、証明書の CN を端末IDとして扱う
+// それ以外は X-Device-Key ヘッダーのAPIキーで認証する
+func (h *CardHandler) authenticate(r *http.Request, deviceID string) (*entity.CardReader, error) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		if r.TLS.VerifiedChains[0][0].Subject.CommonName != deviceID {
+			return nil, domain.ErrInvalidDeviceKey
+		}
+		return h.Usecase.AuthenticateCert(r.Context(), deviceID)
+	}
+	return h.Usecase.Authenticate(r.Context(), deviceID, r.Header.Get("X-Device-Key"))
+}
+
+// Punch: POST /card-readers/punches
+// ボディは card_id・timestamp・device_id（action は省略可）
+func (h *CardHandler) Punch(w http.ResponseWriter, r *http.Request) {
+	var req cardPunchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reader, err := h.authenticate(r, req.DeviceID)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	result, err := h.Usecase.Punch(r.Context(), reader, domain.CardPunch{
+		CardID:    req.CardID,
+		Timestamp: req.Timestamp,
+		Action:    req.Action,
+	}, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	json.NewEncoder(w).Encode(cardPunchResponse{
+		Action:     result.Action,
+		UserID:     result.UserID,
+		UserName:   result.UserName,
+		Attendance: result.Attendance,
+	})
+}
+
+// 端末の登録時だけAPIキーを返す
+type cardReaderCreated struct {
+	entity.CardReader
+	APIKey string
+}
+
+// 端末のAPIキーのハッシュはレスポンスに含めない
+func redactKeyHash(c *entity.CardReader) {
+	c.KeyHash = ""
+}
+
+// CreateReader: POST /card-readers
+// ボディは Name・Location。APIキーはサーバーで生成し、このレスポンスでのみ返す
+func (h *CardHandler) CreateReader(w http.ResponseWriter, r *http.Request) {
+	var req entity.CardReader
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, key, err := h.Usecase.RegisterReader(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	redactKeyHash(c)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cardReaderCreated{CardReader: *c, APIKey: key})
+}
+
+// ListReaders: GET /card-readers
+func (h *CardHandler) ListReaders(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Usecase.ListReaders(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range list {
+		redactKeyHash(&list[i])
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetReader: GET /card-readers/{id}
+func (h *CardHandler) GetReader(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Usecase.FindReader(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	redactKeyHash(c)
+	json.NewEncoder(w).Encode(c)
+}
+
+// PATCHで変更できる端末のフィールド
+var cardReaderPatchAllowlist = patchAllowlist{
+	"Name":     {},
+	"Location": {nullable: true},
+	"Active":   {},
+}
+
+// PatchReader: PATCH /card-readers/{id}
+// ボディは JSON Merge Patch (RFC 7396)
+func (h *CardHandler) PatchReader(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !isMergePatch(r) {
+		http.Error(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	current, err := h.Usecase.FindReader(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	var patched entity.CardReader
+	if err := applyMergePatch(current, patch, cardReaderPatchAllowlist, &patched); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	patched.ID = id
+	if err := h.Usecase.UpdateReader(r.Context(), patched); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	updated, err := h.Usecase.FindReader(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redactKeyHash(updated)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteReader: DELETE /card-readers/{id}
+func (h *CardHandler) DeleteReader(w http.ResponseWriter, r *http.Request) {
+	if err := h.Usecase.DeleteReader(r.Context(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegisterCard: POST /cards
+// ボディは CardID・UserID
+func (h *CardHandler) RegisterCard(w http.ResponseWriter, r *http.Request) {
+	var req entity.Card
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, err := h.Usecase.RegisterCard(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// ListCards: GET /cards?user_id=xxx
+func (h *CardHandler) ListCards(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Usecase.ListCards(r.Context(), r.URL.Query().Get("user_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// UnregisterCard: DELETE /cards/{cardID}
+func (h *CardHandler) UnregisterCard(w http.ResponseWriter, r *http.Request) {
+	if err := h.Usecase.UnregisterCard(r.Context(), chi.URLParam(r, "cardID")); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/audit"
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Slack のリクエストの署名の有効期間（リプレイ対策）
+const slackSignatureTolerance = 5 * time.Minute
+
+// スラッシュコマンドのボディの上限
+const maxChatCommandBody = 64 << 10
+
+// ChatHandlerはチャット連携用のHTTPハンドラー
+type ChatHandler struct {
+	Usecase domain.ChatUsecase
+	Config  domain.ChatConfig
+}
+
+// NewChatHandlerはChatHandlerを生成
+func NewChatHandler(u domain.ChatUsecase, cfg domain.ChatConfig) *ChatHandler {
+	return &ChatHandler{Usecase: u, Config: cfg}
+}
+
+// ルーティング設定
+// スラッシュコマンドはJWTではなく、Slack の署名・Mattermost のトークンで検証する
+func (h *ChatHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/chat/slack/commands", h.SlackCommand)
+	r.Post("/chat/mattermost/commands", h.MattermostCommand)
+
+	// チャットアカウントの紐づけは管理者のみ
+	r.Route("/chat/identities", func(r chi.Router) {
+		r.Use(auth.RequireRole(entity.RoleAdmin))
+		r.Get("/", h.ListIdentities)
+		r.Post("/", h.LinkIdentity)
+		r.Delete("/{provider}/{chatUserID}", h.UnlinkIdentity)
+	})
+}
+
+// SlackCommand: POST /chat/slack/commands
+// X-Slack-Signature は "v0=" + HMAC-SHA256(Signing Secret, "v0:" + timestamp + ":" + body) の16進
+func (h *ChatHandler) SlackCommand(w http.ResponseWriter, r *http.Request) {
+	if h.Config.SlackSigningSecret == "" {
+		http.Error(w, "slack integration is not configured", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxChatCommandBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !verifySlackSignature(h.Config.SlackSigningSecret, r.Header, body, time.Now()) {
+		http.Error(w, "invalid slack signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.command(w, r, entity.ChatSlack, form)
+}
+
+// MattermostCommand: POST /chat/mattermost/commands
+// トークンはフォームの token か Authorization: Token xxx で受け取る
+func (h *ChatHandler) MattermostCommand(w http.ResponseWriter, r *http.Request) {
+	if h.Config.MattermostToken == "" {
+		http.Error(w, "mattermost integration is not configured", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxChatCommandBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	token := form.Get("token")
+	if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Token "); ok {
+		token = v
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.Config.MattermostToken)) != 1 {
+		http.Error(w, "invalid mattermost token", http.StatusUnauthorized)
+		return
+	}
+	h.command(w, r, entity.ChatMattermost, form)
+}
+
+// スラッシュコマンドの共通処理
+// 返信は本人にだけ見える形（ephemeral）で返す。Slack・Mattermost とも同じ形式
+func (h *ChatHandler) command(w http.ResponseWriter, r *http.Request, provider string, form url.Values) {
+	meta := audit.MetaFrom(r.Context())
+	meta.Source = audit.SourceChat
+	ctx := audit.WithMeta(r.Context(), meta)
+
+	text, err := h.Usecase.HandleCommand(ctx, provider, form.Get("user_id"), form.Get("text"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"response_type": "ephemeral",
+		"text":          text,
+	})
+}
+
+// verifySlackSignature は Slack のリクエストの署名と送信時刻を検証する
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) bool {
+	ts, err := strconv.ParseInt(header.Get("X-Slack-Request-Timestamp"), 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > slackSignatureTolerance || d < -slackSignatureTolerance {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + strconv.FormatInt(ts, 10) + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), bytes.TrimSpace([]byte(header.Get("X-Slack-Signature"))))
+}
+
+// ListIdentities: GET /chat/identities
+func (h *ChatHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Usecase.ListIdentities(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// LinkIdentity: POST /chat/identities
+// ボディは Provider（slack・mattermost）・ChatUserID・UserID
+func (h *ChatHandler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	var req entity.ChatIdentity
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	created, err := h.Usecase.LinkIdentity(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// UnlinkIdentity: DELETE /chat/identities/{provider}/{chatUserID}
+func (h *ChatHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	err := h.Usecase.UnlinkIdentity(r.Context(), chi.URLParam(r, "provider"), chi.URLParam(r, "chatUserID"))
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
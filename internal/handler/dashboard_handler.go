@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DashboardHandlerは在席状況のダッシュボード用のHTTPハンドラー
+type DashboardHandler struct {
+	Usecase domain.DashboardUsecase
+	Config  domain.DashboardConfig
+}
+
+// NewDashboardHandlerはDashboardHandlerを生成
+func NewDashboardHandler(u domain.DashboardUsecase, cfg domain.DashboardConfig) *DashboardHandler {
+	return &DashboardHandler{Usecase: u, Config: cfg}
+}
+
+// ルーティング設定
+// ダッシュボードは管理者・マネージャーのみ
+func (h *DashboardHandler) RegisterRoutes(r chi.Router) {
+	r.With(auth.RequireRole(entity.RoleAdmin, entity.RoleManager)).Get("/dashboard", h.Snapshot)
+	r.With(auth.RequireRole(entity.RoleAdmin, entity.RoleManager)).Get("/dashboard/stream", h.Stream)
+}
+
+// departmentScope は表示する部署を決める（ダッシュボード・休暇の一覧）
+// 管理者は department クエリで絞り込め（省略時は全員）、マネージャーは自分の部署だけを見られる
+func departmentScope(w http.ResponseWriter, r *http.Request) (string, bool) {
+	user, _ := auth.UserFrom(r.Context())
+	department := r.URL.Query().Get("department")
+	if user.Role == entity.RoleAdmin {
+		return department, true
+	}
+	if user.Department == "" || (department != "" && department != user.Department) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return "", false
+	}
+	return user.Department, true
+}
+
+// Snapshot: GET /dashboard?department=xxx
+// 今日の在席状況の一覧
+func (h *DashboardHandler) Snapshot(w http.ResponseWriter, r *http.Request) {
+	department, ok := departmentScope(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Usecase.Snapshot(r.Context(), department, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Stream: GET /dashboard/stream?department=xxx
+// Server-Sent Events で在席状況の変化を送る
+// 最初に今日の在席状況の一覧（snapshot）を送り、その後は打刻ごとに attendance.checked_in などのイベントを送る
+// Last-Event-ID ヘッダーを付けて再接続すると、取りこぼしたイベントだけを送る（再開できなければ一覧を送り直す）
+func (h *DashboardHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	department, ok := departmentScope(w, r)
+	if !ok {
+		return
+	}
+	sub, missed, resumed := h.Usecase.Subscribe(r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	var snapshot []domain.Presence
+	if !resumed {
+		var err error
+		snapshot, err = h.Usecase.Snapshot(r.Context(), department, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// nginx などのプロキシにバッファさせない
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if resumed {
+		for _, e := range missed {
+			if department == "" || e.Department == department {
+				writeSSE(w, e.ID, e.Type, e.Data)
+			}
+		}
+	} else {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return
+		}
+		writeSSE(w, sub.StartID(), domain.DashboardSnapshotEvent, data)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.Config.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done():
+			// 取りこぼした・サーバーの停止。クライアントは Last-Event-ID を付けて再接続する
+			return
+		case e := <-sub.Events():
+			if department != "" && e.Department != department {
+				continue
+			}
+			writeSSE(w, e.ID, e.Type, e.Data)
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE は Server-Sent Events の1件を書き込む（data は改行を含まないJSON）
+func writeSSE(w io.Writer, id, event string, data []byte) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event, data)
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/live"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// sseEvent は Server-Sent Events の1件
+type sseEvent struct {
+	id, event, data string
+}
+
+// openStream はユーザー（nil なら未認証）として /dashboard/stream に接続する
+func openStream(t *testing.T, routes func(chi.Router), user *entity.User, query, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	srv := httptest.NewServer(asUser(routes, user))
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/dashboard/stream"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res, bufio.NewReader(res.Body)
+}
+
+// readEvent は次のイベントを読む（コメントの行は読み飛ばす）
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && e.event != "":
+			return e
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestDashboardStreamDepartmentScope(t *testing.T) {
+	db := openTestDB(t)
+	userRepo := repository.NewTimeIsMoneyRepository(db)
+	for _, u := range []entity.User{
+		{ID: "alice", AuthID: "a-alice", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee, Department: "sales"},
+		{ID: "bob", AuthID: "a-bob", Name: "Bob", Email: "bob@example.com", Role: entity.RoleEmployee, Department: "dev"},
+	} {
+		if err := userRepo.CreateUser(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hub := live.NewHub()
+	t.Cleanup(hub.Close)
+	dashboard := domain.NewDashboardUsecase(hub, userRepo, repository.NewAttendanceRepository(db), domain.NewPunchLogUsecase(domain.PunchLogConfig{}, repository.NewPunchEventRepository(db)))
+	h := NewDashboardHandler(dashboard, domain.DashboardConfig{Heartbeat: time.Hour})
+	checkIn := func(userID string) {
+		dashboard.Publish(context.Background(), entity.EventCheckedIn, entity.Attendance{ID: "att-" + userID, UserID: userID, CheckIn: time.Now()})
+	}
+
+	admin := &entity.User{ID: "admin", Role: entity.RoleAdmin}
+	salesManager := &entity.User{ID: "m1", Role: entity.RoleManager, Department: "sales"}
+
+	t.Run("forbidden", func(t *testing.T) {
+		for _, tt := range []struct {
+			name   string
+			user   *entity.User
+			query  string
+			status int
+		}{
+			{"unauthenticated", nil, "", http.StatusUnauthorized},
+			{"employee", &entity.User{ID: "alice", Role: entity.RoleEmployee, Department: "sales"}, "", http.StatusForbidden},
+			{"manager without department", &entity.User{ID: "m2", Role: entity.RoleManager}, "", http.StatusForbidden},
+			{"manager other department", salesManager, "?department=dev", http.StatusForbidden},
+		} {
+			if res, _ := openStream(t, h.RegisterRoutes, tt.user, tt.query, ""); res.StatusCode != tt.status {
+				t.Errorf("%s: status = %d, want %d", tt.name, res.StatusCode, tt.status)
+			}
+		}
+	})
+
+	// マネージャーには自分の部署の一覧とイベントだけを送る
+	t.Run("manager", func(t *testing.T) {
+		res, r := openStream(t, h.RegisterRoutes, salesManager, "", "")
+		if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("status = %d, content type = %q", res.StatusCode, res.Header.Get("Content-Type"))
+		}
+		snapshot := readEvent(t, r)
+		var list []domain.Presence
+		if err := json.Unmarshal([]byte(snapshot.data), &list); err != nil {
+			t.Fatal(err)
+		}
+		if snapshot.event != domain.DashboardSnapshotEvent || len(list) != 1 || list[0].UserID != "alice" {
+			t.Fatalf("snapshot = %+v, want alice only", snapshot)
+		}
+		checkIn("bob")
+		checkIn("alice")
+		if e := readEvent(t, r); e.event != entity.EventCheckedIn || !strings.Contains(e.data, `"alice"`) {
+			t.Errorf("event = %+v, want alice's check-in", e)
+		}
+	})
+
+	// 管理者は department で絞り込める
+	t.Run("admin filtered", func(t *testing.T) {
+		_, r := openStream(t, h.RegisterRoutes, admin, "?department=dev", "")
+		readEvent(t, r)
+		checkIn("alice")
+		checkIn("bob")
+		if e := readEvent(t, r); !strings.Contains(e.data, `"bob"`) {
+			t.Errorf("event = %+v, want bob's check-in", e)
+		}
+	})
+
+	// 再接続で再送するイベントも部署で絞り込む
+	t.Run("resume", func(t *testing.T) {
+		_, r := openStream(t, h.RegisterRoutes, salesManager, "", "")
+		start := readEvent(t, r)
+		checkIn("bob")
+		checkIn("alice")
+		readEvent(t, r)
+
+		_, r = openStream(t, h.RegisterRoutes, salesManager, "", start.id)
+		if e := readEvent(t, r); e.event != entity.EventCheckedIn || !strings.Contains(e.data, `"alice"`) {
+			t.Errorf("resumed event = %+v, want alice's check-in only", e)
+		}
+	})
+}
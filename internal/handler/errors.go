@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// リポジトリ層・ドメイン層のエラーをHTTPステータスに変換する
+// 該当しないエラーは fallback のステータスを返す
+func statusFromError(err error, fallback int) int {
+	switch {
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, domain.ErrInvalidCalendarToken):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrConflict), errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrAlreadyCheckedIn), errors.Is(err, domain.ErrNotCheckedIn),
+		errors.Is(err, domain.ErrAlreadyOnBreak), errors.Is(err, domain.ErrNotOnBreak),
+		errors.Is(err, domain.ErrPeriodClosed):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrInvalidPunchTime), errors.Is(err, domain.ErrInvalidWebhookURL),
+		errors.Is(err, domain.ErrUnknownChatProvider), errors.Is(err, domain.ErrUnsupportedLocale),
+		errors.Is(err, domain.ErrInvalidCardID), errors.Is(err, domain.ErrUnknownCard),
+		errors.Is(err, domain.ErrClockSkew), errors.Is(err, domain.ErrUnknownPunchType),
+		errors.Is(err, domain.ErrInvalidPunchPolicy), errors.Is(err, domain.ErrInvalidStatus),
+		errors.Is(err, domain.ErrInvalidHoliday), errors.Is(err, domain.ErrInvalidLeave),
+		errors.Is(err, domain.ErrInvalidUser), errors.Is(err, domain.ErrInvalidUserImport),
+		errors.Is(err, domain.ErrUserImportRejected):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrInvalidKioskKey), errors.Is(err, domain.ErrInvalidDeviceKey):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrInvalidKioskToken), errors.Is(err, domain.ErrPunchPolicyViolation),
+		errors.Is(err, domain.ErrLeaveForbidden), errors.Is(err, domain.ErrTeamFeedForbidden):
+		return http.StatusForbidden
+	default:
+		return fallback
+	}
+}
+
+// リポジトリ層・ドメイン層のエラーをgRPCのステータスに変換する
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, domain.ErrInvalidCalendarToken):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, repository.ErrVersionConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, domain.ErrAlreadyCheckedIn), errors.Is(err, domain.ErrNotCheckedIn),
+		errors.Is(err, domain.ErrAlreadyOnBreak), errors.Is(err, domain.ErrNotOnBreak),
+		errors.Is(err, domain.ErrPeriodClosed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, domain.ErrInvalidPunchTime), errors.Is(err, domain.ErrInvalidWebhookURL),
+		errors.Is(err, domain.ErrUnknownChatProvider), errors.Is(err, domain.ErrUnsupportedLocale),
+		errors.Is(err, domain.ErrInvalidCardID), errors.Is(err, domain.ErrUnknownCard),
+		errors.Is(err, domain.ErrClockSkew), errors.Is(err, domain.ErrUnknownPunchType),
+		errors.Is(err, domain.ErrInvalidPunchPolicy), errors.Is(err, domain.ErrInvalidStatus),
+		errors.Is(err, domain.ErrInvalidHoliday), errors.Is(err, domain.ErrInvalidLeave),
+		errors.Is(err, domain.ErrInvalidUser), errors.Is(err, domain.ErrInvalidUserImport),
+		errors.Is(err, domain.ErrUserImportRejected):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrInvalidKioskKey), errors.Is(err, domain.ErrInvalidDeviceKey):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, domain.ErrInvalidKioskToken), errors.Is(err, domain.ErrPunchPolicyViolation),
+		errors.Is(err, domain.ErrLeaveForbidden), errors.Is(err, domain.ErrTeamFeedForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errInvalidETag = errors.New("invalid If-Match header")
+
+// バージョン番号からETagを作る（例: "3"）
+func formatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// If-Matchヘッダーからバージョン番号を取り出す
+// ヘッダーが無い場合は ok=false を返す
+// 弱いETag（W/"3"）も受け付けるが、"*" や複数指定には対応しない
+func versionFromIfMatch(r *http.Request) (version int64, ok bool, err error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" {
+		return 0, false, nil
+	}
+	v = strings.TrimPrefix(v, "W/")
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return 0, false, errInvalidETag
+	}
+	version, err = strconv.ParseInt(v[1:len(v)-1], 10, 64)
+	if err != nil {
+		return 0, false, errInvalidETag
+	}
+	return version, true, nil
+}
+
+// If-None-Matchヘッダーが現在のETagと一致するかを判定する
+func matchesIfNoneMatch(r *http.Request, etag string) bool {
+	for _, v := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionFromIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int64
+		ok      bool
+		err     error
+	}{
+		{"", 0, false, nil},
+		{`"3"`, 3, true, nil},
+		{` W/"12" `, 12, true, nil},
+		{"*", 0, false, errInvalidETag},
+		{"3", 0, false, errInvalidETag},
+		{`"abc"`, 0, false, errInvalidETag},
+		{`"1", "2"`, 0, false, errInvalidETag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/users/u1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			version, ok, err := versionFromIfMatch(r)
+			if version != tt.version || ok != tt.ok || !errors.Is(err, tt.err) {
+				t.Errorf("versionFromIfMatch(%q) = %d, %v, %v, want %d, %v, %v", tt.header, version, ok, err, tt.version, tt.ok, tt.err)
+			}
+		})
+	}
+}
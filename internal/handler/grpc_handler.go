@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/pb"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserGRPCServerはUser用のgRPCハンドラー
+type UserGRPCServer struct {
+	pb.UnimplementedUserServiceServer
+	Usecase domain.UserUsecase
+}
+
+// NewUserGRPCServerはUserGRPCServerを生成
+func NewUserGRPCServer(u domain.UserUsecase) *UserGRPCServer {
+	return &UserGRPCServer{Usecase: u}
+}
+
+// CreateUser: rpc CreateUser（管理者のみ）
+func (s *UserGRPCServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if err := requireGRPCRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	user := entity.User{
+		ID:         uuid.NewString(),
+		AuthID:     req.GetAuthId(),
+		Name:       req.GetName(),
+		Email:      req.GetEmail(),
+		Role:       req.GetRole(),
+		Department: req.GetDepartment(),
+	}
+	if err := s.Usecase.CreateUser(ctx, user); err != nil {
+		return nil, grpcError(err)
+	}
+	created, err := s.Usecase.FindFirst(ctx, user.ID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &pb.CreateUserResponse{User: toPBUser(created)}, nil
+}
+
+// ListUsers: rpc ListUsers
+func (s *UserGRPCServer) ListUsers(ctx context.Context, _ *emptypb.Empty) (*pb.ListUsersResponse, error) {
+	users, err := s.Usecase.FindAllUser(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	res := &pb.ListUsersResponse{Users: make([]*pb.User, 0, len(users))}
+	for i := range users {
+		res.Users = append(res.Users, toPBUser(&users[i]))
+	}
+	return res, nil
+}
+
+// GetUser: rpc GetUser
+func (s *UserGRPCServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	user, err := s.Usecase.FindFirst(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &pb.GetUserResponse{User: toPBUser(user)}, nil
+}
+
+// UpdateUser: rpc UpdateUser（管理者のみ）
+// user.version に取得時のバージョンを指定する
+func (s *UserGRPCServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
+	if err := requireGRPCRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	in := req.GetUser()
+	if in.GetId() == "" || in.GetVersion() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user.id and user.version are required")
+	}
+	user := entity.User{
+		ID:         in.GetId(),
+		AuthID:     in.GetAuthId(),
+		Name:       in.GetName(),
+		Email:      in.GetEmail(),
+		Role:       in.GetRole(),
+		Department: in.GetDepartment(),
+		Version:    in.GetVersion(),
+	}
+	updated, err := s.update(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateUserResponse{User: updated}, nil
+}
+
+// PatchUser: rpc PatchUser
+// updateMask に含まれるフィールドだけを上書きする
+// 管理者以外は本人だけを、name・email だけ変更できる（HTTPの PATCH /users/{id} と同じ）
+func (s *UserGRPCServer) PatchUser(ctx context.Context, req *pb.PatchUserRequest) (*pb.PatchUserResponse, error) {
+	in := req.GetUser()
+	if in.GetId() == "" || in.GetVersion() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user.id and user.version are required")
+	}
+	self, ok := auth.UserFrom(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	admin := auth.HasRole(ctx, entity.RoleAdmin)
+	if !admin && self.ID != in.GetId() {
+		return nil, status.Error(codes.PermissionDenied, "forbidden")
+	}
+	mask := req.GetUpdateMask()
+	if mask == nil || len(mask.GetPaths()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "updateMask is required")
+	}
+	if !mask.IsValid(in) {
+		return nil, status.Error(codes.InvalidArgument, "updateMask contains unknown fields")
+	}
+
+	current, err := s.Usecase.FindFirst(ctx, in.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	user := *current
+	user.Version = in.GetVersion()
+	for _, path := range mask.GetPaths() {
+		switch path {
+		case "name":
+			user.Name = in.GetName()
+		case "email":
+			user.Email = in.GetEmail()
+		case "role":
+			if !admin {
+				return nil, status.Error(codes.PermissionDenied, "only admins can change role")
+			}
+			user.Role = in.GetRole()
+		case "department":
+			if !admin {
+				return nil, status.Error(codes.PermissionDenied, "only admins can change department")
+			}
+			user.Department = in.GetDepartment()
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "field %q cannot be patched", path)
+		}
+	}
+	updated, err := s.update(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PatchUserResponse{User: updated}, nil
+}
+
+// DeleteUser: rpc DeleteUser（管理者のみ）
+func (s *UserGRPCServer) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*emptypb.Empty, error) {
+	if err := requireGRPCRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := s.Usecase.DeleteUser(ctx, entity.User{ID: req.GetId()}); err != nil {
+		return nil, grpcError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// 認証済みで、指定したロールのいずれかを持つユーザーかを確かめる（HTTPの auth.RequireRole にあたる）
+func requireGRPCRole(ctx context.Context, roles ...string) error {
+	if _, ok := auth.UserFrom(ctx); !ok {
+		return status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if !auth.HasRole(ctx, roles...) {
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+	return nil
+}
+
+// 更新を実行し、更新後のユーザーを返す
+func (s *UserGRPCServer) update(ctx context.Context, user entity.User) (*pb.User, error) {
+	if err := s.Usecase.UpdateUser(ctx, user); err != nil {
+		return nil, grpcError(err)
+	}
+	updated, err := s.Usecase.FindFirst(ctx, user.ID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPBUser(updated), nil
+}
+
+// エンティティをprotoのメッセージに変換する
+func toPBUser(u *entity.User) *pb.User {
+	res := &pb.User{
+		Id:         u.ID,
+		AuthId:     u.AuthID,
+		Name:       u.Name,
+		Email:      u.Email,
+		Role:       u.Role,
+		Department: u.Department,
+		Version:    u.Version,
+		CreatedAt:  timestamppb.New(u.CreatedAt),
+		UpdatedAt:  timestamppb.New(u.UpdatedAt),
+	}
+	if u.DeletedAt.Valid {
+		res.DeletedAt = timestamppb.New(u.DeletedAt.Time)
+	}
+	return res
+}
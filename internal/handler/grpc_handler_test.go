@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// 古いバージョンでの更新は codes.Aborted（再取得してやり直す）になる
+func TestUserGRPCVersionConflict(t *testing.T) {
+	s := NewUserGRPCServer(newTestUserUsecase(t))
+	ctx := auth.WithUser(context.Background(), &entity.User{ID: "admin", Role: entity.RoleAdmin})
+
+	patched, err := s.PatchUser(ctx, &pb.PatchUserRequest{
+		User:       &pb.User{Id: "alice", Name: "Alice Liddell", Version: 1},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	if err != nil || patched.GetUser().GetVersion() != 2 {
+		t.Fatalf("PatchUser() = %v, %v, want version 2", patched, err)
+	}
+
+	_, err = s.PatchUser(ctx, &pb.PatchUserRequest{
+		User:       &pb.User{Id: "alice", Name: "A", Version: 1},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("PatchUser(stale) = %v, want Aborted", err)
+	}
+	_, err = s.UpdateUser(ctx, &pb.UpdateUserRequest{
+		User: &pb.User{Id: "alice", AuthId: "a-alice", Name: "A", Email: "alice@example.com", Role: entity.RoleEmployee, Version: 1},
+	})
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("UpdateUser(stale) = %v, want Aborted", err)
+	}
+	_, err = s.UpdateUser(ctx, &pb.UpdateUserRequest{
+		User: &pb.User{Id: "none", AuthId: "a-none", Name: "A", Email: "none@example.com", Role: entity.RoleEmployee, Version: 1},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("UpdateUser(missing) = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/health"
+	"github.com/enkazu1116/go_home/internal/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// gRPCのヘルスチェックで liveness を返すサービス名
+// 空のサービス名と各サービスの名前は readiness を返す
+const healthLivenessService = "liveness"
+
+// Watch で状態を確認する間隔
+const healthWatchInterval = 5 * time.Second
+
+// HealthGRPCServerはgRPCのヘルスチェック（grpc.health.v1.Health）のハンドラー
+type HealthGRPCServer struct {
+	healthpb.UnimplementedHealthServer
+	Checker *health.Checker
+}
+
+// NewHealthGRPCServerはHealthGRPCServerを生成
+func NewHealthGRPCServer(c *health.Checker) *HealthGRPCServer {
+	return &HealthGRPCServer{Checker: c}
+}
+
+// Check: rpc Check
+// 知らないサービス名は NotFound を返す
+func (s *HealthGRPCServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	st, ok := s.servingStatus(ctx, req.GetService())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+// List: rpc List
+// すべてのサービスの状態を返す
+func (s *HealthGRPCServer) List(ctx context.Context, _ *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	ready := servingStatus(s.Checker.Ready(ctx))
+	statuses := map[string]*healthpb.HealthCheckResponse{
+		healthLivenessService: {Status: servingStatus(s.Checker.Live(ctx))},
+	}
+	for _, name := range healthReadinessServices() {
+		statuses[name] = &healthpb.HealthCheckResponse{Status: ready}
+	}
+	return &healthpb.HealthListResponse{Statuses: statuses}, nil
+}
+
+// Watch: rpc Watch
+// 最初に今の状態を送り、以降は状態が変わったときに送る
+// 停止中は NOT_SERVING を送ってから終わる
+func (s *HealthGRPCServer) Watch(req *healthpb.HealthCheckRequest, stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse]) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		st, ok := s.servingStatus(ctx, req.GetService())
+		if !ok {
+			st = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if st != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: st}); err != nil {
+				return err
+			}
+			last = st
+		}
+		if s.Checker.ShuttingDown() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.Checker.Done():
+		case <-ticker.C:
+		}
+	}
+}
+
+// servingStatus はサービス名の状態を返す（知らないサービス名なら false）
+func (s *HealthGRPCServer) servingStatus(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	if service == healthLivenessService {
+		return servingStatus(s.Checker.Live(ctx)), true
+	}
+	for _, name := range healthReadinessServices() {
+		if service == name {
+			return servingStatus(s.Checker.Ready(ctx)), true
+		}
+	}
+	return healthpb.HealthCheckResponse_UNKNOWN, false
+}
+
+// healthReadinessServices は readiness を返すサービス名（空はサーバー全体）
+func healthReadinessServices() []string {
+	return []string{"", pb.UserService_ServiceDesc.ServiceName, pb.AttendanceService_ServiceDesc.ServiceName}
+}
+
+func servingStatus(report health.Report) healthpb.HealthCheckResponse_ServingStatus {
+	if report.OK() {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
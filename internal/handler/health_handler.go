@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/enkazu1116/go_home/internal/health"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// HealthHandlerはヘルスチェック用のHTTPハンドラー
+type HealthHandler struct {
+	Checker *health.Checker
+}
+
+// NewHealthHandlerはHealthHandlerを生成
+func NewHealthHandler(c *health.Checker) *HealthHandler {
+	return &HealthHandler{Checker: c}
+}
+
+// ルーティング設定
+// オーケストレーター・ロードバランサーが確認するため認証なし
+func (h *HealthHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/healthz", h.Live)
+	r.Get("/readyz", h.Ready)
+}
+
+// GET /healthz
+// プロセスが動いているか（失敗したら再起動する）
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, h.Checker.Live(r.Context()))
+}
+
+// GET /readyz
+// リクエストを受け付けられるか（失敗したら振り分け先から外す）
+// DB・マイグレーション・ワーカーを確認し、停止中は失敗にする
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, h.Checker.Ready(r.Context()))
+}
+
+// writeHealthReport は結果を返す（失敗なら 503）
+func writeHealthReport(w http.ResponseWriter, report health.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.OK() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -2,32 +2,69 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/enkazu1116/go_home/internal/auth"
 	"github.com/enkazu1116/go_home/internal/domain"
 	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 // UserHandlerはUser用のHTTPハンドラー
 type UserHandler struct {
 	Usecase domain.UserUsecase
+	Imports domain.UserImportUsecase
 }
 
 // NewUserHandlerはUserHandlerを生成
 // UserUsecaseを引数に受け取り、UserHandlerのUsecaseフィールドにセットする
-func NewUserHandler(u domain.UserUsecase) *UserHandler {
-	return &UserHandler{Usecase: u}
+func NewUserHandler(u domain.UserUsecase, imports domain.UserImportUsecase) *UserHandler {
+	return &UserHandler{Usecase: u, Imports: imports}
 }
 
 // ルーティング設定
 func (h *UserHandler) RegisterRoutes(r chi.Router) {
-	r.Post("/users", h.CreateUser)
 	r.Get("/users", h.ListUsers)
 	r.Get("/users/{id}", h.GetUser)
-	r.Put("/users/{id}", h.UpdateUser)
-	r.Delete("/users/{id}", h.DeleteUser)
+	// PATCHは本人か管理者（本人が変えられるのは名前・メールアドレスだけ）
+	r.With(auth.RequireUser).Patch("/users/{id}", h.PatchUser)
+
+	// ロールは認可に使うため、ユーザーの作成・置き換え・削除は管理者のみ
+	r.With(auth.RequireRole(entity.RoleAdmin)).Post("/users", h.CreateUser)
+	r.With(auth.RequireRole(entity.RoleAdmin)).Put("/users/{id}", h.UpdateUser)
+	r.With(auth.RequireRole(entity.RoleAdmin)).Delete("/users/{id}", h.DeleteUser)
+
+	// 論理削除済みユーザーの復元・物理削除は管理者のみ
+	r.With(auth.RequireRole(entity.RoleAdmin)).Post("/users/{id}/restore", h.RestoreUser)
+	r.With(auth.RequireRole(entity.RoleAdmin)).Delete("/users/{id}/purge", h.PurgeUser)
+
+	// CSVからの一括登録は管理者のみ
+	r.With(auth.RequireRole(entity.RoleAdmin)).Post("/users/import", h.ImportUsers)
+}
+
+// include_deleted クエリを解釈する
+// 論理削除済みユーザーの参照は管理者のみ許可するため、それ以外は403を返す
+func includeDeleted(w http.ResponseWriter, r *http.Request) (include bool, ok bool) {
+	v := r.URL.Query().Get("include_deleted")
+	if v == "" {
+		return false, true
+	}
+	include, err := strconv.ParseBool(v)
+	if err != nil {
+		http.Error(w, "include_deleted must be a boolean", http.StatusBadRequest)
+		return false, false
+	}
+	if include && !auth.HasRole(r.Context(), entity.RoleAdmin) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false, false
+	}
+	return include, true
 }
 
 // CreateUser: POST /users
@@ -37,8 +74,12 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	// IDを省略した場合は gRPC の CreateUser と同じく生成する
+	if req.ID == "" {
+		req.ID = uuid.NewString()
+	}
 	if err := h.Usecase.CreateUser(r.Context(), req); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -47,7 +88,17 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 // ListUsers: GET /users
 func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.Usecase.FindAllUser(r.Context())
+	include, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+	var users []entity.User
+	var err error
+	if include {
+		users, err = h.Usecase.FindAllUserIncludeDeleted(r.Context())
+	} else {
+		users, err = h.Usecase.FindAllUser(r.Context())
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -58,15 +109,33 @@ func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 // GetUser: GET /users/{id}
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	user, err := h.Usecase.FindFirst(r.Context(), id)
+	include, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+	var user *entity.User
+	var err error
+	if include {
+		user, err = h.Usecase.FindFirstIncludeDeleted(r.Context(), id)
+	} else {
+		user, err = h.Usecase.FindFirst(r.Context(), id)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	etag := formatETag(user.Version)
+	w.Header().Set("ETag", etag)
+	if matchesIfNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 	json.NewEncoder(w).Encode(user)
 }
 
 // UpdateUser: PUT /users/{id}
+// 更新対象のバージョンは If-Match ヘッダー（GETで返したETag）か、ボディの Version で指定する
+// どちらも無い場合は、他者の更新を上書きしてしまうため 428 を返す
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	var req entity.User
@@ -78,11 +147,107 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	req.ID = id
-	if err := h.Usecase.UpdateUser(r.Context(), req); err != nil {
+
+	version, ifMatch, err := versionFromIfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ifMatch {
+		req.Version = version
+	}
+	if req.Version == 0 {
+		http.Error(w, "If-Match header or Version is required", http.StatusPreconditionRequired)
+		return
+	}
+
+	h.updateAndRespond(w, r, req, ifMatch)
+}
+
+// 管理者がPATCHで変更できるユーザーのフィールド
+// AuthIDは認証基盤と紐づくため、PATCHでは変更させない
+var userPatchAllowlist = patchAllowlist{
+	"Name":       {},
+	"Email":      {},
+	"Role":       {},
+	"Department": {nullable: true},
+}
+
+// 本人がPATCHで変更できるフィールド
+// ロールは認可に、部署はマネージャーの範囲・打刻ポリシーに使うため、本人には変更させない
+var userSelfPatchAllowlist = patchAllowlist{
+	"Name":  {},
+	"Email": {},
+}
+
+// PatchUser: PATCH /users/{id}
+// ボディは JSON Merge Patch (RFC 7396)。送ったフィールドだけが更新される
+// 更新対象のバージョンは If-Match ヘッダーで必ず指定する
+// 管理者以外は本人だけを、userSelfPatchAllowlist のフィールドだけ変更できる
+func (h *UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	allowed := userPatchAllowlist
+	if !auth.HasRole(r.Context(), entity.RoleAdmin) {
+		if self, _ := auth.UserFrom(r.Context()); self.ID != id {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		allowed = userSelfPatchAllowlist
+	}
+	if !isMergePatch(r) {
+		http.Error(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+		return
+	}
+	version, ifMatch, err := versionFromIfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ifMatch {
+		http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.Usecase.FindFirst(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	var patched entity.User
+	if err := applyMergePatch(current, patch, allowed, &patched); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	patched.ID = id
+	patched.Version = version
+	h.updateAndRespond(w, r, patched, true)
+}
+
+// 更新を実行し、更新後のユーザーとETagを返す
+// If-Matchで指定されたバージョンが古い場合は 412、ボディのバージョンが古い場合は 409 を返す
+func (h *UserHandler) updateAndRespond(w http.ResponseWriter, r *http.Request, user entity.User, ifMatch bool) {
+	if err := h.Usecase.UpdateUser(r.Context(), user); err != nil {
+		status := statusFromError(err, http.StatusInternalServerError)
+		if ifMatch && errors.Is(err, repository.ErrVersionConflict) {
+			status = http.StatusPreconditionFailed
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// 更新後の値（新しいバージョン）を返す
+	updated, err := h.Usecase.FindFirst(r.Context(), user.ID)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	json.NewEncoder(w).Encode(req)
+	w.Header().Set("ETag", formatETag(updated.Version))
+	json.NewEncoder(w).Encode(updated)
 }
 
 // DeleteUser: DELETE /users/{id}
@@ -90,8 +255,34 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	user := entity.User{ID: id}
 	if err := h.Usecase.DeleteUser(r.Context(), user); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreUser: POST /users/{id}/restore
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.Usecase.RestoreUser(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	user, err := h.Usecase.FindFirst(r.Context(), id)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("ETag", formatETag(user.Version))
+	json.NewEncoder(w).Encode(user)
+}
+
+// PurgeUser: DELETE /users/{id}/purge
+func (h *UserHandler) PurgeUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.Usecase.PurgeUser(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
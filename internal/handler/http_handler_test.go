@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// newTestUserUsecase は SQLite の上にユーザーのユースケースを作り、alice（バージョン1）を登録する
+func newTestUserUsecase(t *testing.T) domain.UserUsecase {
+	t.Helper()
+	db := openTestDB(t)
+	users := domain.NewUserUsecase(repository.NewTimeIsMoneyRepository(db), repository.NewAuditRepository(db), repository.NewOutboxRepository(db), repository.NewUnitOfWork(db))
+	alice := entity.User{ID: "alice", AuthID: "a-alice", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee}
+	if err := users.CreateUser(context.Background(), alice); err != nil {
+		t.Fatal(err)
+	}
+	return users
+}
+
+func TestUserETagPreconditions(t *testing.T) {
+	users := newTestUserUsecase(t)
+	h := NewUserHandler(users, nil)
+	admin := &entity.User{ID: "admin", Role: entity.RoleAdmin}
+
+	// GET は現在のバージョンを ETag で返す
+	rec := serveAs(h.RegisterRoutes, admin, httptest.NewRequest(http.MethodGet, "/users/alice", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("GET: status = %d, ETag = %q, want 200 \"1\"", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		ifMatch string
+		body    string
+		status  int
+		etag    string
+	}{
+		{"patch without If-Match", http.MethodPatch, "", `{"Name":"A"}`, http.StatusPreconditionRequired, ""},
+		{"patch with If-Match *", http.MethodPatch, "*", `{"Name":"A"}`, http.StatusBadRequest, ""},
+		{"patch with stale If-Match", http.MethodPatch, `"7"`, `{"Name":"A"}`, http.StatusPreconditionFailed, ""},
+		{"patch", http.MethodPatch, `"1"`, `{"Name":"Alice Liddell"}`, http.StatusOK, `"2"`},
+		{"patch with the previous ETag", http.MethodPatch, `"1"`, `{"Name":"A"}`, http.StatusPreconditionFailed, ""},
+		{"put without If-Match or Version", http.MethodPut, "", `{"AuthID":"a-alice","Name":"A","Email":"alice@example.com","Role":"employee"}`, http.StatusPreconditionRequired, ""},
+		{"put with stale If-Match", http.MethodPut, `"1"`, `{"AuthID":"a-alice","Name":"A","Email":"alice@example.com","Role":"employee"}`, http.StatusPreconditionFailed, ""},
+		{"put with stale Version", http.MethodPut, "", `{"AuthID":"a-alice","Name":"A","Email":"alice@example.com","Role":"employee","Version":1}`, http.StatusConflict, ""},
+		{"put", http.MethodPut, `W/"2"`, `{"AuthID":"a-alice","Name":"Alice","Email":"alice@example.com","Role":"employee"}`, http.StatusOK, `"3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/users/alice", strings.NewReader(tt.body))
+			if tt.method == http.MethodPatch {
+				req.Header.Set("Content-Type", "application/merge-patch+json")
+			}
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := serveAs(h.RegisterRoutes, admin, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (body = %s)", rec.Code, tt.status, rec.Body.String())
+			}
+			if rec.Header().Get("ETag") != tt.etag {
+				t.Errorf("ETag = %q, want %q", rec.Header().Get("ETag"), tt.etag)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var got entity.User
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if formatETag(got.Version) != tt.etag {
+				t.Errorf("body version = %d, want ETag %s", got.Version, tt.etag)
+			}
+		})
+	}
+}
+
+// 監査ログは管理者だけが参照できる
+func TestAuditLogsRequireAdmin(t *testing.T) {
+	db := openTestDB(t)
+	auditRepo := repository.NewAuditRepository(db)
+	users := domain.NewUserUsecase(repository.NewTimeIsMoneyRepository(db), auditRepo, repository.NewOutboxRepository(db), repository.NewUnitOfWork(db))
+	alice := entity.User{ID: "alice", AuthID: "a-alice", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee}
+	if err := users.CreateUser(context.Background(), alice); err != nil {
+		t.Fatal(err)
+	}
+	h := NewAuditHandler(domain.NewAuditUsecase(auditRepo))
+
+	tests := []struct {
+		name   string
+		user   *entity.User
+		status int
+	}{
+		{"unauthenticated", nil, http.StatusUnauthorized},
+		{"employee", &entity.User{ID: "alice", Role: entity.RoleEmployee}, http.StatusForbidden},
+		{"manager", &entity.User{ID: "m1", Role: entity.RoleManager, Department: "sales"}, http.StatusForbidden},
+		{"admin", &entity.User{ID: "admin", Role: entity.RoleAdmin}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveAs(h.RegisterRoutes, tt.user, httptest.NewRequest(http.MethodGet, "/audit-logs?entity_type=user&entity_id=alice", nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var logs []entity.AuditLog
+			if err := json.Unmarshal(rec.Body.Bytes(), &logs); err != nil {
+				t.Fatal(err)
+			}
+			if len(logs) != 1 || logs[0].EntityID != "alice" || logs[0].Action != "create" {
+				t.Errorf("logs = %+v, want the creation of alice", logs)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/go-chi/chi/v5"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QRコードの画像の大きさ（ピクセル）
+const kioskQRSize = 320
+
+// KioskHandlerは打刻端末用のHTTPハンドラー
+type KioskHandler struct {
+	Usecase domain.KioskUsecase
+}
+
+// NewKioskHandlerはKioskHandlerを生成
+func NewKioskHandler(u domain.KioskUsecase) *KioskHandler {
+	return &KioskHandler{Usecase: u}
+}
+
+// ルーティング設定
+// 端末の管理は管理者のみ。表示用のエンドポイントはJWTではなく端末の鍵（X-Kiosk-Key ヘッダー）で認証する
+func (h *KioskHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/kiosks/{id}/token", h.Token)
+	r.Get("/kiosks/{id}/qr.png", h.QRCode)
+	r.Get("/kiosks/{id}/display", h.Display)
+
+	r.Route("/kiosks", func(r chi.Router) {
+		r.Use(auth.RequireRole(entity.RoleAdmin))
+		r.Post("/", h.CreateKiosk)
+		r.Get("/", h.ListKiosks)
+		r.Get("/{id}", h.GetKiosk)
+		r.Patch("/{id}", h.PatchKiosk)
+		r.Delete("/{id}", h.DeleteKiosk)
+	})
+}
+
+// 登録時のレスポンス（鍵はこのレスポンスでのみ返す）
+type kioskCreated struct {
+	entity.Kiosk
+	Key string
+}
+
+// 端末の鍵のハッシュはレスポンスに含めない
+func redactKioskKeyHash(k *entity.Kiosk) {
+	k.KeyHash = ""
+}
+
+// CreateKiosk: POST /kiosks
+// ボディは Name・Location。鍵はサーバーで生成し、このレスポンスでのみ返す
+func (h *KioskHandler) CreateKiosk(w http.ResponseWriter, r *http.Request) {
+	var req entity.Kiosk
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	k, key, err := h.Usecase.Register(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	redactKioskKeyHash(k)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(kioskCreated{Kiosk: *k, Key: key})
+}
+
+// ListKiosks: GET /kiosks
+func (h *KioskHandler) ListKiosks(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Usecase.FindAll(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range list {
+		redactKioskKeyHash(&list[i])
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetKiosk: GET /kiosks/{id}
+func (h *KioskHandler) GetKiosk(w http.ResponseWriter, r *http.Request) {
+	k, err := h.Usecase.FindByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	redactKioskKeyHash(k)
+	json.NewEncoder(w).Encode(k)
+}
+
+// PATCHで変更できる端末のフィールド
+var kioskPatchAllowlist = patchAllowlist{
+	"Name":     {},
+	"Location": {nullable: true},
+	"Active":   {},
+}
+
+// PatchKiosk: PATCH /kiosks/{id}
+// ボディは JSON Merge Patch (RFC 7396)
+func (h *KioskHandler) PatchKiosk(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !isMergePatch(r) {
+		http.Error(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	current, err := h.Usecase.FindByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	var patched entity.Kiosk
+	if err := applyMergePatch(current, patch, kioskPatchAllowlist, &patched); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	patched.ID = id
+	if err := h.Usecase.Update(r.Context(), patched); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	updated, err := h.Usecase.FindByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redactKioskKeyHash(updated)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteKiosk: DELETE /kiosks/{id}
+func (h *KioskHandler) DeleteKiosk(w http.ResponseWriter, r *http.Request) {
+	if err := h.Usecase.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// issueToken は端末の鍵（X-Kiosk-Key ヘッダー）を確認してトークンを発行する
+// URL はアクセスログ・ブラウザの履歴・プロキシに残るため、鍵はクエリでは受け付けない
+func (h *KioskHandler) issueToken(w http.ResponseWriter, r *http.Request) (*domain.KioskToken, bool) {
+	t, err := h.Usecase.IssueToken(r.Context(), chi.URLParam(r, "id"), r.Header.Get("X-Kiosk-Key"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return nil, false
+	}
+	// トークンは切り替わるため、キャッシュさせない
+	w.Header().Set("Cache-Control", "no-store")
+	return t, true
+}
+
+// Token: GET /kiosks/{id}/token
+// 端末が自前でQRコードを描く場合に使う
+func (h *KioskHandler) Token(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.issueToken(w, r)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(t)
+}
+
+// QRCode: GET /kiosks/{id}/qr.png
+func (h *KioskHandler) QRCode(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.issueToken(w, r)
+	if !ok {
+		return
+	}
+	png, err := qrcode.Encode(t.Token, qrcode.Medium, kioskQRSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
+
+// 打刻端末の表示画面。トークンが切り替わる時刻に再読み込みする
+var kioskDisplayTemplate = template.Must(template.New("display").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>{{.Name}}</title>
+<style>body{font-family:sans-serif;text-align:center;margin-top:8vh}img{width:min(80vw,60vh)}</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<img src="data:image/png;base64,{{.PNG}}" alt="打刻用QRコード">
+<p>アプリでQRコードを読み取って打刻してください</p>
+</body>
+</html>
+`))
+
+// Display: GET /kiosks/{id}/display
+// 打刻端末のブラウザで開いたままにする画面（ブラウザに X-Kiosk-Key ヘッダーを付けさせる）
+func (h *KioskHandler) Display(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.issueToken(w, r)
+	if !ok {
+		return
+	}
+	k, err := h.Usecase.FindByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	png, err := qrcode.Encode(t.Token, qrcode.Medium, kioskQRSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	refresh := int(time.Until(t.RefreshAt)/time.Second) + 1
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	kioskDisplayTemplate.Execute(w, map[string]any{
+		"Name":    k.Name,
+		"Refresh": refresh,
+		"PNG":     template.URL(base64.StdEncoding.EncodeToString(png)),
+	})
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	jsonmerge "github.com/apapsch/go-jsonmerge/v2"
+)
+
+// PATCHで変更を許可するフィールド
+// キーはJSON上のフィールド名（エンティティのフィールド名そのまま）
+// nullable が false のフィールドに null を送るとエラーにする
+type patchField struct {
+	nullable bool
+}
+
+type patchAllowlist map[string]patchField
+
+// applyMergePatch は RFC 7396 (JSON Merge Patch) を current に適用し、結果を out にデコードする
+// パッチのキーは大文字小文字を区別せずに許可リストと照合し、許可されていないキーがあればエラーを返す
+// null は「値を削除する」という意味なので、ゼロ値として扱う
+func applyMergePatch(current any, patch []byte, allowed patchAllowlist, out any) error {
+	var doc map[string]any
+	if err := json.Unmarshal(patch, &doc); err != nil {
+		return fmt.Errorf("merge patch must be a JSON object: %w", err)
+	}
+
+	normalized := make(map[string]any, len(doc))
+	for key, value := range doc {
+		name, field, ok := allowed.lookup(key)
+		if !ok {
+			return fmt.Errorf("field %q cannot be patched", key)
+		}
+		if value == nil && !field.nullable {
+			return fmt.Errorf("field %q cannot be null", key)
+		}
+		normalized[name] = value
+	}
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	patchBuf, err := json.Marshal(normalized)
+	if err != nil {
+		return err
+	}
+	merger := jsonmerge.Merger{}
+	merged, err := merger.MergeBytes(data, patchBuf)
+	if err != nil {
+		return err
+	}
+	if len(merger.Errors) > 0 {
+		return merger.Errors[0]
+	}
+	return json.Unmarshal(merged, out)
+}
+
+// 大文字小文字を区別せずに許可リストを引く
+func (a patchAllowlist) lookup(key string) (string, patchField, bool) {
+	for name, field := range a {
+		if strings.EqualFold(name, key) {
+			return name, field, true
+		}
+	}
+	return "", patchField{}, false
+}
+
+// Content-Type が JSON Merge Patch かを判定する
+// 既存のクライアントのため application/json も受け付ける
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/merge-patch+json" || mediaType == "application/json"
+}
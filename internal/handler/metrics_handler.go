@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// MetricsHandlerはPrometheusのメトリクス用のHTTPハンドラー
+type MetricsHandler struct {
+	Registry *metrics.Registry
+	KPI      domain.KPIUsecase
+}
+
+// NewMetricsHandlerはMetricsHandlerを生成し、今日の勤怠の集計をメトリクスに登録する
+func NewMetricsHandler(reg *metrics.Registry, kpi domain.KPIUsecase) *MetricsHandler {
+	h := &MetricsHandler{Registry: reg, KPI: kpi}
+	reg.Register(h.collectKPI)
+	return h
+}
+
+// ルーティング設定
+// Prometheus がスクレイプするため認証なし（集計値だけで個人を特定できる値は含まない）
+func (h *MetricsHandler) RegisterRoutes(r chi.Router) {
+	r.Method(http.MethodGet, "/metrics", h.Registry.Handler())
+}
+
+// collectKPI はスクレイプのたびに今日の勤怠を集計する
+func (h *MetricsHandler) collectKPI(ctx context.Context) ([]metrics.Family, error) {
+	k, err := h.KPI.Today(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return []metrics.Family{
+		metrics.Gauge("go_home_attendance_checked_in_users", "Number of users currently checked in today (including on break).", float64(k.CheckedIn)),
+		metrics.Gauge("go_home_attendance_checked_out_users", "Number of users who have checked out today.", float64(k.CheckedOut)),
+		metrics.Gauge("go_home_attendance_late_today", "Number of late check-ins today.", float64(k.Late)),
+		metrics.Gauge("go_home_attendance_needs_review_today", "Number of today's attendances waiting for manager review.", float64(k.NeedsReview)),
+	}, nil
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// NotificationHandlerは通知設定用のHTTPハンドラー
+type NotificationHandler struct {
+	Usecase domain.NotificationUsecase
+}
+
+// NewNotificationHandlerはNotificationHandlerを生成
+func NewNotificationHandler(u domain.NotificationUsecase) *NotificationHandler {
+	return &NotificationHandler{Usecase: u}
+}
+
+// ルーティング設定
+// 通知設定は本人と管理者だけが参照・変更できる
+func (h *NotificationHandler) RegisterRoutes(r chi.Router) {
+	r.With(auth.RequireUser).Get("/users/{id}/notification-preferences", h.GetPreference)
+	r.With(auth.RequireUser).Put("/users/{id}/notification-preferences", h.PutPreference)
+}
+
+// 本人か管理者かを判定し、それ以外は403を返す
+func selfOrAdmin(w http.ResponseWriter, r *http.Request, userID string) bool {
+	user, _ := auth.UserFrom(r.Context())
+	if user.ID != userID && !auth.HasRole(r.Context(), entity.RoleAdmin) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// GetPreference: GET /users/{id}/notification-preferences
+func (h *NotificationHandler) GetPreference(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !selfOrAdmin(w, r, id) {
+		return
+	}
+	p, err := h.Usecase.GetPreference(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
+// PutPreference: PUT /users/{id}/notification-preferences
+// ボディは Locale（ja・en）・Email・Chat
+func (h *NotificationHandler) PutPreference(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !selfOrAdmin(w, r, id) {
+		return
+	}
+	var req entity.NotificationPreference
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.UserID = id
+	p, err := h.Usecase.UpdatePreference(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket の接続を保つために送る ping の間隔
+const presencePingInterval = 30 * time.Second
+
+// WebSocket で受け付けるメッセージの最大サイズ
+const presenceMaxMessageBytes = 4096
+
+// PresenceHandlerは在席ステータス用のHTTPハンドラー
+type PresenceHandler struct {
+	Usecase domain.PresenceUsecase
+}
+
+// NewPresenceHandlerはPresenceHandlerを生成
+func NewPresenceHandler(u domain.PresenceUsecase) *PresenceHandler {
+	return &PresenceHandler{Usecase: u}
+}
+
+// ルーティング設定
+// 在席ステータスはログインユーザー本人が設定し、同じ部署の人が見る
+func (h *PresenceHandler) RegisterRoutes(r chi.Router) {
+	r.With(auth.RequireUser).Get("/presence", h.ListPresence)
+	r.With(auth.RequireUser).Put("/presence", h.SetPresence)
+	r.With(auth.RequireUser).Delete("/presence", h.ClearPresence)
+	r.With(auth.RequireUser).Get("/presence/ws", h.WebSocket)
+}
+
+// presenceRoom は見る部署（部屋）を決める
+// 管理者は department クエリで他の部署も見られ、それ以外は自分の部署だけを見られる
+func presenceRoom(w http.ResponseWriter, r *http.Request) (string, bool) {
+	user, _ := auth.UserFrom(r.Context())
+	department, ok := r.URL.Query()["department"]
+	if !ok || department[0] == user.Department {
+		return user.Department, true
+	}
+	if user.Role != entity.RoleAdmin {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return "", false
+	}
+	return department[0], true
+}
+
+// 在席ステータスの設定のリクエスト
+type presenceRequest struct {
+	Status  string
+	Message string
+	// 有効期間（秒）。省略すると PRESENCE_TTL
+	TTLSeconds int
+}
+
+// ListPresence: GET /presence?department=xxx
+func (h *PresenceHandler) ListPresence(w http.ResponseWriter, r *http.Request) {
+	room, ok := presenceRoom(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Usecase.List(r.Context(), room, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// SetPresence: PUT /presence
+func (h *PresenceHandler) SetPresence(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFrom(r.Context())
+	var req presenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, err := h.Usecase.Set(r.Context(), user.ID, req.Status, req.Message, time.Duration(req.TTLSeconds)*time.Second, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	json.NewEncoder(w).Encode(s)
+}
+
+// ClearPresence: DELETE /presence
+func (h *PresenceHandler) ClearPresence(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFrom(r.Context())
+	if err := h.Usecase.Clear(r.Context(), user.ID); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WebSocket でサーバーから送るメッセージ
+// 接続時に部屋の一覧（snapshot）、その後は presence.updated・presence.cleared を送る
+type presenceMessage struct {
+	Type string
+	Data any `json:",omitempty"`
+}
+
+// WebSocket でクライアントから受け取るメッセージ
+// Type は set（presenceRequest と同じ項目を付ける）・clear・ping
+type presenceCommand struct {
+	Type string
+	presenceRequest
+}
+
+// WebSocket: GET /presence/ws?department=xxx
+// ブラウザからはクエリの access_token でJWTを渡す
+func (h *PresenceHandler) WebSocket(w http.ResponseWriter, r *http.Request) {
+	room, ok := presenceRoom(w, r)
+	if !ok {
+		return
+	}
+	srv := websocket.Server{
+		// JWTで認証しており Cookie は使わないため、Origin は確かめない
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = presenceMaxMessageBytes
+			h.serveWebSocket(ws.Request().Context(), ws, room)
+		},
+	}
+	srv.ServeHTTP(w, r)
+}
+
+// serveWebSocket は部屋の変化を送りながら、クライアントからのメッセージを処理する
+func (h *PresenceHandler) serveWebSocket(ctx context.Context, ws *websocket.Conn, room string) {
+	defer ws.Close()
+	user, _ := auth.UserFrom(ctx)
+	sub := h.Usecase.Subscribe(room)
+	defer sub.Close()
+
+	list, err := h.Usecase.List(ctx, room, time.Now())
+	if err != nil {
+		websocket.JSON.Send(ws, presenceMessage{Type: "error", Data: err.Error()})
+		return
+	}
+	if err := websocket.JSON.Send(ws, presenceMessage{Type: "snapshot", Data: list}); err != nil {
+		return
+	}
+
+	// 受信は別のゴルーチンで行う（ws.Close で Receive が戻る）
+	incoming := make(chan presenceCommand)
+	go func() {
+		defer close(incoming)
+		for {
+			var m presenceCommand
+			if err := websocket.JSON.Receive(ws, &m); err != nil {
+				return
+			}
+			select {
+			case incoming <- m:
+			case <-sub.Done():
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(presencePingInterval)
+	defer ping.Stop()
+	for {
+		var out presenceMessage
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done():
+			// 取りこぼした・サーバーの停止。クライアントは再接続して一覧を受け取り直す
+			return
+		case e := <-sub.Events():
+			out = presenceMessage{Type: e.Type, Data: e.Data}
+		case m, ok := <-incoming:
+			if !ok {
+				return
+			}
+			out = h.handleMessage(ctx, user.ID, m)
+		case <-ping.C:
+			out = presenceMessage{Type: "ping"}
+		}
+		if out.Type == "" {
+			continue
+		}
+		if err := websocket.JSON.Send(ws, out); err != nil {
+			return
+		}
+	}
+}
+
+// handleMessage はクライアントからのメッセージを処理し、返信を返す（返信が無ければ Type が空）
+// set・clear の結果は部屋のイベントとしても届く
+func (h *PresenceHandler) handleMessage(ctx context.Context, userID string, m presenceCommand) presenceMessage {
+	var err error
+	switch m.Type {
+	case "set":
+		_, err = h.Usecase.Set(ctx, userID, m.Status, m.Message, time.Duration(m.TTLSeconds)*time.Second, time.Now())
+	case "clear":
+		err = h.Usecase.Clear(ctx, userID)
+	case "ping":
+		return presenceMessage{Type: "pong"}
+	case "pong":
+	default:
+		return presenceMessage{Type: "error", Data: "unknown message type " + m.Type}
+	}
+	if err != nil {
+		return presenceMessage{Type: "error", Data: err.Error()}
+	}
+	return presenceMessage{}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/live"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/websocket"
+)
+
+var testJWTSecret = []byte("test-secret")
+
+// signToken は subject の HS256 の JWT を作る
+func signToken(subject string, exp time.Time) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(auth.Claims{Subject: subject, ExpiresAt: exp.Unix()})
+	payload := header + "." + enc.EncodeToString(claims)
+	mac := hmac.New(sha256.New, testJWTSecret)
+	mac.Write([]byte(payload))
+	return payload + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+// WebSocket はクエリの access_token で認証し、トークンのユーザーとして在席ステータスを扱う
+func TestPresenceWebSocketAuth(t *testing.T) {
+	db := openTestDB(t)
+	userRepo := repository.NewTimeIsMoneyRepository(db)
+	for _, u := range []entity.User{
+		{ID: "alice", AuthID: "a-alice", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee, Department: "sales"},
+		{ID: "admin", AuthID: "a-admin", Name: "Admin", Email: "admin@example.com", Role: entity.RoleAdmin, Department: "it"},
+	} {
+		if err := userRepo.CreateUser(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	presence := domain.NewPresenceUsecase(domain.PresenceConfig{DefaultTTL: time.Hour}, repository.NewUserStatusRepository(db), userRepo, live.NewBroker(db))
+	h := NewPresenceHandler(presence)
+	r := chi.NewRouter()
+	r.Use(auth.NewAuthenticator(auth.Config{JWTSecret: testJWTSecret}, userRepo).Middleware)
+	h.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	valid := signToken("a-alice", time.Now().Add(time.Hour))
+	tests := []struct {
+		name    string
+		path    string
+		upgrade bool
+		status  int
+	}{
+		{"no token", "/presence/ws", true, http.StatusUnauthorized},
+		{"bad signature", "/presence/ws?access_token=" + valid[:len(valid)-2] + "xx", true, http.StatusUnauthorized},
+		{"expired", "/presence/ws?access_token=" + signToken("a-alice", time.Now().Add(-time.Minute)), true, http.StatusUnauthorized},
+		{"unknown user", "/presence/ws?access_token=" + signToken("a-nobody", time.Now().Add(time.Hour)), true, http.StatusUnauthorized},
+		{"other department", "/presence/ws?department=dev&access_token=" + valid, true, http.StatusForbidden},
+		// WebSocket 以外のリクエストはクエリのトークンを受け付けない
+		{"query token without upgrade", "/presence?access_token=" + valid, false, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+			if tt.upgrade {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+				req.Header.Set("Sec-WebSocket-Version", "13")
+				req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+		})
+	}
+
+	t.Run("connected", func(t *testing.T) {
+		url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/presence/ws?access_token=" + valid
+		ws, err := websocket.Dial(url, "", srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ws.Close()
+		ws.SetDeadline(time.Now().Add(5 * time.Second))
+
+		var m struct {
+			Type string
+			Data json.RawMessage
+		}
+		if err := websocket.JSON.Receive(ws, &m); err != nil || m.Type != "snapshot" {
+			t.Fatalf("first message = %+v, %v, want snapshot", m, err)
+		}
+		// 設定した在席ステータスはトークンのユーザーのものになり、自分の部署の部屋に届く
+		if err := websocket.JSON.Send(ws, map[string]any{"Type": "set", "Status": entity.StatusInMeeting}); err != nil {
+			t.Fatal(err)
+		}
+		if err := websocket.JSON.Receive(ws, &m); err != nil || m.Type != domain.EventPresenceUpdated {
+			t.Fatalf("message = %+v, %v, want %s", m, err, domain.EventPresenceUpdated)
+		}
+		var s entity.UserStatus
+		if err := json.Unmarshal(m.Data, &s); err != nil {
+			t.Fatal(err)
+		}
+		if s.UserID != "alice" || s.Status != entity.StatusInMeeting {
+			t.Errorf("status = %+v, want alice in a meeting", s)
+		}
+	})
+
+	// 管理者は他の部署の部屋にも接続できる
+	t.Run("admin other department", func(t *testing.T) {
+		url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/presence/ws?department=sales&access_token=" + signToken("a-admin", time.Now().Add(time.Hour))
+		ws, err := websocket.Dial(url, "", srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ws.Close()
+		ws.SetDeadline(time.Now().Add(5 * time.Second))
+		var m struct{ Type string }
+		if err := websocket.JSON.Receive(ws, &m); err != nil || m.Type != "snapshot" {
+			t.Fatalf("first message = %+v, %v, want snapshot", m, err)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PunchLogHandlerは打刻ログ（ハッシュチェーン）用のHTTPハンドラー
+type PunchLogHandler struct {
+	Usecase domain.PunchLogUsecase
+}
+
+// NewPunchLogHandlerはPunchLogHandlerを生成
+func NewPunchLogHandler(u domain.PunchLogUsecase) *PunchLogHandler {
+	return &PunchLogHandler{Usecase: u}
+}
+
+// ルーティング設定
+// 打刻ログの検証は管理者のみ
+func (h *PunchLogHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/punch-log", func(r chi.Router) {
+		r.Use(auth.RequireRole(entity.RoleAdmin))
+		r.Get("/events", h.ListEvents)
+		r.Get("/verify", h.Verify)
+		r.Get("/checkpoints", h.ListCheckpoints)
+		r.Post("/checkpoints", h.CreateCheckpoint)
+		r.Get("/checkpoints/{id}/verify", h.VerifyCheckpoint)
+	})
+}
+
+// ListEvents: GET /punch-log/events?user_id=xxx
+func (h *PunchLogHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	list, err := h.Usecase.Events(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Verify: GET /punch-log/verify?user_id=xxx
+// user_id を省略した場合は全ユーザーを検証する
+// 問題が見つかった場合も 200 で返し、valid=false で知らせる
+func (h *PunchLogHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		report, err := h.Usecase.Verify(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+	reports, err := h.Usecase.VerifyAll(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(reports)
+}
+
+// ListCheckpoints: GET /punch-log/checkpoints?limit=
+func (h *PunchLogHandler) ListCheckpoints(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "limit must be an integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	list, err := h.Usecase.ListCheckpoints(r.Context(), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateCheckpoint: POST /punch-log/checkpoints
+func (h *PunchLogHandler) CreateCheckpoint(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Usecase.CreateCheckpoint(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// VerifyCheckpoint: GET /punch-log/checkpoints/{id}/verify
+func (h *PunchLogHandler) VerifyCheckpoint(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	report, err := h.Usecase.VerifyCheckpoint(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PunchPolicyHandlerは打刻ポリシー用のHTTPハンドラー
+type PunchPolicyHandler struct {
+	Usecase domain.PunchPolicyUsecase
+}
+
+// NewPunchPolicyHandlerはPunchPolicyHandlerを生成
+func NewPunchPolicyHandler(u domain.PunchPolicyUsecase) *PunchPolicyHandler {
+	return &PunchPolicyHandler{Usecase: u}
+}
+
+// ルーティング設定
+// 打刻ポリシーの管理は管理者のみ
+func (h *PunchPolicyHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/punch-policies", func(r chi.Router) {
+		r.Use(auth.RequireRole(entity.RoleAdmin))
+		r.Post("/", h.CreatePunchPolicy)
+		r.Get("/", h.ListPunchPolicies)
+		r.Get("/{id}", h.GetPunchPolicy)
+		r.Patch("/{id}", h.PatchPunchPolicy)
+		r.Delete("/{id}", h.DeletePunchPolicy)
+	})
+}
+
+// CreatePunchPolicy: POST /punch-policies
+// UserID・Department のどちらも省略したポリシーは全員の既定になる
+func (h *PunchPolicyHandler) CreatePunchPolicy(w http.ResponseWriter, r *http.Request) {
+	var req entity.PunchPolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := h.Usecase.Create(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+// ListPunchPolicies: GET /punch-policies
+func (h *PunchPolicyHandler) ListPunchPolicies(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Usecase.FindAll(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetPunchPolicy: GET /punch-policies/{id}
+func (h *PunchPolicyHandler) GetPunchPolicy(w http.ResponseWriter, r *http.Request) {
+	p, err := h.Usecase.FindByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
+// PATCHで変更できる打刻ポリシーのフィールド
+// 配列（OfficeCIDRs・Geofences）は丸ごと置き換える
+var punchPolicyPatchAllowlist = patchAllowlist{
+	"Name":        {},
+	"UserID":      {nullable: true},
+	"Department":  {nullable: true},
+	"OfficeCIDRs": {nullable: true},
+	"Geofences":   {nullable: true},
+	"AllowRemote": {},
+	"OnViolation": {},
+}
+
+// PatchPunchPolicy: PATCH /punch-policies/{id}
+// ボディは JSON Merge Patch (RFC 7396)
+func (h *PunchPolicyHandler) PatchPunchPolicy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !isMergePatch(r) {
+		http.Error(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	current, err := h.Usecase.FindByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	var patched entity.PunchPolicy
+	if err := applyMergePatch(current, patch, punchPolicyPatchAllowlist, &patched); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	patched.ID = id
+	if err := h.Usecase.Update(r.Context(), patched); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	updated, err := h.Usecase.FindByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeletePunchPolicy: DELETE /punch-policies/{id}
+func (h *PunchPolicyHandler) DeletePunchPolicy(w http.ResponseWriter, r *http.Request) {
+	if err := h.Usecase.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
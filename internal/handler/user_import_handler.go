@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/enkazu1116/go_home/internal/domain"
+)
+
+// アップロードできるCSVの大きさの上限
+const maxUserImportSize = 10 << 20
+
+// ImportUsers: POST /users/import
+// multipart/form-data の file にCSV（1行目はヘッダー）を送る
+// オプションはクエリかフォームで指定する
+//   - match_by: 既存のユーザーと照合する項目（email（既定）・auth_id）。一致したユーザーは更新する
+//   - map: 列の対応付け（例: Email=メール,Name=氏名）。指定しない項目は列名から推測する
+//   - dry_run: 結果だけを返し、登録・更新は取り消す
+//   - all_or_nothing: 失敗した行が1つでもあればすべて取り消し、422 を返す
+//
+// Accept: text/csv の場合は、失敗した行だけのCSV（line・error 列付き）をダウンロードさせる
+func (h *UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUserImportSize)
+	if err := r.ParseMultipartForm(maxUserImportSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	opts := domain.UserImportOptions{MatchBy: r.FormValue("match_by")}
+	if opts.DryRun, err = formBool(r, "dry_run"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.AllOrNothing, err = formBool(r, "all_or_nothing"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Mapping, err = domain.ParseUserImportMapping(r.Form["map"]...); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.Imports.Import(r.Context(), file, opts)
+	status := http.StatusOK
+	if errors.Is(err, domain.ErrUserImportRejected) {
+		status = http.StatusUnprocessableEntity
+	} else if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="user-import-errors.csv"`)
+		w.WriteHeader(status)
+		report.WriteErrorCSV(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// formBool はクエリ・フォームの真偽値を解釈する（無ければ false）
+func formBool(r *http.Request, key string) (bool, error) {
+	v := r.FormValue(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean", key)
+	}
+	return b, nil
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WebhookHandlerはWebhook用のHTTPハンドラー
+type WebhookHandler struct {
+	Usecase domain.WebhookUsecase
+}
+
+// NewWebhookHandlerはWebhookHandlerを生成
+func NewWebhookHandler(u domain.WebhookUsecase) *WebhookHandler {
+	return &WebhookHandler{Usecase: u}
+}
+
+// ルーティング設定
+// Webhookの管理は管理者のみ
+func (h *WebhookHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(auth.RequireRole(entity.RoleAdmin))
+		r.Post("/", h.CreateSubscription)
+		r.Get("/", h.ListSubscriptions)
+		r.Get("/deliveries", h.ListDeliveries)
+		r.Post("/deliveries/{id}/redeliver", h.Redeliver)
+		r.Get("/{id}", h.GetSubscription)
+		r.Patch("/{id}", h.PatchSubscription)
+		r.Delete("/{id}", h.DeleteSubscription)
+	})
+}
+
+// 署名の鍵は登録時のレスポンスでのみ返す
+func redactSecret(s *entity.WebhookSubscription) {
+	s.Secret = ""
+}
+
+// CreateSubscription: POST /webhooks
+// ボディは URL・Events（カンマ区切り）・Secret（省略時は生成）
+func (h *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req entity.WebhookSubscription
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, err := h.Usecase.CreateSubscription(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// ListSubscriptions: GET /webhooks
+func (h *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Usecase.ListSubscriptions(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range list {
+		redactSecret(&list[i])
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetSubscription: GET /webhooks/{id}
+func (h *WebhookHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	s, err := h.Usecase.FindSubscription(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	redactSecret(s)
+	json.NewEncoder(w).Encode(s)
+}
+
+// PATCHで変更できる購読のフィールド
+var webhookPatchAllowlist = patchAllowlist{
+	"URL":    {},
+	"Events": {nullable: true},
+	"Secret": {},
+	"Active": {},
+}
+
+// PatchSubscription: PATCH /webhooks/{id}
+// ボディは JSON Merge Patch (RFC 7396)
+func (h *WebhookHandler) PatchSubscription(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !isMergePatch(r) {
+		http.Error(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	current, err := h.Usecase.FindSubscription(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	var patched entity.WebhookSubscription
+	if err := applyMergePatch(current, patch, webhookPatchAllowlist, &patched); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	patched.ID = id
+	if err := h.Usecase.UpdateSubscription(r.Context(), patched); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	updated, err := h.Usecase.FindSubscription(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redactSecret(updated)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteSubscription: DELETE /webhooks/{id}
+func (h *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	if err := h.Usecase.DeleteSubscription(r.Context(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries: GET /webhooks/deliveries?subscription_id=&status=&limit=
+// status=dead でデッドレターを確認できる
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.WebhookDeliveryFilter{
+		SubscriptionID: q.Get("subscription_id"),
+		Status:         q.Get("status"),
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "limit must be an integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+	list, err := h.Usecase.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// Redeliver: POST /webhooks/deliveries/{id}/redeliver
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	d, err := h.Usecase.Redeliver(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), statusFromError(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(d)
+}
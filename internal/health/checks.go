@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+// DBCheck は DB に接続できるかを確認する
+func DBCheck(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// MigrationCheck はエンティティのテーブル・カラムがすべて DB にあるかを確認する
+// 一度すべて揃ったことを確認した後は、スキーマを戻すことはないため確認しない
+func MigrationCheck(db *gorm.DB) CheckFunc {
+	var migrated atomic.Bool
+	return func(ctx context.Context) error {
+		if migrated.Load() {
+			return nil
+		}
+		// 接続できないとテーブルが無いように見えるため、先に接続を確認する
+		if err := DBCheck(db)(ctx); err != nil {
+			return err
+		}
+		pending, err := PendingMigrations(db.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+		}
+		migrated.Store(true)
+		return nil
+	}
+}
+
+// PendingMigrations は DB に無いテーブル・カラム（"table" か "table.column"）と、消していない古い一意制約・平文の端末の鍵を返す
+// AutoMigrate が作るものだけを確認し、型・インデックスの違いは確認しない
+func PendingMigrations(db *gorm.DB) ([]string, error) {
+	var pending []string
+	m := db.Migrator()
+	for _, model := range entity.Models() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		table := stmt.Schema.Table
+		if !m.HasTable(model) {
+			pending = append(pending, table)
+			continue
+		}
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName == "" || f.IgnoreMigration {
+				continue
+			}
+			if !m.HasColumn(model, f.DBName) {
+				pending = append(pending, table+"."+f.DBName)
+			}
+		}
+	}
+	legacy, err := dbinfra.LegacyUserUniques(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range legacy {
+		pending = append(pending, "drop unique "+name)
+	}
+	if dbinfra.HasLegacyKioskSecrets(db) {
+		pending = append(pending, "hash kiosks.secret")
+	}
+	return pending, nil
+}
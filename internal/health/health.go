@@ -0,0 +1,240 @@
+// Package health はプロセスの死活（liveness）と受け付けの可否（readiness）を判定する
+// readiness は DB への接続・未適用のマイグレーション・バックグラウンドのワーカーを確認し、停止中は失敗にする
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 確認の結果
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Config はヘルスチェックの設定
+type Config struct {
+	// 1つの確認にかける時間の上限
+	CheckTimeout time.Duration
+	// 停止の合図を受けてから readiness を失敗にしたまま待つ時間
+	// オーケストレーターが振り分け先から外すまで、新しいリクエストも受け付ける
+	DrainDelay time.Duration
+}
+
+// NewConfigFromEnv は環境変数からヘルスチェックの設定を読み込む
+// HEALTH_CHECK_TIMEOUT: 1つの確認にかける時間の上限（既定 2s）
+// SHUTDOWN_DRAIN_DELAY: 停止の合図から HTTP・gRPC サーバーを止めるまで待つ時間（既定 0s）
+func NewConfigFromEnv() Config {
+	cfg := Config{CheckTimeout: 2 * time.Second}
+	if v := os.Getenv("HEALTH_CHECK_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slog.Warn("invalid HEALTH_CHECK_TIMEOUT, using default", "value", v, "default", cfg.CheckTimeout)
+		} else {
+			cfg.CheckTimeout = d
+		}
+	}
+	if v := os.Getenv("SHUTDOWN_DRAIN_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			slog.Warn("invalid SHUTDOWN_DRAIN_DELAY, using default", "value", v, "default", cfg.DrainDelay)
+		} else {
+			cfg.DrainDelay = d
+		}
+	}
+	return cfg
+}
+
+// CheckFunc は依存先を確認し、使えなければエラーを返す
+type CheckFunc func(ctx context.Context) error
+
+// Result は1つの確認の結果
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report はヘルスチェックの結果
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// OK はすべての確認が通ったかどうか
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker は登録した確認を実行する
+type Checker struct {
+	cfg      Config
+	mu       sync.Mutex
+	liveness []check
+	ready    []check
+	// ワーカーの名前ごとの止まった理由（動いていれば nil）
+	workers  map[string]error
+	running  sync.WaitGroup
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// NewChecker はCheckerを生成し、DB への接続と未適用のマイグレーションの確認を readiness に登録する
+func NewChecker(cfg Config, db *gorm.DB) *Checker {
+	c := &Checker{cfg: cfg, workers: map[string]error{}, stopped: make(chan struct{})}
+	c.AddReadiness("database", DBCheck(db))
+	c.AddReadiness("migrations", MigrationCheck(db))
+	c.AddLiveness("workers", c.checkWorkers)
+	return c
+}
+
+// Config はヘルスチェックの設定を返す
+func (c *Checker) Config() Config {
+	return c.cfg
+}
+
+// AddLiveness は liveness の確認を登録する（readiness でも確認する）
+// 失敗すると再起動されるため、再起動で直るもの（止まったワーカーなど）だけを登録する
+func (c *Checker) AddLiveness(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness = append(c.liveness, check{name: name, fn: fn})
+}
+
+// AddReadiness は readiness の確認を登録する
+func (c *Checker) AddReadiness(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready = append(c.ready, check{name: name, fn: fn})
+}
+
+// SetShuttingDown は停止中にして、以降の readiness を失敗にする
+func (c *Checker) SetShuttingDown() {
+	c.stopOnce.Do(func() { close(c.stopped) })
+}
+
+// Done は停止中になると閉じるチャネルを返す
+func (c *Checker) Done() <-chan struct{} {
+	return c.stopped
+}
+
+// ShuttingDown は停止中かどうか
+func (c *Checker) ShuttingDown() bool {
+	select {
+	case <-c.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// Live は liveness の確認を実行する
+func (c *Checker) Live(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := append([]check(nil), c.liveness...)
+	c.mu.Unlock()
+	return c.run(ctx, checks)
+}
+
+// Ready は liveness と readiness の確認を実行する
+// 停止中は確認を実行せずに失敗にする
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.ShuttingDown() {
+		return Report{Status: StatusFail, Checks: []Result{{Name: "shutdown", Status: StatusFail, Error: "server is shutting down", Duration: "0s"}}}
+	}
+	c.mu.Lock()
+	checks := append(append([]check(nil), c.liveness...), c.ready...)
+	c.mu.Unlock()
+	return c.run(ctx, checks)
+}
+
+func (c *Checker) run(ctx context.Context, checks []check) Report {
+	report := Report{Status: StatusOK, Checks: make([]Result, 0, len(checks))}
+	for _, ch := range checks {
+		res := Result{Name: ch.name, Status: StatusOK}
+		start := time.Now()
+		cctx, cancel := context.WithTimeout(ctx, c.cfg.CheckTimeout)
+		err := ch.fn(cctx)
+		cancel()
+		res.Duration = time.Since(start).String()
+		if err != nil {
+			res.Status, res.Error = StatusFail, err.Error()
+			report.Status = StatusFail
+		}
+		report.Checks = append(report.Checks, res)
+	}
+	return report
+}
+
+// Go はワーカーを goroutine で動かし、panic したら liveness を失敗にする
+// panic はプロセスを落とさずに記録し、再起動はオーケストレーターに任せる
+// 設定が無いなどで何もせずに戻るワーカー（SMTP 未設定のメール送信など）は失敗にしない
+func (c *Checker) Go(ctx context.Context, name string, run func(ctx context.Context)) {
+	c.mu.Lock()
+	c.workers[name] = nil
+	c.mu.Unlock()
+	c.running.Add(1)
+	go func() {
+		defer c.running.Done()
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			err := fmt.Errorf("panic: %v", v)
+			slog.Error("background worker stopped", "worker", name, "err", err)
+			c.mu.Lock()
+			c.workers[name] = err
+			c.mu.Unlock()
+		}()
+		run(ctx)
+	}()
+}
+
+// Wait は Go で動かしたワーカーがすべて戻るまで待つ（ワーカーの ctx をキャンセルしてから呼ぶ）
+// ctx が先に終わった場合はそのエラーを返す
+func (c *Checker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkWorkers は止まったワーカーがあればエラーを返す
+func (c *Checker) checkWorkers(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var stopped []string
+	for name, err := range c.workers {
+		if err != nil {
+			stopped = append(stopped, name+": "+err.Error())
+		}
+	}
+	if len(stopped) > 0 {
+		sort.Strings(stopped)
+		return fmt.Errorf("workers stopped: %s", strings.Join(stopped, "; "))
+	}
+	return nil
+}
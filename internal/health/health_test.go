@@ -0,0 +1,166 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, migrate bool) *gorm.DB {
+	t.Helper()
+	db, err := dbinfra.OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrate {
+		if err := dbinfra.Migrate(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// result は名前の確認の結果を返す
+func result(t *testing.T, r Report, name string) Result {
+	t.Helper()
+	for _, res := range r.Checks {
+		if res.Name == name {
+			return res
+		}
+	}
+	t.Fatalf("check %s not in %+v", name, r)
+	return Result{}
+}
+
+func TestCheckerReady(t *testing.T) {
+	ctx := context.Background()
+	c := NewChecker(Config{CheckTimeout: time.Second}, openTestDB(t, true))
+	if r := c.Ready(ctx); !r.OK() || len(r.Checks) != 3 {
+		t.Fatalf("Ready() = %+v, want database, migrations and workers ok", r)
+	}
+
+	// 停止中は確認せずに失敗にし、liveness は通す
+	c.SetShuttingDown()
+	c.SetShuttingDown()
+	if r := c.Ready(ctx); r.OK() || result(t, r, "shutdown").Status != StatusFail {
+		t.Errorf("Ready() after shutdown = %+v, want fail", r)
+	}
+	if r := c.Live(ctx); !r.OK() {
+		t.Errorf("Live() after shutdown = %+v, want ok", r)
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("Done() not closed after SetShuttingDown")
+	}
+}
+
+func TestCheckerPendingMigrations(t *testing.T) {
+	db := openTestDB(t, false)
+	c := NewChecker(Config{CheckTimeout: time.Second}, db)
+	r := c.Ready(context.Background())
+	if res := result(t, r, "migrations"); r.OK() || res.Status != StatusFail || !strings.Contains(res.Error, "users") {
+		t.Fatalf("Ready() before migrate = %+v, want pending migrations", r)
+	}
+	if res := result(t, r, "database"); res.Status != StatusOK {
+		t.Errorf("database = %+v, want ok", res)
+	}
+	if err := dbinfra.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if r := c.Ready(context.Background()); !r.OK() {
+		t.Errorf("Ready() after migrate = %+v, want ok", r)
+	}
+}
+
+func TestCheckerDatabaseDown(t *testing.T) {
+	db := openTestDB(t, true)
+	c := NewChecker(Config{CheckTimeout: time.Second}, db)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	r := c.Ready(context.Background())
+	if r.OK() || result(t, r, "database").Status != StatusFail || result(t, r, "migrations").Status != StatusFail {
+		t.Errorf("Ready() = %+v, want database and migrations failing", r)
+	}
+	// DB が落ちても再起動では直らないため liveness は通す
+	if r := c.Live(context.Background()); !r.OK() {
+		t.Errorf("Live() = %+v, want ok", r)
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	c := NewChecker(Config{CheckTimeout: 10 * time.Millisecond}, openTestDB(t, true))
+	c.AddReadiness("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	r := c.Ready(context.Background())
+	if res := result(t, r, "slow"); r.OK() || !strings.Contains(res.Error, context.DeadlineExceeded.Error()) {
+		t.Errorf("slow = %+v, want deadline exceeded", res)
+	}
+}
+
+// panic したワーカーは liveness を失敗にし、何もせずに戻ったワーカーは失敗にしない
+func TestCheckerWorkers(t *testing.T) {
+	c := NewChecker(Config{CheckTimeout: time.Second}, openTestDB(t, true))
+	done := make(chan struct{})
+	c.Go(context.Background(), "mailer", func(ctx context.Context) { close(done) })
+	<-done
+	if r := c.Live(context.Background()); !r.OK() {
+		t.Fatalf("Live() = %+v, want ok", r)
+	}
+
+	panicked := make(chan struct{})
+	c.Go(context.Background(), "relay", func(ctx context.Context) {
+		defer close(panicked)
+		panic(errors.New("boom"))
+	})
+	<-panicked
+	// recover で記録するまで待つ
+	deadline := time.Now().Add(time.Second)
+	for c.Live(context.Background()).OK() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	r := c.Live(context.Background())
+	if res := result(t, r, "workers"); r.OK() || res.Error != "workers stopped: relay: panic: boom" {
+		t.Errorf("workers = %+v, want relay stopped", res)
+	}
+	if r := c.Ready(context.Background()); r.OK() {
+		t.Errorf("Ready() = %+v, want fail", r)
+	}
+}
+
+// Wait はワーカーの ctx をキャンセルした後、すべてのワーカーが戻るまで待つ
+func TestCheckerWait(t *testing.T) {
+	c := NewChecker(Config{CheckTimeout: time.Second}, openTestDB(t, true))
+	ctx, stop := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	c.Go(ctx, "ticker", func(ctx context.Context) { <-ctx.Done() })
+	c.Go(ctx, "slow", func(ctx context.Context) {
+		<-ctx.Done()
+		<-release
+	})
+	stop()
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Wait(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() = %v while a worker is running, want deadline exceeded", err)
+	}
+	close(release)
+	if err := c.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() = %v, want nil", err)
+	}
+}
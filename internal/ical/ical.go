@@ -0,0 +1,114 @@
+// Package ical は iCalendar（RFC 5545）のフィードを組み立てる
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 時刻の予定のタイムゾーン
+// 日本には夏時間が無いため、VTIMEZONE は固定オフセットの STANDARD だけを持つ
+const TZID = "Asia/Tokyo"
+
+var tokyo = time.FixedZone(TZID, 9*60*60)
+
+// 1行の最大オクテット数（これを超える行は折り返す）
+const maxLineOctets = 75
+
+// Event は VEVENT
+type Event struct {
+	// 予定を識別するID（同じ予定は同じIDにする。カレンダーアプリはこれで更新を判定する）
+	UID         string
+	Summary     string
+	Description string
+	Categories  string
+	// 終日の予定は Start の日から End の日まで（End を含む）、時刻の予定は Start から End まで
+	Start  time.Time
+	End    time.Time
+	AllDay bool
+	// 予定を作った時刻
+	Stamp time.Time
+}
+
+// Calendar は VCALENDAR
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Encode はカレンダーを RFC 5545 の形式（CRLF区切り）にする
+func (c *Calendar) Encode() []byte {
+	var b bytes.Buffer
+	line := func(name, value string) {
+		writeLine(&b, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//go_home//attendance//JA")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	line("X-WR-TIMEZONE", TZID)
+
+	line("BEGIN", "VTIMEZONE")
+	line("TZID", TZID)
+	line("BEGIN", "STANDARD")
+	line("DTSTART", "19700101T000000")
+	line("TZOFFSETFROM", "+0900")
+	line("TZOFFSETTO", "+0900")
+	line("TZNAME", "JST")
+	line("END", "STANDARD")
+	line("END", "VTIMEZONE")
+
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", e.Stamp.UTC().Format("20060102T150405Z"))
+		if e.AllDay {
+			// DTEND は含まないため、最終日の翌日にする
+			writeLine(&b, "DTSTART;VALUE=DATE:"+e.Start.In(tokyo).Format("20060102"))
+			writeLine(&b, "DTEND;VALUE=DATE:"+e.End.In(tokyo).AddDate(0, 0, 1).Format("20060102"))
+			line("TRANSP", "TRANSPARENT")
+		} else {
+			writeLine(&b, "DTSTART;TZID="+TZID+":"+e.Start.In(tokyo).Format("20060102T150405"))
+			writeLine(&b, "DTEND;TZID="+TZID+":"+e.End.In(tokyo).Format("20060102T150405"))
+		}
+		line("SUMMARY", escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escapeText(e.Description))
+		}
+		if e.Categories != "" {
+			line("CATEGORIES", escapeText(e.Categories))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return b.Bytes()
+}
+
+// escapeText は TEXT の値の特殊文字をエスケープする
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeLine は1行を書き込む。75オクテットを超える場合は、UTF-8 の文字の途中で切らないように折り返す
+func writeLine(b *bytes.Buffer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// 続きの行は先頭の空白の分だけ短くする
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,174 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// Postgres の LISTEN/NOTIFY で使うチャネル名
+const pgChannel = "go_home_live"
+
+// LISTEN の接続が切れた場合に張り直すまでの間隔
+const pgRetryInterval = 5 * time.Second
+
+// Broker は部屋（部署）ごとにイベントを配る
+// 複数のサーバーで動かす場合でも、どのサーバーで Publish したイベントもすべてのサーバーの購読者に届ける
+type Broker interface {
+	// Publish は部屋 e.Department の購読者にイベントを配る
+	Publish(ctx context.Context, e Event) error
+
+	// Subscribe はこのサーバーで部屋の購読を始める
+	Subscribe(room string) *Subscription
+
+	// Run は他のサーバーからのイベントを受け取る（ctx が終わるまで戻らない）
+	Run(ctx context.Context)
+}
+
+// NewEvent は data をJSONにして部屋 room のイベントを作る
+func NewEvent(eventType, room string, data any) (Event, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: eventType, Department: room, Data: b}, nil
+}
+
+// NewBroker はデータベースに合わせたBrokerを生成する
+// Postgres では LISTEN/NOTIFY でサーバー間に配り、SQLite（1プロセス）ではプロセス内だけで配る
+func NewBroker(db *gorm.DB) Broker {
+	r := &rooms{subs: map[string]map[*Subscription]struct{}{}}
+	if db.Dialector.Name() == "postgres" {
+		return &pgBroker{db: db, rooms: r}
+	}
+	return &localBroker{rooms: r}
+}
+
+// rooms はこのサーバーの部屋ごとの購読者
+type rooms struct {
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+func (r *rooms) subscribe(room string) *Subscription {
+	sub := newSubscription(func(s *Subscription) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.subs[room], s)
+		if len(r.subs[room]) == 0 {
+			delete(r.subs, room)
+		}
+	})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.subs[room] == nil {
+		r.subs[room] = map[*Subscription]struct{}{}
+	}
+	r.subs[room][sub] = struct{}{}
+	return sub
+}
+
+// deliver はこのサーバーの部屋の購読者にイベントを渡す
+func (r *rooms) deliver(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for s := range r.subs[e.Department] {
+		if !s.send(e) {
+			delete(r.subs[e.Department], s)
+		}
+	}
+}
+
+// プロセス内だけで配るBroker
+type localBroker struct {
+	rooms *rooms
+}
+
+func (b *localBroker) Publish(ctx context.Context, e Event) error {
+	b.rooms.deliver(e)
+	return nil
+}
+
+func (b *localBroker) Subscribe(room string) *Subscription {
+	return b.rooms.subscribe(room)
+}
+
+func (b *localBroker) Run(ctx context.Context) {
+	<-ctx.Done()
+}
+
+// Postgres の LISTEN/NOTIFY で配るBroker
+// 自分の NOTIFY も LISTEN で受け取るため、Publish したサーバーの購読者にも同じ経路で届く
+// NOTIFY はトランザクションの中で呼ぶとコミット時に送られる
+type pgBroker struct {
+	db    *gorm.DB
+	rooms *rooms
+}
+
+func (b *pgBroker) Publish(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", pgChannel, string(payload)).Error
+}
+
+func (b *pgBroker) Subscribe(room string) *Subscription {
+	return b.rooms.subscribe(room)
+}
+
+func (b *pgBroker) Run(ctx context.Context) {
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.ErrorContext(ctx, "live listen failed", "channel", pgChannel, "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pgRetryInterval):
+		}
+	}
+}
+
+// listen はプールから接続を1つ借りて LISTEN し、届いた通知を購読者に渡す
+func (b *pgBroker) listen(ctx context.Context) error {
+	sqlDB, err := b.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("not a pgx connection")
+		}
+		pc := c.Conn()
+		if _, err := pc.Exec(ctx, "LISTEN "+pgChannel); err != nil {
+			return err
+		}
+		for {
+			n, err := pc.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+				slog.WarnContext(ctx, "live invalid notification", "err", err)
+				continue
+			}
+			b.rooms.deliver(e)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package live
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// 再接続時に再送できるよう保持しておくイベントの数
+const replaySize = 1024
+
+// 購読者ごとに溜めておけるイベントの数（溢れた購読者は切断し、再接続させる）
+const subscriberBuffer = 64
+
+// Event はハブで配るイベント
+type Event struct {
+	// "起動ID-連番" の形式。Server-Sent Events の id としてそのまま使い、Last-Event-ID からの再開に使う
+	ID   string
+	Type string
+	// イベントの対象ユーザーの部署（購読者の絞り込みに使う）
+	Department string
+	// イベントの内容（JSON）
+	Data json.RawMessage
+}
+
+// Hub はプロセス内のイベントの配信（pub/sub）
+// ユースケースが Publish したイベントを、その時点で購読しているすべての購読者に配る
+// 直近のイベントは保持しておき、Last-Event-ID を指定した再接続には取りこぼした分を再送する
+type Hub struct {
+	mu     sync.Mutex
+	bootID string
+	seq    uint64
+	recent []Event
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewHub はHubを生成する
+// 起動IDはプロセスごとに変わるため、再起動前のイベントIDからは再開できない（スナップショットを送り直す）
+func NewHub() *Hub {
+	return &Hub{
+		bootID: uuid.NewString()[:8],
+		subs:   map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription はハブ・ブローカーの購読
+type Subscription struct {
+	unsubscribe func(*Subscription)
+	startID     string
+	events      chan Event
+	done        chan struct{}
+	once        sync.Once
+}
+
+// StartID は購読を始めた時点で最後に配ったイベントのID
+// 購読を始めた時点の状態を送る場合は、このIDを付ければその後のイベントから再開できる
+func (s *Subscription) StartID() string {
+	return s.startID
+}
+
+// Events は購読したイベントを受け取るチャネル
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Done は購読が終わった（ハブが閉じた・イベントを取りこぼした）ときに閉じるチャネル
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close は購読をやめる
+func (s *Subscription) Close() {
+	s.unsubscribe(s)
+	s.stop()
+}
+
+func newSubscription(unsubscribe func(*Subscription)) *Subscription {
+	return &Subscription{
+		unsubscribe: unsubscribe,
+		events:      make(chan Event, subscriberBuffer),
+		done:        make(chan struct{}),
+	}
+}
+
+// send は待たずにイベントを渡す。受け取りきれない購読は終わらせて false を返す
+func (s *Subscription) send(e Event) bool {
+	select {
+	case s.events <- e:
+		return true
+	default:
+		s.stop()
+		return false
+	}
+}
+
+func (s *Subscription) stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// Publish はイベントを購読者に配る
+// 購読者が受け取りきれない場合は待たずにその購読者を切断する
+func (h *Hub) Publish(eventType, department string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.seq++
+	e := Event{
+		ID:         h.bootID + "-" + strconv.FormatUint(h.seq, 10),
+		Type:       eventType,
+		Department: department,
+		Data:       b,
+	}
+	h.recent = append(h.recent, e)
+	if len(h.recent) > replaySize {
+		h.recent = h.recent[len(h.recent)-replaySize:]
+	}
+	for s := range h.subs {
+		if !s.send(e) {
+			delete(h.subs, s)
+		}
+	}
+	return nil
+}
+
+// Subscribe は購読を始める
+// lastEventID が保持しているイベントのIDであれば、その後のイベントを missed に入れて resumed=true を返す
+// 再開できない場合（未指定・再起動前のID・古すぎるID）は resumed=false を返すので、呼び出し側で現在の状態を送り直すこと
+func (h *Hub) Subscribe(lastEventID string) (sub *Subscription, missed []Event, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sub = newSubscription(h.unsubscribe)
+	sub.startID = h.bootID + "-" + strconv.FormatUint(h.seq, 10)
+	if h.closed {
+		sub.stop()
+		return sub, nil, false
+	}
+	h.subs[sub] = struct{}{}
+	missed, resumed = h.since(lastEventID)
+	return sub, missed, resumed
+}
+
+func (h *Hub) unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	delete(h.subs, s)
+	h.mu.Unlock()
+}
+
+// Close はすべての購読を終わらせる（サーバーの停止時に呼ぶ）
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subs {
+		delete(h.subs, s)
+		s.stop()
+	}
+}
+
+// since は lastEventID より後に配ったイベントを返す
+func (h *Hub) since(lastEventID string) ([]Event, bool) {
+	boot, seqStr, ok := strings.Cut(lastEventID, "-")
+	if !ok || boot != h.bootID {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil || seq > h.seq {
+		return nil, false
+	}
+	// 保持しているイベントより古いIDからは再開できない
+	oldest := h.seq - uint64(len(h.recent)) // 保持している最初のイベントの1つ前
+	if seq < oldest {
+		return nil, false
+	}
+	missed := make([]Event, int(h.seq-seq))
+	copy(missed, h.recent[len(h.recent)-len(missed):])
+	return missed, true
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// この時間を超えたクエリは遅いクエリとして警告する
+const slowQueryThreshold = 200 * time.Millisecond
+
+// GormLogger は Gorm のログを slog に出力する
+// クエリの値（メールアドレス・名前など）は出力せず、プレースホルダーのままの SQL を出す
+type GormLogger struct {
+	logger *slog.Logger
+	level  gormlogger.LogLevel
+}
+
+// NewGormLogger は GormLogger を生成する
+func NewGormLogger(logger *slog.Logger) *GormLogger {
+	return &GormLogger{logger: logger, level: gormlogger.Warn}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	c := *l
+	c.level = level
+	return &c
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormlogger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormlogger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormlogger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace はクエリの失敗と遅いクエリを出力する
+// 見つからない・一意制約違反はリポジトリで ErrNotFound・ErrConflict に変換して扱うため出力しない
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, gorm.ErrDuplicatedKey):
+		sql, rows := fc()
+		l.logger.ErrorContext(ctx, "query failed", "sql", sql, "rows", rows, "duration", elapsed, "err", err)
+	case elapsed > slowQueryThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		l.logger.WarnContext(ctx, "slow query", "sql", sql, "rows", rows, "duration", elapsed)
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		l.logger.DebugContext(ctx, "query", "sql", sql, "rows", rows, "duration", elapsed)
+	}
+}
+
+// ParamsFilter はログに出す SQL からクエリの値を除く（gorm.ParamsFilter）
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	return sql, nil
+}
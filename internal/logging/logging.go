@@ -0,0 +1,97 @@
+// Package logging は構造化ログ（log/slog）のロガーを組み立てる
+// ログにはリクエストID・ユーザーID・トレースIDを付け、メールアドレス・名前・トークンは伏せる
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/enkazu1116/go_home/internal/audit"
+	"github.com/enkazu1116/go_home/internal/auth"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config はログの設定
+type Config struct {
+	// 出力する最低のレベル
+	Level slog.Level
+	// 出力形式（json・text）
+	Format string
+}
+
+// NewConfigFromEnv は環境変数 LOG_LEVEL（既定 info）・LOG_FORMAT（既定 json）からログの設定を読み込む
+func NewConfigFromEnv() Config {
+	cfg := Config{Level: slog.LevelInfo, Format: "json"}
+	var invalid []string
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := cfg.Level.UnmarshalText([]byte(v)); err != nil {
+			invalid = append(invalid, "LOG_LEVEL="+v)
+		}
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		if v == "json" || v == "text" {
+			cfg.Format = v
+		} else {
+			invalid = append(invalid, "LOG_FORMAT="+v)
+		}
+	}
+	// ロガーを作る前のため、標準のロガーに出力する
+	for _, v := range invalid {
+		slog.Warn("invalid log config, using default", "value", v)
+	}
+	return cfg
+}
+
+// New は標準エラー出力に書き込むロガーを生成する
+func New(cfg Config) *slog.Logger {
+	return NewWithWriter(cfg, os.Stderr)
+}
+
+// NewWithWriter は w に書き込むロガーを生成する
+// メッセージと属性の値は伏せ字にしてから書き込み、コンテキストのリクエストID・ユーザーID・トレースIDを付ける
+func NewWithWriter(cfg Config, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level, ReplaceAttr: redactAttr}
+	var h slog.Handler
+	if cfg.Format == "text" {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(&contextHandler{next: h})
+}
+
+// contextHandler はコンテキストのリクエストID・ユーザーID・トレースID・スパンIDをログに付ける
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		r = r.Clone()
+		if meta := audit.MetaFrom(ctx); meta.RequestID != "" {
+			r.AddAttrs(slog.String("request_id", meta.RequestID))
+		}
+		if user, ok := auth.UserFrom(ctx); ok {
+			r.AddAttrs(slog.String("user_id", user.ID))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// 伏せ字
+const redacted = "[REDACTED]"
+
+// 伏せる属性のキー（小文字にして完全に一致するものだけ）
+// 名前などの他の個人情報は entity.User の LogValue のように、出力する側で項目を絞る
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"secret":        true,
+	"authorization": true,
+	"email":         true,
+	"api_key":       true,
+}
+
+// 文字列の中から伏せる値
+// メッセージやエラーに埋め込まれたメールアドレス・JWT・カレンダーのトークンなど
+var sensitivePatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), "[REDACTED_EMAIL]"},
+	{regexp.MustCompile(`(?i)\bbearer\s+\S+`), "Bearer " + redacted},
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`), "[REDACTED_TOKEN]"},
+	{regexp.MustCompile(`\bcal_[0-9a-f]{16,}`), "[REDACTED_TOKEN]"},
+	{regexp.MustCompile(`(?i)\b(access_token|token|key)=[^&\s"]+`), "$1=" + redacted},
+}
+
+// sensitiveKey は属性のキーが伏せる対象かを判定する
+func sensitiveKey(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
+
+// redactString は文字列に含まれるメールアドレス・トークンを伏せる
+func redactString(s string) string {
+	for _, p := range sensitivePatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
+
+// redactAttr は slog.HandlerOptions.ReplaceAttr として属性を伏せ字にする
+// 伏せるキーの値はすべて伏せ、それ以外の文字列・エラーとメッセージは中のメールアドレス・トークンを伏せる
+// 構造体は slog.LogValuer で出力する項目を絞る（entity.User など）
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if len(groups) == 0 {
+		switch a.Key {
+		case slog.TimeKey, slog.LevelKey, slog.SourceKey:
+			return a
+		case slog.MessageKey:
+			return slog.String(a.Key, redactString(a.Value.String()))
+		}
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		return a
+	}
+	if sensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, redactString(err.Error()))
+		}
+	}
+	return a
+}
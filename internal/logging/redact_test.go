@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestRedactKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithWriter(Config{Level: slog.LevelInfo, Format: "json"}, &buf)
+	logger.Info("login",
+		"password", "hunter2", "Token", "abc", "secret", "s3", "authorization", "Basic xyz", "email", "alice", "api_key", "k1",
+		// 伏せるキーに似ているだけのキーはそのまま出す
+		"name", "check_in", "to", "2026-10", "key", "idempotency", "user_email", "none", "token_count", 3, "route", "/users/{id}",
+		// 伏せないキーでも、文字列中のメールアドレスは伏せる
+		"detail", "sent to alice@example.com",
+	)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	want := map[string]any{
+		"password": redacted, "Token": redacted, "secret": redacted, "authorization": redacted, "email": redacted, "api_key": redacted,
+		"name": "check_in", "to": "2026-10", "key": "idempotency", "user_email": "none", "token_count": float64(3), "route": "/users/{id}",
+		"detail": "sent to [REDACTED_EMAIL]",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+}
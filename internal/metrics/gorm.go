@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Statement にクエリの開始時刻を入れるキー
+const gormStartKey = "metrics:start"
+
+// DBMetrics は Gorm のクエリの処理時間とコネクションプールの状態を集める
+type DBMetrics struct {
+	duration *Histogram
+	errors   *Counter
+}
+
+// NewDBMetrics は Gorm のコールバックを登録して、クエリごとに処理時間を記録する
+// コネクションプールの状態はスクレイプのたびに sql.DB.Stats から集める
+func NewDBMetrics(db *gorm.DB, reg *Registry) (*DBMetrics, error) {
+	m := &DBMetrics{
+		duration: reg.NewHistogram("go_home_db_query_duration_seconds", "Duration of database queries issued through GORM.", nil, "operation", "table"),
+		errors:   reg.NewCounter("go_home_db_query_errors_total", "Number of failed database queries (not found and duplicate key are not counted).", "operation", "table"),
+	}
+	cb := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("*").Register, cb.Create().After("*").Register},
+		{"query", cb.Query().Before("*").Register, cb.Query().After("*").Register},
+		{"update", cb.Update().Before("*").Register, cb.Update().After("*").Register},
+		{"delete", cb.Delete().Before("*").Register, cb.Delete().After("*").Register},
+		{"row", cb.Row().Before("*").Register, cb.Row().After("*").Register},
+		{"raw", cb.Raw().Before("*").Register, cb.Raw().After("*").Register},
+	}
+	for _, p := range processors {
+		if err := p.before("metrics:before_"+p.operation, m.before); err != nil {
+			return nil, err
+		}
+		if err := p.after("metrics:after_"+p.operation, m.after(p.operation)); err != nil {
+			return nil, err
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	reg.Register(func(ctx context.Context) ([]Family, error) {
+		s := sqlDB.Stats()
+		return []Family{
+			Gauge("go_home_db_connections_max_open", "Maximum number of open connections to the database.", float64(s.MaxOpenConnections)),
+			Gauge("go_home_db_connections_open", "Number of established connections to the database.", float64(s.OpenConnections)),
+			Gauge("go_home_db_connections_in_use", "Number of connections currently in use.", float64(s.InUse)),
+			Gauge("go_home_db_connections_idle", "Number of idle connections.", float64(s.Idle)),
+			CounterValue("go_home_db_connections_wait_total", "Total number of connections waited for.", float64(s.WaitCount)),
+			CounterValue("go_home_db_connections_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", s.WaitDuration.Seconds()),
+		}, nil
+	})
+	return m, nil
+}
+
+func (m *DBMetrics) before(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+func (m *DBMetrics) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		m.duration.Observe(time.Since(start).Seconds(), operation, table)
+		if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, gorm.ErrDuplicatedKey) {
+			m.errors.Inc(operation, table)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package metrics は Prometheus のテキスト形式（version 0.0.4）でメトリクスを公開する
+// クライアントライブラリを使わずに、カウンター・ヒストグラムとスクレイプ時に値を集める関数だけを持つ
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType はテキスト形式の Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// メトリクスの種類
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefBuckets は処理時間（秒）のヒストグラムの既定のバケット
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Label はラベルの名前と値
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample は1つの値
+// Suffix はヒストグラムの _bucket・_sum・_count
+type Sample struct {
+	Suffix string
+	Labels []Label
+	Value  float64
+}
+
+// Family は同じ名前のメトリクスの値の集まり
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// CollectFunc はスクレイプのたびに値を集める
+type CollectFunc func(ctx context.Context) ([]Family, error)
+
+// Registry はメトリクスを登録して、テキスト形式で書き出す
+type Registry struct {
+	mu         sync.Mutex
+	names      map[string]bool
+	collectors []CollectFunc
+}
+
+// NewRegistry はRegistryを生成する（Go ランタイムのメトリクスを含む）
+func NewRegistry() *Registry {
+	r := &Registry{names: map[string]bool{}}
+	r.Register(collectRuntime)
+	return r
+}
+
+// Register はスクレイプのたびに呼ぶ関数を登録する
+func (r *Registry) Register(fn CollectFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, fn)
+}
+
+// NewCounter はカウンターを登録する
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{vec: newVec(name, help, labelNames)}
+	r.add(name, func(context.Context) ([]Family, error) { return []Family{c.family()}, nil })
+	return c
+}
+
+// NewHistogram はヒストグラムを登録する（buckets が nil なら DefBuckets）
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	h := &Histogram{vec: newVec(name, help, labelNames), buckets: buckets}
+	r.add(name, func(context.Context) ([]Family, error) { return []Family{h.family()}, nil })
+	return h
+}
+
+// add は名前が重複していないことを確かめて登録する（重複は実装の誤りのため panic する）
+func (r *Registry) add(name string, fn CollectFunc) {
+	r.mu.Lock()
+	if r.names[name] {
+		r.mu.Unlock()
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.mu.Unlock()
+	r.Register(fn)
+}
+
+// Gather はすべての値を集めて名前順に並べる
+// 集められなかった関数はログに残して飛ばす（他のメトリクスは返す）
+func (r *Registry) Gather(ctx context.Context) []Family {
+	r.mu.Lock()
+	collectors := append([]CollectFunc(nil), r.collectors...)
+	r.mu.Unlock()
+
+	var families []Family
+	for _, fn := range collectors {
+		fs, err := fn(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "metrics collect failed", "err", err)
+			continue
+		}
+		families = append(families, fs...)
+	}
+	sort.SliceStable(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
+
+// WriteText はテキスト形式で書き出す
+func (r *Registry) WriteText(ctx context.Context, w io.Writer) error {
+	var b strings.Builder
+	for _, f := range r.Gather(ctx) {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.Name, f.Type)
+		for _, s := range f.Samples {
+			b.WriteString(f.Name)
+			b.WriteString(s.Suffix)
+			if len(s.Labels) > 0 {
+				b.WriteByte('{')
+				for i, l := range s.Labels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", l.Name, escapeLabel(l.Value))
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(formatFloat(s.Value))
+			b.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Handler は GET /metrics のハンドラー
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(req.Context(), w)
+	})
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"context"
+	"runtime"
+)
+
+// collectRuntime は Go ランタイムのメトリクスを集める
+func collectRuntime(ctx context.Context) ([]Family, error) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return []Family{
+		Gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine())),
+		Gauge("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", float64(m.HeapAlloc)),
+		Gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", float64(m.Sys)),
+		CounterValue("go_gc_cycles_total", "Number of completed GC cycles.", float64(m.NumGC)),
+	}, nil
+}
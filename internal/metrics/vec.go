@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// vec はラベルの値の組ごとの系列を持つ
+type vec struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]any
+}
+
+func newVec(name, help string, labelNames []string) vec {
+	return vec{name: name, help: help, labelNames: labelNames, series: map[string]any{}}
+}
+
+// get はラベルの値の組の系列を返す（無ければ create で作る）
+// ラベルの数が合わないのは実装の誤りのため panic する
+func (v *vec) get(labelValues []string, create func() any) any {
+	if len(labelValues) != len(v.labelNames) {
+		panic("metrics: " + v.name + ": wrong number of label values")
+	}
+	key := strings.Join(labelValues, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = create()
+		v.series[key] = s
+	}
+	return s
+}
+
+// each はラベルの値の組の順に系列を渡す
+func (v *vec) each(fn func(labels []Label, s any)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	series := make([]any, len(keys))
+	for i, k := range keys {
+		series[i] = v.series[k]
+	}
+	v.mu.Unlock()
+
+	for i, k := range keys {
+		var values []string
+		if len(v.labelNames) > 0 {
+			values = strings.Split(k, "\xff")
+		}
+		labels := make([]Label, len(v.labelNames))
+		for j, n := range v.labelNames {
+			labels[j] = Label{Name: n, Value: values[j]}
+		}
+		fn(labels, series[i])
+	}
+}
+
+// Counter は増えるだけの値
+type Counter struct {
+	vec
+}
+
+type counterSeries struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Inc は1増やす
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add は v（0以上）増やす
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	s := c.get(labelValues, func() any { return &counterSeries{} }).(*counterSeries)
+	s.mu.Lock()
+	s.value += v
+	s.mu.Unlock()
+}
+
+func (c *Counter) family() Family {
+	f := Family{Name: c.name, Help: c.help, Type: TypeCounter}
+	c.each(func(labels []Label, s any) {
+		cs := s.(*counterSeries)
+		cs.mu.Lock()
+		f.Samples = append(f.Samples, Sample{Labels: labels, Value: cs.value})
+		cs.mu.Unlock()
+	})
+	return f
+}
+
+// Histogram は値の分布（バケットごとの件数・合計・件数）
+type Histogram struct {
+	vec
+	buckets []float64
+}
+
+type histogramSeries struct {
+	mu     sync.Mutex
+	counts []uint64 // バケットごとの件数（累積ではない）
+	sum    float64
+	count  uint64
+}
+
+// Observe は値を1つ記録する
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	s := h.get(labelValues, func() any { return &histogramSeries{counts: make([]uint64, len(h.buckets))} }).(*histogramSeries)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *Histogram) family() Family {
+	f := Family{Name: h.name, Help: h.help, Type: TypeHistogram}
+	h.each(func(labels []Label, s any) {
+		hs := s.(*histogramSeries)
+		hs.mu.Lock()
+		defer hs.mu.Unlock()
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hs.counts[i]
+			f.Samples = append(f.Samples, Sample{Suffix: "_bucket", Labels: withLabel(labels, "le", formatFloat(upper)), Value: float64(cumulative)})
+		}
+		f.Samples = append(f.Samples,
+			Sample{Suffix: "_bucket", Labels: withLabel(labels, "le", "+Inf"), Value: float64(hs.count)},
+			Sample{Suffix: "_sum", Labels: labels, Value: hs.sum},
+			Sample{Suffix: "_count", Labels: labels, Value: float64(hs.count)},
+		)
+	})
+	return f
+}
+
+func withLabel(labels []Label, name, value string) []Label {
+	out := make([]Label, 0, len(labels)+1)
+	out = append(out, labels...)
+	return append(out, Label{Name: name, Value: value})
+}
+
+// Gauge は1つの値のゲージを作る
+func Gauge(name, help string, value float64) Family {
+	return Family{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: value}}}
+}
+
+// CounterValue は1つの値のカウンターを作る（外部で数えている累計を公開する場合）
+func CounterValue(name, help string, value float64) Family {
+	return Family{Name: name, Help: help, Type: TypeCounter, Samples: []Sample{{Value: value}}}
+}
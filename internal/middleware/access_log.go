@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AccessLog はリクエストごとにアクセスログを出力する
+// パスはトークンを含むことがあるため、ルートのパターン（/calendar/{token}/personal.ics など）を出す
+type AccessLog struct {
+	logger *slog.Logger
+}
+
+// NewAccessLog はAccessLogを生成する
+func NewAccessLog(logger *slog.Logger) *AccessLog {
+	return &AccessLog{logger: logger}
+}
+
+// Middleware はHTTPリクエストのメソッド・ルート・ステータス・サイズ・処理時間を出力するミドルウェア
+// リクエストIDを付けるため audit.Middleware の後に置く
+func (a *AccessLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		route := routePattern(r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		a.logger.LogAttrs(r.Context(), level, "http request",
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// UnaryServerInterceptor はgRPCリクエストのメソッド・ステータスコード・処理時間を出力するインターセプター
+// リクエストIDを付けるため audit.UnaryServerInterceptor の後に置く
+func (a *AccessLog) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+		level := slog.LevelInfo
+		switch code {
+		case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss:
+			level = slog.LevelError
+		}
+		a.logger.LogAttrs(ctx, level, "grpc request",
+			slog.String("method", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Duration("duration", time.Since(start)),
+		)
+		return resp, err
+	}
+}
+
+// statusRecorder はステータスと書き込んだバイト数を記録する
+// Server-Sent Events の Flush は Unwrap で、WebSocket の Hijack はそのまま元の ResponseWriter に渡す
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
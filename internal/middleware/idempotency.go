@@ -0,0 +1,327 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// 冪等キーのヘッダー名（gRPCではメタデータのキー）
+const (
+	IdempotencyKeyHeader   = "Idempotency-Key"
+	idempotencyKeyMetadata = "idempotency-key"
+	idempotentReplayHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLen   = 255
+)
+
+var (
+	errKeyReused  = errors.New("Idempotency-Key was already used with a different request")
+	errInProgress = errors.New("a request with the same Idempotency-Key is in progress")
+	errKeyTooLong = errors.New("Idempotency-Key is too long")
+)
+
+// IdempotencyConfig は冪等キーの設定
+type IdempotencyConfig struct {
+	// キーを保持する期間。これを過ぎたキーは再利用できる
+	TTL time.Duration
+	// 処理中のキーを保持する期間。プロセスが落ちて完了・解放されなかったキーも、これを過ぎれば再送で再実行できる
+	Lease time.Duration
+}
+
+// NewIdempotencyConfigFromEnv は環境変数 IDEMPOTENCY_TTL（例: "24h"）・IDEMPOTENCY_LEASE（例: "1m"）から設定を読み込む
+func NewIdempotencyConfigFromEnv() IdempotencyConfig {
+	cfg := IdempotencyConfig{TTL: 24 * time.Hour, Lease: time.Minute}
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			slog.Warn("invalid IDEMPOTENCY_TTL, using default", "value", v, "default", cfg.TTL, "err", err)
+		} else {
+			cfg.TTL = ttl
+		}
+	}
+	if v := os.Getenv("IDEMPOTENCY_LEASE"); v != "" {
+		lease, err := time.ParseDuration(v)
+		if err != nil || lease <= 0 {
+			slog.Warn("invalid IDEMPOTENCY_LEASE, using default", "value", v, "default", cfg.Lease, "err", err)
+		} else {
+			cfg.Lease = lease
+		}
+	}
+	return cfg
+}
+
+// Idempotency は Idempotency-Key による再送の重複実行を防ぐ
+// 同じキー・同じリクエストの再送には保存済みのレスポンスを返し、
+// 同じキーで異なるリクエストが来た場合は拒否する
+type Idempotency struct {
+	cfg  IdempotencyConfig
+	repo repository.IdempotencyRepository
+	now  func() time.Time
+}
+
+// NewIdempotency はIdempotencyを生成する
+func NewIdempotency(cfg IdempotencyConfig, repo repository.IdempotencyRepository) *Idempotency {
+	return &Idempotency{cfg: cfg, repo: repo, now: time.Now}
+}
+
+// PurgeExpired は期限切れのキーを削除する
+func (m *Idempotency) PurgeExpired(ctx context.Context) (int64, error) {
+	return m.repo.DeleteExpired(ctx, m.now())
+}
+
+// begin はキーを処理中として登録する（期限は Lease、完了すると TTL に延ばす）
+// 完了済みのキーがあれば、そのレコードを返す（呼び出し元は保存済みのレスポンスを返す）
+func (m *Idempotency) begin(ctx context.Context, key, scope, hash string) (*entity.IdempotencyKey, error) {
+	if len(key) > maxIdempotencyKeyLen {
+		return nil, errKeyTooLong
+	}
+	now := m.now()
+	stored, err := m.repo.Find(ctx, key, scope)
+	switch {
+	case err == nil && !stored.ExpiresAt.After(now):
+		// 期限切れのキー（リースが切れた処理中のキーを含む）は削除して新しく登録し直す
+		if err := m.repo.Delete(ctx, key, scope); err != nil {
+			return nil, err
+		}
+	case err == nil:
+		if stored.RequestHash != hash {
+			return nil, errKeyReused
+		}
+		if stored.StatusCode == 0 {
+			return nil, errInProgress
+		}
+		return stored, nil
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	}
+
+	err = m.repo.Claim(ctx, entity.IdempotencyKey{
+		Key:         key,
+		Scope:       scope,
+		RequestHash: hash,
+		ExpiresAt:   now.Add(m.cfg.Lease),
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		// 同じキーのリクエストが同時に届いた
+		return nil, errInProgress
+	}
+	return nil, err
+}
+
+// complete はレスポンスを保存し、キーの期限を TTL に延ばす
+func (m *Idempotency) complete(ctx context.Context, k entity.IdempotencyKey) {
+	k.ExpiresAt = m.now().Add(m.cfg.TTL)
+	if err := m.repo.Complete(ctx, k); err != nil {
+		slog.ErrorContext(ctx, "idempotency save response failed", "err", err)
+	}
+}
+
+// release は処理中のキーを削除し、再送で再実行できるようにする
+func (m *Idempotency) release(ctx context.Context, key, scope string) {
+	if err := m.repo.Delete(ctx, key, scope); err != nil {
+		slog.ErrorContext(ctx, "idempotency release key failed", "err", err)
+	}
+}
+
+// 呼び出し元ユーザーを含めたスコープを作る（他人のキーと衝突しないように）
+func idempotencyScope(ctx context.Context, operation string) string {
+	principal := "-"
+	if user, ok := auth.UserFrom(ctx); ok {
+		principal = user.ID
+	}
+	return principal + " " + operation
+}
+
+func hashRequest(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Middleware は POST リクエストに Idempotency-Key ヘッダーがある場合だけ働くミドルウェア
+// 5xx のレスポンスは保存せず、キーを解放して再送で再実行できるようにする（panic した場合も同じ）
+func (m *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		scope := idempotencyScope(ctx, r.Method+" "+r.URL.Path)
+		stored, err := m.begin(ctx, key, scope, hashRequest([]byte(r.Method), []byte(r.URL.RequestURI()), body))
+		if err != nil {
+			http.Error(w, err.Error(), idempotencyStatus(err))
+			return
+		}
+		if stored != nil {
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+			w.Header().Set(idempotentReplayHeader, "true")
+			w.WriteHeader(stored.StatusCode)
+			w.Write(stored.Body)
+			return
+		}
+
+		// レスポンスの保存はクライアントの切断に影響されないようにする
+		saveCtx := context.WithoutCancel(ctx)
+		defer func() {
+			if p := recover(); p != nil {
+				m.release(saveCtx, key, scope)
+				panic(p)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= http.StatusInternalServerError {
+			m.release(saveCtx, key, scope)
+			return
+		}
+		m.complete(saveCtx, entity.IdempotencyKey{
+			Key:         key,
+			Scope:       scope,
+			StatusCode:  rec.status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		})
+	})
+}
+
+func idempotencyStatus(err error) int {
+	switch {
+	case errors.Is(err, errKeyReused):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errInProgress):
+		return http.StatusConflict
+	case errors.Is(err, errKeyTooLong):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// UnaryServerInterceptor はメタデータ idempotency-key がある unary RPC に冪等性を持たせる
+// レスポンスは google.protobuf.Any として保存し、再送時に復元して返す
+func (m *Idempotency) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		keys := md.Get(idempotencyKeyMetadata)
+		msg, ok := req.(proto.Message)
+		if len(keys) == 0 || keys[0] == "" || !ok {
+			return handler(ctx, req)
+		}
+		key := keys[0]
+
+		payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		scope := idempotencyScope(ctx, info.FullMethod)
+		stored, err := m.begin(ctx, key, scope, hashRequest([]byte(info.FullMethod), payload))
+		if err != nil {
+			return nil, status.Error(idempotencyCode(err), err.Error())
+		}
+		if stored != nil {
+			var saved anypb.Any
+			if err := proto.Unmarshal(stored.Body, &saved); err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			grpc.SetHeader(ctx, metadata.Pairs(idempotencyKeyMetadata+"-replayed", "true"))
+			return saved.UnmarshalNew()
+		}
+
+		saveCtx := context.WithoutCancel(ctx)
+		defer func() {
+			if p := recover(); p != nil {
+				m.release(saveCtx, key, scope)
+				panic(p)
+			}
+		}()
+
+		resp, herr := handler(ctx, req)
+		if herr != nil {
+			// エラーは保存せず、再送で再実行できるようにする
+			m.release(saveCtx, key, scope)
+			return resp, herr
+		}
+		if out, ok := resp.(proto.Message); ok {
+			wrapped, err := anypb.New(out)
+			var body []byte
+			if err == nil {
+				body, err = proto.Marshal(wrapped)
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "idempotency save response failed", "err", err)
+				m.release(saveCtx, key, scope)
+				return resp, nil
+			}
+			m.complete(saveCtx, entity.IdempotencyKey{
+				Key:        key,
+				Scope:      scope,
+				StatusCode: http.StatusOK, // 0は処理中を表すため、gRPCの成功は200として記録する
+				Body:       body,
+			})
+		}
+		return resp, nil
+	}
+}
+
+func idempotencyCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, errKeyReused), errors.Is(err, errKeyTooLong):
+		return codes.InvalidArgument
+	case errors.Is(err, errInProgress):
+		return codes.Aborted
+	default:
+		return codes.Internal
+	}
+}
+
+// responseRecorder はレスポンスを書き込みつつ、ステータスとボディを記録する
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/auth"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// newTestIdempotency は SQLite のリポジトリと進められる時計で Idempotency を作る
+func newTestIdempotency(t *testing.T) (*Idempotency, *time.Time) {
+	t.Helper()
+	db, err := dbinfra.OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dbinfra.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	m := NewIdempotency(IdempotencyConfig{TTL: 24 * time.Hour, Lease: time.Minute}, repository.NewIdempotencyRepository(db))
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+// postWithKey は Idempotency-Key を付けた POST をユーザー（nil なら未認証）として送る
+func postWithKey(h http.Handler, user *entity.User, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/attendances/check-in", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	if user != nil {
+		req = req.WithContext(auth.WithUser(req.Context(), user))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	m, _ := newTestIdempotency(t)
+	var calls atomic.Int32
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"call":`+strconv.Itoa(int(n))+`}`)
+	}))
+	alice := &entity.User{ID: "alice"}
+
+	first := postWithKey(h, alice, "k1", `{}`)
+	second := postWithKey(h, alice, "k1", `{}`)
+	if calls.Load() != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls.Load())
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body.String(), first.Code, first.Body.String())
+	}
+	if second.Header().Get(idempotentReplayHeader) != "true" || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replay headers = %v", second.Header())
+	}
+
+	// 同じキーで異なるボディは 422
+	if rec := postWithKey(h, alice, "k1", `{"Location":{}}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body: status = %d, want 422", rec.Code)
+	}
+	// キーは呼び出し元ごとに分かれる
+	if rec := postWithKey(h, &entity.User{ID: "bob"}, "k1", `{}`); rec.Code != http.StatusCreated || rec.Header().Get(idempotentReplayHeader) != "" {
+		t.Errorf("other principal: status = %d, replayed = %q, want a fresh 201", rec.Code, rec.Header().Get(idempotentReplayHeader))
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestIdempotencyRejectsConcurrentDuplicate(t *testing.T) {
+	m, _ := newTestIdempotency(t)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+	alice := &entity.User{ID: "alice"}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postWithKey(h, alice, "k1", `{}`) }()
+	<-entered
+	if rec := postWithKey(h, alice, "k1", `{}`); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate while in progress: status = %d, want 409", rec.Code)
+	}
+	close(release)
+	if rec := <-done; rec.Code != http.StatusCreated {
+		t.Errorf("first request: status = %d, want 201", rec.Code)
+	}
+}
+
+func TestIdempotencyReleasesKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+	}{
+		{"5xx", func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) }},
+		{"panic", func(w http.ResponseWriter) { panic("boom") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestIdempotency(t)
+			var calls atomic.Int32
+			h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					tt.handler(w)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			alice := &entity.User{ID: "alice"}
+
+			func() {
+				defer func() { recover() }()
+				postWithKey(h, alice, "k1", `{}`)
+			}()
+			// 失敗したリクエストのキーは解放され、再送で再実行できる
+			if rec := postWithKey(h, alice, "k1", `{}`); rec.Code != http.StatusCreated {
+				t.Fatalf("retry: status = %d, want 201", rec.Code)
+			}
+			if calls.Load() != 2 {
+				t.Errorf("handler calls = %d, want 2", calls.Load())
+			}
+		})
+	}
+}
+
+// プロセスが落ちて処理中のまま残ったキーは、リースが切れると再実行できる
+func TestIdempotencyLeaseExpires(t *testing.T) {
+	m, now := newTestIdempotency(t)
+	alice := &entity.User{ID: "alice"}
+	ctx := auth.WithUser(context.Background(), alice)
+	scope := idempotencyScope(ctx, http.MethodPost+" /attendances/check-in")
+	hash := hashRequest([]byte(http.MethodPost), []byte("/attendances/check-in"), []byte(`{}`))
+	if _, err := m.begin(ctx, "k1", scope, hash); err != nil {
+		t.Fatal(err)
+	}
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	*now = now.Add(30 * time.Second)
+	if rec := postWithKey(h, alice, "k1", `{}`); rec.Code != http.StatusConflict {
+		t.Fatalf("within lease: status = %d, want 409", rec.Code)
+	}
+	*now = now.Add(time.Minute)
+	if rec := postWithKey(h, alice, "k1", `{}`); rec.Code != http.StatusCreated {
+		t.Fatalf("after lease: status = %d, want 201", rec.Code)
+	}
+	// 完了したキーは TTL まで保持される
+	*now = now.Add(time.Hour)
+	if rec := postWithKey(h, alice, "k1", `{}`); rec.Header().Get(idempotentReplayHeader) != "true" {
+		t.Errorf("after completion: status = %d, want a replay", rec.Code)
+	}
+}
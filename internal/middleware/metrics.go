@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics はHTTP・gRPCのリクエストの件数と処理時間を記録する
+// ラベルはルートのパターン・gRPCのメソッドのため、系列の数はルートの数までに収まる
+// HTTPメソッドは標準のメソッド以外を OTHER にまとめる
+type Metrics struct {
+	httpRequests *metrics.Counter
+	httpDuration *metrics.Histogram
+	grpcRequests *metrics.Counter
+	grpcDuration *metrics.Histogram
+}
+
+// NewMetrics はMetricsを生成し、メトリクスを登録する
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{
+		httpRequests: reg.NewCounter("go_home_http_requests_total", "Number of HTTP requests by route and status.", "method", "route", "status"),
+		httpDuration: reg.NewHistogram("go_home_http_request_duration_seconds", "Duration of HTTP requests by route.", nil, "method", "route"),
+		grpcRequests: reg.NewCounter("go_home_grpc_requests_total", "Number of gRPC requests by method and status code.", "method", "code"),
+		grpcDuration: reg.NewHistogram("go_home_grpc_request_duration_seconds", "Duration of gRPC requests by method.", nil, "method"),
+	}
+}
+
+// Middleware はHTTPリクエストの件数と処理時間を記録するミドルウェア
+// Server-Sent Events・WebSocket は接続している時間が処理時間になる
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		route := routePattern(r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		method := methodLabel(r.Method)
+		m.httpRequests.Inc(method, route, strconv.Itoa(rec.status))
+		m.httpDuration.Observe(time.Since(start).Seconds(), method, route)
+	})
+}
+
+// UnaryServerInterceptor はgRPCリクエストの件数と処理時間を記録するインターセプター
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.grpcRequests.Inc(info.FullMethod, status.Code(err).String())
+		m.grpcDuration.Observe(time.Since(start).Seconds(), info.FullMethod)
+		return resp, err
+	}
+}
+
+// ラベルにそのまま使うHTTPメソッド
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// methodLabel はラベルに使うHTTPメソッドを返す（知らないメソッドは OTHER）
+// メソッドはクライアントが自由に付けられるため、そのまま使うと系列の数が際限なく増える
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "OTHER"
+}
+
+// routePattern は処理したルートのパターンを返す（どのルートにも一致しなければ unmatched）
+// 呼び出すのはハンドラーを処理した後にすること
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return "unmatched"
+}
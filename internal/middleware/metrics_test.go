@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/enkazu1116/go_home/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMetricsMethodLabel(t *testing.T) {
+	reg := metrics.NewRegistry()
+	r := chi.NewRouter()
+	r.Use(NewMetrics(reg).Middleware)
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, method := range []string{http.MethodGet, "BREW", "X-RANDOM-1", "get"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/users/u1", nil))
+	}
+
+	var b strings.Builder
+	if err := reg.WriteText(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+	var series []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.HasPrefix(line, "go_home_http_requests_total{") {
+			series = append(series, line)
+		}
+	}
+	want := []string{
+		`go_home_http_requests_total{method="GET",route="/users/{id}",status="200"} 1`,
+		`go_home_http_requests_total{method="OTHER",route="unmatched",status="405"} 3`,
+	}
+	if strings.Join(series, "\n") != strings.Join(want, "\n") {
+		t.Errorf("series =\n%s\nwant\n%s", strings.Join(series, "\n"), strings.Join(want, "\n"))
+	}
+}
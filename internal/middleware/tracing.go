@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Tracing はHTTP・gRPCのリクエストごとにサーバーのスパンを作る
+// W3C traceparent を受け取ったら、そのトレースの子のスパンにする
+type Tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracing はTracingを生成する
+func NewTracing(tp *sdktrace.TracerProvider) *Tracing {
+	return &Tracing{
+		tracer:     tp.Tracer("github.com/enkazu1116/go_home/internal/middleware"),
+		propagator: otel.GetTextMapPropagator(),
+	}
+}
+
+// Middleware はHTTPリクエストのスパンを作るミドルウェア
+// スパン名はハンドラーを処理した後にルートのパターン（GET /users/{id} など）にする
+// トレースIDをログに付けるため AccessLog より前に置く
+func (t *Tracing) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := t.tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.scheme", scheme(r)),
+			),
+		)
+		defer span.End()
+
+		// 呼び出し元がトレースを続けられるように、レスポンスにも traceparent を返す
+		t.propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		route := routePattern(r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", rec.status),
+		)
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// UnaryServerInterceptor はgRPCリクエストのスパンを作るインターセプター
+// traceparent はメタデータから受け取る
+func (t *Tracing) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = t.propagator.Extract(ctx, metadataCarrier(md))
+		ctx, span := t.tracer.Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("rpc.system", "grpc"),
+				attribute.String("rpc.method", info.FullMethod),
+			),
+		)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+		span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, code.String())
+		}
+		return resp, err
+	}
+}
+
+// scheme はリクエストのスキーム（http・https）を返す
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// metadataCarrier はgRPCのメタデータを TextMapCarrier として扱う
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+	"github.com/enkazu1116/go_home/internal/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// テスト用の呼び出し元のトレース
+const (
+	parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID  = "00f067aa0ba902b7"
+)
+
+// HTTP → ユースケース → Gorm のスパンが親子になり、traceparent を引き継いで返すことを確かめる
+func TestTracingPropagatesAndNestsSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.NewTracerProviderWithExporter(tracing.Config{ServiceName: "test", SampleRatio: 1}, exporter)
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	db, err := dbinfra.OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dbinfra.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracing.NewDBTracing(db, tp); err != nil {
+		t.Fatal(err)
+	}
+	users := domain.NewUserUsecase(repository.NewTimeIsMoneyRepository(db), repository.NewAuditRepository(db), repository.NewOutboxRepository(db), repository.NewUnitOfWork(db))
+
+	r := chi.NewRouter()
+	r.Use(NewTracing(tp).Middleware)
+	r.Post("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		user := entity.User{ID: chi.URLParam(r, "id"), AuthID: "a1", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee}
+		if err := users.CreateUser(r.Context(), user); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/u1", nil)
+	req.Header.Set("traceparent", "00-"+parentTraceID+"-"+parentSpanID+"-01")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		if s.SpanContext.TraceID().String() != parentTraceID {
+			t.Errorf("span %q: trace id = %s, want %s", s.Name, s.SpanContext.TraceID(), parentTraceID)
+		}
+		if _, ok := byName[s.Name]; !ok {
+			byName[s.Name] = s
+		}
+	}
+	server, ok := byName["POST /users/{id}"]
+	if !ok {
+		t.Fatalf("server span not found in %v", spanNames(spans))
+	}
+	if server.SpanKind != trace.SpanKindServer || server.Parent.SpanID().String() != parentSpanID || !server.Parent.IsRemote() {
+		t.Errorf("server span: kind=%v parent=%s remote=%v, want child of the incoming traceparent", server.SpanKind, server.Parent.SpanID(), server.Parent.IsRemote())
+	}
+	usecase, ok := byName["UserUsecase.CreateUser"]
+	if !ok {
+		t.Fatalf("usecase span not found in %v", spanNames(spans))
+	}
+	if usecase.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("usecase span parent = %s, want server span %s", usecase.Parent.SpanID(), server.SpanContext.SpanID())
+	}
+	insert, ok := byName["db.create users"]
+	if !ok {
+		t.Fatalf("db span not found in %v", spanNames(spans))
+	}
+	if insert.SpanKind != trace.SpanKindClient || insert.Parent.SpanID() != usecase.SpanContext.SpanID() {
+		t.Errorf("db span: kind=%v parent=%s, want child of usecase span %s", insert.SpanKind, insert.Parent.SpanID(), usecase.SpanContext.SpanID())
+	}
+
+	// レスポンスの traceparent は同じトレースのサーバーのスパンを指す
+	want := "00-" + parentTraceID + "-" + server.SpanContext.SpanID().String() + "-01"
+	if got := rec.Header().Get("traceparent"); got != want {
+		t.Errorf("response traceparent = %q, want %q", got, want)
+	}
+}
+
+// 呼び出し元のトレースが無ければ新しいトレースを始める
+func TestTracingStartsNewTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.NewTracerProviderWithExporter(tracing.Config{ServiceName: "test", SampleRatio: 1}, exporter)
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	r := chi.NewRouter()
+	r.Use(NewTracing(tp).Middleware)
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %v", spanNames(spans))
+	}
+	s := spans[0]
+	if s.Name != "GET /health" || s.Parent.IsValid() {
+		t.Errorf("span %q: parent = %v, want a root span", s.Name, s.Parent)
+	}
+	if got := rec.Header().Get("traceparent"); !strings.HasPrefix(got, "00-"+s.SpanContext.TraceID().String()+"-") {
+		t.Errorf("response traceparent = %q, want trace %s", got, s.SpanContext.TraceID())
+	}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, 0, len(spans))
+	for _, s := range spans {
+		names = append(names, s.Name)
+	}
+	return names
+}
@@ -0,0 +1,91 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrForbiddenAddress は送信先が内部のネットワークのアドレスであることを表す
+var ErrForbiddenAddress = errors.New("destination address is not allowed")
+
+// 公開されたアドレスではない範囲（netip.Addr のメソッドで判定できないもの）
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // このネットワーク
+	netip.MustParsePrefix("100.64.0.0/10"),   // キャリアグレードNAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF プロトコル割り当て
+	netip.MustParsePrefix("198.18.0.0/15"),   // ベンチマーク
+	netip.MustParsePrefix("240.0.0.0/4"),     // 予約（ブロードキャストを含む）
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64（IPv4 の内部アドレスに変換される）
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // ローカルの NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // ドキュメント用
+	netip.MustParsePrefix("2002::/16"),       // 6to4（IPv4 のアドレスを含む）
+	netip.MustParsePrefix("fec0::/10"),       // サイトローカル（廃止）
+	netip.MustParsePrefix("100::/64"),        // 破棄用
+	netip.MustParsePrefix("2001::/23"),       // IETF プロトコル割り当て
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4 変換アドレス
+}
+
+// Guard は外部への送信先を公開されたアドレスに限る（SSRF 対策）
+// 送信先のURLを利用者が登録できる場合に、ループバック・リンクローカル（クラウドのメタデータ 169.254.169.254 など）・
+// プライベートアドレスへの送信を拒否する
+type Guard struct {
+	// 内部のネットワークへの送信を許す（ローカルの検証用の受信先など）
+	AllowPrivate bool
+}
+
+// NewGuard はGuardを生成する
+func NewGuard(allowPrivate bool) *Guard {
+	return &Guard{AllowPrivate: allowPrivate}
+}
+
+// CheckAddr は送信先のアドレスが許されているかを返す
+func (g *Guard) CheckAddr(addr netip.Addr) error {
+	if g.AllowPrivate {
+		return nil
+	}
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+		}
+	}
+	return nil
+}
+
+// CheckHost はホスト名を名前解決し、すべてのアドレスが許されているかを返す
+// 名前解決の結果は送信時に変わることがある（DNS リバインディング）ため、送信時にも Control で確かめる
+func (g *Guard) CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.CheckAddr(addr)
+	}
+	if g.AllowPrivate {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := g.CheckAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Control は net.Dialer の Control に使い、接続する直前に名前解決後のアドレスを確かめる
+func (g *Guard) Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	return g.CheckAddr(addrPort.Addr())
+}
@@ -0,0 +1,87 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestGuardCheckAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"127.1.2.3", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // クラウドのメタデータ
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false}, // NAT64 で 169.254.169.254 になる
+		{"2002:7f00:1::", false},      // 6to4 で 127.0.0.1 を含む
+	}
+	guard := NewGuard(false)
+	open := NewGuard(true)
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			addr := netip.MustParseAddr(tt.addr)
+			err := guard.CheckAddr(addr)
+			if tt.allowed && err != nil {
+				t.Errorf("CheckAddr() = %v, want nil", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrForbiddenAddress) {
+				t.Errorf("CheckAddr() = %v, want ErrForbiddenAddress", err)
+			}
+			if err := open.CheckAddr(addr); err != nil {
+				t.Errorf("CheckAddr() with AllowPrivate = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestGuardCheckHostLiteral(t *testing.T) {
+	guard := NewGuard(false)
+	if err := guard.CheckHost(context.Background(), "169.254.169.254"); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("CheckHost(169.254.169.254) = %v, want ErrForbiddenAddress", err)
+	}
+	if err := guard.CheckHost(context.Background(), "93.184.216.34"); err != nil {
+		t.Errorf("CheckHost(93.184.216.34) = %v, want nil", err)
+	}
+}
+
+func TestGuardControl(t *testing.T) {
+	guard := NewGuard(false)
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:4700:4700::1111]:443", true},
+		{"169.254.169.254:80", false},
+		{"127.0.0.1:8080", false},
+		{"[::1]:80", false},
+		{"not-an-address", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := guard.Control("tcp", tt.address, nil)
+			if tt.allowed != (err == nil) {
+				t.Errorf("Control() = %v, want allowed=%v", err, tt.allowed)
+			}
+		})
+	}
+}
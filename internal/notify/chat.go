@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ChatWebhook は Slack・Mattermost の Incoming Webhook に送る送信先
+// どちらも {"text": "..."} の形式で受け付ける
+type ChatWebhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewChatWebhook はChatWebhookを生成する
+func NewChatWebhook(url string) *ChatWebhook {
+	return &ChatWebhook{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (c *ChatWebhook) Notify(ctx context.Context, m Message) error {
+	body, err := json.Marshal(map[string]string{"text": m.Text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("chat webhook: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
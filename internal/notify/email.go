@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Email はメールで送る送信先
+// 宛先のユーザーの通知設定の言語でテンプレートから本文を組み立て、送信待ちのメールとして積む
+// 実際の送信は Mailer が行うため、SMTP サーバーが止まっていても通知は失われない
+type Email struct {
+	users repository.UserRepository
+	prefs repository.NotificationPreferenceRepository
+	queue repository.EmailRepository
+	now   func() time.Time
+}
+
+// NewEmail はEmailを生成する
+func NewEmail(users repository.UserRepository, prefs repository.NotificationPreferenceRepository, queue repository.EmailRepository) *Email {
+	return &Email{users: users, prefs: prefs, queue: queue, now: time.Now}
+}
+
+func (e *Email) Notify(ctx context.Context, m Message) error {
+	// 宛先の無い通知（チャンネル向けなど）はメールにしない
+	if m.UserID == "" {
+		return nil
+	}
+	pref, err := e.prefs.Find(ctx, m.UserID)
+	if err != nil {
+		return err
+	}
+	if !pref.Email {
+		return nil
+	}
+	user, err := e.users.FindFirst(ctx, m.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+
+	rendered := Rendered{Subject: m.Subject, Text: m.Text}
+	if m.Kind != "" {
+		data := map[string]any{}
+		for k, v := range m.Data {
+			data[k] = v
+		}
+		data["Name"] = user.Name
+		rendered, err = Render(m.Kind, pref.Locale, data)
+		if err != nil {
+			return err
+		}
+	}
+	return e.queue.Enqueue(ctx, entity.EmailMessage{
+		ID:            uuid.NewString(),
+		UserID:        user.ID,
+		To:            user.Email,
+		Subject:       rendered.Subject,
+		Text:          rendered.Text,
+		HTML:          rendered.HTML,
+		Status:        entity.EmailPending,
+		NextAttemptAt: e.now(),
+	})
+}
@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// 再試行の間隔の上限
+const maxEmailBackoff = 6 * time.Hour
+
+// Mailer は送信待ちのメールを SMTP サーバーへ送る
+type Mailer struct {
+	cfg    Config
+	repo   repository.EmailRepository
+	sender Sender
+	now    func() time.Time
+}
+
+// NewMailer はMailerを生成する
+func NewMailer(cfg Config, repo repository.EmailRepository) *Mailer {
+	return &Mailer{cfg: cfg, repo: repo, sender: NewSMTPSender(cfg.SMTP), now: time.Now}
+}
+
+// Run は ctx がキャンセルされるまで、PollInterval ごとに送信を繰り返す
+// SMTP サーバーが設定されていない場合は何もしない
+func (m *Mailer) Run(ctx context.Context) {
+	if m.cfg.SMTP.Host == "" {
+		return
+	}
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "mailer failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce は送信時刻を過ぎたメールを1回分送り、送れた件数を返す
+func (m *Mailer) RunOnce(ctx context.Context) (int, error) {
+	now := m.now()
+	list, err := m.repo.FindDue(ctx, now, m.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, msg := range list {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		// 送信中に他のプロセスが同じメールを取らないよう、タイムアウトより長く押さえる
+		ok, err := m.repo.Claim(ctx, msg, now.Add(2*m.cfg.SMTP.Timeout))
+		if err != nil {
+			return sent, err
+		}
+		if !ok {
+			continue
+		}
+		if err := m.sender.Send(ctx, msg); err != nil {
+			if err := m.fail(ctx, msg, err); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		if err := m.repo.MarkSent(ctx, msg.ID, m.now()); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// 失敗を記録し、指数バックオフで次の送信時刻を決める
+func (m *Mailer) fail(ctx context.Context, msg entity.EmailMessage, cause error) error {
+	attempts := msg.Attempts + 1
+	backoff := maxEmailBackoff
+	if attempts < 20 {
+		backoff = min(time.Duration(1<<attempts)*30*time.Second, maxEmailBackoff)
+	}
+	dead := attempts >= m.cfg.MaxAttempts
+	if dead {
+		slog.ErrorContext(ctx, "mailer giving up email", "email_id", msg.ID, "err", cause)
+	}
+	return m.repo.MarkFailed(ctx, msg.ID, attempts, m.now().Add(backoff), cause.Error(), dead)
+}
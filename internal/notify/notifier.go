@@ -0,0 +1,147 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// Message は通知の内容
+// Kind を指定した場合、メールは Text ではなく Kind のテンプレートに Data を当てはめて組み立てる
+type Message struct {
+	UserID  string // 宛先のユーザー（チャンネルへの通知など、宛先が無い場合は空）
+	Subject string
+	Text    string
+	Kind    string
+	Data    map[string]any
+}
+
+// Notifier は通知の送信先
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// Config は通知の設定
+type Config struct {
+	// チャットの Incoming Webhook の URL（Slack・Mattermost 共通、未設定の場合はチャットに送らない）
+	ChatWebhookURL string
+	// メールの送信に使う SMTP サーバー
+	SMTP SMTPConfig
+	// 送信待ちのメールを確認する間隔
+	PollInterval time.Duration
+	// 1回の確認で送る最大件数
+	BatchSize int
+	// メールの再試行の上限（これを超えたメールは dead にする）
+	MaxAttempts int
+}
+
+// NewConfigFromEnv は環境変数から通知の設定を読み込む
+// CHAT_WEBHOOK_URL: チャットの Incoming Webhook の URL
+// SMTP_HOST・SMTP_PORT（既定 587）・SMTP_USERNAME・SMTP_PASSWORD・SMTP_FROM: SMTP サーバー
+// SMTP_STARTTLS: false で STARTTLS を使わない（既定 true、true の場合は STARTTLS に対応していないサーバーには送らない）
+// EMAIL_MAX_ATTEMPTS: メールの再試行の上限（既定 8）
+func NewConfigFromEnv() Config {
+	cfg := Config{
+		ChatWebhookURL: os.Getenv("CHAT_WEBHOOK_URL"),
+		SMTP: SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     587,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			StartTLS: true,
+			Timeout:  30 * time.Second,
+		},
+		PollInterval: 10 * time.Second,
+		BatchSize:    100,
+		MaxAttempts:  8,
+	}
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			slog.Warn("invalid SMTP_PORT, using default", "value", v, "default", cfg.SMTP.Port)
+		} else {
+			cfg.SMTP.Port = n
+		}
+	}
+	if v := os.Getenv("SMTP_STARTTLS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			slog.Warn("invalid SMTP_STARTTLS, using default", "value", v, "default", cfg.SMTP.StartTLS)
+		} else {
+			cfg.SMTP.StartTLS = b
+		}
+	}
+	if v := os.Getenv("EMAIL_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			slog.Warn("invalid EMAIL_MAX_ATTEMPTS, using default", "value", v, "default", cfg.MaxAttempts)
+		} else {
+			cfg.MaxAttempts = n
+		}
+	}
+	return cfg
+}
+
+// NewNotifier は設定された送信先すべてに送る Notifier を返す
+// 送信先が1つも無い場合はログに出力するだけにする
+func NewNotifier(cfg Config, users repository.UserRepository, prefs repository.NotificationPreferenceRepository, emails repository.EmailRepository) Notifier {
+	var list Multi
+	if cfg.ChatWebhookURL != "" {
+		list = append(list, &chatOptOut{prefs: prefs, next: NewChatWebhook(cfg.ChatWebhookURL)})
+	}
+	if cfg.SMTP.Host != "" {
+		list = append(list, NewEmail(users, prefs, emails))
+	}
+	if len(list) == 0 {
+		return LogNotifier{}
+	}
+	return list
+}
+
+// Multi は複数の送信先に送る
+// 一部の送信先が失敗しても残りには送り、失敗をまとめて返す
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// chatOptOut はチャットでの通知を断っているユーザー宛ての通知を送らない
+type chatOptOut struct {
+	prefs repository.NotificationPreferenceRepository
+	next  Notifier
+}
+
+func (c *chatOptOut) Notify(ctx context.Context, m Message) error {
+	if m.UserID != "" {
+		pref, err := c.prefs.Find(ctx, m.UserID)
+		if err != nil {
+			return err
+		}
+		if !pref.Chat {
+			return nil
+		}
+	}
+	return c.next.Notify(ctx, m)
+}
+
+// LogNotifier はログに出力するだけの送信先
+// 本文は宛先の名前を含むため出力しない
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, m Message) error {
+	slog.InfoContext(ctx, "notify", "recipient", m.UserID, "subject", m.Subject)
+	return nil
+}
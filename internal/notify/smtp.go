@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+)
+
+// ErrStartTLSUnsupported は STARTTLS を有効にしているのに、サーバーが対応していないことを表す
+var ErrStartTLSUnsupported = errors.New("smtp: server does not support STARTTLS")
+
+// SMTPConfig は SMTP サーバーの設定
+type SMTPConfig struct {
+	Host     string // 未設定の場合はメールを送らない
+	Port     int
+	Username string // 未設定の場合は認証しない
+	Password string
+	From     string
+	// STARTTLS で暗号化する（サーバーが STARTTLS に対応していなければ送らずにエラーにする）
+	// 無効にできるのは、ローカルの検証用SMTPサーバーなど TLS を使えない場合だけ
+	StartTLS bool
+	// 1通の送信のタイムアウト
+	Timeout time.Duration
+}
+
+// Sender はメールの送信先
+type Sender interface {
+	Send(ctx context.Context, m entity.EmailMessage) error
+}
+
+// SMTPSender は SMTP サーバーにメールを送る
+type SMTPSender struct {
+	cfg SMTPConfig
+	// STARTTLS でサーバーの証明書を検証するルート証明書（nil の場合はシステムの証明書）
+	rootCAs *x509.CertPool
+}
+
+// NewSMTPSender はSMTPSenderを生成する
+func NewSMTPSender(cfg SMTPConfig) *SMTPSender {
+	return &SMTPSender{cfg: cfg}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, m entity.EmailMessage) error {
+	from, err := mail.ParseAddress(s.cfg.From)
+	if err != nil {
+		return fmt.Errorf("smtp: invalid from address: %w", err)
+	}
+	body, err := buildMIME(from, m, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(nc, s.cfg.Host)
+	if err != nil {
+		nc.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.cfg.StartTLS {
+		// 平文に切り替えて送ると、経路上で STARTTLS を取り除く攻撃で本文・認証情報が読まれるため送らない
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return ErrStartTLSUnsupported
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: s.cfg.Host, RootCAs: s.rootCAs}); err != nil {
+			return err
+		}
+	}
+	// PlainAuth は TLS でない接続では localhost 以外に認証情報を送らない
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(m.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// MIME の1パート
+type mimePart struct {
+	contentType string
+	body        string
+}
+
+// buildMIME は text/plain と text/html を multipart/alternative にまとめたメッセージを組み立てる
+// 件名は日本語を含むため MIME エンコードし、本文は quoted-printable で送る
+func buildMIME(from *mail.Address, m entity.EmailMessage, now time.Time) ([]byte, error) {
+	parts := []mimePart{{"text/plain; charset=UTF-8", m.Text}}
+	if m.HTML != "" {
+		parts = append(parts, mimePart{"text/html; charset=UTF-8", m.HTML})
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(strings.ReplaceAll(p.body, "\n", "\r\n"))); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	_, domain, _ := strings.Cut(from.Address, "@")
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", m.ID, domain)
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
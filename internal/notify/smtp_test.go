@@ -0,0 +1,439 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+)
+
+// smtpStandIn はテスト用の最小限の SMTP サーバー
+// EHLO・STARTTLS・AUTH PLAIN・MAIL・RCPT・DATA・QUIT だけに応答し、受け取った内容を記録する
+type smtpStandIn struct {
+	addr     string
+	tls      *tls.Config // nil の場合は STARTTLS を提供しない
+	username string
+	password string
+
+	mu       sync.Mutex
+	sessions []smtpSession
+}
+
+// smtpSession は1回の接続で受け取った内容
+type smtpSession struct {
+	commands []string
+	// AUTH を受け取った時点で TLS だったか
+	authOverTLS bool
+	authUser    string
+	from        string
+	rcpt        []string
+	data        []byte
+}
+
+func newSMTPStandIn(t *testing.T, tlsConfig *tls.Config, username, password string) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &smtpStandIn{addr: ln.Addr().String(), tls: tlsConfig, username: username, password: password}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(c net.Conn) {
+	defer c.Close()
+	var sess smtpSession
+	defer func() {
+		s.mu.Lock()
+		s.sessions = append(s.sessions, sess)
+		s.mu.Unlock()
+	}()
+	conn := textproto.NewConn(c)
+	reply := func(format string, args ...any) { conn.PrintfLine(format, args...) }
+	secure := false
+
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+		sess.commands = append(sess.commands, verb)
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			if s.tls != nil && !secure {
+				reply("250-STARTTLS")
+			}
+			if s.username != "" {
+				reply("250-AUTH PLAIN")
+			}
+			reply("250 8BITMIME")
+		case "STARTTLS":
+			if s.tls == nil || secure {
+				reply("502 not supported")
+				continue
+			}
+			reply("220 ready to start TLS")
+			tc := tls.Server(c, s.tls)
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			c, conn, secure = tc, textproto.NewConn(tc), true
+		case "AUTH":
+			mech, resp, _ := strings.Cut(arg, " ")
+			decoded, err := base64.StdEncoding.DecodeString(resp)
+			parts := strings.Split(string(decoded), "\x00")
+			sess.authOverTLS = secure
+			if !strings.EqualFold(mech, "PLAIN") || err != nil || len(parts) != 3 || parts[1] != s.username || parts[2] != s.password {
+				reply("535 authentication failed")
+				continue
+			}
+			sess.authUser = parts[1]
+			reply("235 authenticated")
+		case "MAIL":
+			// BODY=8BITMIME などのパラメーターは除く
+			addr, _, _ := strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ")
+			sess.from = strings.Trim(addr, "<>")
+			reply("250 ok")
+		case "RCPT":
+			sess.rcpt = append(sess.rcpt, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			sess.data = data
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// wait は n 回目の接続が終わるまで待ち、その内容を返す
+func (s *smtpStandIn) wait(t *testing.T, n int) smtpSession {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		if len(s.sessions) >= n {
+			sess := s.sessions[n-1]
+			s.mu.Unlock()
+			return sess
+		}
+		s.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("smtp session %d did not finish", n)
+	return smtpSession{}
+}
+
+// selfSignedTLS は 127.0.0.1 の自己署名証明書と、それを信頼するルート証明書を作る
+func selfSignedTLS(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "smtp stand-in"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+func testSender(t *testing.T, addr string, cfg SMTPConfig, rootCAs *x509.CertPool) *SMTPSender {
+	t.Helper()
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Host = host
+	cfg.Port, _ = strconv.Atoi(port)
+	cfg.From = "勤怠システム <noreply@example.com>"
+	cfg.Timeout = 5 * time.Second
+	s := NewSMTPSender(cfg)
+	s.rootCAs = rootCAs
+	return s
+}
+
+var testEmail = entity.EmailMessage{
+	ID:      "msg1",
+	To:      "taro@example.com",
+	Subject: "【勤怠】退勤の打刻漏れがあります",
+	Text:    "山田さん\n10月1日の退勤が打刻されていません。\n",
+	HTML:    "<p>山田さん</p>\n<p>10月1日の退勤が打刻されていません。</p>\n",
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	serverTLS, rootCAs := selfSignedTLS(t)
+	tests := []struct {
+		name       string
+		offerTLS   bool
+		startTLS   bool
+		username   string
+		password   string
+		serverPass string // 空の場合は password と同じ
+		wantErr    error  // nil でなければこのエラーになる
+		wantFail   bool   // 送れない（エラーの種類は問わない）
+		wantTLS    bool   // AUTH が TLS の上で行われる
+		wantNoMail bool   // MAIL コマンドまで進まない
+	}{
+		{
+			name:     "starttls and auth",
+			offerTLS: true, startTLS: true,
+			username: "mailer", password: "secret",
+			wantTLS: true,
+		},
+		{
+			name:     "starttls without auth",
+			offerTLS: true, startTLS: true,
+		},
+		{
+			name:     "starttls required but not offered",
+			startTLS: true,
+			username: "mailer", password: "secret",
+			wantErr:    ErrStartTLSUnsupported,
+			wantNoMail: true,
+		},
+		{
+			name:     "starttls disabled for a local server",
+			offerTLS: false, startTLS: false,
+		},
+		{
+			name:     "wrong password",
+			offerTLS: true, startTLS: true,
+			username: "mailer", password: "wrong", serverPass: "secret",
+			wantFail:   true,
+			wantNoMail: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var offered *tls.Config
+			if tt.offerTLS {
+				offered = serverTLS
+			}
+			serverPassword := tt.password
+			if tt.serverPass != "" {
+				serverPassword = tt.serverPass
+			}
+			srv := newSMTPStandIn(t, offered, tt.username, serverPassword)
+			sender := testSender(t, srv.addr, SMTPConfig{Username: tt.username, Password: tt.password, StartTLS: tt.startTLS}, rootCAs)
+
+			err := sender.Send(context.Background(), testEmail)
+			sess := srv.wait(t, 1)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Send() = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantFail:
+				if err == nil {
+					t.Fatal("Send() = nil, want an error")
+				}
+			case err != nil:
+				t.Fatalf("Send() = %v", err)
+			}
+			if tt.wantNoMail {
+				for _, c := range sess.commands {
+					if c == "MAIL" || c == "DATA" {
+						t.Fatalf("commands = %v, want no MAIL/DATA", sess.commands)
+					}
+				}
+				return
+			}
+
+			if tt.username != "" && (sess.authUser != tt.username || sess.authOverTLS != tt.wantTLS) {
+				t.Errorf("auth user=%q overTLS=%v, want %q %v", sess.authUser, sess.authOverTLS, tt.username, tt.wantTLS)
+			}
+			if sess.from != "noreply@example.com" || len(sess.rcpt) != 1 || sess.rcpt[0] != testEmail.To {
+				t.Errorf("envelope from=%q rcpt=%v", sess.from, sess.rcpt)
+			}
+			if len(sess.data) == 0 {
+				t.Fatal("no message data")
+			}
+		})
+	}
+}
+
+func TestSMTPSenderMIME(t *testing.T) {
+	serverTLS, rootCAs := selfSignedTLS(t)
+	srv := newSMTPStandIn(t, serverTLS, "", "")
+	sender := testSender(t, srv.addr, SMTPConfig{StartTLS: true}, rootCAs)
+	if err := sender.Send(context.Background(), testEmail); err != nil {
+		t.Fatal(err)
+	}
+	sess := srv.wait(t, 1)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(sess.data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != testEmail.Subject {
+		t.Errorf("Subject = %q, want %q", subject, testEmail.Subject)
+	}
+	// 件名はエンコードして ASCII だけで送る
+	if raw := msg.Header.Get("Subject"); !isASCII(raw) || !strings.HasPrefix(raw, "=?UTF-8?b?") {
+		t.Errorf("raw Subject = %q, want a B-encoded word", raw)
+	}
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) != 1 || from[0].Name != "勤怠システム" || from[0].Address != "noreply@example.com" {
+		t.Errorf("From = %v, %v", from, err)
+	}
+	if msg.Header.Get("To") != testEmail.To || msg.Header.Get("Message-ID") != "<msg1@example.com>" || msg.Header.Get("MIME-Version") != "1.0" {
+		t.Errorf("headers = %v", msg.Header)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	wants := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=UTF-8", testEmail.Text},
+		{"text/html; charset=UTF-8", testEmail.HTML},
+	}
+	for i, want := range wants {
+		p, err := mr.NextRawPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if ct := p.Header.Get("Content-Type"); ct != want.contentType {
+			t.Errorf("part %d: Content-Type = %q, want %q", i, ct, want.contentType)
+		}
+		if cte := p.Header.Get("Content-Transfer-Encoding"); cte != "quoted-printable" {
+			t.Errorf("part %d: Content-Transfer-Encoding = %q", i, cte)
+		}
+		raw, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isASCII(string(raw)) {
+			t.Errorf("part %d: body is not 7bit", i)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(string(raw))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != want.body {
+			t.Errorf("part %d: body = %q, want %q", i, got, want.body)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("unexpected extra part: %v", err)
+	}
+}
+
+// HTML が無い場合は text/plain だけを送る
+func TestBuildMIMETextOnly(t *testing.T) {
+	m := testEmail
+	m.HTML = ""
+	from := &mail.Address{Address: "noreply@example.com"}
+	data, err := buildMIME(from, m, time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(data))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	n := 0
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct := p.Header.Get("Content-Type"); ct != "text/plain; charset=UTF-8" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("got %d parts, want 1", n)
+	}
+	if got := msg.Header.Get("Date"); got != "Thu, 01 Oct 2026 09:00:00 +0000" {
+		t.Errorf("Date = %q", got)
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
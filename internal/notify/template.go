@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+)
+
+// 通知の種類（テンプレートの名前）
+const (
+	KindCheckInReminder  = "check_in_reminder"  // 出勤打刻のリマインド（Date）
+	KindCheckOutReminder = "check_out_reminder" // 退勤打刻のリマインド（Date・CheckIn・AutoClosed）
+	KindApprovalRequest  = "approval_request"   // 承認依頼（Requester・Summary・URL）
+	KindOvertimeWarning  = "overtime_warning"   // 36協定の上限に近づいた警告（Month・OvertimeHours・LimitHours）
+)
+
+// テンプレートは templates/{種類}.{言語}.txt（"subject" と "text" を define する）と
+// templates/{種類}.{言語}.html（HTMLの本文）の組で置く
+//
+//go:embed templates
+var templateFS embed.FS
+
+// "subject" などの define の名前はファイル間で重なるため、ファイルごとに別のテンプレートとして読み込む
+// キーは "{種類}.{言語}"
+var textTemplates, htmlTemplates = loadTemplates()
+
+func loadTemplates() (map[string]*texttemplate.Template, map[string]*htmltemplate.Template) {
+	texts := map[string]*texttemplate.Template{}
+	htmls := map[string]*htmltemplate.Template{}
+	files, err := fs.Glob(templateFS, "templates/*")
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range files {
+		base := path.Base(f)
+		name := strings.TrimSuffix(base, path.Ext(base))
+		switch path.Ext(base) {
+		case ".txt":
+			texts[name] = texttemplate.Must(texttemplate.New(base).Option("missingkey=zero").ParseFS(templateFS, f))
+		case ".html":
+			htmls[name] = htmltemplate.Must(htmltemplate.New(base).Option("missingkey=zero").ParseFS(templateFS, f))
+		}
+	}
+	return texts, htmls
+}
+
+// Rendered はテンプレートから組み立てたメール
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Render は通知の種類と言語のテンプレートに data を当てはめる
+// 対応していない言語は日本語にする
+func Render(kind, locale string, data map[string]any) (Rendered, error) {
+	if locale != entity.LocaleEn {
+		locale = entity.LocaleJa
+	}
+	name := kind + "." + locale
+	t, ok := textTemplates[name]
+	if !ok {
+		return Rendered{}, fmt.Errorf("notify: unknown template %q", kind)
+	}
+	var subject, text, html bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Rendered{}, err
+	}
+	if err := t.ExecuteTemplate(&text, "text", data); err != nil {
+		return Rendered{}, err
+	}
+	if h, ok := htmlTemplates[name]; ok {
+		html.WriteString(`<!DOCTYPE html><html lang="` + locale + `"><head><meta charset="utf-8"></head><body>`)
+		if err := h.Execute(&html, data); err != nil {
+			return Rendered{}, err
+		}
+		html.WriteString("</body></html>")
+	}
+	return Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// Sink はドメインイベントの配信先
+// 同じイベントが複数回届くことがあるため、m.ID で重複を除くこと
+type Sink interface {
+	// 配信先の名前（ログ用）
+	Name() string
+	// エラーを返した場合はリレーが時間をおいて再試行する
+	Deliver(ctx context.Context, m entity.OutboxMessage) error
+}
+
+// RelayConfig はリレーの設定
+type RelayConfig struct {
+	// 配信待ちのイベントを確認する間隔
+	PollInterval time.Duration
+	// 1回の確認で配信する最大件数
+	BatchSize int
+	// 再試行の上限（これを超えたイベントは dead にする）
+	MaxAttempts int
+	// 配信中のイベントを他のリレーが取らないようにする時間
+	Lease time.Duration
+}
+
+// NewRelayConfigFromEnv は環境変数からリレーの設定を読み込む
+// OUTBOX_POLL_INTERVAL: 確認する間隔（既定 5s）
+// OUTBOX_MAX_ATTEMPTS: 再試行の上限（既定 10）
+func NewRelayConfigFromEnv() RelayConfig {
+	cfg := RelayConfig{PollInterval: 5 * time.Second, BatchSize: 100, MaxAttempts: 10, Lease: time.Minute}
+	if v := os.Getenv("OUTBOX_POLL_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slog.Warn("invalid OUTBOX_POLL_INTERVAL, using default", "value", v, "default", cfg.PollInterval)
+		} else {
+			cfg.PollInterval = d
+		}
+	}
+	if v := os.Getenv("OUTBOX_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			slog.Warn("invalid OUTBOX_MAX_ATTEMPTS, using default", "value", v, "default", cfg.MaxAttempts)
+		} else {
+			cfg.MaxAttempts = n
+		}
+	}
+	return cfg
+}
+
+// 再試行の間隔の上限
+const maxBackoff = time.Hour
+
+// Relay はアウトボックスのイベントを配信先へ送る
+// 複数のプロセスで動かしても、Claim で取得したリレーだけが配信する
+type Relay struct {
+	cfg   RelayConfig
+	repo  repository.OutboxRepository
+	sinks []Sink
+	now   func() time.Time
+}
+
+// NewRelay はRelayを生成する
+func NewRelay(cfg RelayConfig, repo repository.OutboxRepository, sinks []Sink) *Relay {
+	return &Relay{cfg: cfg, repo: repo, sinks: sinks, now: time.Now}
+}
+
+// Run は ctx がキャンセルされるまで、PollInterval ごとに配信を繰り返す
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "outbox relay failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce は配信時刻を過ぎたイベントを1回分配信し、配信できた件数を返す
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	now := r.now()
+	list, err := r.repo.FindDue(ctx, now, r.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, m := range list {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		ok, err := r.repo.Claim(ctx, m, now.Add(r.cfg.Lease))
+		if err != nil {
+			return delivered, err
+		}
+		if !ok {
+			continue
+		}
+		if err := r.deliver(ctx, m); err != nil {
+			if err := r.fail(ctx, m, err); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err := r.repo.MarkDelivered(ctx, m.ID, r.now()); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// すべての配信先に送る
+// 一部の配信先だけ失敗した場合も全体を再試行するため、成功済みの配信先には同じイベントが再度届く
+func (r *Relay) deliver(ctx context.Context, m entity.OutboxMessage) error {
+	var errs []error
+	for _, s := range r.sinks {
+		if err := s.Deliver(ctx, m); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 失敗を記録し、指数バックオフで次の配信時刻を決める
+func (r *Relay) fail(ctx context.Context, m entity.OutboxMessage, cause error) error {
+	attempts := m.Attempts + 1
+	backoff := maxBackoff
+	if attempts < 20 {
+		backoff = min(time.Duration(1<<attempts)*time.Second, maxBackoff)
+	}
+	dead := attempts >= r.cfg.MaxAttempts
+	if dead {
+		slog.ErrorContext(ctx, "outbox relay giving up", "message_id", m.ID, "event_type", m.EventType, "attempts", attempts, "err", cause)
+	}
+	return r.repo.MarkFailed(ctx, m.ID, attempts, r.now().Add(backoff), cause.Error(), dead)
+}
@@ -0,0 +1,199 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// recordSink は届いたイベントの ID を記録し、fail が返すエラーで失敗する配信先
+type recordSink struct {
+	ids  []string
+	fail func(m entity.OutboxMessage) error
+}
+
+func (s *recordSink) Name() string { return "record" }
+
+func (s *recordSink) Deliver(ctx context.Context, m entity.OutboxMessage) error {
+	if s.fail != nil {
+		if err := s.fail(m); err != nil {
+			return err
+		}
+	}
+	s.ids = append(s.ids, m.ID)
+	return nil
+}
+
+// newTestRelay は SQLite のリポジトリと進められる時計でリレーを作る
+func newTestRelay(t *testing.T, sink Sink) (*Relay, *gorm.DB, *time.Time) {
+	t.Helper()
+	db, err := dbinfra.OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dbinfra.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	r := NewRelay(RelayConfig{BatchSize: 10, MaxAttempts: 3, Lease: time.Minute}, repository.NewOutboxRepository(db), []Sink{sink})
+	r.now = func() time.Time { return now }
+	return r, db, &now
+}
+
+func addMessage(t *testing.T, db *gorm.DB, id string, occurredAt time.Time) {
+	t.Helper()
+	m := entity.OutboxMessage{ID: id, EventType: entity.EventUserCreated, AggregateType: entity.AuditEntityUser, AggregateID: id, OccurredAt: occurredAt}
+	if err := repository.NewOutboxRepository(db).Add(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func findMessage(t *testing.T, db *gorm.DB, id string) entity.OutboxMessage {
+	t.Helper()
+	var m entity.OutboxMessage
+	if err := db.First(&m, "id = ?", id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// 配信時刻を過ぎたイベントだけを古い順に配信する
+func TestRelayDeliversInOrder(t *testing.T) {
+	sink := &recordSink{}
+	r, db, now := newTestRelay(t, sink)
+	addMessage(t, db, "m2", now.Add(-time.Minute))
+	addMessage(t, db, "m1", now.Add(-time.Hour))
+	addMessage(t, db, "m3", now.Add(time.Minute))
+
+	n, err := r.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(sink.ids) != 2 || sink.ids[0] != "m1" || sink.ids[1] != "m2" {
+		t.Fatalf("delivered %d %v, want m1 then m2", n, sink.ids)
+	}
+	if m := findMessage(t, db, "m1"); m.Status != entity.OutboxDelivered || !m.DeliveredAt.Equal(*now) {
+		t.Errorf("m1 = %s at %v, want delivered", m.Status, m.DeliveredAt)
+	}
+	if m := findMessage(t, db, "m3"); m.Status != entity.OutboxPending {
+		t.Errorf("m3 = %s, want pending", m.Status)
+	}
+
+	// 配信済みのイベントは再度送らない
+	*now = now.Add(time.Hour)
+	if _, err := r.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.ids) != 3 || sink.ids[2] != "m3" {
+		t.Errorf("delivered %v, want m3 only once more", sink.ids)
+	}
+}
+
+// 失敗したイベントは指数バックオフで再試行し、上限に達したら dead にする
+func TestRelayRetriesWithBackoff(t *testing.T) {
+	sink := &recordSink{fail: func(entity.OutboxMessage) error { return errors.New("unavailable") }}
+	r, db, now := newTestRelay(t, sink)
+	addMessage(t, db, "m1", *now)
+	start := *now
+
+	for attempt, backoff := range []time.Duration{2 * time.Second, 4 * time.Second} {
+		if n, err := r.RunOnce(context.Background()); err != nil || n != 0 {
+			t.Fatalf("attempt %d: RunOnce() = %d, %v", attempt+1, n, err)
+		}
+		m := findMessage(t, db, "m1")
+		if m.Status != entity.OutboxPending || m.Attempts != attempt+1 || !m.NextAttemptAt.Equal(now.Add(backoff)) || m.LastError != "record: unavailable" {
+			t.Fatalf("attempt %d: message = %+v, want pending until +%v", attempt+1, m, backoff)
+		}
+		// 次の配信時刻までは再試行しない
+		*now = now.Add(backoff - time.Second)
+		if _, err := r.RunOnce(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if m := findMessage(t, db, "m1"); m.Attempts != attempt+1 {
+			t.Fatalf("retried before backoff: attempts = %d", m.Attempts)
+		}
+		*now = now.Add(time.Second)
+	}
+
+	if _, err := r.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m := findMessage(t, db, "m1"); m.Status != entity.OutboxDead || m.Attempts != 3 {
+		t.Fatalf("message = %s after %d attempts, want dead after 3", m.Status, m.Attempts)
+	}
+	*now = start.Add(24 * time.Hour)
+	sink.fail = nil
+	if _, err := r.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.ids) != 0 {
+		t.Errorf("delivered %v, want dead messages skipped", sink.ids)
+	}
+}
+
+// 一部のイベントが失敗しても、後ろのイベントの配信は続ける
+func TestRelayContinuesAfterFailure(t *testing.T) {
+	sink := &recordSink{fail: func(m entity.OutboxMessage) error {
+		if m.ID == "m1" {
+			return errors.New("rejected")
+		}
+		return nil
+	}}
+	r, db, now := newTestRelay(t, sink)
+	addMessage(t, db, "m1", now.Add(-2*time.Minute))
+	addMessage(t, db, "m2", now.Add(-time.Minute))
+
+	n, err := r.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || len(sink.ids) != 1 || sink.ids[0] != "m2" {
+		t.Fatalf("delivered %d %v, want m2", n, sink.ids)
+	}
+	if m := findMessage(t, db, "m1"); m.Status != entity.OutboxPending || m.Attempts != 1 {
+		t.Errorf("m1 = %s after %d attempts, want pending for retry", m.Status, m.Attempts)
+	}
+}
+
+// 他のリレーが先に取得したイベントは配信しない
+func TestRelaySkipsClaimedMessage(t *testing.T) {
+	sink := &recordSink{}
+	r, db, now := newTestRelay(t, sink)
+	addMessage(t, db, "m1", *now)
+	repo := repository.NewOutboxRepository(db)
+	due, err := repo.FindDue(context.Background(), *now, 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("FindDue() = %v, %v", due, err)
+	}
+	if ok, err := repo.Claim(context.Background(), due[0], now.Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("Claim() = %v, %v", ok, err)
+	}
+	if ok, err := repo.Claim(context.Background(), due[0], now.Add(time.Minute)); err != nil || ok {
+		t.Fatalf("second Claim() = %v, %v, want false", ok, err)
+	}
+
+	if _, err := r.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.ids) != 0 {
+		t.Fatalf("delivered %v while another relay holds the lease", sink.ids)
+	}
+	// リースが切れたら配信する
+	*now = now.Add(time.Minute)
+	if _, err := r.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.ids) != 1 {
+		t.Errorf("delivered %v after the lease expired, want m1", sink.ids)
+	}
+}
@@ -0,0 +1,32 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/webhook"
+)
+
+// LogSink はイベントをログに出力するだけの配信先
+// 配信先が1つも設定されていない場合でも、イベントが流れていることを確認できる
+type LogSink struct{}
+
+// NewLogSink はLogSinkを生成する
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+func (s *LogSink) Name() string {
+	return "log"
+}
+
+func (s *LogSink) Deliver(ctx context.Context, m entity.OutboxMessage) error {
+	slog.InfoContext(ctx, "event", "message_id", m.ID, "event_type", m.EventType, "aggregate_type", m.AggregateType, "aggregate_id", m.AggregateID)
+	return nil
+}
+
+// NewSinks は有効な配信先の一覧を返す
+func NewSinks(logSink *LogSink, webhookSink *webhook.Sink) []Sink {
+	return []Sink{logSink, webhookSink}
+}
@@ -0,0 +1,337 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v6.32.1
+// source: api/attendance.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// オフラインで記録した1件の打刻
+type OfflinePunch struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// クライアントが打刻ごとに付けるID（再送しても二重に取り込まない）
+	ClientId string `protobuf:"bytes,1,opt,name=clientId,proto3" json:"clientId,omitempty"`
+	// check_in・check_out・break_start・break_end
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=occurredAt,proto3" json:"occurredAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OfflinePunch) Reset() {
+	*x = OfflinePunch{}
+	mi := &file_api_attendance_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OfflinePunch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OfflinePunch) ProtoMessage() {}
+
+func (x *OfflinePunch) ProtoReflect() protoreflect.Message {
+	mi := &file_api_attendance_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OfflinePunch.ProtoReflect.Descriptor instead.
+func (*OfflinePunch) Descriptor() ([]byte, []int) {
+	return file_api_attendance_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *OfflinePunch) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *OfflinePunch) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *OfflinePunch) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+// 1件の打刻の取り込みの結果
+type SyncResult struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ClientId string                 `protobuf:"bytes,1,opt,name=clientId,proto3" json:"clientId,omitempty"`
+	// accepted・duplicate・overlaps_existing・closed_period・invalid
+	Status        string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	EventId       string `protobuf:"bytes,3,opt,name=eventId,proto3" json:"eventId,omitempty"`
+	AttendanceId  string `protobuf:"bytes,4,opt,name=attendanceId,proto3" json:"attendanceId,omitempty"`
+	Message       string `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncResult) Reset() {
+	*x = SyncResult{}
+	mi := &file_api_attendance_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncResult) ProtoMessage() {}
+
+func (x *SyncResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_attendance_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncResult.ProtoReflect.Descriptor instead.
+func (*SyncResult) Descriptor() ([]byte, []int) {
+	return file_api_attendance_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SyncResult) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *SyncResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SyncResult) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *SyncResult) GetAttendanceId() string {
+	if x != nil {
+		return x.AttendanceId
+	}
+	return ""
+}
+
+func (x *SyncResult) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// ログインユーザー（メタデータ authorization の Bearer トークン）の打刻として取り込む
+type SyncPunchesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Punches       []*OfflinePunch        `protobuf:"bytes,1,rep,name=punches,proto3" json:"punches,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncPunchesRequest) Reset() {
+	*x = SyncPunchesRequest{}
+	mi := &file_api_attendance_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncPunchesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncPunchesRequest) ProtoMessage() {}
+
+func (x *SyncPunchesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_attendance_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncPunchesRequest.ProtoReflect.Descriptor instead.
+func (*SyncPunchesRequest) Descriptor() ([]byte, []int) {
+	return file_api_attendance_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SyncPunchesRequest) GetPunches() []*OfflinePunch {
+	if x != nil {
+		return x.Punches
+	}
+	return nil
+}
+
+type SyncPunchesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 送られた順の結果
+	Results       []*SyncResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncPunchesResponse) Reset() {
+	*x = SyncPunchesResponse{}
+	mi := &file_api_attendance_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncPunchesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncPunchesResponse) ProtoMessage() {}
+
+func (x *SyncPunchesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_attendance_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncPunchesResponse.ProtoReflect.Descriptor instead.
+func (*SyncPunchesResponse) Descriptor() ([]byte, []int) {
+	return file_api_attendance_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SyncPunchesResponse) GetResults() []*SyncResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_api_attendance_proto protoreflect.FileDescriptor
+
+const file_api_attendance_proto_rawDesc = "" +
+	"\n" +
+	"\x14api/attendance.proto\x12\n" +
+	"attendance\x1a\x1fgoogle/protobuf/timestamp.proto\"z\n" +
+	"\fOfflinePunch\x12\x1a\n" +
+	"\bclientId\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12:\n" +
+	"\n" +
+	"occurredAt\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\"\x98\x01\n" +
+	"\n" +
+	"SyncResult\x12\x1a\n" +
+	"\bclientId\x18\x01 \x01(\tR\bclientId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x18\n" +
+	"\aeventId\x18\x03 \x01(\tR\aeventId\x12\"\n" +
+	"\fattendanceId\x18\x04 \x01(\tR\fattendanceId\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\"H\n" +
+	"\x12SyncPunchesRequest\x122\n" +
+	"\apunches\x18\x01 \x03(\v2\x18.attendance.OfflinePunchR\apunches\"G\n" +
+	"\x13SyncPunchesResponse\x120\n" +
+	"\aresults\x18\x01 \x03(\v2\x16.attendance.SyncResultR\aresults2c\n" +
+	"\x11AttendanceService\x12N\n" +
+	"\vSyncPunches\x12\x1e.attendance.SyncPunchesRequest\x1a\x1f.attendance.SyncPunchesResponseB.Z,github.com/enkazu1116/go_home/internal/pb;pbb\x06proto3"
+
+var (
+	file_api_attendance_proto_rawDescOnce sync.Once
+	file_api_attendance_proto_rawDescData []byte
+)
+
+func file_api_attendance_proto_rawDescGZIP() []byte {
+	file_api_attendance_proto_rawDescOnce.Do(func() {
+		file_api_attendance_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_attendance_proto_rawDesc), len(file_api_attendance_proto_rawDesc)))
+	})
+	return file_api_attendance_proto_rawDescData
+}
+
+var file_api_attendance_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_api_attendance_proto_goTypes = []any{
+	(*OfflinePunch)(nil),          // 0: attendance.OfflinePunch
+	(*SyncResult)(nil),            // 1: attendance.SyncResult
+	(*SyncPunchesRequest)(nil),    // 2: attendance.SyncPunchesRequest
+	(*SyncPunchesResponse)(nil),   // 3: attendance.SyncPunchesResponse
+	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+}
+var file_api_attendance_proto_depIdxs = []int32{
+	4, // 0: attendance.OfflinePunch.occurredAt:type_name -> google.protobuf.Timestamp
+	0, // 1: attendance.SyncPunchesRequest.punches:type_name -> attendance.OfflinePunch
+	1, // 2: attendance.SyncPunchesResponse.results:type_name -> attendance.SyncResult
+	2, // 3: attendance.AttendanceService.SyncPunches:input_type -> attendance.SyncPunchesRequest
+	3, // 4: attendance.AttendanceService.SyncPunches:output_type -> attendance.SyncPunchesResponse
+	4, // [4:5] is the sub-list for method output_type
+	3, // [3:4] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_api_attendance_proto_init() }
+func file_api_attendance_proto_init() {
+	if File_api_attendance_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_attendance_proto_rawDesc), len(file_api_attendance_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_attendance_proto_goTypes,
+		DependencyIndexes: file_api_attendance_proto_depIdxs,
+		MessageInfos:      file_api_attendance_proto_msgTypes,
+	}.Build()
+	File_api_attendance_proto = out.File
+	file_api_attendance_proto_goTypes = nil
+	file_api_attendance_proto_depIdxs = nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/enkazu1116/go_home/internal/entity"
 	"gorm.io/gorm"
@@ -14,7 +15,13 @@ type AttendanceRepository interface {
 	Update(ctx context.Context, a entity.Attendance) error
 	FindByID(ctx context.Context, id string) (*entity.Attendance, error)
 	FindByUserID(ctx context.Context, userID string) ([]entity.Attendance, error)
+	FindByUserAndDate(ctx context.Context, userID string, date time.Time) (*entity.Attendance, error)
+	FindByUserAndRange(ctx context.Context, userID string, from, to time.Time) ([]entity.Attendance, error)
+	// FindByDate は全員の指定日の勤怠を取得する
+	FindByDate(ctx context.Context, date time.Time) ([]entity.Attendance, error)
 	FindAll(ctx context.Context) ([]entity.Attendance, error)
+	// FindOpenBefore は勤務日が date 以前で、退勤していない（打刻漏れの印も無い）勤怠を取得する
+	FindOpenBefore(ctx context.Context, date time.Time) ([]entity.Attendance, error)
 	Delete(ctx context.Context, a entity.Attendance) error
 }
 
@@ -27,20 +34,51 @@ func NewAttendanceRepository(db *gorm.DB) AttendanceRepository {
 	return &attendanceGormRepo{db: db}
 }
 
+// Create は勤怠を作成する。バージョンの指定が無い場合は 1 から始める
 func (r *attendanceGormRepo) Create(ctx context.Context, a entity.Attendance) error {
-	return r.db.WithContext(ctx).Create(&a).Error
+	if a.Version == 0 {
+		a.Version = 1
+	}
+	err := conn(ctx, r.db).Create(&a).Error
+
+	// 同じユーザー・同じ日の勤怠が既にある場合
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	return err
 }
 
+// Update は a.Version が DB上のバージョンと一致する場合のみ更新する（楽観的排他制御）
 func (r *attendanceGormRepo) Update(ctx context.Context, a entity.Attendance) error {
-	return r.db.WithContext(ctx).Save(&a).Error
+	result := conn(ctx, r.db).
+		Model(&entity.Attendance{}).
+		Where("id = ? AND version = ?", a.ID, a.Version).
+		Select("CheckIn", "CheckOut", "IsLate", "BreakMinutes", "Incomplete", "WorkLocation", "NeedsReview", "Version", "UpdatedAt").
+		Updates(&entity.Attendance{
+			CheckIn:      a.CheckIn,
+			CheckOut:     a.CheckOut,
+			IsLate:       a.IsLate,
+			BreakMinutes: a.BreakMinutes,
+			Incomplete:   a.Incomplete,
+			WorkLocation: a.WorkLocation,
+			NeedsReview:  a.NeedsReview,
+			Version:      a.Version + 1,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return notFoundOrStale(conn(ctx, r.db), &entity.Attendance{}, a.ID)
+	}
+	return nil
 }
 
 func (r *attendanceGormRepo) FindByID(ctx context.Context, id string) (*entity.Attendance, error) {
 	var a entity.Attendance
-	err := r.db.WithContext(ctx).First(&a, "id = ?", id).Error
+	err := conn(ctx, r.db).First(&a, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("attendance not found")
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
@@ -49,16 +87,54 @@ func (r *attendanceGormRepo) FindByID(ctx context.Context, id string) (*entity.A
 
 func (r *attendanceGormRepo) FindByUserID(ctx context.Context, userID string) ([]entity.Attendance, error) {
 	var list []entity.Attendance
-	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&list).Error
+	err := conn(ctx, r.db).Where("user_id = ?", userID).Find(&list).Error
+	return list, err
+}
+
+// FindByUserAndDate はユーザーの指定日の勤怠を取得する
+func (r *attendanceGormRepo) FindByUserAndDate(ctx context.Context, userID string, date time.Time) (*entity.Attendance, error) {
+	var a entity.Attendance
+	err := conn(ctx, r.db).Where("user_id = ? AND date = ?", userID, date).First(&a).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// FindByUserAndRange はユーザーの勤務日が from 以上 to 未満の勤怠を日付順に取得する
+func (r *attendanceGormRepo) FindByUserAndRange(ctx context.Context, userID string, from, to time.Time) ([]entity.Attendance, error) {
+	var list []entity.Attendance
+	err := conn(ctx, r.db).
+		Where("user_id = ? AND date >= ? AND date < ?", userID, from, to).
+		Order("date ASC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *attendanceGormRepo) FindByDate(ctx context.Context, date time.Time) ([]entity.Attendance, error) {
+	var list []entity.Attendance
+	err := conn(ctx, r.db).Where("date = ?", date).Find(&list).Error
 	return list, err
 }
 
 func (r *attendanceGormRepo) FindAll(ctx context.Context) ([]entity.Attendance, error) {
 	var list []entity.Attendance
-	err := r.db.WithContext(ctx).Find(&list).Error
+	err := conn(ctx, r.db).Find(&list).Error
+	return list, err
+}
+
+func (r *attendanceGormRepo) FindOpenBefore(ctx context.Context, date time.Time) ([]entity.Attendance, error) {
+	var list []entity.Attendance
+	err := conn(ctx, r.db).
+		Where("check_out = ? AND incomplete = ? AND date <= ?", time.Time{}, false, date).
+		Order("date ASC").
+		Find(&list).Error
 	return list, err
 }
 
 func (r *attendanceGormRepo) Delete(ctx context.Context, a entity.Attendance) error {
-	return r.db.WithContext(ctx).Delete(&a).Error
+	return conn(ctx, r.db).Delete(&a).Error
 }
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// AuditFilter は監査ログの検索条件（ゼロ値の項目は条件にしない）
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	ActorID    string
+	From       time.Time // この時刻以降（含む）
+	To         time.Time // この時刻より前（含まない）
+	Limit      int
+}
+
+// AuditRepository は監査ログのリポジトリインターフェース
+// 監査ログは追記のみのため、更新・削除のメソッドは持たない
+type AuditRepository interface {
+	Create(ctx context.Context, log entity.AuditLog) error
+	Find(ctx context.Context, filter AuditFilter) ([]entity.AuditLog, error)
+}
+
+// Gorm実装
+type auditGormRepo struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditGormRepo{db: db}
+}
+
+func (r *auditGormRepo) Create(ctx context.Context, log entity.AuditLog) error {
+	return conn(ctx, r.db).Create(&log).Error
+}
+
+func (r *auditGormRepo) Find(ctx context.Context, filter AuditFilter) ([]entity.AuditLog, error) {
+	q := conn(ctx, r.db).Model(&entity.AuditLog{})
+	if filter.EntityType != "" {
+		q = q.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		q = q.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.ActorID != "" {
+		q = q.Where("actor_id = ?", filter.ActorID)
+	}
+	if !filter.From.IsZero() {
+		q = q.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q = q.Where("created_at < ?", filter.To)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	var list []entity.AuditLog
+	err := q.Order("created_at DESC").Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HolidayRepository は会社の休日のリポジトリインターフェース
+type HolidayRepository interface {
+	// Create は休日を登録する。既に登録している日なら ErrConflict を返す
+	Create(ctx context.Context, h entity.Holiday) error
+	Delete(ctx context.Context, date string) error
+	// FindRange は from 以上 to 以下の日（"2006-01-02"）の休日を日付順に取得する
+	FindRange(ctx context.Context, from, to string) ([]entity.Holiday, error)
+}
+
+// LeaveRepository は休暇のリポジトリインターフェース
+type LeaveRepository interface {
+	Create(ctx context.Context, l entity.Leave) error
+	Delete(ctx context.Context, id string) error
+	FindByID(ctx context.Context, id string) (*entity.Leave, error)
+	// FindRange は from から to（"2006-01-02"、両端を含む）に重なる休暇を取得する（userID が空なら全員）
+	FindRange(ctx context.Context, userID, from, to string) ([]entity.Leave, error)
+}
+
+// CalendarTokenRepository はカレンダーのフィードのトークンのリポジトリインターフェース
+type CalendarTokenRepository interface {
+	// Save はユーザーのトークンを保存する（既にあれば置き換える）
+	Save(ctx context.Context, t entity.CalendarToken) error
+	Delete(ctx context.Context, userID string) error
+	FindByHash(ctx context.Context, tokenHash string) (*entity.CalendarToken, error)
+}
+
+// Gorm実装
+type holidayGormRepo struct {
+	db *gorm.DB
+}
+
+func NewHolidayRepository(db *gorm.DB) HolidayRepository {
+	return &holidayGormRepo{db: db}
+}
+
+func (r *holidayGormRepo) Create(ctx context.Context, h entity.Holiday) error {
+	err := conn(ctx, r.db).Create(&h).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *holidayGormRepo) Delete(ctx context.Context, date string) error {
+	result := conn(ctx, r.db).Delete(&entity.Holiday{}, "date = ?", date)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *holidayGormRepo) FindRange(ctx context.Context, from, to string) ([]entity.Holiday, error) {
+	var list []entity.Holiday
+	err := conn(ctx, r.db).Where("date >= ? AND date <= ?", from, to).Order("date").Find(&list).Error
+	return list, err
+}
+
+type leaveGormRepo struct {
+	db *gorm.DB
+}
+
+func NewLeaveRepository(db *gorm.DB) LeaveRepository {
+	return &leaveGormRepo{db: db}
+}
+
+func (r *leaveGormRepo) Create(ctx context.Context, l entity.Leave) error {
+	return conn(ctx, r.db).Create(&l).Error
+}
+
+func (r *leaveGormRepo) Delete(ctx context.Context, id string) error {
+	result := conn(ctx, r.db).Delete(&entity.Leave{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *leaveGormRepo) FindByID(ctx context.Context, id string) (*entity.Leave, error) {
+	var l entity.Leave
+	if err := conn(ctx, r.db).First(&l, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *leaveGormRepo) FindRange(ctx context.Context, userID, from, to string) ([]entity.Leave, error) {
+	var list []entity.Leave
+	q := conn(ctx, r.db).Where("start_date <= ? AND end_date >= ?", to, from)
+	if userID != "" {
+		q = q.Where("user_id = ?", userID)
+	}
+	err := q.Order("start_date, user_id").Find(&list).Error
+	return list, err
+}
+
+type calendarTokenGormRepo struct {
+	db *gorm.DB
+}
+
+func NewCalendarTokenRepository(db *gorm.DB) CalendarTokenRepository {
+	return &calendarTokenGormRepo{db: db}
+}
+
+func (r *calendarTokenGormRepo) Save(ctx context.Context, t entity.CalendarToken) error {
+	return conn(ctx, r.db).Clauses(clause.OnConflict{UpdateAll: true}).Create(&t).Error
+}
+
+func (r *calendarTokenGormRepo) Delete(ctx context.Context, userID string) error {
+	result := conn(ctx, r.db).Delete(&entity.CalendarToken{}, "user_id = ?", userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *calendarTokenGormRepo) FindByHash(ctx context.Context, tokenHash string) (*entity.CalendarToken, error) {
+	var t entity.CalendarToken
+	if err := conn(ctx, r.db).First(&t, "token_hash = ?", tokenHash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
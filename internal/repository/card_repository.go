@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// CardReaderRepository はICカードリーダーのリポジトリインターフェース
+type CardReaderRepository interface {
+	Create(ctx context.Context, c entity.CardReader) error
+	Update(ctx context.Context, c entity.CardReader) error
+	Delete(ctx context.Context, id string) error
+	FindByID(ctx context.Context, id string) (*entity.CardReader, error)
+	FindAll(ctx context.Context) ([]entity.CardReader, error)
+	// Touch は端末が最後に打刻を送った時刻を記録する
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// CardRepository はICカードのリポジトリインターフェース
+type CardRepository interface {
+	// Create はカードをユーザーに紐づける。同じカードが既にある場合は ErrConflict を返す
+	Create(ctx context.Context, c entity.Card) error
+	Delete(ctx context.Context, cardID string) error
+	Find(ctx context.Context, cardID string) (*entity.Card, error)
+	// FindAll はカードの一覧を取得する。userID を指定した場合はそのユーザーのカードのみ
+	FindAll(ctx context.Context, userID string) ([]entity.Card, error)
+}
+
+// Gorm実装
+type cardReaderGormRepo struct {
+	db *gorm.DB
+}
+
+func NewCardReaderRepository(db *gorm.DB) CardReaderRepository {
+	return &cardReaderGormRepo{db: db}
+}
+
+func (r *cardReaderGormRepo) Create(ctx context.Context, c entity.CardReader) error {
+	return conn(ctx, r.db).Create(&c).Error
+}
+
+// Update は名前・設置場所・有効かどうかを更新する（APIキーは変えない）
+func (r *cardReaderGormRepo) Update(ctx context.Context, c entity.CardReader) error {
+	result := conn(ctx, r.db).
+		Model(&entity.CardReader{}).
+		Where("id = ?", c.ID).
+		Select("Name", "Location", "Active", "UpdatedAt").
+		Updates(&c)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *cardReaderGormRepo) Delete(ctx context.Context, id string) error {
+	result := conn(ctx, r.db).Delete(&entity.CardReader{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *cardReaderGormRepo) FindByID(ctx context.Context, id string) (*entity.CardReader, error) {
+	var c entity.CardReader
+	err := conn(ctx, r.db).First(&c, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *cardReaderGormRepo) FindAll(ctx context.Context) ([]entity.CardReader, error) {
+	var list []entity.CardReader
+	err := conn(ctx, r.db).Order("name").Find(&list).Error
+	return list, err
+}
+
+func (r *cardReaderGormRepo) Touch(ctx context.Context, id string, at time.Time) error {
+	return conn(ctx, r.db).Model(&entity.CardReader{}).Where("id = ?", id).UpdateColumn("last_seen_at", at).Error
+}
+
+// Gorm実装
+type cardGormRepo struct {
+	db *gorm.DB
+}
+
+func NewCardRepository(db *gorm.DB) CardRepository {
+	return &cardGormRepo{db: db}
+}
+
+func (r *cardGormRepo) Create(ctx context.Context, c entity.Card) error {
+	err := conn(ctx, r.db).Create(&c).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *cardGormRepo) Delete(ctx context.Context, cardID string) error {
+	result := conn(ctx, r.db).Delete(&entity.Card{}, "card_id = ?", cardID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *cardGormRepo) Find(ctx context.Context, cardID string) (*entity.Card, error) {
+	var c entity.Card
+	err := conn(ctx, r.db).First(&c, "card_id = ?", cardID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *cardGormRepo) FindAll(ctx context.Context, userID string) ([]entity.Card, error) {
+	var list []entity.Card
+	q := conn(ctx, r.db).Order("user_id, card_id")
+	if userID != "" {
+		q = q.Where("user_id = ?", userID)
+	}
+	err := q.Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// ChatIdentityRepository はチャットアカウントのリポジトリインターフェース
+type ChatIdentityRepository interface {
+	// Create は紐づけを追加する。同じチャットアカウントが既にある場合は ErrConflict を返す
+	Create(ctx context.Context, c entity.ChatIdentity) error
+	Delete(ctx context.Context, provider, chatUserID string) error
+	Find(ctx context.Context, provider, chatUserID string) (*entity.ChatIdentity, error)
+	FindAll(ctx context.Context) ([]entity.ChatIdentity, error)
+}
+
+// Gorm実装
+type chatIdentityGormRepo struct {
+	db *gorm.DB
+}
+
+func NewChatIdentityRepository(db *gorm.DB) ChatIdentityRepository {
+	return &chatIdentityGormRepo{db: db}
+}
+
+func (r *chatIdentityGormRepo) Create(ctx context.Context, c entity.ChatIdentity) error {
+	err := conn(ctx, r.db).Create(&c).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *chatIdentityGormRepo) Delete(ctx context.Context, provider, chatUserID string) error {
+	result := conn(ctx, r.db).Delete(&entity.ChatIdentity{}, "provider = ? AND chat_user_id = ?", provider, chatUserID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *chatIdentityGormRepo) Find(ctx context.Context, provider, chatUserID string) (*entity.ChatIdentity, error) {
+	var c entity.ChatIdentity
+	err := conn(ctx, r.db).First(&c, "provider = ? AND chat_user_id = ?", provider, chatUserID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *chatIdentityGormRepo) FindAll(ctx context.Context) ([]entity.ChatIdentity, error) {
+	var list []entity.ChatIdentity
+	err := conn(ctx, r.db).Order("provider, chat_user_id").Find(&list).Error
+	return list, err
+}
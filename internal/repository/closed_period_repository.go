@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// ClosedPeriodRepository は締め済みの期間のリポジトリインターフェース
+type ClosedPeriodRepository interface {
+	// Create は期間を締める。既に締めている場合は ErrConflict を返す
+	Create(ctx context.Context, c entity.ClosedPeriod) error
+	Delete(ctx context.Context, month string) error
+	// IsClosed は月（"2006-01"）が締め済みかどうかを返す
+	IsClosed(ctx context.Context, month string) (bool, error)
+	FindAll(ctx context.Context) ([]entity.ClosedPeriod, error)
+}
+
+// Gorm実装
+type closedPeriodGormRepo struct {
+	db *gorm.DB
+}
+
+func NewClosedPeriodRepository(db *gorm.DB) ClosedPeriodRepository {
+	return &closedPeriodGormRepo{db: db}
+}
+
+func (r *closedPeriodGormRepo) Create(ctx context.Context, c entity.ClosedPeriod) error {
+	err := conn(ctx, r.db).Create(&c).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *closedPeriodGormRepo) Delete(ctx context.Context, month string) error {
+	result := conn(ctx, r.db).Delete(&entity.ClosedPeriod{}, "month = ?", month)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *closedPeriodGormRepo) IsClosed(ctx context.Context, month string) (bool, error) {
+	var n int64
+	err := conn(ctx, r.db).Model(&entity.ClosedPeriod{}).Where("month = ?", month).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *closedPeriodGormRepo) FindAll(ctx context.Context) ([]entity.ClosedPeriod, error) {
+	var list []entity.ClosedPeriod
+	err := conn(ctx, r.db).Order("month DESC").Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// リポジトリ層で共通して返すエラー
+// ハンドラー層では errors.Is で判定し、HTTPステータスに変換する
+var (
+	// 対象のレコードが存在しない
+	ErrNotFound = errors.New("record not found")
+
+	// 一意制約違反など、既存のレコードと競合した
+	ErrConflict = errors.New("record conflicts with an existing one")
+
+	// 楽観的排他制御で、他のリクエストが先に更新していた
+	ErrVersionConflict = errors.New("record was modified by another request")
+)
+
+// 条件付き UPDATE で1行も更新されなかった理由を判定する
+// 行が存在しなければ ErrNotFound、存在すればバージョン不一致として ErrVersionConflict を返す
+func notFoundOrStale(db *gorm.DB, model any, id string) error {
+	var count int64
+	if err := db.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return ErrVersionConflict
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// IdempotencyRepository は冪等キーのリポジトリインターフェース
+type IdempotencyRepository interface {
+	// Claim はキーを処理中として登録する。既に登録済みの場合は ErrConflict を返す
+	Claim(ctx context.Context, k entity.IdempotencyKey) error
+	Find(ctx context.Context, key, scope string) (*entity.IdempotencyKey, error)
+	// Complete は処理中のキーにレスポンスと新しい期限を保存する
+	Complete(ctx context.Context, k entity.IdempotencyKey) error
+	Delete(ctx context.Context, key, scope string) error
+	// DeleteExpired は期限切れのキーを削除し、削除した件数を返す
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Gorm実装
+type idempotencyGormRepo struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyGormRepo{db: db}
+}
+
+func (r *idempotencyGormRepo) Claim(ctx context.Context, k entity.IdempotencyKey) error {
+	k.StatusCode = 0
+	err := conn(ctx, r.db).Create(&k).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *idempotencyGormRepo) Find(ctx context.Context, key, scope string) (*entity.IdempotencyKey, error) {
+	var k entity.IdempotencyKey
+	err := conn(ctx, r.db).First(&k, "key = ? AND scope = ?", key, scope).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *idempotencyGormRepo) Complete(ctx context.Context, k entity.IdempotencyKey) error {
+	return conn(ctx, r.db).
+		Model(&entity.IdempotencyKey{}).
+		Where("key = ? AND scope = ?", k.Key, k.Scope).
+		Updates(map[string]any{
+			"status_code":  k.StatusCode,
+			"content_type": k.ContentType,
+			"body":         k.Body,
+			"expires_at":   k.ExpiresAt,
+		}).Error
+}
+
+func (r *idempotencyGormRepo) Delete(ctx context.Context, key, scope string) error {
+	return conn(ctx, r.db).
+		Where("key = ? AND scope = ?", key, scope).
+		Delete(&entity.IdempotencyKey{}).Error
+}
+
+func (r *idempotencyGormRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("expires_at <= ?", now).Delete(&entity.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobLockRepository はジョブのロックのリポジトリインターフェース
+type JobLockRepository interface {
+	// Acquire は予定の時刻 scheduledAt の実行権を owner が得る
+	// 同じ予定の時刻をまだ誰も実行しておらず、他のプロセスのロックが切れている場合だけ true を返す
+	Acquire(ctx context.Context, name, owner string, scheduledAt, now, until time.Time) (bool, error)
+	// Release は owner のロックを解放し、実行結果を記録する
+	Release(ctx context.Context, name, owner string, now time.Time, lastErr string) error
+	FindAll(ctx context.Context) ([]entity.JobLock, error)
+}
+
+// Gorm実装
+type jobLockGormRepo struct {
+	db *gorm.DB
+}
+
+func NewJobLockRepository(db *gorm.DB) JobLockRepository {
+	return &jobLockGormRepo{db: db}
+}
+
+// 行が無ければ作ってから、条件付きの UPDATE で取る
+// SQLite は時刻を文字列で比較するため、時刻はすべて UTC に揃える
+// Postgres では UPDATE が行ロックを取り、後から来たプロセスは先のコミットを待ってから条件を評価し直すため、
+// 複数のレプリカが同時に取ろうとしても更新できるのは1つだけになる（SQLite はデータベース全体のロックで同じになる）
+func (r *jobLockGormRepo) Acquire(ctx context.Context, name, owner string, scheduledAt, now, until time.Time) (bool, error) {
+	db := conn(ctx, r.db)
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entity.JobLock{Name: name}).Error
+	if err != nil {
+		return false, err
+	}
+	result := db.Model(&entity.JobLock{}).
+		Where("name = ? AND last_run_at < ? AND locked_until <= ?", name, scheduledAt.UTC(), now.UTC()).
+		Updates(map[string]any{
+			"owner":        owner,
+			"last_run_at":  scheduledAt.UTC(),
+			"locked_until": until.UTC(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *jobLockGormRepo) Release(ctx context.Context, name, owner string, now time.Time, lastErr string) error {
+	return conn(ctx, r.db).
+		Model(&entity.JobLock{}).
+		Where("name = ? AND owner = ?", name, owner).
+		Updates(map[string]any{
+			"locked_until": now.UTC(),
+			"last_error":   lastErr,
+		}).Error
+}
+
+func (r *jobLockGormRepo) FindAll(ctx context.Context) ([]entity.JobLock, error) {
+	var list []entity.JobLock
+	err := conn(ctx, r.db).Order("name").Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// KioskRepository は打刻端末のリポジトリインターフェース
+type KioskRepository interface {
+	Create(ctx context.Context, k entity.Kiosk) error
+	Update(ctx context.Context, k entity.Kiosk) error
+	Delete(ctx context.Context, id string) error
+	FindByID(ctx context.Context, id string) (*entity.Kiosk, error)
+	FindAll(ctx context.Context) ([]entity.Kiosk, error)
+	// Touch は端末が最後にトークンを取得した時刻を記録する
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// Gorm実装
+type kioskGormRepo struct {
+	db *gorm.DB
+}
+
+func NewKioskRepository(db *gorm.DB) KioskRepository {
+	return &kioskGormRepo{db: db}
+}
+
+func (r *kioskGormRepo) Create(ctx context.Context, k entity.Kiosk) error {
+	return conn(ctx, r.db).Create(&k).Error
+}
+
+// Update は名前・設置場所・有効かどうかを更新する（鍵は変えない）
+func (r *kioskGormRepo) Update(ctx context.Context, k entity.Kiosk) error {
+	result := conn(ctx, r.db).
+		Model(&entity.Kiosk{}).
+		Where("id = ?", k.ID).
+		Select("Name", "Location", "Active", "UpdatedAt").
+		Updates(&k)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *kioskGormRepo) Delete(ctx context.Context, id string) error {
+	result := conn(ctx, r.db).Delete(&entity.Kiosk{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *kioskGormRepo) FindByID(ctx context.Context, id string) (*entity.Kiosk, error) {
+	var k entity.Kiosk
+	err := conn(ctx, r.db).First(&k, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *kioskGormRepo) FindAll(ctx context.Context) ([]entity.Kiosk, error) {
+	var list []entity.Kiosk
+	err := conn(ctx, r.db).Order("name").Find(&list).Error
+	return list, err
+}
+
+func (r *kioskGormRepo) Touch(ctx context.Context, id string, at time.Time) error {
+	return conn(ctx, r.db).Model(&entity.Kiosk{}).Where("id = ?", id).UpdateColumn("last_seen_at", at).Error
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// NotificationPreferenceRepository は通知設定のリポジトリインターフェース
+type NotificationPreferenceRepository interface {
+	// Find はユーザーの通知設定を取得する。設定が無い場合は既定値を返す
+	Find(ctx context.Context, userID string) (*entity.NotificationPreference, error)
+	// Save は通知設定を保存する（無ければ作成する）
+	Save(ctx context.Context, p entity.NotificationPreference) error
+}
+
+// Gorm実装
+type notificationPreferenceGormRepo struct {
+	db *gorm.DB
+}
+
+func NewNotificationPreferenceRepository(db *gorm.DB) NotificationPreferenceRepository {
+	return &notificationPreferenceGormRepo{db: db}
+}
+
+func (r *notificationPreferenceGormRepo) Find(ctx context.Context, userID string) (*entity.NotificationPreference, error) {
+	var p entity.NotificationPreference
+	err := conn(ctx, r.db).First(&p, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		p = entity.DefaultNotificationPreference(userID)
+		return &p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *notificationPreferenceGormRepo) Save(ctx context.Context, p entity.NotificationPreference) error {
+	return conn(ctx, r.db).Save(&p).Error
+}
+
+// EmailRepository は送信待ちのメールのリポジトリインターフェース
+type EmailRepository interface {
+	Enqueue(ctx context.Context, m entity.EmailMessage) error
+	// FindDue は送信時刻を過ぎた送信待ちのメールを古い順に取得する
+	FindDue(ctx context.Context, now time.Time, limit int) ([]entity.EmailMessage, error)
+	// Claim は送信待ちのメールの次の送信時刻を until まで延ばして、送信する権利を得る
+	Claim(ctx context.Context, m entity.EmailMessage, until time.Time) (bool, error)
+	MarkSent(ctx context.Context, id string, at time.Time) error
+	MarkFailed(ctx context.Context, id string, attempts int, next time.Time, lastErr string, dead bool) error
+}
+
+// Gorm実装
+type emailGormRepo struct {
+	db *gorm.DB
+}
+
+func NewEmailRepository(db *gorm.DB) EmailRepository {
+	return &emailGormRepo{db: db}
+}
+
+func (r *emailGormRepo) Enqueue(ctx context.Context, m entity.EmailMessage) error {
+	return conn(ctx, r.db).Create(&m).Error
+}
+
+func (r *emailGormRepo) FindDue(ctx context.Context, now time.Time, limit int) ([]entity.EmailMessage, error) {
+	var list []entity.EmailMessage
+	err := conn(ctx, r.db).
+		Where("status = ? AND next_attempt_at <= ?", entity.EmailPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+// 取得した時点の次の送信時刻と一致する場合のみ更新する（楽観的排他制御）
+func (r *emailGormRepo) Claim(ctx context.Context, m entity.EmailMessage, until time.Time) (bool, error) {
+	result := conn(ctx, r.db).
+		Model(&entity.EmailMessage{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", m.ID, entity.EmailPending, m.NextAttemptAt).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *emailGormRepo) MarkSent(ctx context.Context, id string, at time.Time) error {
+	return conn(ctx, r.db).
+		Model(&entity.EmailMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":     entity.EmailSent,
+			"last_error": "",
+			"sent_at":    at,
+		}).Error
+}
+
+func (r *emailGormRepo) MarkFailed(ctx context.Context, id string, attempts int, next time.Time, lastErr string, dead bool) error {
+	status := entity.EmailPending
+	if dead {
+		status = entity.EmailDead
+	}
+	return conn(ctx, r.db).
+		Model(&entity.EmailMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":          status,
+			"attempts":        attempts,
+			"next_attempt_at": next,
+			"last_error":      lastErr,
+		}).Error
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// OutboxRepository はアウトボックスのリポジトリインターフェース
+type OutboxRepository interface {
+	// Add はイベントを配信待ちとして追加する（変更と同じトランザクションの ctx で呼ぶ）
+	Add(ctx context.Context, m entity.OutboxMessage) error
+	// FindDue は配信時刻を過ぎた配信待ちのイベントを古い順に取得する
+	FindDue(ctx context.Context, now time.Time, limit int) ([]entity.OutboxMessage, error)
+	// Claim は配信待ちのイベントの次の配信時刻を until まで延ばして、配信する権利を得る
+	// 他のリレーが先に取得した場合は false を返す
+	Claim(ctx context.Context, m entity.OutboxMessage, until time.Time) (bool, error)
+	// MarkDelivered は配信済みにする
+	MarkDelivered(ctx context.Context, id string, at time.Time) error
+	// MarkFailed は配信の失敗を記録する。dead が true の場合は以降再試行しない
+	MarkFailed(ctx context.Context, id string, attempts int, next time.Time, lastErr string, dead bool) error
+}
+
+// Gorm実装
+type outboxGormRepo struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxGormRepo{db: db}
+}
+
+func (r *outboxGormRepo) Add(ctx context.Context, m entity.OutboxMessage) error {
+	m.Status = entity.OutboxPending
+	if m.NextAttemptAt.IsZero() {
+		m.NextAttemptAt = m.OccurredAt
+	}
+	return conn(ctx, r.db).Create(&m).Error
+}
+
+func (r *outboxGormRepo) FindDue(ctx context.Context, now time.Time, limit int) ([]entity.OutboxMessage, error) {
+	var list []entity.OutboxMessage
+	err := conn(ctx, r.db).
+		Where("status = ? AND next_attempt_at <= ?", entity.OutboxPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+// 取得した時点の次の配信時刻と一致する場合のみ更新する（楽観的排他制御）
+func (r *outboxGormRepo) Claim(ctx context.Context, m entity.OutboxMessage, until time.Time) (bool, error) {
+	result := conn(ctx, r.db).
+		Model(&entity.OutboxMessage{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", m.ID, entity.OutboxPending, m.NextAttemptAt).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *outboxGormRepo) MarkDelivered(ctx context.Context, id string, at time.Time) error {
+	return conn(ctx, r.db).
+		Model(&entity.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":       entity.OutboxDelivered,
+			"delivered_at": at,
+			"last_error":   "",
+		}).Error
+}
+
+func (r *outboxGormRepo) MarkFailed(ctx context.Context, id string, attempts int, next time.Time, lastErr string, dead bool) error {
+	status := entity.OutboxPending
+	if dead {
+		status = entity.OutboxDead
+	}
+	return conn(ctx, r.db).
+		Model(&entity.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":          status,
+			"attempts":        attempts,
+			"next_attempt_at": next,
+			"last_error":      lastErr,
+		}).Error
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// PunchEventRepository は打刻イベントとチェックポイントのリポジトリインターフェース
+// 打刻イベントは追記のみのため、更新・削除のメソッドは持たない
+type PunchEventRepository interface {
+	// Append はイベントを追加する。同じユーザー・同じSeqが既にある場合は ErrConflict を返す
+	Append(ctx context.Context, e entity.PunchEvent) error
+	// LastByUser はユーザーの最新のイベントを取得する
+	LastByUser(ctx context.Context, userID string) (*entity.PunchEvent, error)
+	// ListByUser はユーザーのイベントをSeq順に取得する
+	ListByUser(ctx context.Context, userID string) ([]entity.PunchEvent, error)
+	// ListByUserAndRange はユーザーの OccurredAt が from 以上 to 未満のイベントと、それらと同じ勤怠（AttendanceID）のイベントをSeq順に取得する
+	// to がゼロの場合は上限を設けない
+	ListByUserAndRange(ctx context.Context, userID string, from, to time.Time) ([]entity.PunchEvent, error)
+	// LastByUserAndType はユーザーの OccurredAt が before 以前で最新の種類 eventType のイベントを取得する
+	LastByUserAndType(ctx context.Context, userID, eventType string, before time.Time) (*entity.PunchEvent, error)
+	// ListByClientIDs はユーザーのクライアントのIDが ids のいずれかのイベントを取得する
+	ListByClientIDs(ctx context.Context, userID string, ids []string) ([]entity.PunchEvent, error)
+	// FindByUserAndSeq はユーザーの指定したSeqのイベントを取得する
+	FindByUserAndSeq(ctx context.Context, userID string, seq int64) (*entity.PunchEvent, error)
+	// UserIDs はイベントのあるユーザーのIDを取得する
+	UserIDs(ctx context.Context) ([]string, error)
+
+	CreateCheckpoint(ctx context.Context, c entity.PunchCheckpoint) error
+	FindCheckpoint(ctx context.Context, id string) (*entity.PunchCheckpoint, error)
+	ListCheckpoints(ctx context.Context, limit int) ([]entity.PunchCheckpoint, error)
+}
+
+// Gorm実装
+type punchEventGormRepo struct {
+	db *gorm.DB
+}
+
+func NewPunchEventRepository(db *gorm.DB) PunchEventRepository {
+	return &punchEventGormRepo{db: db}
+}
+
+func (r *punchEventGormRepo) Append(ctx context.Context, e entity.PunchEvent) error {
+	// トランザクション中に一意制約違反になってもSeqを取り直して続けられるよう、セーブポイントで囲む
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&e).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *punchEventGormRepo) LastByUser(ctx context.Context, userID string) (*entity.PunchEvent, error) {
+	var e entity.PunchEvent
+	err := conn(ctx, r.db).Where("user_id = ?", userID).Order("seq DESC").First(&e).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *punchEventGormRepo) ListByUser(ctx context.Context, userID string) ([]entity.PunchEvent, error) {
+	var list []entity.PunchEvent
+	err := conn(ctx, r.db).Where("user_id = ?", userID).Order("seq ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *punchEventGormRepo) ListByUserAndRange(ctx context.Context, userID string, from, to time.Time) ([]entity.PunchEvent, error) {
+	// 時刻はUTCで保存しているため、比較する値もUTCにそろえる
+	// 期間の上限が無い場合は、どの時刻よりも後になる最大値を使う
+	upper := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	if !to.IsZero() {
+		upper = to.UTC()
+	}
+	var list []entity.PunchEvent
+	err := conn(ctx, r.db).
+		Where("user_id = ?", userID).
+		Where("(occurred_at >= ? AND occurred_at < ?) OR attendance_id IN (?)", from.UTC(), upper,
+			conn(ctx, r.db).Model(&entity.PunchEvent{}).
+				Select("attendance_id").
+				Where("user_id = ? AND attendance_id <> '' AND occurred_at >= ? AND occurred_at < ?", userID, from.UTC(), upper)).
+		Order("seq ASC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *punchEventGormRepo) LastByUserAndType(ctx context.Context, userID, eventType string, before time.Time) (*entity.PunchEvent, error) {
+	var e entity.PunchEvent
+	err := conn(ctx, r.db).
+		Where("user_id = ? AND type = ? AND occurred_at <= ?", userID, eventType, before.UTC()).
+		Order("occurred_at DESC, seq DESC").
+		First(&e).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *punchEventGormRepo) ListByClientIDs(ctx context.Context, userID string, ids []string) ([]entity.PunchEvent, error) {
+	var list []entity.PunchEvent
+	if len(ids) == 0 {
+		return list, nil
+	}
+	err := conn(ctx, r.db).Where("user_id = ? AND client_id IN ?", userID, ids).Order("seq ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *punchEventGormRepo) FindByUserAndSeq(ctx context.Context, userID string, seq int64) (*entity.PunchEvent, error) {
+	var e entity.PunchEvent
+	err := conn(ctx, r.db).Where("user_id = ? AND seq = ?", userID, seq).First(&e).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *punchEventGormRepo) UserIDs(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := conn(ctx, r.db).Model(&entity.PunchEvent{}).Distinct().Order("user_id").Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func (r *punchEventGormRepo) CreateCheckpoint(ctx context.Context, c entity.PunchCheckpoint) error {
+	return conn(ctx, r.db).Create(&c).Error
+}
+
+func (r *punchEventGormRepo) FindCheckpoint(ctx context.Context, id string) (*entity.PunchCheckpoint, error) {
+	var c entity.PunchCheckpoint
+	err := conn(ctx, r.db).First(&c, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *punchEventGormRepo) ListCheckpoints(ctx context.Context, limit int) ([]entity.PunchCheckpoint, error) {
+	var list []entity.PunchCheckpoint
+	q := conn(ctx, r.db).Order("created_at DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// PunchPolicyRepository は打刻ポリシーのリポジトリインターフェース
+type PunchPolicyRepository interface {
+	Create(ctx context.Context, p entity.PunchPolicy) error
+	Update(ctx context.Context, p entity.PunchPolicy) error
+	Delete(ctx context.Context, id string) error
+	FindByID(ctx context.Context, id string) (*entity.PunchPolicy, error)
+	FindAll(ctx context.Context) ([]entity.PunchPolicy, error)
+	// FindForUser はユーザーに適用するポリシーを取得する
+	// ユーザー指定・部署指定・既定の順に探し、どれも無ければ ErrNotFound を返す
+	FindForUser(ctx context.Context, userID, department string) (*entity.PunchPolicy, error)
+}
+
+// Gorm実装
+type punchPolicyGormRepo struct {
+	db *gorm.DB
+}
+
+func NewPunchPolicyRepository(db *gorm.DB) PunchPolicyRepository {
+	return &punchPolicyGormRepo{db: db}
+}
+
+func (r *punchPolicyGormRepo) Create(ctx context.Context, p entity.PunchPolicy) error {
+	return conn(ctx, r.db).Create(&p).Error
+}
+
+func (r *punchPolicyGormRepo) Update(ctx context.Context, p entity.PunchPolicy) error {
+	result := conn(ctx, r.db).
+		Model(&entity.PunchPolicy{}).
+		Where("id = ?", p.ID).
+		Select("Name", "UserID", "Department", "OfficeCIDRs", "Geofences", "AllowRemote", "OnViolation", "UpdatedAt").
+		Updates(&p)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *punchPolicyGormRepo) Delete(ctx context.Context, id string) error {
+	result := conn(ctx, r.db).Delete(&entity.PunchPolicy{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *punchPolicyGormRepo) FindByID(ctx context.Context, id string) (*entity.PunchPolicy, error) {
+	var p entity.PunchPolicy
+	err := conn(ctx, r.db).First(&p, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *punchPolicyGormRepo) FindAll(ctx context.Context) ([]entity.PunchPolicy, error) {
+	var list []entity.PunchPolicy
+	err := conn(ctx, r.db).Order("name").Find(&list).Error
+	return list, err
+}
+
+func (r *punchPolicyGormRepo) FindForUser(ctx context.Context, userID, department string) (*entity.PunchPolicy, error) {
+	scopes := []func(*gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB { return db.Where("user_id = ?", userID) },
+		func(db *gorm.DB) *gorm.DB { return db.Where("user_id = '' AND department = ?", department) },
+		func(db *gorm.DB) *gorm.DB { return db.Where("user_id = '' AND department = ''") },
+	}
+	for i, scope := range scopes {
+		if i == 1 && department == "" {
+			continue
+		}
+		var p entity.PunchPolicy
+		err := conn(ctx, r.db).Scopes(scope).Order("created_at").First(&p).Error
+		if err == nil {
+			return &p, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -34,16 +34,46 @@ func NewTimeIsMoneyRepository(db *gorm.DB) *TimeIsMoneyGormRepo {
 func (repo *TimeIsMoneyGormRepo) CreateUser(context context.Context, user entity.User) error {
 	// エラーハンドリングは呼び出し元で行う
 	// Createは新規登録のため、アドレスを渡す。
-	return repo.db.WithContext(context).Create(&user).Error
+	// バージョンは1から始める
+	user.Version = 1
+	err := conn(context, repo.db).Create(&user).Error
+
+	// 有効なユーザーと AuthID・Email が重複した場合は競合として返す
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	return err
 }
 
 // 更新処理 UPDATE
 // 引数: user(User型)
 // 戻り値: error型
+// 楽観的排他制御: user.Version が DB上のバージョンと一致する場合のみ更新し、バージョンを1つ進める
+// Saveは該当行が無いと INSERT してしまうため、条件付きの UPDATE を使う
 func (repo *TimeIsMoneyGormRepo) UpdateUser(context context.Context, user entity.User) error {
-	// エラーハンドリングは呼び出し元で行う
-	// Saveは主キーを元にレコードを更新するため、アドレスを渡す
-	return repo.db.WithContext(context).Save(&user).Error
+	result := conn(context, repo.db).
+		Model(&entity.User{}).
+		Where("id = ? AND version = ?", user.ID, user.Version).
+		Select("AuthID", "Name", "Email", "Role", "Department", "Version", "UpdatedAt").
+		Updates(&entity.User{
+			AuthID:     user.AuthID,
+			Name:       user.Name,
+			Email:      user.Email,
+			Role:       user.Role,
+			Department: user.Department,
+			Version:    user.Version + 1,
+		})
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return ErrConflict
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 行が存在しないのか、バージョンが古いのかを区別する
+		return notFoundOrStale(conn(context, repo.db), &entity.User{}, user.ID)
+	}
+	return nil
 }
 
 // 取得処理 SELECT (1件)
@@ -57,18 +87,20 @@ func (repo *TimeIsMoneyGormRepo) FindFirst(context context.Context, id string) (
 	// 失敗 errorを変数名にすると、Go言語の組み込み型と衝突する
 	// IDを元に検索する
 	// Firstは最初の1件を取得する
-	err := repo.db.WithContext(context).First(&user, "id = ?", id).Error
+	err := conn(context, repo.db).First(&user, "id = ?", id).Error
 
 	// 条件に一致しない場合は、errにエラーが格納される
 	if err != nil {
 		// レコードが見つからない場合のエラーハンドリング
 		// ErrRecordNotFoundはGormが提供するエラー
 		// errorsは標準パッケージで、Is関数を使ってエラーの種類を判定する
+		// 呼び出し元が errors.Is で判定できるよう、共通の ErrNotFound を返す
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-
-			// New関数でカスタムエラーメッセージを作成して返す。
-			return nil, errors.New("検索結果が見つかりません。")
+			return nil, ErrNotFound
 		}
+
+		// それ以外（DBに接続できない等）はそのまま返す
+		return nil, err
 	}
 
 	// 正常に取得できた場合は、userとnil(err)を返す
@@ -84,7 +116,7 @@ func (repo *TimeIsMoneyGormRepo) FindAllUser(context context.Context) ([]entity.
 	var users []entity.User
 
 	// Findは条件に一致する全てのレコードを取得する
-	err := repo.db.WithContext(context).Find(&users).Error
+	err := conn(context, repo.db).Find(&users).Error
 	if err != nil {
 		// レコードが見つからない場合のエラーハンドリング
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -99,5 +131,86 @@ func (repo *TimeIsMoneyGormRepo) FindAllUser(context context.Context) ([]entity.
 // 戻り値: (error)
 func (repo *TimeIsMoneyGormRepo) DeleteUser(context context.Context, user entity.User) error {
 	// Deleteはレコードを削除する
-	return repo.db.WithContext(context).Delete(&user).Error
+	return conn(context, repo.db).Delete(&user).Error
+}
+
+// 取得処理 SELECT (1件・論理削除済みを含む)
+// 引数: context(context.Context型), id(string型)
+// 戻り値: (*User, error)
+func (repo *TimeIsMoneyGormRepo) FindFirstIncludeDeleted(context context.Context, id string) (*entity.User, error) {
+	var user entity.User
+
+	// Unscopedを付けると、deleted_atによる絞り込みが外れる
+	err := conn(context, repo.db).Unscoped().First(&user, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// 取得処理 SELECT (認証IDで1件)
+// 引数: context(context.Context型), authID(string型)
+// 戻り値: (*User, error)
+func (repo *TimeIsMoneyGormRepo) FindByAuthID(context context.Context, authID string) (*entity.User, error) {
+	var user entity.User
+
+	err := conn(context, repo.db).First(&user, "auth_id = ?", authID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// 取得処理 SELECT (複数・論理削除済みを含む)
+// 引数: context(context.Context型)
+// 戻り値: ([]User, error)
+func (repo *TimeIsMoneyGormRepo) FindAllUserIncludeDeleted(context context.Context) ([]entity.User, error) {
+	var users []entity.User
+	err := conn(context, repo.db).Unscoped().Find(&users).Error
+	return users, err
+}
+
+// 論理削除の取り消し UPDATE
+// 引数: context(context.Context型), id(string型)
+// 戻り値: (error)
+func (repo *TimeIsMoneyGormRepo) RestoreUser(context context.Context, id string) error {
+	// 論理削除済みの行だけを対象に deleted_at を NULL に戻す
+	result := conn(context, repo.db).Unscoped().
+		Model(&entity.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		// 同じ AuthID・Email の有効なユーザーが既にいる場合は部分ユニークインデックスに違反する
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return ErrConflict
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// 物理削除 DELETE
+// 引数: context(context.Context型), id(string型)
+// 戻り値: (error)
+func (repo *TimeIsMoneyGormRepo) PurgeUser(context context.Context, id string) error {
+	// 誤って有効なユーザーを消さないよう、論理削除済みの行だけを対象にする
+	result := conn(context, repo.db).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Delete(&entity.User{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/entity"
+)
+
+func TestFindFirstErrors(t *testing.T) {
+	db, err := dbinfra.OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dbinfra.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewTimeIsMoneyRepository(db)
+	ctx := context.Background()
+	if err := repo.CreateUser(ctx, entity.User{ID: "u1", AuthID: "a1", Name: "Alice", Email: "alice@example.com", Role: entity.RoleEmployee}); err != nil {
+		t.Fatal(err)
+	}
+
+	if user, err := repo.FindFirst(ctx, "u1"); err != nil || user.ID != "u1" {
+		t.Fatalf("FindFirst(u1) = %v, %v", user, err)
+	}
+	if user, err := repo.FindFirst(ctx, "none"); !errors.Is(err, ErrNotFound) || user != nil {
+		t.Fatalf("FindFirst(none) = %v, %v, want nil, ErrNotFound", user, err)
+	}
+	// 論理削除したユーザーも見つからない扱いにする
+	if err := repo.DeleteUser(ctx, entity.User{ID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.FindFirst(ctx, "u1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FindFirst(deleted) = %v, want ErrNotFound", err)
+	}
+
+	// DBのエラーは空のユーザーで隠さず、ErrNotFound とも区別して返す
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	user, err := repo.FindFirst(ctx, "u1")
+	if err == nil || errors.Is(err, ErrNotFound) || user != nil {
+		t.Fatalf("FindFirst(closed db) = %v, %v, want a database error", user, err)
+	}
+}
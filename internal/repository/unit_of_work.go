@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork はユースケースのトランザクション境界
+// Do に渡した関数の中で、受け取った ctx を使ったリポジトリの操作はすべて同じトランザクションで実行される
+type UnitOfWork interface {
+	// fn がエラーを返した場合はロールバックし、そのエラーを返す
+	// すでにトランザクション中の ctx で呼ばれた場合はセーブポイントを使って入れ子にする
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ctx に入れるトランザクションのキー
+type txKey struct{}
+
+// Gorm実装
+type gormUnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &gormUnitOfWork{db: db}
+}
+
+func (u *gormUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, u.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn は ctx にトランザクションがあればそれを、無ければ db を返す
+// リポジトリは r.db を直接使わずにこれを通して DB にアクセスする
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
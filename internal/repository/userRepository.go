@@ -16,9 +16,24 @@ type UserRepository interface {
 	// 最初の1件を取得
 	FindFirst(ctx context.Context, id string) (*entity.User, error)
 
+	// 最初の1件を取得（論理削除済みを含む）
+	FindFirstIncludeDeleted(ctx context.Context, id string) (*entity.User, error)
+
+	// 認証IDで1件取得
+	FindByAuthID(ctx context.Context, authID string) (*entity.User, error)
+
 	// 全件取得
 	FindAllUser(ctx context.Context) ([]entity.User, error)
 
+	// 全件取得（論理削除済みを含む）
+	FindAllUserIncludeDeleted(ctx context.Context) ([]entity.User, error)
+
 	// 削除
 	DeleteUser(ctx context.Context, user entity.User) error
+
+	// 論理削除の取り消し
+	RestoreUser(ctx context.Context, id string) error
+
+	// 物理削除（論理削除済みのユーザーのみ）
+	PurgeUser(ctx context.Context, id string) error
 }
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserStatusRepository は在席ステータスのリポジトリインターフェース
+type UserStatusRepository interface {
+	// Save はユーザーの在席ステータスを保存する（既にあれば置き換える）
+	Save(ctx context.Context, s entity.UserStatus) error
+	Delete(ctx context.Context, userID string) error
+	FindByUserID(ctx context.Context, userID string) (*entity.UserStatus, error)
+	// FindByDepartment は部署の期限切れでない在席ステータスを取得する
+	FindByDepartment(ctx context.Context, department string, now time.Time) ([]entity.UserStatus, error)
+	// DeleteExpired は期限切れの在席ステータスを削除し、削除したものを返す
+	DeleteExpired(ctx context.Context, now time.Time) ([]entity.UserStatus, error)
+}
+
+// Gorm実装
+// 期限の比較は SQLite では文字列の比較になるため、時刻はすべてUTCで保存・比較する
+type userStatusGormRepo struct {
+	db *gorm.DB
+}
+
+func NewUserStatusRepository(db *gorm.DB) UserStatusRepository {
+	return &userStatusGormRepo{db: db}
+}
+
+func (r *userStatusGormRepo) Save(ctx context.Context, s entity.UserStatus) error {
+	s.ExpiresAt = s.ExpiresAt.UTC()
+	return conn(ctx, r.db).Clauses(clause.OnConflict{UpdateAll: true}).Create(&s).Error
+}
+
+func (r *userStatusGormRepo) Delete(ctx context.Context, userID string) error {
+	result := conn(ctx, r.db).Delete(&entity.UserStatus{}, "user_id = ?", userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *userStatusGormRepo) FindByUserID(ctx context.Context, userID string) (*entity.UserStatus, error) {
+	var s entity.UserStatus
+	if err := conn(ctx, r.db).First(&s, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *userStatusGormRepo) FindByDepartment(ctx context.Context, department string, now time.Time) ([]entity.UserStatus, error) {
+	var list []entity.UserStatus
+	err := conn(ctx, r.db).
+		Where("department = ? AND expires_at > ?", department, now.UTC()).
+		Order("updated_at DESC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *userStatusGormRepo) DeleteExpired(ctx context.Context, now time.Time) ([]entity.UserStatus, error) {
+	var list []entity.UserStatus
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", now.UTC()).Find(&list).Error; err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		userIDs := make([]string, len(list))
+		for i, s := range list {
+			userIDs[i] = s.UserID
+		}
+		// 取得した後に設定し直されたものは消さない
+		return tx.Where("user_id IN ? AND expires_at <= ?", userIDs, now.UTC()).Delete(&entity.UserStatus{}).Error
+	})
+	return list, err
+}
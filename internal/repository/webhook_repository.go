@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"gorm.io/gorm"
+)
+
+// WebhookDeliveryFilter は配信の検索条件（ゼロ値の項目は条件にしない）
+type WebhookDeliveryFilter struct {
+	SubscriptionID string
+	Status         string
+	Limit          int
+}
+
+// WebhookRepository は Webhook の購読と配信のリポジトリインターフェース
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, s entity.WebhookSubscription) error
+	UpdateSubscription(ctx context.Context, s entity.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id string) error
+	FindSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error)
+	// ActiveSubscriptions は有効な購読を取得する
+	ActiveSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error)
+
+	// CreateDelivery は配信を追加する。同じ購読・同じイベントの配信が既にある場合は ErrConflict を返す
+	CreateDelivery(ctx context.Context, d entity.WebhookDelivery) error
+	FindDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error)
+	// ListDeliveries は配信を新しい順に取得する
+	ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]entity.WebhookDelivery, error)
+	// FindDueDeliveries は配信時刻を過ぎた配信待ちの配信を古い順に取得する
+	FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	// ClaimDelivery は配信待ちの配信の次の配信時刻を until まで延ばして、配信する権利を得る
+	ClaimDelivery(ctx context.Context, d entity.WebhookDelivery, until time.Time) (bool, error)
+	MarkDeliveryDelivered(ctx context.Context, id string, statusCode int, at time.Time) error
+	MarkDeliveryFailed(ctx context.Context, id string, attempts int, next time.Time, statusCode int, lastErr string, dead bool) error
+	// ResetDelivery は配信を配信待ちに戻し、再試行の回数を0にする（手動の再配信）
+	ResetDelivery(ctx context.Context, id string, now time.Time) error
+}
+
+// Gorm実装
+type webhookGormRepo struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookGormRepo{db: db}
+}
+
+func (r *webhookGormRepo) CreateSubscription(ctx context.Context, s entity.WebhookSubscription) error {
+	return conn(ctx, r.db).Create(&s).Error
+}
+
+func (r *webhookGormRepo) UpdateSubscription(ctx context.Context, s entity.WebhookSubscription) error {
+	result := conn(ctx, r.db).
+		Model(&entity.WebhookSubscription{}).
+		Where("id = ?", s.ID).
+		Select("URL", "Events", "Secret", "Active", "UpdatedAt").
+		Updates(&s)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *webhookGormRepo) DeleteSubscription(ctx context.Context, id string) error {
+	result := conn(ctx, r.db).Delete(&entity.WebhookSubscription{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *webhookGormRepo) FindSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	var s entity.WebhookSubscription
+	err := conn(ctx, r.db).First(&s, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *webhookGormRepo) ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	var list []entity.WebhookSubscription
+	err := conn(ctx, r.db).Order("created_at ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *webhookGormRepo) ActiveSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	var list []entity.WebhookSubscription
+	err := conn(ctx, r.db).Where("active = ?", true).Find(&list).Error
+	return list, err
+}
+
+func (r *webhookGormRepo) CreateDelivery(ctx context.Context, d entity.WebhookDelivery) error {
+	d.Status = entity.WebhookPending
+	err := conn(ctx, r.db).Create(&d).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *webhookGormRepo) FindDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	var d entity.WebhookDelivery
+	err := conn(ctx, r.db).First(&d, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *webhookGormRepo) ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]entity.WebhookDelivery, error) {
+	q := conn(ctx, r.db).Order("created_at DESC")
+	if filter.SubscriptionID != "" {
+		q = q.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	var list []entity.WebhookDelivery
+	err := q.Find(&list).Error
+	return list, err
+}
+
+func (r *webhookGormRepo) FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	var list []entity.WebhookDelivery
+	err := conn(ctx, r.db).
+		Where("status = ? AND next_attempt_at <= ?", entity.WebhookPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+// 取得した時点の次の配信時刻と一致する場合のみ更新する（楽観的排他制御）
+func (r *webhookGormRepo) ClaimDelivery(ctx context.Context, d entity.WebhookDelivery, until time.Time) (bool, error) {
+	result := conn(ctx, r.db).
+		Model(&entity.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", d.ID, entity.WebhookPending, d.NextAttemptAt).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *webhookGormRepo) MarkDeliveryDelivered(ctx context.Context, id string, statusCode int, at time.Time) error {
+	return conn(ctx, r.db).
+		Model(&entity.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":           entity.WebhookDelivered,
+			"last_status_code": statusCode,
+			"last_error":       "",
+			"delivered_at":     at,
+		}).Error
+}
+
+func (r *webhookGormRepo) MarkDeliveryFailed(ctx context.Context, id string, attempts int, next time.Time, statusCode int, lastErr string, dead bool) error {
+	status := entity.WebhookPending
+	if dead {
+		status = entity.WebhookDead
+	}
+	return conn(ctx, r.db).
+		Model(&entity.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":           status,
+			"attempts":         attempts,
+			"next_attempt_at":  next,
+			"last_status_code": statusCode,
+			"last_error":       lastErr,
+		}).Error
+}
+
+func (r *webhookGormRepo) ResetDelivery(ctx context.Context, id string, now time.Time) error {
+	result := conn(ctx, r.db).
+		Model(&entity.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":          entity.WebhookPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// Job は予定の時刻に実行する処理
+// scheduledAt は実行する予定だった時刻（実際に呼ばれた時刻ではない）
+type Job func(ctx context.Context, scheduledAt time.Time) error
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       Job
+}
+
+// Scheduler はプロセス内で cron 形式の予定に従ってジョブを実行する
+// 同じジョブを複数のプロセスで動かしても、予定の時刻ごとにロックを取れた1つのプロセスだけが実行する
+type Scheduler struct {
+	locks repository.JobLockRepository
+	owner string
+	// ロックの期限（実行中にプロセスが落ちた場合、この時間が過ぎると他のプロセスが次の予定を実行できる）
+	LockTTL time.Duration
+
+	mu   sync.Mutex
+	jobs []job
+}
+
+// New はSchedulerを生成する
+func New(locks repository.JobLockRepository) *Scheduler {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return &Scheduler{
+		locks:   locks,
+		owner:   fmt.Sprintf("%s/%d/%s", host, os.Getpid(), hex.EncodeToString(b)),
+		LockTTL: 30 * time.Minute,
+	}
+}
+
+// Add はジョブを登録する。spec は loc のタイムゾーンで評価する cron 式
+// Run を呼ぶ前に登録する
+func (s *Scheduler) Add(name, spec string, loc *time.Location, fn Job) error {
+	schedule, err := Parse(spec, loc)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job{name: name, spec: spec, schedule: schedule, fn: fn})
+	return nil
+}
+
+// Run は ctx がキャンセルされるまで、登録したジョブを予定の時刻に実行する
+// 止まっていた間の予定はさかのぼって実行しない
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, j)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			slog.WarnContext(ctx, "scheduler job will never run", "job", j.name, "spec", j.spec)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := s.RunJob(ctx, j.name, next); err != nil {
+			slog.ErrorContext(ctx, "scheduler job failed", "job", j.name, "err", err)
+		}
+	}
+}
+
+// RunJob は予定の時刻 scheduledAt の分としてジョブを1回実行する
+// 他のプロセスが同じ予定の時刻を実行済み・実行中の場合は何もしない
+func (s *Scheduler) RunJob(ctx context.Context, name string, scheduledAt time.Time) error {
+	var fn Job
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		if j.name == name {
+			fn = j.fn
+		}
+	}
+	s.mu.Unlock()
+	if fn == nil {
+		return fmt.Errorf("unknown job %q", name)
+	}
+
+	now := time.Now()
+	ok, err := s.locks.Acquire(ctx, name, s.owner, scheduledAt, now, now.Add(s.LockTTL))
+	if err != nil || !ok {
+		return err
+	}
+	runErr := fn(ctx, scheduledAt)
+	lastErr := ""
+	if runErr != nil {
+		lastErr = runErr.Error()
+	}
+	// ジョブが失敗してもロックは解放する（次の予定で再実行する）
+	if err := s.locks.Release(context.WithoutCancel(ctx), name, s.owner, time.Now(), lastErr); err != nil {
+		return err
+	}
+	return runErr
+}
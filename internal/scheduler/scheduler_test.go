@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// newTestLocks は SQLite のジョブのロックのリポジトリを作る
+// 同じリポジトリを使う Scheduler は、同じデータベースを共有する別々のプロセスとして振る舞う
+func newTestLocks(t *testing.T) repository.JobLockRepository {
+	t.Helper()
+	db, err := dbinfra.OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dbinfra.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return repository.NewJobLockRepository(db)
+}
+
+func newTestScheduler(t *testing.T, locks repository.JobLockRepository, fn Job) *Scheduler {
+	t.Helper()
+	s := New(locks)
+	if err := s.Add("daily", "0 9 * * *", time.UTC, fn); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// 同じ予定の時刻は、複数のプロセスが同時に実行しようとしても1回だけ実行する
+func TestRunJobOncePerScheduledTime(t *testing.T) {
+	locks := newTestLocks(t)
+	var calls atomic.Int32
+	count := func(ctx context.Context, scheduledAt time.Time) error {
+		calls.Add(1)
+		return nil
+	}
+	a := newTestScheduler(t, locks, count)
+	b := newTestScheduler(t, locks, count)
+	slot := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		s := a
+		if i%2 == 1 {
+			s = b
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.RunJob(context.Background(), "daily", slot); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Fatalf("calls = %d, want 1", calls.Load())
+	}
+
+	// 実行済みの予定の時刻やそれより前はさかのぼらない
+	for _, at := range []time.Time{slot, slot.Add(-24 * time.Hour)} {
+		if err := b.RunJob(context.Background(), "daily", at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d after replaying old slots, want 1", calls.Load())
+	}
+	// 次の予定の時刻はどちらのプロセスでも実行できる
+	if err := b.RunJob(context.Background(), "daily", slot.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d after the next slot, want 2", calls.Load())
+	}
+}
+
+// 実行中のロックがある間は、他のプロセスは次の予定の時刻も実行しない
+func TestRunJobWaitsForRunningLock(t *testing.T) {
+	locks := newTestLocks(t)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	a := newTestScheduler(t, locks, func(ctx context.Context, scheduledAt time.Time) error {
+		close(entered)
+		<-release
+		return nil
+	})
+	var calls atomic.Int32
+	b := newTestScheduler(t, locks, func(ctx context.Context, scheduledAt time.Time) error {
+		calls.Add(1)
+		return nil
+	})
+	slot := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	done := make(chan error)
+	go func() { done <- a.RunJob(context.Background(), "daily", slot) }()
+	<-entered
+	if err := b.RunJob(context.Background(), "daily", slot.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 0 {
+		t.Fatalf("calls = %d while another process holds the lock, want 0", calls.Load())
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// ロックが解放されたら実行できる
+	if err := b.RunJob(context.Background(), "daily", slot.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d after the lock was released, want 1", calls.Load())
+	}
+}
+
+// ジョブが失敗してもロックを解放し、エラーを記録する
+func TestRunJobReleasesLockOnFailure(t *testing.T) {
+	locks := newTestLocks(t)
+	fail := true
+	s := newTestScheduler(t, locks, func(ctx context.Context, scheduledAt time.Time) error {
+		if fail {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	slot := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	if err := s.RunJob(context.Background(), "daily", slot); err == nil || err.Error() != "boom" {
+		t.Fatalf("RunJob() = %v, want boom", err)
+	}
+	list, err := locks.FindAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].LastError != "boom" || list[0].LockedUntil.After(time.Now()) {
+		t.Fatalf("locks = %+v, want a released lock with the error", list)
+	}
+
+	fail = false
+	if err := s.RunJob(context.Background(), "daily", slot.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := locks.FindAll(context.Background()); list[0].LastError != "" {
+		t.Errorf("last error = %q after a successful run, want empty", list[0].LastError)
+	}
+}
+
+func TestRunJobUnknown(t *testing.T) {
+	s := New(newTestLocks(t))
+	if err := s.RunJob(context.Background(), "missing", time.Now()); err == nil {
+		t.Error("RunJob() = nil, want an error for an unknown job")
+	}
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// Statement にスパンを入れるキー
+const gormSpanKey = "tracing:span"
+
+// DBTracing は Gorm のクエリごとにスパンを作る
+type DBTracing struct {
+	tracer trace.Tracer
+	system string
+}
+
+// NewDBTracing は Gorm のコールバックを登録して、クエリごとにスパンを作る
+// SQL はプレースホルダーのまま記録し、クエリの値（メールアドレス・名前など）は記録しない
+func NewDBTracing(db *gorm.DB, tp *sdktrace.TracerProvider) (*DBTracing, error) {
+	t := &DBTracing{tracer: tp.Tracer(instrumentationName), system: db.Dialector.Name()}
+	cb := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("*").Register, cb.Create().After("*").Register},
+		{"query", cb.Query().Before("*").Register, cb.Query().After("*").Register},
+		{"update", cb.Update().Before("*").Register, cb.Update().After("*").Register},
+		{"delete", cb.Delete().Before("*").Register, cb.Delete().After("*").Register},
+		{"row", cb.Row().Before("*").Register, cb.Row().After("*").Register},
+		{"raw", cb.Raw().Before("*").Register, cb.Raw().After("*").Register},
+	}
+	for _, p := range processors {
+		if err := p.before("tracing:before_"+p.operation, t.before(p.operation)); err != nil {
+			return nil, err
+		}
+		if err := p.after("tracing:after_"+p.operation, t.after(p.operation)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *DBTracing) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+			// リクエスト・ジョブの外のクエリ（定期的な配信のポーリングなど）はスパンを作らない
+			return
+		}
+		_, span := t.tracer.Start(ctx, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", t.system),
+				attribute.String("db.operation.name", operation),
+			),
+		)
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func (t *DBTracing) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(gormSpanKey)
+		if !ok {
+			return
+		}
+		span, ok := v.(trace.Span)
+		if !ok {
+			return
+		}
+		if table := db.Statement.Table; table != "" {
+			span.SetName("db." + operation + " " + table)
+			span.SetAttributes(attribute.String("db.collection.name", table))
+		}
+		span.SetAttributes(
+			attribute.String("db.query.text", db.Statement.SQL.String()),
+			attribute.Int64("db.response.rows", db.RowsAffected),
+		)
+		if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,114 @@
+// Package tracing は OpenTelemetry の分散トレースを設定する
+// W3C traceparent を受け取って親のトレースを引き継ぎ、OTLP/HTTP でスパンを送る
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// スパンを作るトレーサーの名前
+const instrumentationName = "github.com/enkazu1116/go_home"
+
+// スパンの送り先
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+// Config はトレースの設定
+type Config struct {
+	// スパンの送り先（none・otlp）
+	// none でもスパンは作るため、トレースIDはログに付き、traceparent も引き継ぐ
+	Exporter string
+	// サービス名（service.name）
+	ServiceName string
+	// 親の無いトレースを記録する割合（0〜1）
+	SampleRatio float64
+}
+
+// NewConfigFromEnv は環境変数からトレースの設定を読み込む
+// OTEL_TRACES_EXPORTER（既定 none）・OTEL_SERVICE_NAME（既定 go_home）・OTEL_TRACES_SAMPLER_ARG（既定 1）
+// 送り先の URL などは otlptracehttp が OTEL_EXPORTER_OTLP_ENDPOINT・OTEL_EXPORTER_OTLP_TRACES_ENDPOINT・OTEL_EXPORTER_OTLP_HEADERS から読む
+func NewConfigFromEnv() Config {
+	cfg := Config{Exporter: ExporterNone, ServiceName: "go_home", SampleRatio: 1}
+	if v := os.Getenv("OTEL_TRACES_EXPORTER"); v != "" {
+		if v == ExporterNone || v == ExporterOTLP {
+			cfg.Exporter = v
+		} else {
+			slog.Warn("invalid OTEL_TRACES_EXPORTER, using default", "value", v, "default", cfg.Exporter)
+		}
+	}
+	if v := os.Getenv("OTEL_SERVICE_NAME"); v != "" {
+		cfg.ServiceName = v
+	}
+	if v := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || r < 0 || r > 1 {
+			slog.Warn("invalid OTEL_TRACES_SAMPLER_ARG, using default", "value", v, "default", cfg.SampleRatio)
+		} else {
+			cfg.SampleRatio = r
+		}
+	}
+	return cfg
+}
+
+// NewTracerProvider は設定に合わせた TracerProvider を生成し、グローバルに設定する
+// 停止時は Shutdown を呼んで、送っていないスパンを送り切ること
+func NewTracerProvider(cfg Config) (*sdktrace.TracerProvider, error) {
+	var exporter sdktrace.SpanExporter
+	if cfg.Exporter == ExporterOTLP {
+		exp, err := otlptracehttp.New(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	}
+	return NewTracerProviderWithExporter(cfg, exporter), nil
+}
+
+// NewTracerProviderWithExporter は exporter に送る TracerProvider を生成し、グローバルに設定する
+// exporter が nil なら送らない。テストでは tracetest.NewInMemoryExporter を渡す
+func NewTracerProviderWithExporter(cfg Config, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		res = resource.Default()
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp
+}
+
+// Start はスパンを始める（ユースケースのメソッドなど）
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End はエラーを記録してスパンを終える
+// 名前付きの戻り値のエラーを defer tracing.End(span, &err) で渡す
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
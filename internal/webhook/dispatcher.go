@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/netguard"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+// Config は Webhook の配信の設定
+type Config struct {
+	// 配信待ちを確認する間隔
+	PollInterval time.Duration
+	// 1回の確認で配信する最大件数
+	BatchSize int
+	// 再試行の上限（これを超えた配信はデッドレターにする）
+	MaxAttempts int
+	// 1回の送信のタイムアウト
+	Timeout time.Duration
+	// ループバック・プライベートアドレスなど内部のネットワークへの送信を許す（ローカルの検証用）
+	AllowPrivateNetworks bool
+}
+
+// NewConfigFromEnv は環境変数から Webhook の配信の設定を読み込む
+// WEBHOOK_MAX_ATTEMPTS: 再試行の上限（既定 8）
+// WEBHOOK_TIMEOUT: 1回の送信のタイムアウト（既定 10s）
+// WEBHOOK_ALLOW_PRIVATE_NETWORKS: true で内部のネットワークへの送信を許す（既定 false）
+func NewConfigFromEnv() Config {
+	cfg := Config{PollInterval: 5 * time.Second, BatchSize: 100, MaxAttempts: 8, Timeout: 10 * time.Second}
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			slog.Warn("invalid WEBHOOK_MAX_ATTEMPTS, using default", "value", v, "default", cfg.MaxAttempts)
+		} else {
+			cfg.MaxAttempts = n
+		}
+	}
+	if v := os.Getenv("WEBHOOK_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slog.Warn("invalid WEBHOOK_TIMEOUT, using default", "value", v, "default", cfg.Timeout)
+		} else {
+			cfg.Timeout = d
+		}
+	}
+	if v := os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			slog.Warn("invalid WEBHOOK_ALLOW_PRIVATE_NETWORKS, using default", "value", v, "default", cfg.AllowPrivateNetworks)
+		} else {
+			cfg.AllowPrivateNetworks = b
+		}
+	}
+	return cfg
+}
+
+// NewGuard は購読のURLと配信の送信先を確かめるGuardを生成する
+func NewGuard(cfg Config) *netguard.Guard {
+	return netguard.NewGuard(cfg.AllowPrivateNetworks)
+}
+
+// 再試行の間隔の上限
+const maxBackoff = 6 * time.Hour
+
+// レスポンスのボディはエラーの記録用に先頭だけ読む
+const maxErrorBody = 512
+
+// Dispatcher は Webhook の配信を購読先へ送る
+type Dispatcher struct {
+	cfg    Config
+	repo   repository.WebhookRepository
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewDispatcher はDispatcherを生成する
+// 購読の登録後に名前解決の結果が内部のアドレスに変わっても送らないよう、接続する直前にアドレスを確かめる
+// （リダイレクト先への接続も同じ）。プロキシを経由すると送信先を確かめられないため、プロキシは使わない
+func NewDispatcher(cfg Config, repo repository.WebhookRepository, guard *netguard.Guard) *Dispatcher {
+	dialer := &net.Dialer{Timeout: cfg.Timeout, Control: guard.Control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Dispatcher{cfg: cfg, repo: repo, client: &http.Client{Timeout: cfg.Timeout, Transport: transport}, now: time.Now}
+}
+
+// Run は ctx がキャンセルされるまで、PollInterval ごとに配信を繰り返す
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "webhook dispatcher failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce は配信時刻を過ぎた配信を1回分送り、送れた件数を返す
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	now := d.now()
+	list, err := d.repo.FindDueDeliveries(ctx, now, d.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, del := range list {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		// 送信中に他のプロセスが同じ配信を取らないよう、タイムアウトより長く押さえる
+		ok, err := d.repo.ClaimDelivery(ctx, del, now.Add(2*d.cfg.Timeout))
+		if err != nil {
+			return delivered, err
+		}
+		if !ok {
+			continue
+		}
+		code, err := d.send(ctx, del)
+		if err != nil {
+			if err := d.fail(ctx, del, code, err); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err := d.repo.MarkDeliveryDelivered(ctx, del.ID, code, d.now()); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// 署名を付けて POST し、2xx 以外はエラーにする
+func (d *Dispatcher) send(ctx context.Context, del entity.WebhookDelivery) (int, error) {
+	sub, err := d.repo.FindSubscription(ctx, del.SubscriptionID)
+	if err != nil {
+		return 0, err
+	}
+	body := []byte(del.Payload)
+	ts := d.now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, del.EventID)
+	req.Header.Set(HeaderEvent, del.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// 失敗を記録し、指数バックオフで次の配信時刻を決める
+// 購読が削除されていた場合・送信先が内部のアドレスの場合は再試行しても届かないので、すぐにデッドレターにする
+func (d *Dispatcher) fail(ctx context.Context, del entity.WebhookDelivery, code int, cause error) error {
+	attempts := del.Attempts + 1
+	backoff := maxBackoff
+	if attempts < 20 {
+		backoff = min(time.Duration(1<<attempts)*10*time.Second, maxBackoff)
+	}
+	dead := attempts >= d.cfg.MaxAttempts || errors.Is(cause, repository.ErrNotFound) || errors.Is(cause, netguard.ErrForbiddenAddress)
+	return d.repo.MarkDeliveryFailed(ctx, del.ID, attempts, d.now().Add(backoff), code, cause.Error(), dead)
+}
@@ -0,0 +1,360 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	dbinfra "github.com/enkazu1116/go_home/infrastructure/db"
+	"github.com/enkazu1116/go_home/internal/domain"
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/netguard"
+	"github.com/enkazu1116/go_home/internal/repository"
+)
+
+const testSecret = "whsec_test"
+
+// receiver は受け取ったリクエストを記録し、statuses の順に応答する（使い切ったら最後の応答を繰り返す）
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+	// 受信側での署名の検証結果
+	verifyErr error
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, receivedRequest{
+		header:    r.Header.Clone(),
+		body:      body,
+		verifyErr: Verify(testSecret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, 5*time.Minute, time.Now()),
+	})
+	status := rc.statuses[min(len(rc.requests), len(rc.statuses))-1]
+	w.WriteHeader(status)
+	if status >= 300 {
+		io.WriteString(w, "receiver is down\n")
+	}
+}
+
+func (rc *receiver) received() []receivedRequest {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]receivedRequest(nil), rc.requests...)
+}
+
+type dispatcherTest struct {
+	cfg        Config
+	repo       repository.WebhookRepository
+	dispatcher *Dispatcher
+	receiver   *receiver
+	now        time.Time
+	sub        entity.WebhookSubscription
+}
+
+// newDispatcherTest は購読1件と、受信側の httptest サーバーを用意する
+func newDispatcherTest(t *testing.T, maxAttempts int, statuses ...int) *dispatcherTest {
+	t.Helper()
+	db, err := dbinfra.OpenSQLite(filepath.Join(t.TempDir(), "app.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dbinfra.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	rc := &receiver{statuses: statuses}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	dt := &dispatcherTest{repo: repository.NewWebhookRepository(db), receiver: rc, now: time.Now().UTC()}
+	dt.sub = entity.WebhookSubscription{ID: "sub1", URL: srv.URL + "/hook", Events: "attendance.*", Secret: testSecret, Active: true}
+	if err := dt.repo.CreateSubscription(context.Background(), dt.sub); err != nil {
+		t.Fatal(err)
+	}
+	// httptest の受信先はループバックアドレスなので、内部のネットワークへの送信を許す
+	dt.cfg = Config{PollInterval: time.Second, BatchSize: 10, MaxAttempts: maxAttempts, Timeout: 5 * time.Second, AllowPrivateNetworks: true}
+	dt.useDispatcher(NewGuard(dt.cfg))
+	return dt
+}
+
+func (dt *dispatcherTest) useDispatcher(guard *netguard.Guard) {
+	dt.dispatcher = NewDispatcher(dt.cfg, dt.repo, guard)
+	dt.dispatcher.now = func() time.Time { return dt.now }
+}
+
+// publish はアウトボックスのイベントを Sink で配信に振り分け、作られた配信を返す
+func (dt *dispatcherTest) publish(t *testing.T, eventID string) entity.WebhookDelivery {
+	t.Helper()
+	ctx := context.Background()
+	err := NewSink(dt.repo).Deliver(ctx, entity.OutboxMessage{
+		ID:            eventID,
+		EventType:     entity.EventCheckedIn,
+		AggregateType: entity.AuditEntityAttendance,
+		AggregateID:   "a1",
+		OccurredAt:    dt.now,
+		Payload:       `{"ID":"a1"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sink は今の時刻を配信時刻にするため、時計をそれより後に進める
+	dt.now = time.Now().UTC()
+	list, err := dt.repo.ListDeliveries(ctx, repository.WebhookDeliveryFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range list {
+		if d.EventID == eventID {
+			return d
+		}
+	}
+	t.Fatalf("no delivery for event %s", eventID)
+	return entity.WebhookDelivery{}
+}
+
+func (dt *dispatcherTest) runOnce(t *testing.T) int {
+	t.Helper()
+	n, err := dt.dispatcher.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func (dt *dispatcherTest) delivery(t *testing.T, id string) *entity.WebhookDelivery {
+	t.Helper()
+	d, err := dt.repo.FindDelivery(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	dt := newDispatcherTest(t, 3, http.StatusNoContent)
+	del := dt.publish(t, "evt1")
+	if n := dt.runOnce(t); n != 1 {
+		t.Fatalf("RunOnce() = %d, want 1", n)
+	}
+
+	got := dt.receiver.received()
+	if len(got) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(got))
+	}
+	req := got[0]
+	if req.verifyErr != nil {
+		t.Errorf("signature verification failed: %v", req.verifyErr)
+	}
+	if req.header.Get(HeaderID) != "evt1" || req.header.Get(HeaderEvent) != entity.EventCheckedIn {
+		t.Errorf("headers = %v", req.header)
+	}
+	if ct := req.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var payload Payload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID != "evt1" || payload.Type != entity.EventCheckedIn || string(payload.Data) != `{"ID":"a1"}` {
+		t.Errorf("payload = %+v", payload)
+	}
+	// 別の鍵やボディの書き換えでは検証に失敗する
+	ts, sig := req.header.Get(HeaderTimestamp), req.header.Get(HeaderSignature)
+	if err := Verify("other", ts, sig, req.body, 5*time.Minute, time.Now()); err != ErrInvalidSignature {
+		t.Errorf("Verify() with another secret = %v, want ErrInvalidSignature", err)
+	}
+	if err := Verify(testSecret, ts, sig, append(req.body, ' '), 5*time.Minute, time.Now()); err != ErrInvalidSignature {
+		t.Errorf("Verify() with modified body = %v, want ErrInvalidSignature", err)
+	}
+	if err := Verify(testSecret, ts, sig, req.body, 5*time.Minute, time.Now().Add(time.Hour)); err != ErrStaleTimestamp {
+		t.Errorf("Verify() an hour later = %v, want ErrStaleTimestamp", err)
+	}
+
+	after := dt.delivery(t, del.ID)
+	if after.Status != entity.WebhookDelivered || after.LastStatusCode != http.StatusNoContent || after.DeliveredAt.IsZero() {
+		t.Errorf("delivery = %+v, want delivered", after)
+	}
+	// 配信済みの配信は送り直さない
+	dt.now = dt.now.Add(time.Hour)
+	if n := dt.runOnce(t); n != 0 || len(dt.receiver.received()) != 1 {
+		t.Errorf("delivered again: RunOnce() = %d", n)
+	}
+}
+
+func TestDispatcherRetriesIntoDeadLetter(t *testing.T) {
+	dt := newDispatcherTest(t, 3, http.StatusInternalServerError)
+	del := dt.publish(t, "evt1")
+
+	// 1回目・2回目の失敗は 20秒・40秒後に再試行し、3回目でデッドレターにする
+	tests := []struct {
+		backoff time.Duration
+		status  string
+	}{
+		{20 * time.Second, entity.WebhookPending},
+		{40 * time.Second, entity.WebhookPending},
+		{80 * time.Second, entity.WebhookDead},
+	}
+	for i, tt := range tests {
+		if n := dt.runOnce(t); n != 0 {
+			t.Fatalf("attempt %d: RunOnce() = %d, want 0", i+1, n)
+		}
+		d := dt.delivery(t, del.ID)
+		if d.Attempts != i+1 || d.Status != tt.status || d.LastStatusCode != http.StatusInternalServerError || d.LastError == "" {
+			t.Fatalf("attempt %d: delivery = %+v", i+1, d)
+		}
+		if !d.NextAttemptAt.Equal(dt.now.Add(tt.backoff)) {
+			t.Errorf("attempt %d: next attempt = %s, want %s", i+1, d.NextAttemptAt, dt.now.Add(tt.backoff))
+		}
+
+		// 次の配信時刻より前は送らない
+		dt.now = d.NextAttemptAt.Add(-time.Second)
+		dt.runOnce(t)
+		if got := len(dt.receiver.received()); got != i+1 {
+			t.Fatalf("attempt %d: sent before the backoff elapsed (%d requests)", i+1, got)
+		}
+		dt.now = d.NextAttemptAt
+	}
+
+	// デッドレターは再試行しない
+	dt.now = dt.now.Add(24 * time.Hour)
+	dt.runOnce(t)
+	if got := len(dt.receiver.received()); got != 3 {
+		t.Errorf("dead letter was retried: %d requests", got)
+	}
+	dead, err := domain.NewWebhookUsecase(dt.repo, NewGuard(dt.cfg)).ListDeliveries(context.Background(), repository.WebhookDeliveryFilter{Status: entity.WebhookDead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != del.ID {
+		t.Errorf("dead letters = %+v", dead)
+	}
+}
+
+func TestDispatcherDeadLettersDeletedSubscription(t *testing.T) {
+	dt := newDispatcherTest(t, 3, http.StatusOK)
+	del := dt.publish(t, "evt1")
+	if err := dt.repo.DeleteSubscription(context.Background(), dt.sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	dt.runOnce(t)
+	if d := dt.delivery(t, del.ID); d.Status != entity.WebhookDead || d.Attempts != 1 {
+		t.Errorf("delivery = %+v, want dead after one attempt", d)
+	}
+	if got := len(dt.receiver.received()); got != 0 {
+		t.Errorf("receiver got %d requests", got)
+	}
+}
+
+// 登録後に名前解決の結果が内部のアドレスに変わった場合も、接続する直前に拒否する
+func TestDispatcherRefusesPrivateAddresses(t *testing.T) {
+	dt := newDispatcherTest(t, 3, http.StatusOK)
+	dt.useDispatcher(netguard.NewGuard(false))
+	del := dt.publish(t, "evt1")
+
+	dt.runOnce(t)
+	d := dt.delivery(t, del.ID)
+	if d.Status != entity.WebhookDead || d.Attempts != 1 || !strings.Contains(d.LastError, netguard.ErrForbiddenAddress.Error()) {
+		t.Errorf("delivery = %+v, want dead with a forbidden address error", d)
+	}
+	if got := len(dt.receiver.received()); got != 0 {
+		t.Errorf("receiver got %d requests", got)
+	}
+}
+
+// リダイレクト先も接続する直前に確かめる
+func TestDispatcherRefusesRedirectToPrivateAddress(t *testing.T) {
+	dt := newDispatcherTest(t, 3, http.StatusOK)
+	internal := httptest.NewServer(dt.receiver)
+	t.Cleanup(internal.Close)
+	redirector := httptest.NewServer(http.RedirectHandler(internal.URL+"/hook", http.StatusTemporaryRedirect))
+	t.Cleanup(redirector.Close)
+	dt.sub.URL = redirector.URL + "/hook"
+	if err := dt.repo.UpdateSubscription(context.Background(), dt.sub); err != nil {
+		t.Fatal(err)
+	}
+	// リダイレクトする受信先は公開アドレスとみなし、リダイレクト先（ループバック）だけを拒否する
+	redirectorAddr := strings.TrimPrefix(redirector.URL, "http://")
+	guard := netguard.NewGuard(false)
+	dialer := &net.Dialer{Control: func(network, address string, c syscall.RawConn) error {
+		if address == redirectorAddr {
+			return nil
+		}
+		return guard.Control(network, address, c)
+	}}
+	dt.useDispatcher(guard)
+	dt.dispatcher.client.Transport.(*http.Transport).DialContext = dialer.DialContext
+	del := dt.publish(t, "evt1")
+
+	dt.runOnce(t)
+	if d := dt.delivery(t, del.ID); d.Status != entity.WebhookDead || !strings.Contains(d.LastError, netguard.ErrForbiddenAddress.Error()) {
+		t.Errorf("delivery = %+v, want dead with a forbidden address error", d)
+	}
+	if got := len(dt.receiver.received()); got != 0 {
+		t.Errorf("internal receiver got %d requests", got)
+	}
+}
+
+func TestDispatcherRedelivery(t *testing.T) {
+	dt := newDispatcherTest(t, 1, http.StatusBadGateway, http.StatusOK, http.StatusOK)
+	del := dt.publish(t, "evt1")
+	webhooks := domain.NewWebhookUsecase(dt.repo, NewGuard(dt.cfg))
+	ctx := context.Background()
+
+	dt.runOnce(t)
+	if d := dt.delivery(t, del.ID); d.Status != entity.WebhookDead {
+		t.Fatalf("delivery = %+v, want dead", d)
+	}
+
+	tests := []struct {
+		name string
+	}{
+		// デッドレターを再配信する
+		{"dead letter"},
+		// 受信側で処理をやり直すため、配信済みも再配信できる
+		{"delivered"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset, err := webhooks.Redeliver(ctx, del.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reset.Status != entity.WebhookPending || reset.Attempts != 0 {
+				t.Fatalf("redelivered = %+v, want pending", reset)
+			}
+			dt.now = time.Now().UTC().Add(time.Second)
+			if n := dt.runOnce(t); n != 1 {
+				t.Fatalf("RunOnce() = %d, want 1", n)
+			}
+			if d := dt.delivery(t, del.ID); d.Status != entity.WebhookDelivered || d.LastStatusCode != http.StatusOK {
+				t.Errorf("delivery = %+v, want delivered", d)
+			}
+			// 再配信も同じイベントIDで送るので、受信側で重複を除ける
+			got := dt.receiver.received()
+			if len(got) != i+2 || got[i+1].header.Get(HeaderID) != "evt1" || got[i+1].verifyErr != nil {
+				t.Errorf("redelivered request = %+v", got[len(got)-1])
+			}
+		})
+	}
+
+	if _, err := webhooks.Redeliver(ctx, "missing"); err != repository.ErrNotFound {
+		t.Errorf("Redeliver(missing) = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 配信時に付けるヘッダー
+const (
+	HeaderID        = "X-Webhook-Id"        // イベントID（受信側での重複除去に使う）
+	HeaderEvent     = "X-Webhook-Event"     // イベントの種類
+	HeaderTimestamp = "X-Webhook-Timestamp" // 送信時刻（Unix秒）
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" + HMAC-SHA256(secret, timestamp + "." + body) の16進
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp is too old")
+)
+
+// Sign は送信時刻とボディから署名を計算する
+// 時刻を含めることで、古いリクエストの再送（リプレイ）を受信側で拒否できる
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify は受信側で署名と送信時刻を検証する
+// 送信時刻が now から tolerance より離れている場合は ErrStaleTimestamp を返す
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	ts, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return ErrStaleTimestamp
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(strings.TrimSpace(signature))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/enkazu1116/go_home/internal/entity"
+	"github.com/enkazu1116/go_home/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Payload は Webhook で送るボディ
+type Payload struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregateType"`
+	AggregateID   string          `json:"aggregateId"`
+	OccurredAt    time.Time       `json:"occurredAt"`
+	Data          json.RawMessage `json:"data,omitempty"`
+}
+
+// Matches は購読のイベントの指定（カンマ区切り）が eventType に一致するかを返す
+// 空や "*" はすべて、"attendance.*" のように末尾が ".*" のものは前方一致
+func Matches(events, eventType string) bool {
+	if strings.TrimSpace(events) == "" {
+		return true
+	}
+	for _, e := range strings.Split(events, ",") {
+		e = strings.TrimSpace(e)
+		switch {
+		case e == "*", e == eventType:
+			return true
+		case strings.HasSuffix(e, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(e, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+// Sink はアウトボックスのイベントを、一致する購読ごとの配信に振り分ける配信先
+// 実際の送信は Dispatcher が購読ごとに行うため、1つの購読先が落ちていても他の購読先には重複して届かない
+type Sink struct {
+	repo repository.WebhookRepository
+}
+
+// NewSink はSinkを生成する
+func NewSink(repo repository.WebhookRepository) *Sink {
+	return &Sink{repo: repo}
+}
+
+func (s *Sink) Name() string {
+	return "webhook"
+}
+
+func (s *Sink) Deliver(ctx context.Context, m entity.OutboxMessage) error {
+	subs, err := s.repo.ActiveSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	payload := Payload{
+		ID:            m.ID,
+		Type:          m.EventType,
+		AggregateType: m.AggregateType,
+		AggregateID:   m.AggregateID,
+		OccurredAt:    m.OccurredAt,
+	}
+	if m.Payload != "" {
+		payload.Data = json.RawMessage(m.Payload)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if !Matches(sub.Events, m.EventType) {
+			continue
+		}
+		err := s.repo.CreateDelivery(ctx, entity.WebhookDelivery{
+			ID:             uuid.NewString(),
+			SubscriptionID: sub.ID,
+			EventID:        m.ID,
+			EventType:      m.EventType,
+			Payload:        string(body),
+			NextAttemptAt:  time.Now(),
+		})
+		// アウトボックスの再試行で同じイベントが再度来た場合は、作成済みの配信を使う
+		if err != nil && !errors.Is(err, repository.ErrConflict) {
+			return err
+		}
+	}
+	return nil
+}
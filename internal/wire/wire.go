@@ -4,25 +4,147 @@
 package wire
 
 import (
+	"log/slog"
+
+	"github.com/enkazu1116/go_home/internal/auth"
 	"github.com/enkazu1116/go_home/internal/domain"
 	"github.com/enkazu1116/go_home/internal/handler"
+	"github.com/enkazu1116/go_home/internal/health"
+	"github.com/enkazu1116/go_home/internal/live"
+	"github.com/enkazu1116/go_home/internal/metrics"
+	"github.com/enkazu1116/go_home/internal/middleware"
+	"github.com/enkazu1116/go_home/internal/notify"
+	"github.com/enkazu1116/go_home/internal/outbox"
 	"github.com/enkazu1116/go_home/internal/repository"
+	"github.com/enkazu1116/go_home/internal/scheduler"
+	"github.com/enkazu1116/go_home/internal/tracing"
+	"github.com/enkazu1116/go_home/internal/webhook"
 	"github.com/google/wire"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/gorm"
 )
 
 // InitializeApp はアプリケーション全体の依存関係を初期化する関数
-func InitializeApp(db *gorm.DB) (*App, error) {
+func InitializeApp(db *gorm.DB, logger *slog.Logger) (*App, error) {
 	wire.Build(
 		// リポジトリ層の依存関係
 		repository.NewTimeIsMoneyRepository,
 		wire.Bind(new(repository.UserRepository), new(*repository.TimeIsMoneyGormRepo)),
+		repository.NewAttendanceRepository,
+		repository.NewIdempotencyRepository,
+		repository.NewAuditRepository,
+		repository.NewPunchEventRepository,
+		repository.NewOutboxRepository,
+		repository.NewUnitOfWork,
+		repository.NewWebhookRepository,
+		repository.NewChatIdentityRepository,
+		repository.NewNotificationPreferenceRepository,
+		repository.NewEmailRepository,
+		repository.NewJobLockRepository,
+		repository.NewKioskRepository,
+		repository.NewCardReaderRepository,
+		repository.NewCardRepository,
+		repository.NewClosedPeriodRepository,
+		repository.NewPunchPolicyRepository,
+		repository.NewUserStatusRepository,
+		repository.NewHolidayRepository,
+		repository.NewLeaveRepository,
+		repository.NewCalendarTokenRepository,
+
+		// 認証の依存関係
+		auth.NewConfigFromEnv,
+		auth.NewAuthenticator,
+		wire.Bind(new(auth.UserFinder), new(*repository.TimeIsMoneyGormRepo)),
+
+		// ミドルウェアの依存関係
+		middleware.NewIdempotencyConfigFromEnv,
+		middleware.NewIdempotency,
+		middleware.NewTracing,
+		middleware.NewAccessLog,
+		middleware.NewMetrics,
+
+		// メトリクスの依存関係
+		metrics.NewRegistry,
+		metrics.NewDBMetrics,
+
+		// トレースの依存関係
+		tracing.NewConfigFromEnv,
+		tracing.NewTracerProvider,
+		tracing.NewDBTracing,
+
+		// ヘルスチェックの依存関係
+		health.NewConfigFromEnv,
+		health.NewChecker,
+
+		// アウトボックスの依存関係
+		outbox.NewRelayConfigFromEnv,
+		outbox.NewLogSink,
+		outbox.NewSinks,
+		outbox.NewRelay,
+
+		// 通知の依存関係
+		notify.NewConfigFromEnv,
+		notify.NewNotifier,
+		notify.NewMailer,
+
+		// スケジューラーの依存関係
+		scheduler.New,
+
+		// ダッシュボードの配信の依存関係
+		live.NewHub,
+		live.NewBroker,
+
+		// Webhookの依存関係
+		webhook.NewConfigFromEnv,
+		webhook.NewGuard,
+		webhook.NewSink,
+		webhook.NewDispatcher,
 
 		// ドメイン層の依存関係
 		domain.NewUserUsecase,
+		domain.NewUserImportUsecase,
+		domain.NewAttendanceUsecase,
+		domain.NewAuditUsecase,
+		domain.NewPunchLogConfigFromEnv,
+		domain.NewPunchLogUsecase,
+		domain.NewWebhookUsecase,
+		domain.NewChatConfigFromEnv,
+		domain.NewChatUsecase,
+		domain.NewNotificationUsecase,
+		domain.NewMissingCheckOutConfigFromEnv,
+		domain.NewMissingCheckOutUsecase,
+		domain.NewKioskConfigFromEnv,
+		domain.NewKioskUsecase,
+		domain.NewCardConfigFromEnv,
+		domain.NewCardUsecase,
+		domain.NewPunchPolicyUsecase,
+		domain.NewDashboardConfigFromEnv,
+		domain.NewDashboardUsecase,
+		domain.NewPresenceConfigFromEnv,
+		domain.NewPresenceUsecase,
+		domain.NewCalendarConfigFromEnv,
+		domain.NewCalendarUsecase,
+		domain.NewKPIUsecase,
 
 		// ハンドラー層の依存関係
 		handler.NewUserHandler,
+		handler.NewAttendanceHandler,
+		handler.NewAuditHandler,
+		handler.NewPunchLogHandler,
+		handler.NewWebhookHandler,
+		handler.NewChatHandler,
+		handler.NewNotificationHandler,
+		handler.NewKioskHandler,
+		handler.NewCardHandler,
+		handler.NewPunchPolicyHandler,
+		handler.NewDashboardHandler,
+		handler.NewPresenceHandler,
+		handler.NewCalendarHandler,
+		handler.NewMetricsHandler,
+		handler.NewHealthHandler,
+		handler.NewHealthGRPCServer,
+		handler.NewUserGRPCServer,
+		handler.NewAttendanceGRPCServer,
 
 		// アプリケーション全体の依存関係
 		NewApp,
@@ -30,14 +152,178 @@ func InitializeApp(db *gorm.DB) (*App, error) {
 	return &App{}, nil
 }
 
+// InitializeAdmin は管理用CLIの依存関係を初期化する関数
+// HTTP・gRPC・ワーカーは動かさないため、CLIが使うユースケースとその依存関係だけを組み立てる
+func InitializeAdmin(db *gorm.DB) (*Admin, error) {
+	wire.Build(
+		// リポジトリ層の依存関係
+		repository.NewTimeIsMoneyRepository,
+		wire.Bind(new(repository.UserRepository), new(*repository.TimeIsMoneyGormRepo)),
+		repository.NewAttendanceRepository,
+		repository.NewAuditRepository,
+		repository.NewPunchEventRepository,
+		repository.NewOutboxRepository,
+		repository.NewUnitOfWork,
+		repository.NewClosedPeriodRepository,
+		repository.NewPunchPolicyRepository,
+
+		// ダッシュボードの配信の依存関係（CLIでは購読者がいない）
+		live.NewHub,
+
+		// ドメイン層の依存関係
+		domain.NewUserUsecase,
+		domain.NewUserImportUsecase,
+		domain.NewAttendanceUsecase,
+		domain.NewPunchLogConfigFromEnv,
+		domain.NewPunchLogUsecase,
+		domain.NewPunchPolicyUsecase,
+		domain.NewDashboardUsecase,
+
+		NewAdmin,
+	)
+	return nil, nil
+}
+
 // App はアプリケーション全体を表す構造体
 type App struct {
-	UserHandler *handler.UserHandler
+	Authenticator         *auth.Authenticator
+	Idempotency           *middleware.Idempotency
+	Tracing               *middleware.Tracing
+	AccessLog             *middleware.AccessLog
+	Metrics               *middleware.Metrics
+	UserHandler           *handler.UserHandler
+	AttendanceHandler     *handler.AttendanceHandler
+	AuditHandler          *handler.AuditHandler
+	PunchLogHandler       *handler.PunchLogHandler
+	WebhookHandler        *handler.WebhookHandler
+	ChatHandler           *handler.ChatHandler
+	NotificationHandler   *handler.NotificationHandler
+	KioskHandler          *handler.KioskHandler
+	CardHandler           *handler.CardHandler
+	PunchPolicyHandler    *handler.PunchPolicyHandler
+	DashboardHandler      *handler.DashboardHandler
+	PresenceHandler       *handler.PresenceHandler
+	CalendarHandler       *handler.CalendarHandler
+	MetricsHandler        *handler.MetricsHandler
+	HealthHandler         *handler.HealthHandler
+	PunchLog              domain.PunchLogUsecase
+	PunchLogConfig        domain.PunchLogConfig
+	Chat                  domain.ChatUsecase
+	ChatConfig            domain.ChatConfig
+	MissingCheckOut       domain.MissingCheckOutUsecase
+	MissingCheckOutConfig domain.MissingCheckOutConfig
+	Presence              domain.PresenceUsecase
+	Relay                 *outbox.Relay
+	WebhookDispatcher     *webhook.Dispatcher
+	Mailer                *notify.Mailer
+	Scheduler             *scheduler.Scheduler
+	LiveHub               *live.Hub
+	LiveBroker            live.Broker
+	DBMetrics             *metrics.DBMetrics
+	TracerProvider        *sdktrace.TracerProvider
+	DBTracing             *tracing.DBTracing
+	Health                *health.Checker
+	UserGRPCServer        *handler.UserGRPCServer
+	AttendanceGRPCServer  *handler.AttendanceGRPCServer
+	HealthGRPCServer      *handler.HealthGRPCServer
 }
 
 // NewApp はアプリケーション全体の構造体を作成する
-func NewApp(userHandler *handler.UserHandler) *App {
+func NewApp(
+	authenticator *auth.Authenticator,
+	idempotency *middleware.Idempotency,
+	middlewareTracing *middleware.Tracing,
+	accessLog *middleware.AccessLog,
+	middlewareMetrics *middleware.Metrics,
+	userHandler *handler.UserHandler,
+	attendanceHandler *handler.AttendanceHandler,
+	auditHandler *handler.AuditHandler,
+	punchLogHandler *handler.PunchLogHandler,
+	webhookHandler *handler.WebhookHandler,
+	chatHandler *handler.ChatHandler,
+	notificationHandler *handler.NotificationHandler,
+	kioskHandler *handler.KioskHandler,
+	cardHandler *handler.CardHandler,
+	punchPolicyHandler *handler.PunchPolicyHandler,
+	dashboardHandler *handler.DashboardHandler,
+	presenceHandler *handler.PresenceHandler,
+	calendarHandler *handler.CalendarHandler,
+	metricsHandler *handler.MetricsHandler,
+	healthHandler *handler.HealthHandler,
+	punchLog domain.PunchLogUsecase,
+	punchLogConfig domain.PunchLogConfig,
+	chat domain.ChatUsecase,
+	chatConfig domain.ChatConfig,
+	missingCheckOut domain.MissingCheckOutUsecase,
+	missingCheckOutConfig domain.MissingCheckOutConfig,
+	presence domain.PresenceUsecase,
+	relay *outbox.Relay,
+	webhookDispatcher *webhook.Dispatcher,
+	mailer *notify.Mailer,
+	jobScheduler *scheduler.Scheduler,
+	liveHub *live.Hub,
+	liveBroker live.Broker,
+	dbMetrics *metrics.DBMetrics,
+	tracerProvider *sdktrace.TracerProvider,
+	dbTracing *tracing.DBTracing,
+	healthChecker *health.Checker,
+	userGRPCServer *handler.UserGRPCServer,
+	attendanceGRPCServer *handler.AttendanceGRPCServer,
+	healthGRPCServer *handler.HealthGRPCServer,
+) *App {
 	return &App{
-		UserHandler: userHandler,
+		Authenticator:         authenticator,
+		Idempotency:           idempotency,
+		Tracing:               middlewareTracing,
+		AccessLog:             accessLog,
+		Metrics:               middlewareMetrics,
+		UserHandler:           userHandler,
+		AttendanceHandler:     attendanceHandler,
+		AuditHandler:          auditHandler,
+		PunchLogHandler:       punchLogHandler,
+		WebhookHandler:        webhookHandler,
+		ChatHandler:           chatHandler,
+		NotificationHandler:   notificationHandler,
+		KioskHandler:          kioskHandler,
+		CardHandler:           cardHandler,
+		PunchPolicyHandler:    punchPolicyHandler,
+		DashboardHandler:      dashboardHandler,
+		PresenceHandler:       presenceHandler,
+		CalendarHandler:       calendarHandler,
+		MetricsHandler:        metricsHandler,
+		HealthHandler:         healthHandler,
+		PunchLog:              punchLog,
+		PunchLogConfig:        punchLogConfig,
+		Chat:                  chat,
+		ChatConfig:            chatConfig,
+		MissingCheckOut:       missingCheckOut,
+		MissingCheckOutConfig: missingCheckOutConfig,
+		Presence:              presence,
+		Relay:                 relay,
+		WebhookDispatcher:     webhookDispatcher,
+		Mailer:                mailer,
+		Scheduler:             jobScheduler,
+		LiveHub:               liveHub,
+		LiveBroker:            liveBroker,
+		DBMetrics:             dbMetrics,
+		TracerProvider:        tracerProvider,
+		DBTracing:             dbTracing,
+		Health:                healthChecker,
+		UserGRPCServer:        userGRPCServer,
+		AttendanceGRPCServer:  attendanceGRPCServer,
+		HealthGRPCServer:      healthGRPCServer,
 	}
 }
+
+// Admin は管理用CLIが使うユースケース
+type Admin struct {
+	Users       domain.UserUsecase
+	UserImports domain.UserImportUsecase
+	Attendance  domain.AttendanceUsecase
+	Tx          repository.UnitOfWork
+}
+
+// NewAdmin は管理用CLIが使うユースケースの構造体を作成する
+func NewAdmin(users domain.UserUsecase, imports domain.UserImportUsecase, attendance domain.AttendanceUsecase, tx repository.UnitOfWork) *Admin {
+	return &Admin{Users: users, UserImports: imports, Attendance: attendance, Tx: tx}
+}
@@ -7,33 +7,298 @@
 package wire
 
 import (
+	"log/slog"
+
+	"github.com/enkazu1116/go_home/internal/auth"
 	"github.com/enkazu1116/go_home/internal/domain"
 	"github.com/enkazu1116/go_home/internal/handler"
+	"github.com/enkazu1116/go_home/internal/health"
+	"github.com/enkazu1116/go_home/internal/live"
+	"github.com/enkazu1116/go_home/internal/metrics"
+	"github.com/enkazu1116/go_home/internal/middleware"
+	"github.com/enkazu1116/go_home/internal/notify"
+	"github.com/enkazu1116/go_home/internal/outbox"
 	"github.com/enkazu1116/go_home/internal/repository"
+	"github.com/enkazu1116/go_home/internal/scheduler"
+	"github.com/enkazu1116/go_home/internal/tracing"
+	"github.com/enkazu1116/go_home/internal/webhook"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/gorm"
 )
 
 // Injectors from wire.go:
 
 // InitializeApp はアプリケーション全体の依存関係を初期化する関数
-func InitializeApp(db *gorm.DB) (*App, error) {
+func InitializeApp(db *gorm.DB, logger *slog.Logger) (*App, error) {
+	config := auth.NewConfigFromEnv()
 	timeIsMoneyGormRepo := repository.NewTimeIsMoneyRepository(db)
-	userUsecase := domain.NewUserUsecase(timeIsMoneyGormRepo)
-	userHandler := handler.NewUserHandler(userUsecase)
-	app := NewApp(userHandler)
+	authenticator := auth.NewAuthenticator(config, timeIsMoneyGormRepo)
+	idempotencyConfig := middleware.NewIdempotencyConfigFromEnv()
+	idempotencyRepository := repository.NewIdempotencyRepository(db)
+	idempotency := middleware.NewIdempotency(idempotencyConfig, idempotencyRepository)
+	tracingConfig := tracing.NewConfigFromEnv()
+	tracerProvider, err := tracing.NewTracerProvider(tracingConfig)
+	if err != nil {
+		return nil, err
+	}
+	middlewareTracing := middleware.NewTracing(tracerProvider)
+	accessLog := middleware.NewAccessLog(logger)
+	registry := metrics.NewRegistry()
+	middlewareMetrics := middleware.NewMetrics(registry)
+	auditRepository := repository.NewAuditRepository(db)
+	outboxRepository := repository.NewOutboxRepository(db)
+	unitOfWork := repository.NewUnitOfWork(db)
+	userUsecase := domain.NewUserUsecase(timeIsMoneyGormRepo, auditRepository, outboxRepository, unitOfWork)
+	userImportUsecase := domain.NewUserImportUsecase(userUsecase, unitOfWork)
+	userHandler := handler.NewUserHandler(userUsecase, userImportUsecase)
+	attendanceRepository := repository.NewAttendanceRepository(db)
+	punchLogConfig := domain.NewPunchLogConfigFromEnv()
+	punchEventRepository := repository.NewPunchEventRepository(db)
+	punchLogUsecase := domain.NewPunchLogUsecase(punchLogConfig, punchEventRepository)
+	closedPeriodRepository := repository.NewClosedPeriodRepository(db)
+	punchPolicyRepository := repository.NewPunchPolicyRepository(db)
+	punchPolicyUsecase := domain.NewPunchPolicyUsecase(punchPolicyRepository, timeIsMoneyGormRepo)
+	hub := live.NewHub()
+	dashboardUsecase := domain.NewDashboardUsecase(hub, timeIsMoneyGormRepo, attendanceRepository, punchLogUsecase)
+	attendanceUsecase := domain.NewAttendanceUsecase(attendanceRepository, auditRepository, outboxRepository, unitOfWork, punchLogUsecase, closedPeriodRepository, punchPolicyUsecase, dashboardUsecase)
+	kioskConfig := domain.NewKioskConfigFromEnv()
+	kioskRepository := repository.NewKioskRepository(db)
+	kioskUsecase := domain.NewKioskUsecase(kioskConfig, kioskRepository)
+	attendanceHandler := handler.NewAttendanceHandler(attendanceUsecase, kioskUsecase, userUsecase)
+	auditUsecase := domain.NewAuditUsecase(auditRepository)
+	auditHandler := handler.NewAuditHandler(auditUsecase)
+	punchLogHandler := handler.NewPunchLogHandler(punchLogUsecase)
+	webhookRepository := repository.NewWebhookRepository(db)
+	webhookConfig := webhook.NewConfigFromEnv()
+	guard := webhook.NewGuard(webhookConfig)
+	webhookUsecase := domain.NewWebhookUsecase(webhookRepository, guard)
+	webhookHandler := handler.NewWebhookHandler(webhookUsecase)
+	chatConfig := domain.NewChatConfigFromEnv()
+	chatIdentityRepository := repository.NewChatIdentityRepository(db)
+	notifyConfig := notify.NewConfigFromEnv()
+	notificationPreferenceRepository := repository.NewNotificationPreferenceRepository(db)
+	emailRepository := repository.NewEmailRepository(db)
+	notifier := notify.NewNotifier(notifyConfig, timeIsMoneyGormRepo, notificationPreferenceRepository, emailRepository)
+	chatUsecase := domain.NewChatUsecase(chatIdentityRepository, timeIsMoneyGormRepo, attendanceUsecase, notifier)
+	chatHandler := handler.NewChatHandler(chatUsecase, chatConfig)
+	notificationUsecase := domain.NewNotificationUsecase(notificationPreferenceRepository, timeIsMoneyGormRepo)
+	notificationHandler := handler.NewNotificationHandler(notificationUsecase)
+	missingCheckOutConfig := domain.NewMissingCheckOutConfigFromEnv()
+	missingCheckOutUsecase := domain.NewMissingCheckOutUsecase(missingCheckOutConfig, attendanceRepository, timeIsMoneyGormRepo, attendanceUsecase, notifier)
+	relayConfig := outbox.NewRelayConfigFromEnv()
+	logSink := outbox.NewLogSink()
+	sink := webhook.NewSink(webhookRepository)
+	v := outbox.NewSinks(logSink, sink)
+	relay := outbox.NewRelay(relayConfig, outboxRepository, v)
+	dispatcher := webhook.NewDispatcher(webhookConfig, webhookRepository, guard)
+	mailer := notify.NewMailer(notifyConfig, emailRepository)
+	jobLockRepository := repository.NewJobLockRepository(db)
+	schedulerScheduler := scheduler.New(jobLockRepository)
+	userGRPCServer := handler.NewUserGRPCServer(userUsecase)
+	kioskHandler := handler.NewKioskHandler(kioskUsecase)
+	cardConfig := domain.NewCardConfigFromEnv()
+	cardReaderRepository := repository.NewCardReaderRepository(db)
+	cardRepository := repository.NewCardRepository(db)
+	cardUsecase := domain.NewCardUsecase(cardConfig, cardReaderRepository, cardRepository, timeIsMoneyGormRepo, attendanceUsecase)
+	cardHandler := handler.NewCardHandler(cardUsecase)
+	punchPolicyHandler := handler.NewPunchPolicyHandler(punchPolicyUsecase)
+	dashboardConfig := domain.NewDashboardConfigFromEnv()
+	dashboardHandler := handler.NewDashboardHandler(dashboardUsecase, dashboardConfig)
+	presenceConfig := domain.NewPresenceConfigFromEnv()
+	userStatusRepository := repository.NewUserStatusRepository(db)
+	broker := live.NewBroker(db)
+	presenceUsecase := domain.NewPresenceUsecase(presenceConfig, userStatusRepository, timeIsMoneyGormRepo, broker)
+	presenceHandler := handler.NewPresenceHandler(presenceUsecase)
+	calendarTokenRepository := repository.NewCalendarTokenRepository(db)
+	holidayRepository := repository.NewHolidayRepository(db)
+	leaveRepository := repository.NewLeaveRepository(db)
+	calendarConfig := domain.NewCalendarConfigFromEnv()
+	calendarUsecase := domain.NewCalendarUsecase(calendarConfig, calendarTokenRepository, holidayRepository, leaveRepository, timeIsMoneyGormRepo)
+	calendarHandler := handler.NewCalendarHandler(calendarUsecase)
+	kpiUsecase := domain.NewKPIUsecase(attendanceRepository)
+	metricsHandler := handler.NewMetricsHandler(registry, kpiUsecase)
+	healthConfig := health.NewConfigFromEnv()
+	checker := health.NewChecker(healthConfig, db)
+	healthHandler := handler.NewHealthHandler(checker)
+	dbMetrics, err := metrics.NewDBMetrics(db, registry)
+	if err != nil {
+		return nil, err
+	}
+	dbTracing, err := tracing.NewDBTracing(db, tracerProvider)
+	if err != nil {
+		return nil, err
+	}
+	attendanceGRPCServer := handler.NewAttendanceGRPCServer(attendanceUsecase)
+	healthGRPCServer := handler.NewHealthGRPCServer(checker)
+	app := NewApp(authenticator, idempotency, middlewareTracing, accessLog, middlewareMetrics, userHandler, attendanceHandler, auditHandler, punchLogHandler, webhookHandler, chatHandler, notificationHandler, kioskHandler, cardHandler, punchPolicyHandler, dashboardHandler, presenceHandler, calendarHandler, metricsHandler, healthHandler, punchLogUsecase, punchLogConfig, chatUsecase, chatConfig, missingCheckOutUsecase, missingCheckOutConfig, presenceUsecase, relay, dispatcher, mailer, schedulerScheduler, hub, broker, dbMetrics, tracerProvider, dbTracing, checker, userGRPCServer, attendanceGRPCServer, healthGRPCServer)
 	return app, nil
 }
 
+// InitializeAdmin は管理用CLIの依存関係を初期化する関数
+// HTTP・gRPC・ワーカーは動かさないため、CLIが使うユースケースとその依存関係だけを組み立てる
+func InitializeAdmin(db *gorm.DB) (*Admin, error) {
+	timeIsMoneyGormRepo := repository.NewTimeIsMoneyRepository(db)
+	auditRepository := repository.NewAuditRepository(db)
+	outboxRepository := repository.NewOutboxRepository(db)
+	unitOfWork := repository.NewUnitOfWork(db)
+	userUsecase := domain.NewUserUsecase(timeIsMoneyGormRepo, auditRepository, outboxRepository, unitOfWork)
+	userImportUsecase := domain.NewUserImportUsecase(userUsecase, unitOfWork)
+	attendanceRepository := repository.NewAttendanceRepository(db)
+	punchLogConfig := domain.NewPunchLogConfigFromEnv()
+	punchEventRepository := repository.NewPunchEventRepository(db)
+	punchLogUsecase := domain.NewPunchLogUsecase(punchLogConfig, punchEventRepository)
+	closedPeriodRepository := repository.NewClosedPeriodRepository(db)
+	punchPolicyRepository := repository.NewPunchPolicyRepository(db)
+	punchPolicyUsecase := domain.NewPunchPolicyUsecase(punchPolicyRepository, timeIsMoneyGormRepo)
+	hub := live.NewHub()
+	dashboardUsecase := domain.NewDashboardUsecase(hub, timeIsMoneyGormRepo, attendanceRepository, punchLogUsecase)
+	attendanceUsecase := domain.NewAttendanceUsecase(attendanceRepository, auditRepository, outboxRepository, unitOfWork, punchLogUsecase, closedPeriodRepository, punchPolicyUsecase, dashboardUsecase)
+	admin := NewAdmin(userUsecase, userImportUsecase, attendanceUsecase, unitOfWork)
+	return admin, nil
+}
+
 // wire.go:
 
 // App はアプリケーション全体を表す構造体
 type App struct {
-	UserHandler *handler.UserHandler
+	Authenticator         *auth.Authenticator
+	Idempotency           *middleware.Idempotency
+	Tracing               *middleware.Tracing
+	AccessLog             *middleware.AccessLog
+	Metrics               *middleware.Metrics
+	UserHandler           *handler.UserHandler
+	AttendanceHandler     *handler.AttendanceHandler
+	AuditHandler          *handler.AuditHandler
+	PunchLogHandler       *handler.PunchLogHandler
+	WebhookHandler        *handler.WebhookHandler
+	ChatHandler           *handler.ChatHandler
+	NotificationHandler   *handler.NotificationHandler
+	KioskHandler          *handler.KioskHandler
+	CardHandler           *handler.CardHandler
+	PunchPolicyHandler    *handler.PunchPolicyHandler
+	DashboardHandler      *handler.DashboardHandler
+	PresenceHandler       *handler.PresenceHandler
+	CalendarHandler       *handler.CalendarHandler
+	MetricsHandler        *handler.MetricsHandler
+	HealthHandler         *handler.HealthHandler
+	PunchLog              domain.PunchLogUsecase
+	PunchLogConfig        domain.PunchLogConfig
+	Chat                  domain.ChatUsecase
+	ChatConfig            domain.ChatConfig
+	MissingCheckOut       domain.MissingCheckOutUsecase
+	MissingCheckOutConfig domain.MissingCheckOutConfig
+	Presence              domain.PresenceUsecase
+	Relay                 *outbox.Relay
+	WebhookDispatcher     *webhook.Dispatcher
+	Mailer                *notify.Mailer
+	Scheduler             *scheduler.Scheduler
+	LiveHub               *live.Hub
+	LiveBroker            live.Broker
+	DBMetrics             *metrics.DBMetrics
+	TracerProvider        *sdktrace.TracerProvider
+	DBTracing             *tracing.DBTracing
+	Health                *health.Checker
+	UserGRPCServer        *handler.UserGRPCServer
+	AttendanceGRPCServer  *handler.AttendanceGRPCServer
+	HealthGRPCServer      *handler.HealthGRPCServer
 }
 
 // NewApp はアプリケーション全体の構造体を作成する
-func NewApp(userHandler *handler.UserHandler) *App {
+func NewApp(
+	authenticator *auth.Authenticator,
+	idempotency *middleware.Idempotency,
+	middlewareTracing *middleware.Tracing,
+	accessLog *middleware.AccessLog,
+	middlewareMetrics *middleware.Metrics,
+	userHandler *handler.UserHandler,
+	attendanceHandler *handler.AttendanceHandler,
+	auditHandler *handler.AuditHandler,
+	punchLogHandler *handler.PunchLogHandler,
+	webhookHandler *handler.WebhookHandler,
+	chatHandler *handler.ChatHandler,
+	notificationHandler *handler.NotificationHandler,
+	kioskHandler *handler.KioskHandler,
+	cardHandler *handler.CardHandler,
+	punchPolicyHandler *handler.PunchPolicyHandler,
+	dashboardHandler *handler.DashboardHandler,
+	presenceHandler *handler.PresenceHandler,
+	calendarHandler *handler.CalendarHandler,
+	metricsHandler *handler.MetricsHandler,
+	healthHandler *handler.HealthHandler,
+	punchLog domain.PunchLogUsecase,
+	punchLogConfig domain.PunchLogConfig,
+	chat domain.ChatUsecase,
+	chatConfig domain.ChatConfig,
+	missingCheckOut domain.MissingCheckOutUsecase,
+	missingCheckOutConfig domain.MissingCheckOutConfig,
+	presence domain.PresenceUsecase,
+	relay *outbox.Relay,
+	webhookDispatcher *webhook.Dispatcher,
+	mailer *notify.Mailer,
+	jobScheduler *scheduler.Scheduler,
+	liveHub *live.Hub,
+	liveBroker live.Broker,
+	dbMetrics *metrics.DBMetrics,
+	tracerProvider *sdktrace.TracerProvider,
+	dbTracing *tracing.DBTracing,
+	healthChecker *health.Checker,
+	userGRPCServer *handler.UserGRPCServer,
+	attendanceGRPCServer *handler.AttendanceGRPCServer,
+	healthGRPCServer *handler.HealthGRPCServer,
+) *App {
 	return &App{
-		UserHandler: userHandler,
+		Authenticator:         authenticator,
+		Idempotency:           idempotency,
+		Tracing:               middlewareTracing,
+		AccessLog:             accessLog,
+		Metrics:               middlewareMetrics,
+		UserHandler:           userHandler,
+		AttendanceHandler:     attendanceHandler,
+		AuditHandler:          auditHandler,
+		PunchLogHandler:       punchLogHandler,
+		WebhookHandler:        webhookHandler,
+		ChatHandler:           chatHandler,
+		NotificationHandler:   notificationHandler,
+		KioskHandler:          kioskHandler,
+		CardHandler:           cardHandler,
+		PunchPolicyHandler:    punchPolicyHandler,
+		DashboardHandler:      dashboardHandler,
+		PresenceHandler:       presenceHandler,
+		CalendarHandler:       calendarHandler,
+		MetricsHandler:        metricsHandler,
+		HealthHandler:         healthHandler,
+		PunchLog:              punchLog,
+		PunchLogConfig:        punchLogConfig,
+		Chat:                  chat,
+		ChatConfig:            chatConfig,
+		MissingCheckOut:       missingCheckOut,
+		MissingCheckOutConfig: missingCheckOutConfig,
+		Presence:              presence,
+		Relay:                 relay,
+		WebhookDispatcher:     webhookDispatcher,
+		Mailer:                mailer,
+		Scheduler:             jobScheduler,
+		LiveHub:               liveHub,
+		LiveBroker:            liveBroker,
+		DBMetrics:             dbMetrics,
+		TracerProvider:        tracerProvider,
+		DBTracing:             dbTracing,
+		Health:                healthChecker,
+		UserGRPCServer:        userGRPCServer,
+		AttendanceGRPCServer:  attendanceGRPCServer,
+		HealthGRPCServer:      healthGRPCServer,
 	}
 }
+
+// Admin は管理用CLIが使うユースケース
+type Admin struct {
+	Users       domain.UserUsecase
+	UserImports domain.UserImportUsecase
+	Attendance  domain.AttendanceUsecase
+	Tx          repository.UnitOfWork
+}
+
+// NewAdmin は管理用CLIが使うユースケースの構造体を作成する
+func NewAdmin(users domain.UserUsecase, imports domain.UserImportUsecase, attendance domain.AttendanceUsecase, tx repository.UnitOfWork) *Admin {
+	return &Admin{Users: users, UserImports: imports, Attendance: attendance, Tx: tx}
+}